@@ -22,7 +22,9 @@ import (
 	"os"
 
 	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
 	"github.com/ionchain/ionchain-core/common/math"
+	"github.com/ionchain/ionchain-core/consensus/ipos"
 	//"github.com/ionchain/ionchain-core/consensus/misc"
 	"github.com/ionchain/ionchain-core/core"
 	"github.com/ionchain/ionchain-core/core/rawdb"
@@ -53,6 +55,13 @@ type ExecutionResult struct {
 	Bloom       types.Bloom    `json:"logsBloom"        gencodec:"required"`
 	Receipts    types.Receipts `json:"receipts"`
 	Rejected    []int          `json:"rejected,omitempty"`
+
+	// IPos header fields, only set if the IPos engine was used
+	Difficulty          *math.HexOrDecimal256 `json:"currentDifficulty,omitempty"`
+	BaseTarget          *math.HexOrDecimal256 `json:"currentBaseTarget,omitempty"`
+	GenerationSignature hexutil.Bytes         `json:"currentGenerationSignature,omitempty"`
+	BlockSignature      hexutil.Bytes         `json:"currentBlockSignature,omitempty"`
+	SealHash            *common.Hash          `json:"sealHash,omitempty"`
 }
 
 type ommer struct {
@@ -60,6 +69,15 @@ type ommer struct {
 	Address common.Address `json:"address"`
 }
 
+// ancestor contains the header fields of a preceding block the IPos engine and
+// the IPos staking precompile derive the fields of the current block from.
+type ancestor struct {
+	Timestamp           math.HexOrDecimal64   `json:"timestamp"`
+	GasLimit            math.HexOrDecimal64   `json:"gasLimit"`
+	BaseTarget          *math.HexOrDecimal256 `json:"baseTarget"`
+	GenerationSignature hexutil.Bytes         `json:"generationSignature"`
+}
+
 //go:generate gencodec -type stEnv -field-override stEnvMarshaling -out gen_stenv.go
 type stEnv struct {
 	Coinbase    common.Address                      `json:"currentCoinbase"   gencodec:"required"`
@@ -69,19 +87,110 @@ type stEnv struct {
 	Timestamp   uint64                              `json:"currentTimestamp"  gencodec:"required"`
 	BlockHashes map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
 	Ommers      []ommer                             `json:"ommers,omitempty"`
+
+	BaseTarget          *big.Int                         `json:"currentBaseTarget,omitempty"`
+	GenerationSignature []byte                           `json:"currentGenerationSignature,omitempty"`
+	BlockSignature      []byte                           `json:"currentBlockSignature,omitempty"`
+	Ancestors           map[math.HexOrDecimal64]ancestor `json:"ancestors,omitempty"`
 }
 
 type stEnvMarshaling struct {
-	Coinbase   common.UnprefixedAddress
-	Difficulty *math.HexOrDecimal256
-	GasLimit   math.HexOrDecimal64
-	Number     math.HexOrDecimal64
-	Timestamp  math.HexOrDecimal64
+	Coinbase            common.UnprefixedAddress
+	Difficulty          *math.HexOrDecimal256
+	GasLimit            math.HexOrDecimal64
+	Number              math.HexOrDecimal64
+	Timestamp           math.HexOrDecimal64
+	BaseTarget          *math.HexOrDecimal256
+	GenerationSignature hexutil.Bytes
+	BlockSignature      hexutil.Bytes
+}
+
+// ancestorChain is a consensus.ChainHeaderReader serving the ancestors of the
+// env to the IPos engine. The env carries no ancestor hashes, so the headers
+// are looked up by number only.
+type ancestorChain struct {
+	config  *params.ChainConfig
+	headers map[uint64]*types.Header
+}
+
+// newAncestorChain assembles the ancestor headers of the env.
+func newAncestorChain(config *params.ChainConfig, env *stEnv) *ancestorChain {
+	headers := make(map[uint64]*types.Header)
+	for number, a := range env.Ancestors {
+		headers[uint64(number)] = &types.Header{
+			ParentHash:          env.BlockHashes[number-1],
+			Number:              new(big.Int).SetUint64(uint64(number)),
+			GasLimit:            uint64(a.GasLimit),
+			Time:                uint64(a.Timestamp),
+			BaseTarget:          (*big.Int)(a.BaseTarget),
+			GenerationSignature: a.GenerationSignature,
+		}
+	}
+	return &ancestorChain{config: config, headers: headers}
+}
+
+func (c *ancestorChain) Config() *params.ChainConfig { return c.config }
+
+// CurrentHeader retrieves the ancestor with the highest number.
+func (c *ancestorChain) CurrentHeader() *types.Header {
+	var current *types.Header
+	for _, header := range c.headers {
+		if current == nil || header.Number.Cmp(current.Number) > 0 {
+			current = header
+		}
+	}
+	return current
+}
+
+// GetHeader retrieves an ancestor by number, ignoring the hash.
+func (c *ancestorChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.headers[number]
+}
+
+// GetHeaderByNumber retrieves an ancestor by number.
+func (c *ancestorChain) GetHeaderByNumber(number uint64) *types.Header {
+	return c.headers[number]
+}
+
+// GetHeaderByHash is not supported, as the env carries no ancestor hashes.
+func (c *ancestorChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return nil
+}
+
+// checkAncestors ensures all the ancestors the IPos engine needs to derive the
+// fields of a header at the given number are available.
+func (c *ancestorChain) checkAncestors(number uint64) error {
+	if number == 0 {
+		return fmt.Errorf("the genesis block is not supported")
+	}
+	required := []uint64{number - 1}
+	if parent := number - 1; parent > 2 && parent%2 == 0 {
+		// The base target is retargeted on the time of the last three blocks
+		required = append(required, number-2, number-3)
+	}
+	for _, n := range required {
+		header := c.headers[n]
+		if header == nil {
+			return fmt.Errorf("ancestor %d not provided", n)
+		}
+		if header.BaseTarget == nil || len(header.GenerationSignature) == 0 {
+			return fmt.Errorf("ancestor %d misses the base target or generation signature", n)
+		}
+		if header.BaseTarget.Sign() <= 0 {
+			return fmt.Errorf("ancestor %d has non-positive base target %v", n, header.BaseTarget)
+		}
+	}
+	return nil
 }
 
 // Apply applies a set of transactions to a pre-state
+//
+// If an IPos engine is given, the IPos header fields missing from the env are
+// derived from its ancestors, the header is verified after the transactions
+// have been applied and it's finalized by the engine instead of applying the
+// mining reward.
 func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig *params.ChainConfig,
-	txs types.Transactions, miningReward int64,
+	txs types.Transactions, miningReward int64, engine *ipos.IPos,
 	getTracerFn func(txIndex int, txHash common.Hash) (tracer vm.Tracer, err error)) (*state.StateDB, *ExecutionResult, error) {
 
 	// Capture errors for BLOCKHASH operation, if we haven't been supplied the
@@ -98,6 +207,13 @@ func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig *params.ChainConfig,
 		}
 		return h
 	}
+	getGenerationSignature := func(num uint64) []byte {
+		a, ok := pre.Env.Ancestors[math.HexOrDecimal64(num)]
+		if !ok {
+			hashError = fmt.Errorf("getGenerationSignature(%d) invoked, ancestor not provided", num)
+		}
+		return a.GenerationSignature
+	}
 	var (
 		statedb     = MakePreState(rawdb.NewMemoryDatabase(), pre.Pre)
 		signer      = types.MakeSigner(chainConfig, new(big.Int).SetUint64(pre.Env.Number))
@@ -108,17 +224,50 @@ func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig *params.ChainConfig,
 		gasUsed     = uint64(0)
 		receipts    = make(types.Receipts, 0)
 		txIndex     = 0
+		chain       = newAncestorChain(chainConfig, &pre.Env)
+		header      = &types.Header{
+			ParentHash:          pre.Env.BlockHashes[math.HexOrDecimal64(pre.Env.Number-1)],
+			UncleHash:           types.EmptyUncleHash,
+			Coinbase:            pre.Env.Coinbase,
+			Difficulty:          pre.Env.Difficulty,
+			Number:              new(big.Int).SetUint64(pre.Env.Number),
+			GasLimit:            pre.Env.GasLimit,
+			Time:                pre.Env.Timestamp,
+			BaseTarget:          pre.Env.BaseTarget,
+			GenerationSignature: pre.Env.GenerationSignature,
+			BlockSignature:      pre.Env.BlockSignature,
+		}
 	)
+	if engine != nil {
+		if err := chain.checkAncestors(pre.Env.Number); err != nil {
+			return nil, nil, NewError(ErrorConsensus, err)
+		}
+		// The engine derives the difficulty from the time elapsed since the parent
+		if parent := chain.GetHeaderByNumber(pre.Env.Number - 1); header.Time <= parent.Time {
+			return nil, nil, NewError(ErrorConsensus, fmt.Errorf("current timestamp %d not after parent timestamp %d", header.Time, parent.Time))
+		}
+		if header.BaseTarget == nil {
+			header.BaseTarget = new(big.Int)
+			if err := engine.Prepare(chain, header); err != nil {
+				return nil, nil, NewError(ErrorConsensus, fmt.Errorf("could not prepare header: %v", err))
+			}
+		}
+		if len(header.GenerationSignature) == 0 {
+			header.GenerationSignature = engine.GenerationSignature(chain, header)
+		}
+	}
 	gaspool.AddGas(pre.Env.GasLimit)
 	vmContext := vm.BlockContext{
-		CanTransfer: core.CanTransfer,
-		Transfer:    core.Transfer,
-		Coinbase:    pre.Env.Coinbase,
-		BlockNumber: new(big.Int).SetUint64(pre.Env.Number),
-		Time:        new(big.Int).SetUint64(pre.Env.Timestamp),
-		Difficulty:  pre.Env.Difficulty,
-		GasLimit:    pre.Env.GasLimit,
-		GetHash:     getHash,
+		CanTransfer:            core.CanTransfer,
+		Transfer:               core.Transfer,
+		GetGenerationSignature: getGenerationSignature,
+		Coinbase:               pre.Env.Coinbase,
+		BlockNumber:            new(big.Int).SetUint64(pre.Env.Number),
+		Time:                   new(big.Int).SetUint64(pre.Env.Timestamp),
+		Difficulty:             header.Difficulty,
+		BaseTarget:             header.BaseTarget,
+		GasLimit:               pre.Env.GasLimit,
+		GetHash:                getHash,
 	}
 	// If DAO is supported/enabled, we need to handle it here. In ionc 'proper', it's
 	// done in StateProcessor.Process(block, ...), right before transactions are applied.
@@ -146,14 +295,7 @@ func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig *params.ChainConfig,
 
 		evm := vm.NewEVM(vmContext, txContext, statedb, chainConfig, vmConfig)
 		if chainConfig.IsYoloV2(vmContext.BlockNumber) {
-			statedb.AddAddressToAccessList(msg.From())
-			if dst := msg.To(); dst != nil {
-				statedb.AddAddressToAccessList(*dst)
-				// If it's a create-tx, the destination will be added inside evm.create
-			}
-			for _, addr := range evm.ActivePrecompiles() {
-				statedb.AddAddressToAccessList(addr)
-			}
+			statedb.PrepareAccessList(msg.From(), msg.To(), evm.ActivePrecompiles(), nil)
 		}
 		snapshot := statedb.Snapshot()
 		// (ret []byte, usedGas uint64, failed bool, err error)
@@ -197,8 +339,18 @@ func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig *params.ChainConfig,
 		txIndex++
 	}
 	statedb.IntermediateRoot(chainConfig.IsEIP158(vmContext.BlockNumber))
-	// Add mining reward?
-	if miningReward > 0 {
+	if engine != nil {
+		// IPos grants no block rewards, the engine only finalizes the state
+		header.GasUsed = gasUsed
+		header.TxHash = types.DeriveSha(includedTxs, new(trie.Trie))
+		header.ReceiptHash = types.DeriveSha(receipts, new(trie.Trie))
+		header.Bloom = types.CreateBloom(receipts)
+		engine.Finalize(chain, header, statedb, includedTxs, nil)
+
+		if err := engine.VerifyFields(chain, header, len(header.BlockSignature) > 0); err != nil {
+			return nil, nil, NewError(ErrorConsensus, fmt.Errorf("invalid header: %v", err))
+		}
+	} else if miningReward > 0 {
 		// Add mining reward. The mining reward may be `0`, which only makes a difference in the cases
 		// where
 		// - the coinbase suicided, or
@@ -236,6 +388,14 @@ func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig *params.ChainConfig,
 		Receipts:    receipts,
 		Rejected:    rejectedTxs,
 	}
+	if engine != nil {
+		sealHash := engine.SealHash(header)
+		execRs.Difficulty = (*math.HexOrDecimal256)(header.Difficulty)
+		execRs.BaseTarget = (*math.HexOrDecimal256)(header.BaseTarget)
+		execRs.GenerationSignature = header.GenerationSignature
+		execRs.BlockSignature = header.BlockSignature
+		execRs.SealHash = &sealHash
+	}
 	return statedb, execRs, nil
 }
 
@@ -88,6 +88,15 @@ var (
 		Usage: "ChainID to use",
 		Value: 1,
 	}
+	IPosFlag = cli.BoolFlag{
+		Name:  "state.ipos",
+		Usage: "Derive, finalize and verify the IPos header fields instead of applying the mining reward",
+	}
+	IPosStakingBlockFlag = cli.Int64Flag{
+		Name:  "state.iposstaking",
+		Usage: "Block number to activate the IPos staking precompile at. Set to -1 to disable",
+		Value: -1,
+	}
 	ForknameFlag = cli.StringFlag{
 		Name: "state.fork",
 		Usage: fmt.Sprintf("Name of ruleset to use."+
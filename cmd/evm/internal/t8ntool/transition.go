@@ -25,6 +25,7 @@ import (
 	"path"
 
 	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/consensus/ipos"
 	"github.com/ionchain/ionchain-core/core"
 	"github.com/ionchain/ionchain-core/core/state"
 	"github.com/ionchain/ionchain-core/core/types"
@@ -39,6 +40,7 @@ const (
 	ErrorEVM              = 2
 	ErrorVMConfig         = 3
 	ErrorMissingBlockhash = 4
+	ErrorConsensus        = 5
 
 	ErrorJson = 10
 	ErrorIO   = 11
@@ -200,8 +202,16 @@ func Main(ctx *cli.Context) error {
 	// Set the chain id
 	chainConfig.ChainID = big.NewInt(ctx.Int64(ChainIDFlag.Name))
 
+	// Set up the IonChain specific rules
+	if block := ctx.Int64(IPosStakingBlockFlag.Name); block >= 0 {
+		chainConfig.IPosStakingBlock = big.NewInt(block)
+	}
+	var engine *ipos.IPos
+	if ctx.Bool(IPosFlag.Name) {
+		engine = ipos.New(nil, "")
+	}
 	// Run the test and aggregate the result
-	state, result, err := prestate.Apply(vmConfig, chainConfig, txs, ctx.Int64(RewardFlag.Name), getTracer)
+	state, result, err := prestate.Apply(vmConfig, chainConfig, txs, ctx.Int64(RewardFlag.Name), engine, getTracer)
 	if err != nil {
 		return err
 	}
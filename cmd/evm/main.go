@@ -46,6 +46,20 @@ var (
 		Name:  "cpuprofile",
 		Usage: "creates a CPU profile at the given path",
 	}
+	ProfileFlag = cli.StringFlag{
+		Name:  "profile",
+		Usage: "writes an opcode gas and time profile to the given path, '-' for stdout",
+	}
+	ProfileFormatFlag = cli.StringFlag{
+		Name:  "profile.format",
+		Usage: "format of the profile, either json or folded (flame graph stacks)",
+		Value: "json",
+	}
+	ProfileWeightFlag = cli.StringFlag{
+		Name:  "profile.weight",
+		Usage: "weight of the folded stacks in the profile, either gas or time",
+		Value: "gas",
+	}
 	StatDumpFlag = cli.BoolFlag{
 		Name:  "statdump",
 		Usage: "displays stack and heap memory information",
@@ -155,6 +169,8 @@ var stateTransitionCommand = cli.Command{
 		t8ntool.ForknameFlag,
 		t8ntool.ChainIDFlag,
 		t8ntool.RewardFlag,
+		t8ntool.IPosFlag,
+		t8ntool.IPosStakingBlockFlag,
 		t8ntool.VerbosityFlag,
 	},
 }
@@ -175,6 +191,9 @@ func init() {
 		InputFileFlag,
 		MemProfileFlag,
 		CPUProfileFlag,
+		ProfileFlag,
+		ProfileFormatFlag,
+		ProfileWeightFlag,
 		StatDumpFlag,
 		GenesisFlag,
 		MachineFlag,
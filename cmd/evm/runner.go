@@ -118,13 +118,26 @@ func runCmd(ctx *cli.Context) error {
 	var (
 		tracer        vm.Tracer
 		debugLogger   *vm.StructLogger
+		profiler      *vm.Profiler
 		statedb       *state.StateDB
 		chainConfig   *params.ChainConfig
 		sender        = common.BytesToAddress([]byte("sender"))
 		receiver      = common.BytesToAddress([]byte("receiver"))
 		genesisConfig *core.Genesis
 	)
-	if ctx.GlobalBool(MachineFlag.Name) {
+	if ctx.GlobalString(ProfileFlag.Name) != "" {
+		if ctx.GlobalBool(MachineFlag.Name) || ctx.GlobalBool(DebugFlag.Name) {
+			utils.Fatalf("--%s can't be combined with --%s or --%s", ProfileFlag.Name, MachineFlag.Name, DebugFlag.Name)
+		}
+		if format := ctx.GlobalString(ProfileFormatFlag.Name); format != "json" && format != "folded" {
+			utils.Fatalf("Unknown profile format %q", format)
+		}
+		if weight := ctx.GlobalString(ProfileWeightFlag.Name); weight != "gas" && weight != "time" {
+			utils.Fatalf("Unknown profile weight %q", weight)
+		}
+		profiler = vm.NewProfiler()
+		tracer = profiler
+	} else if ctx.GlobalBool(MachineFlag.Name) {
 		tracer = vm.NewJSONLogger(logconfig, os.Stdout)
 	} else if ctx.GlobalBool(DebugFlag.Name) {
 		debugLogger = vm.NewStructLogger(logconfig)
@@ -212,7 +225,7 @@ func runCmd(ctx *cli.Context) error {
 		BlockNumber: new(big.Int).SetUint64(genesisConfig.Number),
 		EVMConfig: vm.Config{
 			Tracer:         tracer,
-			Debug:          ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name),
+			Debug:          ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name) || profiler != nil,
 			EVMInterpreter: ctx.GlobalString(EVMInterpreterFlag.Name),
 		},
 	}
@@ -286,6 +299,13 @@ func runCmd(ctx *cli.Context) error {
 		f.Close()
 	}
 
+	if profiler != nil {
+		if err := writeProfile(profiler, ctx.GlobalString(ProfileFlag.Name), ctx.GlobalString(ProfileFormatFlag.Name), ctx.GlobalString(ProfileWeightFlag.Name)); err != nil {
+			fmt.Println("could not write profile: ", err)
+			os.Exit(1)
+		}
+	}
+
 	if ctx.GlobalBool(DebugFlag.Name) {
 		if debugLogger != nil {
 			fmt.Fprintln(os.Stderr, "#### TRACE ####")
@@ -302,7 +322,7 @@ allocations:     %d
 allocated bytes: %d
 `, initialGas-leftOverGas, stats.time, stats.allocs, stats.bytesAllocated)
 	}
-	if tracer == nil {
+	if tracer == nil || profiler != nil {
 		fmt.Printf("0x%x\n", output)
 		if err != nil {
 			fmt.Printf(" error: %v\n", err)
@@ -311,3 +331,23 @@ allocated bytes: %d
 
 	return nil
 }
+
+// writeProfile writes the profile assembled by the profiler to the given path,
+// or to stdout if the path is '-'.
+func writeProfile(profiler *vm.Profiler, path, format, weight string) error {
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if format == "folded" {
+		return profiler.WriteFolded(out, weight)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(profiler.Result())
+}
@@ -81,6 +81,8 @@ The dumpgenesis command dumps the genesis block configuration in JSON format to
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
 			utils.SnapshotFlag,
+			utils.ParallelExecFlag,
+			utils.WitnessFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
 			utils.MetricsEnabledFlag,
@@ -209,6 +211,7 @@ Use "ethereum dump 0" to dump the genesis block.`,
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
 			utils.AncientFlag,
+			utils.AncientBlobsFlag,
 			utils.CacheFlag,
 			utils.RopstenFlag,
 			utils.RinkebyFlag,
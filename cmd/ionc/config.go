@@ -28,6 +28,7 @@ import (
 
 	"github.com/ionchain/ionchain-core/cmd/utils"
 	"github.com/ionchain/ionchain-core/ionc"
+	"github.com/ionchain/ionchain-core/ionc/downloader"
 	"github.com/ionchain/ionchain-core/internal/ioncapi"
 	"github.com/ionchain/ionchain-core/log"
 	"github.com/ionchain/ionchain-core/node"
@@ -117,6 +118,23 @@ func defaultNodeConfig() node.Config {
 
 // makeConfigNode loads ionc configuration and creates a blank node instance.
 func makeConfigNode(ctx *cli.Context) (*node.Node, ioncConfig) {
+	cfg := loadBaseConfig(ctx)
+	stack, err := node.New(&cfg.Node) //创建一个Node实例
+	if err != nil {
+		utils.Fatalf("Failed to create the protocol stack: %v", err)
+	}
+	utils.SetIoncConfig(ctx, stack, &cfg.Ionc)
+	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
+		cfg.Ethstats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
+	}
+	utils.SetShhConfig(ctx, stack)
+
+	return stack, cfg
+}
+
+// loadBaseConfig loads the ioncConfig based on the given command line
+// parameters and config file.
+func loadBaseConfig(ctx *cli.Context) ioncConfig {
 	// Load defaults.
 	cfg := ioncConfig{
 		Ionc: ionc.DefaultConfig,  //ionc的config，默认的是快速同步模式
@@ -136,17 +154,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, ioncConfig) {
 
 	// Apply flags.
 	utils.SetNodeConfig(ctx, &cfg.Node) //检查是否有global的配置用来覆盖默认配置
-	stack, err := node.New(&cfg.Node)   //创建一个Node实例
-	if err != nil {
-		utils.Fatalf("Failed to create the protocol stack: %v", err)
-	}
-	utils.SetIoncConfig(ctx, stack, &cfg.Ionc)
-	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
-		cfg.Ethstats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
-	}
-	utils.SetShhConfig(ctx, stack)
-
-	return stack, cfg
+	return cfg
 }
 
 // enableWhisper returns true in case one of the whisper flags is set.
@@ -167,7 +175,7 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ioncapi.Backend) {
 	checkWhisper(ctx)
 	// Configure GraphQL if requested
 	if ctx.GlobalIsSet(utils.GraphQLEnabledFlag.Name) {
-		utils.RegisterGraphQLService(stack, backend, cfg.Node)
+		utils.RegisterGraphQLService(stack, backend, cfg.Node, cfg.Ionc.SyncMode == downloader.LightSync)
 	}
 	// Add the IonChain Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
@@ -65,6 +65,7 @@ var (
 		utils.LegacyBootnodesV5Flag,
 		utils.DataDirFlag,
 		utils.AncientFlag,
+		utils.AncientBlobsFlag,
 		utils.KeyStoreDirFlag,
 		utils.ExternalSignerFlag,
 		utils.NoUSBFlag,
@@ -83,7 +84,12 @@ var (
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
+		utils.GCModeDiffLimitFlag,
 		utils.SnapshotFlag,
+		utils.ParallelExecFlag,
+		utils.WitnessFlag,
+		utils.CallTraceIndexFlag,
+		utils.LogIndexFlag,
 		utils.TxLookupLimitFlag,
 		utils.LightServeFlag,
 		utils.LegacyLightServFlag,
@@ -97,6 +103,8 @@ var (
 		utils.UltraLightFractionFlag,
 		utils.UltraLightOnlyAnnounceFlag,
 		utils.WhitelistFlag,
+		utils.ReorgMaxDepthFlag,
+		utils.ReorgCheckpointsFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheTrieFlag,
@@ -167,6 +175,12 @@ var (
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
 		utils.HTTPApiFlag,
+		utils.HTTPHealthFlag,
+		utils.HealthChecksFlag,
+		utils.ReadyChecksFlag,
+		utils.HealthMinPeersFlag,
+		utils.HealthSyncBlocksFlag,
+		utils.HealthHeadBlocksFlag,
 		utils.LegacyRPCApiFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
@@ -182,6 +196,22 @@ var (
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCTracerCustomFlag,
+		utils.RPCTracerStepsFlag,
+		utils.RPCTracerMemoryFlag,
+		utils.RPCTracerOutputFlag,
+		utils.TraceJobDirFlag,
+		utils.RPCAuthFileFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCResponseLimitFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCComputeLimitFlag,
+		utils.RPCLogsRangeFlag,
+		utils.RPCLogsBackfillFlag,
+		utils.RPCSlowThresholdFlag,
+		utils.RPCTLSCertFlag,
+		utils.RPCTLSKeyFlag,
+		utils.RPCTLSClientCAFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -237,6 +267,8 @@ func init() {
 		//licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See openrpccmd.go
+		openrpcCommand,
 		// See cmd/utils/flags_legacy.go
 		utils.ShowDeprecated,
 	}
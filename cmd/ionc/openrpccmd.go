@@ -0,0 +1,89 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of go-ionchain.
+//
+// go-ionchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ionchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ionchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/ionchain/ionchain-core/cmd/utils"
+	"github.com/ionchain/ionchain-core/node"
+	"github.com/ionchain/ionchain-core/params"
+	"github.com/ionchain/ionchain-core/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var openrpcCommand = cli.Command{
+	Action:    utils.MigrateFlags(dumpOpenRPC),
+	Name:      "openrpc",
+	Usage:     "Dump the OpenRPC discovery document of the RPC API",
+	ArgsUsage: "[<file>]",
+	Flags:     append(nodeFlags, rpcFlags...),
+	Category:  "MISCELLANEOUS COMMANDS",
+	Description: `
+The openrpc command writes the OpenRPC discovery document of all the RPC
+methods the node provides with the given configuration, in every namespace
+regardless of the modules exposed over HTTP or WebSocket, to the given file
+or to stdout. Client SDKs can be generated from it.
+
+The same document restricted to the exposed modules is served by running
+nodes through the rpc.discover method.`,
+}
+
+// dumpOpenRPC is the openrpc command. The APIs are assembled on an ephemeral node
+// keeping its databases in memory, so the data directory is neither opened nor
+// locked and the command can run alongside the node using it.
+func dumpOpenRPC(ctx *cli.Context) error {
+	cfg := loadBaseConfig(ctx)
+	cfg.Node.DataDir = ""
+
+	stack, err := node.New(&cfg.Node)
+	if err != nil {
+		return err
+	}
+	defer stack.Close()
+
+	utils.SetIoncConfig(ctx, stack, &cfg.Ionc)
+	utils.RegisterEthService(stack, &cfg.Ionc)
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	for _, api := range stack.APIs() {
+		if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+			return err
+		}
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	doc := new(rpc.OpenRPCDocument)
+	if err := client.Call(doc, "rpc_discover"); err != nil {
+		return err
+	}
+	doc.Info = rpc.OpenRPCInfo{Title: "IonChain JSON-RPC API", Version: params.VersionWithMeta}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if ctx.NArg() > 0 {
+		return ioutil.WriteFile(ctx.Args().Get(0), append(out, '\n'), 0644)
+	}
+	_, err = os.Stdout.Write(append(out, '\n'))
+	return err
+}
@@ -36,6 +36,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			configFileFlag,
 			utils.DataDirFlag,
 			utils.AncientFlag,
+			utils.AncientBlobsFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
 			utils.SmartCardDaemonPathFlag,
@@ -47,11 +48,14 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.SyncModeFlag,
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
+			utils.GCModeDiffLimitFlag,
 			utils.TxLookupLimitFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
+			utils.ReorgMaxDepthFlag,
+			utils.ReorgCheckpointsFlag,
 		},
 	},
 	{
@@ -122,6 +126,12 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.HTTPListenAddrFlag,
 			utils.HTTPPortFlag,
 			utils.HTTPApiFlag,
+			utils.HTTPHealthFlag,
+			utils.HealthChecksFlag,
+			utils.ReadyChecksFlag,
+			utils.HealthMinPeersFlag,
+			utils.HealthSyncBlocksFlag,
+			utils.HealthHeadBlocksFlag,
 			utils.HTTPCORSDomainFlag,
 			utils.HTTPVirtualHostsFlag,
 			utils.WSEnabledFlag,
@@ -134,6 +144,22 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.GraphQLVirtualHostsFlag,
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalTxFeeCapFlag,
+			utils.RPCTracerCustomFlag,
+			utils.RPCTracerStepsFlag,
+			utils.RPCTracerMemoryFlag,
+			utils.RPCTracerOutputFlag,
+			utils.TraceJobDirFlag,
+			utils.RPCAuthFileFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCResponseLimitFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCComputeLimitFlag,
+			utils.RPCLogsRangeFlag,
+			utils.RPCLogsBackfillFlag,
+			utils.RPCSlowThresholdFlag,
+			utils.RPCTLSCertFlag,
+			utils.RPCTLSKeyFlag,
+			utils.RPCTLSClientCAFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -226,6 +252,10 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Name: "MISC",
 		Flags: []cli.Flag{
 			utils.SnapshotFlag,
+			utils.ParallelExecFlag,
+			utils.WitnessFlag,
+			utils.CallTraceIndexFlag,
+			utils.LogIndexFlag,
 			cli.HelpFlag,
 		},
 	},
@@ -83,6 +83,19 @@ func StartNode(stack *node.Node) {
 		debug.Exit() // ensure trace and CPU profile data is flushed.
 		debug.LoudPanic("boom")
 	}()
+	// Reload the RPC TLS credentials on SIGHUP. The signal keeps its default
+	// behavior of terminating the process if the RPC endpoints don't serve TLS.
+	if stack.Config().RPCTLSCert != "" {
+		go func() {
+			sighup := make(chan os.Signal, 1)
+			signal.Notify(sighup, syscall.SIGHUP)
+			for range sighup {
+				if err := stack.ReloadTLS(); err != nil {
+					log.Error("Failed to reload RPC TLS credentials", "err", err)
+				}
+			}
+		}()
+	}
 }
 
 func ImportChain(chain *core.BlockChain, fn string) error {
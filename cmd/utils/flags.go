@@ -112,6 +112,10 @@ var (
 		Name:  "datadir.ancient",
 		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
 	}
+	AncientBlobsFlag = DirectoryFlag{
+		Name:  "datadir.ancient.blobs",
+		Usage: "Blob store directory for ancient chain segments shared by multiple nodes (overrides datadir.ancient)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -191,13 +195,33 @@ var (
 	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
-		Usage: `Blockchain garbage collection mode ("full", "archive")`,
+		Usage: `Blockchain garbage collection mode ("full", "archive", "diff")`,
 		Value: "full",
 	}
+	GCModeDiffLimitFlag = cli.Uint64Flag{
+		Name:  "gcmode.difflimit",
+		Usage: "Maximum number of blocks to roll the state back through reverse diffs in diff mode (default = 90000)",
+	}
 	SnapshotFlag = cli.BoolFlag{
 		Name:  "snapshot",
 		Usage: `Enables snapshot-database mode -- experimental work in progress feature`,
 	}
+	ParallelExecFlag = cli.BoolFlag{
+		Name:  "parallelexec",
+		Usage: "Execute block transactions optimistically in parallel -- experimental work in progress feature",
+	}
+	WitnessFlag = cli.BoolFlag{
+		Name:  "witness",
+		Usage: "Record stateless witnesses of the imported blocks (served via debug_getBlockWitness)",
+	}
+	CallTraceIndexFlag = cli.BoolFlag{
+		Name:  "trace.index",
+		Usage: "Index the flattened call traces of the canonical chain (served via trace_*)",
+	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Index the logs of the canonical chain by address and topic for fast log queries over long ranges",
+	}
 	TxLookupLimitFlag = cli.Int64Flag{
 		Name:  "txlookuplimit",
 		Usage: "Number of recent blocks to maintain transactions index by-hash for (default = index all blocks)",
@@ -211,6 +235,14 @@ var (
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>)",
 	}
+	ReorgMaxDepthFlag = cli.Uint64Flag{
+		Name:  "reorg.maxdepth",
+		Usage: "Maximum number of canonical blocks a chain reorg may drop (0 = unlimited)",
+	}
+	ReorgCheckpointsFlag = cli.StringFlag{
+		Name:  "reorg.checkpoints",
+		Usage: "Comma separated block number-to-hash checkpoints no chain reorg may cross (<number>=<hash>)",
+	}
 	// Light server and client settings
 	LightServeFlag = cli.IntFlag{
 		Name:  "light.serve",
@@ -467,6 +499,74 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ionc.DefaultConfig.RPCTxFeeCap,
 	}
+	RPCTracerCustomFlag = cli.BoolFlag{
+		Name:  "rpc.tracer.custom",
+		Usage: "Allow tracing with custom JavaScript code, not only the tracers built into the node",
+	}
+	RPCTracerStepsFlag = cli.Uint64Flag{
+		Name:  "rpc.tracer.steps",
+		Usage: "Sets a cap on the steps a JavaScript tracer can execute per transaction (0 = no cap)",
+		Value: ionc.DefaultConfig.RPCTracerSteps,
+	}
+	RPCTracerMemoryFlag = cli.Uint64Flag{
+		Name:  "rpc.tracer.memory",
+		Usage: "Sets a cap on the state size (in bytes) a JavaScript tracer can gather per transaction (0 = no cap)",
+		Value: ionc.DefaultConfig.RPCTracerMemory,
+	}
+	RPCTracerOutputFlag = cli.Uint64Flag{
+		Name:  "rpc.tracer.output",
+		Usage: "Sets a cap on the result size (in bytes) of a JavaScript tracer per transaction (0 = no cap)",
+		Value: ionc.DefaultConfig.RPCTracerOutput,
+	}
+	TraceJobDirFlag = DirectoryFlag{
+		Name:  "tracejobs.dir",
+		Usage: "Directory the outputs of the chain tracing jobs are confined to (default = inside the datadir)",
+	}
+	RPCAuthFileFlag = cli.StringFlag{
+		Name:  "rpc.authfile",
+		Usage: "JSON file of the JWT secret and API keys required by the HTTP and WebSocket RPC endpoints, with their allowed namespaces",
+	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in an HTTP or WebSocket RPC batch (0 = no limit)",
+	}
+	RPCResponseLimitFlag = cli.IntFlag{
+		Name:  "rpc.responselimit",
+		Usage: "Maximum size (in bytes) of the response to an HTTP or WebSocket RPC request or batch (0 = no limit)",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit",
+		Usage: "Requests per second each HTTP or WebSocket RPC client (token or IP) may send (0 = no limit)",
+	}
+	RPCComputeLimitFlag = cli.Float64Flag{
+		Name:  "rpc.computelimit",
+		Usage: "Compute units per second each HTTP or WebSocket RPC client (token or IP) may spend (0 = no limit)",
+	}
+	RPCLogsRangeFlag = cli.Uint64Flag{
+		Name:  "rpc.logsrange",
+		Usage: "Maximum number of blocks a log query over RPC may span (0 = no limit)",
+	}
+	RPCLogsBackfillFlag = cli.Uint64Flag{
+		Name:  "rpc.logsbackfill",
+		Usage: "Maximum number of blocks a log subscription may backfill logs of (0 = no limit)",
+		Value: ionc.DefaultConfig.RPCLogsBackfill,
+	}
+	RPCSlowThresholdFlag = cli.DurationFlag{
+		Name:  "rpc.slowthreshold",
+		Usage: "Log HTTP and WebSocket RPC calls taking longer than this (0 = don't log)",
+	}
+	RPCTLSCertFlag = cli.StringFlag{
+		Name:  "rpc.tlscert",
+		Usage: "PEM certificate chain to serve the HTTP and WebSocket RPC endpoints over TLS with (reloaded on SIGHUP)",
+	}
+	RPCTLSKeyFlag = cli.StringFlag{
+		Name:  "rpc.tlskey",
+		Usage: "PEM private key of the HTTP and WebSocket RPC TLS certificate (reloaded on SIGHUP)",
+	}
+	RPCTLSClientCAFlag = cli.StringFlag{
+		Name:  "rpc.tlsclientca",
+		Usage: "PEM certificate authorities the HTTP and WebSocket RPC clients must present a certificate of (reloaded on SIGHUP)",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ioncstats",
@@ -518,6 +618,35 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	HTTPHealthFlag = cli.BoolFlag{
+		Name:  "http.health",
+		Usage: "Enable the /health and /ready endpoints on the HTTP-RPC server",
+	}
+	HealthChecksFlag = cli.StringFlag{
+		Name:  "health.checks",
+		Usage: "Comma separated list of checks the /health endpoint requires to pass (sync, head, peers, forger)",
+		Value: strings.Join(node.DefaultConfig.HealthChecks, ","),
+	}
+	ReadyChecksFlag = cli.StringFlag{
+		Name:  "health.ready",
+		Usage: "Comma separated list of checks the /ready endpoint requires to pass (sync, head, peers, forger)",
+		Value: strings.Join(node.DefaultConfig.ReadyChecks, ","),
+	}
+	HealthMinPeersFlag = cli.IntFlag{
+		Name:  "health.minpeers",
+		Usage: "Number of peers below which the peers check fails",
+		Value: node.DefaultConfig.HealthMinPeers,
+	}
+	HealthSyncBlocksFlag = cli.Uint64Flag{
+		Name:  "health.syncblocks",
+		Usage: "Number of blocks the chain may be behind the peers before the sync check fails",
+		Value: node.DefaultConfig.HealthSyncBlocks,
+	}
+	HealthHeadBlocksFlag = cli.Uint64Flag{
+		Name:  "health.headblocks",
+		Usage: "Age of the head block, in block times, above which the head check fails",
+		Value: node.DefaultConfig.HealthHeadBlocks,
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
@@ -941,6 +1070,25 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(HTTPVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = SplitAndTrim(ctx.GlobalString(HTTPVirtualHostsFlag.Name))
 	}
+
+	if ctx.GlobalIsSet(HTTPHealthFlag.Name) {
+		cfg.HTTPHealth = ctx.GlobalBool(HTTPHealthFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthChecksFlag.Name) {
+		cfg.HealthChecks = SplitAndTrim(ctx.GlobalString(HealthChecksFlag.Name))
+	}
+	if ctx.GlobalIsSet(ReadyChecksFlag.Name) {
+		cfg.ReadyChecks = SplitAndTrim(ctx.GlobalString(ReadyChecksFlag.Name))
+	}
+	if ctx.GlobalIsSet(HealthMinPeersFlag.Name) {
+		cfg.HealthMinPeers = ctx.GlobalInt(HealthMinPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthSyncBlocksFlag.Name) {
+		cfg.HealthSyncBlocks = ctx.GlobalUint64(HealthSyncBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthHeadBlocksFlag.Name) {
+		cfg.HealthHeadBlocks = ctx.GlobalUint64(HealthHeadBlocksFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -1221,6 +1369,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
 	}
+	if ctx.GlobalIsSet(AncientBlobsFlag.Name) {
+		cfg.AncientBlobDir = ctx.GlobalString(AncientBlobsFlag.Name)
+	}
 	if ctx.GlobalIsSet(LightKDFFlag.Name) {
 		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	}
@@ -1230,6 +1381,33 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(InsecureUnlockAllowedFlag.Name) {
 		cfg.InsecureUnlockAllowed = ctx.GlobalBool(InsecureUnlockAllowedFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCAuthFileFlag.Name) {
+		cfg.RPCAuthFile = ctx.GlobalString(RPCAuthFileFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCBatchLimit = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		cfg.RPCResponseLimit = ctx.GlobalInt(RPCResponseLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCComputeLimitFlag.Name) {
+		cfg.RPCComputeLimit = ctx.GlobalFloat64(RPCComputeLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCSlowThresholdFlag.Name) {
+		cfg.RPCSlowThreshold = ctx.GlobalDuration(RPCSlowThresholdFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSCertFlag.Name) {
+		cfg.RPCTLSCert = ctx.GlobalString(RPCTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSKeyFlag.Name) {
+		cfg.RPCTLSKey = ctx.GlobalString(RPCTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSClientCAFlag.Name) {
+		cfg.RPCTLSClientCA = ctx.GlobalString(RPCTLSClientCAFlag.Name)
+	}
 }
 
 func setSmartCard(ctx *cli.Context, cfg *node.Config) {
@@ -1433,6 +1611,32 @@ func setWhitelist(ctx *cli.Context, cfg *ionc.Config) {
 	}
 }
 
+func setReorgProtection(ctx *cli.Context, cfg *ionc.Config) {
+	if ctx.GlobalIsSet(ReorgMaxDepthFlag.Name) {
+		cfg.MaxReorgDepth = ctx.GlobalUint64(ReorgMaxDepthFlag.Name)
+	}
+	checkpoints := ctx.GlobalString(ReorgCheckpointsFlag.Name)
+	if checkpoints == "" {
+		return
+	}
+	cfg.ReorgCheckpoints = nil
+	for _, entry := range strings.Split(checkpoints, ",") {
+		parts := strings.Split(entry, "=")
+		if len(parts) != 2 {
+			Fatalf("Invalid reorg checkpoint entry: %s", entry)
+		}
+		number, err := strconv.ParseUint(parts[0], 0, 64)
+		if err != nil {
+			Fatalf("Invalid reorg checkpoint block number %s: %v", parts[0], err)
+		}
+		var hash common.Hash
+		if err = hash.UnmarshalText([]byte(parts[1])); err != nil {
+			Fatalf("Invalid reorg checkpoint hash %s: %v", parts[1], err)
+		}
+		cfg.ReorgCheckpoints = append(cfg.ReorgCheckpoints, core.Checkpoint{Number: number, Hash: hash})
+	}
+}
+
 // CheckExclusive verifies that only a single instance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -1505,6 +1709,7 @@ func SetIoncConfig(ctx *cli.Context, stack *node.Node, cfg *ionc.Config) {
 	//setEthash(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)//设置矿工相关参数
 	setWhitelist(ctx, cfg)
+	setReorgProtection(ctx, cfg)
 	setLes(ctx, cfg)
 
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
@@ -1521,15 +1726,34 @@ func SetIoncConfig(ctx *cli.Context, stack *node.Node, cfg *ionc.Config) {
 		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
 	}
 
-	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
-		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" && gcmode != "diff" {
+		Fatalf("--%s must be either 'full', 'archive' or 'diff'", GCModeFlag.Name)
 	}
 	if ctx.GlobalIsSet(GCModeFlag.Name) {
 		cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+		cfg.StateDiffs = ctx.GlobalString(GCModeFlag.Name) == "diff"
+	}
+	if cfg.StateDiffs && !ctx.GlobalIsSet(SnapshotFlag.Name) {
+		Fatalf("--%s=diff requires --%s", GCModeFlag.Name, SnapshotFlag.Name)
+	}
+	if ctx.GlobalIsSet(GCModeDiffLimitFlag.Name) {
+		cfg.StateDiffLimit = ctx.GlobalUint64(GCModeDiffLimitFlag.Name)
 	}
 	if ctx.GlobalIsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.GlobalBool(CacheNoPrefetchFlag.Name)
 	}
+	if ctx.GlobalIsSet(ParallelExecFlag.Name) {
+		cfg.ParallelExec = ctx.GlobalBool(ParallelExecFlag.Name)
+	}
+	if ctx.GlobalIsSet(WitnessFlag.Name) {
+		cfg.Witnesses = ctx.GlobalBool(WitnessFlag.Name)
+	}
+	if ctx.GlobalIsSet(CallTraceIndexFlag.Name) {
+		cfg.CallTraceIndex = ctx.GlobalBool(CallTraceIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
 	// Read the value from the flag no matter if it's set or not.
 	cfg.Preimages = ctx.GlobalBool(CachePreimagesFlag.Name)
 	if cfg.NoPruning && !cfg.Preimages {
@@ -1584,6 +1808,27 @@ func SetIoncConfig(ctx *cli.Context, stack *node.Node, cfg *ionc.Config) {
 	if ctx.GlobalIsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.GlobalFloat64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCTracerCustomFlag.Name) {
+		cfg.RPCTracerCustom = ctx.GlobalBool(RPCTracerCustomFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTracerStepsFlag.Name) {
+		cfg.RPCTracerSteps = ctx.GlobalUint64(RPCTracerStepsFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTracerMemoryFlag.Name) {
+		cfg.RPCTracerMemory = ctx.GlobalUint64(RPCTracerMemoryFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTracerOutputFlag.Name) {
+		cfg.RPCTracerOutput = ctx.GlobalUint64(RPCTracerOutputFlag.Name)
+	}
+	if ctx.GlobalIsSet(TraceJobDirFlag.Name) {
+		cfg.TraceJobDir = ctx.GlobalString(TraceJobDirFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCLogsRangeFlag.Name) {
+		cfg.RPCLogsRange = ctx.GlobalUint64(RPCLogsRangeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCLogsBackfillFlag.Name) {
+		cfg.RPCLogsBackfill = ctx.GlobalUint64(RPCLogsBackfillFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) {
 		cfg.DiscoveryURLs = []string{}
 	} else if ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
@@ -1720,8 +1965,8 @@ func RegisterEthStatsService(stack *node.Node, backend ioncapi.Backend, url stri
 }
 
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
-func RegisterGraphQLService(stack *node.Node, backend ioncapi.Backend, cfg node.Config) {
-	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
+func RegisterGraphQLService(stack *node.Node, backend ioncapi.Backend, cfg node.Config, lightMode bool) {
+	if err := graphql.New(stack, backend, lightMode, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
 		Fatalf("Failed to register the GraphQL service: %v", err)
 	}
 }
@@ -1822,8 +2067,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readOnly bool) (chain *core.B
 
 	engine = ipos.New(chainDb, stack.IPCEndpoint())
 
-	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
-		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" && gcmode != "diff" {
+		Fatalf("--%s must be either 'full', 'archive' or 'diff'", GCModeFlag.Name)
 	}
 	cache := &core.CacheConfig{
 		TrieCleanLimit:      ionc.DefaultConfig.TrieCleanCache,
@@ -1833,6 +2078,10 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readOnly bool) (chain *core.B
 		TrieTimeLimit:       ionc.DefaultConfig.TrieTimeout,
 		SnapshotLimit:       ionc.DefaultConfig.SnapshotCache,
 		Preimages:           ctx.GlobalBool(CachePreimagesFlag.Name),
+		StateDiffs:          ctx.GlobalString(GCModeFlag.Name) == "diff",
+		StateDiffLimit:      ctx.GlobalUint64(GCModeDiffLimitFlag.Name),
+		ParallelExec:        ctx.GlobalBool(ParallelExecFlag.Name),
+		Witnesses:           ctx.GlobalBool(WitnessFlag.Name),
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
@@ -582,6 +582,21 @@ func (c *IPos) Authorize(signer common.Address, signFn SignerFn) {
 	c.signFn = signFn
 }
 
+// Signer returns the address authorized to mint blocks, or the zero address if
+// none is.
+func (c *IPos) Signer() common.Address {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.signer
+}
+
+// MintPower retrieves the effective deposit of the given address from the IPOS
+// contract, in ether.
+func (c *IPos) MintPower(addr common.Address) (*big.Int, error) {
+	return mintPower(addr, c.IpcEndpoint)
+}
+
 //获取当前节点地址和父块签名的总hash
 func (c *IPos) getHit(chain consensus.ChainHeaderReader, header *types.Header) *big.Int {
 	parentHeader := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
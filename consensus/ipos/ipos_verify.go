@@ -103,3 +103,31 @@ func (c *IPos)ecrecover(header *types.Header) (common.Address, error) {
 	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
 	return signer, nil
 }
+
+// VerifyFields checks the IPos specific fields of a header, the generation
+// signature, the base target, the difficulty and optionally the block signature,
+// against its ancestors. Contrary to VerifySeal it does not check the hit, as
+// that needs the mint power of the coinbase from the staking contract.
+func (c *IPos) VerifyFields(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	if header.Number.Uint64() == 0 {
+		return errUnknownBlock
+	}
+	if parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if seal {
+		if err := c.verifyBlockSignature(chain, header); err != nil {
+			return err
+		}
+	}
+	if err := c.verifyGenerationSignature(chain, header); err != nil {
+		return err
+	}
+	return c.verifyBaseTarget(chain, header)
+}
+
+// GenerationSignature derives the generation signature of a header from the
+// one of its parent and the coinbase of the header.
+func (c *IPos) GenerationSignature(chain consensus.ChainHeaderReader, header *types.Header) []byte {
+	return c.generationSignature(chain, header)
+}
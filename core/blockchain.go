@@ -80,6 +80,8 @@ var (
 	blockPrefetchInterruptMeter = metrics.NewRegisteredMeter("chain/prefetch/interrupts", nil)
 
 	errInsertionInterrupted = errors.New("insertion is interrupted")
+
+	errStateDiffsDisabled = errors.New("state diff recording disabled")
 )
 
 const (
@@ -92,6 +94,10 @@ const (
 	badBlockLimit       = 10
 	TriesInMemory       = 128
 
+	// defaultStateDiffLimit is the maximum number of reverse state diffs applied
+	// to reconstruct a historical state if no limit was configured.
+	defaultStateDiffLimit = 90000
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	//
 	// Changelog:
@@ -130,6 +136,10 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	StateDiffs          bool          // Whether to store reverse state diffs for serving historical state
+	StateDiffLimit      uint64        // Maximum number of blocks to roll the head state back through reverse diffs
+	ParallelExec        bool          // Whether to execute block transactions optimistically in parallel
+	Witnesses           bool          // Whether to record stateless witnesses of the processed blocks
 
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
@@ -174,15 +184,16 @@ type BlockChain struct {
 	//  * nil: disable tx reindexer/deleter, but still index new blocks
 	txLookupLimit uint64
 
-	hc            *HeaderChain
-	rmLogsFeed    event.Feed
-	chainFeed     event.Feed
-	chainSideFeed event.Feed
-	chainHeadFeed event.Feed
-	logsFeed      event.Feed
-	blockProcFeed event.Feed
-	scope         event.SubscriptionScope
-	genesisBlock  *types.Block
+	hc              *HeaderChain
+	rmLogsFeed      event.Feed
+	chainFeed       event.Feed
+	chainSideFeed   event.Feed
+	chainHeadFeed   event.Feed
+	logsFeed        event.Feed
+	blockProcFeed   event.Feed
+	reorgRejectFeed event.Feed
+	scope           event.SubscriptionScope
+	genesisBlock    *types.Block
 
 	chainmu sync.RWMutex // blockchain insertion lock
 
@@ -208,6 +219,10 @@ type BlockChain struct {
 	processor  Processor  // Block transaction processor interface
 	vmConfig   vm.Config
 
+	reorgLock     sync.RWMutex           // Lock protecting the reorg restrictions
+	maxReorgDepth uint64                 // Maximum number of canonical blocks a reorg may drop (0 = unlimited)
+	checkpoints   map[uint64]common.Hash // Operator pinned blocks which may not be reorged away
+
 	badBlocks          *lru.Cache                     // Bad block cache
 	shouldPreserve     func(*types.Block) bool        // Function used to determine whether should preserve the given block.
 	terminateInsert    func(common.Hash, uint64) bool // Testing hook used to terminate ancient receipt chain insertion.
@@ -249,6 +264,7 @@ func NewBlockChain(db ioncdb.Database, cacheConfig *CacheConfig, chainConfig *pa
 		futureBlocks:   futureBlocks,
 		engine:         engine,
 		vmConfig:       vmConfig,
+		checkpoints:    rawdb.ReadReorgCheckpoints(db),
 		badBlocks:      badBlocks,
 	}
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
@@ -297,7 +313,7 @@ func NewBlockChain(db ioncdb.Database, cacheConfig *CacheConfig, chainConfig *pa
 		if diskRoot != (common.Hash{}) {
 			log.Warn("Head state missing, repairing", "number", head.Number(), "hash", head.Hash(), "snaproot", diskRoot)
 
-			snapDisk, err := bc.SetHeadBeyondRoot(head.NumberU64(), diskRoot)
+			snapDisk, err := bc.setHeadBeyondRoot(head.NumberU64(), diskRoot)
 			if err != nil {
 				return nil, err
 			}
@@ -307,7 +323,7 @@ func NewBlockChain(db ioncdb.Database, cacheConfig *CacheConfig, chainConfig *pa
 			}
 		} else {
 			log.Warn("Head state missing, repairing", "number", head.Number(), "hash", head.Hash())
-			if err := bc.SetHead(head.NumberU64()); err != nil {
+			if _, err := bc.setHeadBeyondRoot(head.NumberU64(), common.Hash{}); err != nil {
 				return nil, err
 			}
 		}
@@ -338,7 +354,7 @@ func NewBlockChain(db ioncdb.Database, cacheConfig *CacheConfig, chainConfig *pa
 		}
 		if needRewind {
 			log.Error("Truncating ancient chain", "from", bc.CurrentHeader().Number.Uint64(), "to", low)
-			if err := bc.SetHead(low); err != nil {
+			if _, err := bc.setHeadBeyondRoot(low, common.Hash{}); err != nil {
 				return nil, err
 			}
 		}
@@ -356,7 +372,7 @@ func NewBlockChain(db ioncdb.Database, cacheConfig *CacheConfig, chainConfig *pa
 			// make sure the headerByNumber (if present) is in our current canonical chain
 			if headerByNumber != nil && headerByNumber.Hash() == header.Hash() {
 				log.Error("Found bad hash, rewinding chain", "number", header.Number, "hash", header.ParentHash)
-				if err := bc.SetHead(header.Number.Uint64() - 1); err != nil {
+				if _, err := bc.setHeadBeyondRoot(header.Number.Uint64()-1, common.Hash{}); err != nil {
 					return nil, err
 				}
 				log.Error("Chain rewind was successful, resuming normal operation")
@@ -480,6 +496,9 @@ func (bc *BlockChain) loadLastState() error {
 // SetHead rewinds the local chain to a new head. Depending on whether the node
 // was fast synced or full synced and in which state, the method will try to
 // delete minimal data from disk whilst retaining chain consistency.
+//
+// Rewinds dropping more blocks than the maximum reorg depth, or dropping any of
+// the pinned checkpoints, are refused.
 func (bc *BlockChain) SetHead(head uint64) error {
 	_, err := bc.SetHeadBeyondRoot(head, common.Hash{})
 	return err
@@ -493,7 +512,18 @@ func (bc *BlockChain) SetHead(head uint64) error {
 // retaining chain consistency.
 //
 // The method returns the block number where the requested root cap was found.
+// Like SetHead, it refuses to rewind the requested head beyond the reorg limits.
 func (bc *BlockChain) SetHeadBeyondRoot(head uint64, root common.Hash) (uint64, error) {
+	if err := bc.verifyRewind(head); err != nil {
+		return 0, err
+	}
+	return bc.setHeadBeyondRoot(head, root)
+}
+
+// setHeadBeyondRoot is the unrestricted version of SetHeadBeyondRoot, used for
+// repairing the chain on startup, where the head must be rewound regardless of
+// any reorg restrictions.
+func (bc *BlockChain) setHeadBeyondRoot(head uint64, root common.Hash) (uint64, error) {
 	bc.chainmu.Lock()
 	defer bc.chainmu.Unlock()
 
@@ -598,6 +628,8 @@ func (bc *BlockChain) SetHeadBeyondRoot(head uint64, root common.Hash) (uint64,
 			rawdb.DeleteBody(db, hash, num)
 			rawdb.DeleteReceipts(db, hash, num)
 		}
+		rawdb.DeleteStateDiff(db, hash, num)
+		rawdb.DeleteBlockWitness(db, hash, num)
 		// Todo(rjl493456442) txlookup, bloombits, etc
 	}
 	// If SetHead was only called as a chain reparation method, try to skip
@@ -694,6 +726,62 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache, bc.snaps)
 }
 
+// HistoricStateAt returns a read only state of an old block, reconstructed from
+// the snapshot of the current head and the reverse state diffs recorded for all
+// the blocks in between. It's only available if state diffs are recorded.
+func (bc *BlockChain) HistoricStateAt(header *types.Header) (*state.StateDB, error) {
+	if !bc.cacheConfig.StateDiffs || bc.snaps == nil {
+		return nil, errStateDiffsDisabled
+	}
+	head := bc.CurrentBlock().Header()
+	if header.Number.Uint64() > head.Number.Uint64() {
+		return nil, fmt.Errorf("block #%d above head #%d", header.Number, head.Number)
+	}
+	limit := bc.cacheConfig.StateDiffLimit
+	if limit == 0 {
+		limit = defaultStateDiffLimit
+	}
+	if depth := head.Number.Uint64() - header.Number.Uint64(); depth > limit {
+		return nil, fmt.Errorf("block #%d is %d blocks below head, state diff limit is %d", header.Number, depth, limit)
+	}
+	base := bc.snaps.Snapshot(head.Root)
+	if base == nil {
+		return nil, fmt.Errorf("snapshot of head #%d [%x..] unavailable", head.Number, head.Root.Bytes()[:4])
+	}
+	// Walk back from the head, collecting the reverse diffs of every block
+	var (
+		current = head
+		diffs   = make([]*state.StateDiff, 0, head.Number.Uint64()-header.Number.Uint64())
+	)
+	for current.Number.Uint64() > header.Number.Uint64() {
+		number := current.Number.Uint64()
+		blob := rawdb.ReadStateDiff(bc.db, current.Hash(), number)
+		if len(blob) == 0 {
+			return nil, fmt.Errorf("missing state diff of block #%d [%x..]", number, current.Hash().Bytes()[:4])
+		}
+		diff := new(state.StateDiff)
+		if err := rlp.DecodeBytes(blob, diff); err != nil {
+			return nil, fmt.Errorf("invalid state diff of block #%d [%x..]: %v", number, current.Hash().Bytes()[:4], err)
+		}
+		diffs = append(diffs, diff)
+
+		if current = bc.GetHeader(current.ParentHash, number-1); current == nil {
+			return nil, fmt.Errorf("missing ancestor #%d of head", number-1)
+		}
+	}
+	if current.Hash() != header.Hash() {
+		return nil, fmt.Errorf("block #%d [%x..] not canonical", header.Number, header.Hash().Bytes()[:4])
+	}
+	// Order the diffs from oldest to newest and roll the head state back
+	for i, j := 0, len(diffs)-1; i < j; i, j = i+1, j-1 {
+		diffs[i], diffs[j] = diffs[j], diffs[i]
+	}
+	if len(diffs) > 0 && diffs[0].Parent != header.Root {
+		return nil, fmt.Errorf("state diff root mismatch: have %x, want %x", diffs[0].Parent, header.Root)
+	}
+	return state.NewHistoric(bc.stateCache, base, diffs)
+}
+
 // StateCache returns the caching database underpinning the blockchain instance.
 func (bc *BlockChain) StateCache() state.Database {
 	return bc.stateCache
@@ -1530,10 +1618,20 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 		log.Crit("Failed to write block into disk", "err", err)
 	}
 	// Commit all cached state changes into underlying memory database.
+	if bc.cacheConfig.StateDiffs {
+		state.RecordReverseDiff()
+	}
 	root, err := state.Commit(bc.chainConfig.IsEIP158(block.Number()))
 	if err != nil {
 		return NonStatTy, err
 	}
+	if diff := state.ReverseDiff(); diff != nil {
+		blob, err := rlp.EncodeToBytes(diff)
+		if err != nil {
+			log.Crit("Failed to encode state diff", "err", err)
+		}
+		rawdb.WriteStateDiff(bc.db, block.Hash(), block.NumberU64(), blob)
+	}
 	triedb := bc.stateCache.TrieDB()
 
 	// If we're running an archive node, always flush
@@ -1921,6 +2019,13 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 
 		blockValidationTimer.Update(time.Since(substart) - (statedb.AccountHashes + statedb.StorageHashes - triehash))
 
+		// Gather the stateless witness while the parent state is surely available
+		var witness *Witness
+		if bc.cacheConfig.Witnesses {
+			if witness, err = bc.recordWitness(block, parent); err != nil {
+				log.Error("Failed to record block witness", "number", block.Number(), "hash", block.Hash(), "err", err)
+			}
+		}
 		// Write the block to the chain and get the status.
 		substart = time.Now()
 		status, err := bc.writeBlockWithState(block, receipts, logs, statedb, false)
@@ -1928,6 +2033,11 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 		if err != nil {
 			return it.index, err
 		}
+		if witness != nil {
+			if err := bc.writeWitness(block, witness); err != nil {
+				log.Error("Failed to store block witness", "number", block.Number(), "hash", block.Hash(), "err", err)
+			}
+		}
 
 		// Update the metrics touched during block commit
 		accountCommitTimer.Update(statedb.AccountCommits)   // Account commits are complete, we can mark them
@@ -2060,6 +2170,10 @@ func (bc *BlockChain) insertSideChain(block *types.Block, it *insertIterator) (i
 		log.Info("Sidechain written to disk", "start", it.first().NumberU64(), "end", it.previous().Number, "sidetd", externTd, "localtd", localTd)
 		return it.index, err
 	}
+	// Refuse to reprocess side chains which are not permitted to become canonical
+	if err := bc.verifySideChain(it.previous()); err != nil {
+		return it.index, err
+	}
 	// Gather all the sidechain hashes (full blocks may be memory heavy)
 	var (
 		hashes  []common.Hash
@@ -2215,6 +2329,22 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 			return fmt.Errorf("invalid new chain")
 		}
 	}
+	// Refuse reorgs crossing the operator configured restrictions
+	if len(oldChain) > 0 && bc.reorgGuarded() {
+		newHead := commonBlock.Header()
+		if len(newChain) > 0 {
+			newHead = newChain[0].Header()
+		}
+		err := bc.verifyReorg(oldChain[0].Header(), newHead, commonBlock.NumberU64(), func(number uint64) (common.Hash, bool) {
+			if number <= commonBlock.NumberU64() || number > newHead.Number.Uint64() {
+				return common.Hash{}, false
+			}
+			return newChain[newHead.Number.Uint64()-number].Hash(), true
+		})
+		if err != nil {
+			return err
+		}
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Info
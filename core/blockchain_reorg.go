@@ -0,0 +1,225 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/event"
+	"github.com/ionchain/ionchain-core/log"
+	"github.com/ionchain/ionchain-core/metrics"
+)
+
+var (
+	// ErrReorgTooDeep is returned if a reorg would drop more canonical blocks
+	// than the configured maximum reorg depth.
+	ErrReorgTooDeep = errors.New("reorg exceeds maximum depth")
+
+	// ErrCheckpointConflict is returned if a reorg would replace or drop one of
+	// the operator pinned checkpoints.
+	ErrCheckpointConflict = errors.New("reorg conflicts with checkpoint")
+
+	blockReorgRejectDepthMeter      = metrics.NewRegisteredMeter("chain/reorg/rejected/depth", nil)
+	blockReorgRejectCheckpointMeter = metrics.NewRegisteredMeter("chain/reorg/rejected/checkpoint", nil)
+)
+
+// Checkpoint is a block pinned by the node operator. Any reorg that would remove
+// it from the canonical chain, or make a different block canonical at its height,
+// is refused. On a stake based chain this protects against long range attacks,
+// where old stakes are used to forge an alternative history.
+type Checkpoint struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// SetMaxReorgDepth sets the maximum number of canonical blocks a reorg may drop.
+// Zero disables the limit.
+func (bc *BlockChain) SetMaxReorgDepth(depth uint64) {
+	bc.reorgLock.Lock()
+	defer bc.reorgLock.Unlock()
+
+	bc.maxReorgDepth = depth
+}
+
+// MaxReorgDepth returns the maximum number of canonical blocks a reorg may drop,
+// zero meaning unlimited.
+func (bc *BlockChain) MaxReorgDepth() uint64 {
+	bc.reorgLock.RLock()
+	defer bc.reorgLock.RUnlock()
+
+	return bc.maxReorgDepth
+}
+
+// AddCheckpoint pins a block, refusing any future reorg or rewind crossing it.
+// Checkpoints are persisted, surviving restarts until removed.
+func (bc *BlockChain) AddCheckpoint(number uint64, hash common.Hash) {
+	bc.reorgLock.Lock()
+	defer bc.reorgLock.Unlock()
+
+	if canon := rawdb.ReadCanonicalHash(bc.db, number); canon != (common.Hash{}) && canon != hash {
+		log.Warn("Checkpoint conflicts with local canonical chain", "number", number, "checkpoint", hash, "canonical", canon)
+	}
+	bc.checkpoints[number] = hash
+	rawdb.WriteReorgCheckpoint(bc.db, number, hash)
+}
+
+// RemoveCheckpoint unpins the checkpoint at the given height, returning whether
+// there was any.
+func (bc *BlockChain) RemoveCheckpoint(number uint64) bool {
+	bc.reorgLock.Lock()
+	defer bc.reorgLock.Unlock()
+
+	_, ok := bc.checkpoints[number]
+	delete(bc.checkpoints, number)
+	rawdb.DeleteReorgCheckpoint(bc.db, number)
+	return ok
+}
+
+// Checkpoints returns all the pinned checkpoints, ordered by number.
+func (bc *BlockChain) Checkpoints() []Checkpoint {
+	bc.reorgLock.RLock()
+	defer bc.reorgLock.RUnlock()
+
+	checkpoints := make([]Checkpoint, 0, len(bc.checkpoints))
+	for number, hash := range bc.checkpoints {
+		checkpoints = append(checkpoints, Checkpoint{Number: number, Hash: hash})
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Number < checkpoints[j].Number
+	})
+	return checkpoints
+}
+
+// reorgGuarded returns whether any reorg restriction is configured.
+func (bc *BlockChain) reorgGuarded() bool {
+	bc.reorgLock.RLock()
+	defer bc.reorgLock.RUnlock()
+
+	return bc.maxReorgDepth > 0 || len(bc.checkpoints) > 0
+}
+
+// verifyReorg checks whether the canonical chain may be switched from oldHead
+// to newHead, sharing the given common ancestor. The hashes of the new chain
+// above the ancestor are resolved via the given function. If the reorg is not
+// permitted, a ChainReorgRejectedEvent is posted and an error returned.
+func (bc *BlockChain) verifyReorg(oldHead, newHead *types.Header, ancestor uint64, newHash func(number uint64) (common.Hash, bool)) error {
+	bc.reorgLock.RLock()
+	var (
+		depth = oldHead.Number.Uint64() - ancestor
+		err   error
+	)
+	if bc.maxReorgDepth > 0 && depth > bc.maxReorgDepth {
+		err = fmt.Errorf("%w: dropping %d blocks, limit %d", ErrReorgTooDeep, depth, bc.maxReorgDepth)
+		blockReorgRejectDepthMeter.Mark(1)
+	} else {
+		for number, hash := range bc.checkpoints {
+			if number <= ancestor {
+				continue
+			}
+			// Checkpoints on the old chain may not be dropped, different blocks
+			// at their heights on the new chain may not be adopted
+			if number <= oldHead.Number.Uint64() && rawdb.ReadCanonicalHash(bc.db, number) == hash {
+				err = fmt.Errorf("%w: dropping #%d [%x..]", ErrCheckpointConflict, number, hash.Bytes()[:4])
+			} else if have, ok := newHash(number); ok && have != hash {
+				err = fmt.Errorf("%w: replacing #%d [%x..] with [%x..]", ErrCheckpointConflict, number, hash.Bytes()[:4], have.Bytes()[:4])
+			}
+			if err != nil {
+				blockReorgRejectCheckpointMeter.Mark(1)
+				break
+			}
+		}
+	}
+	bc.reorgLock.RUnlock()
+
+	if err != nil {
+		log.Warn("Rejected chain reorg", "ancestor", ancestor, "oldnum", oldHead.Number, "oldhash", oldHead.Hash(),
+			"newnum", newHead.Number, "newhash", newHead.Hash(), "depth", depth, "err", err)
+		bc.reorgRejectFeed.Send(ChainReorgRejectedEvent{OldHead: oldHead, NewHead: newHead, Depth: depth, Err: err})
+	}
+	return err
+}
+
+// verifyRewind checks whether the canonical chain may be rewound to the given
+// head without violating the reorg restrictions, which apply to explicit head
+// rewinds just like to reorgs.
+func (bc *BlockChain) verifyRewind(head uint64) error {
+	if !bc.reorgGuarded() {
+		return nil
+	}
+	current := bc.CurrentBlock().Header()
+	if head >= current.Number.Uint64() {
+		return nil
+	}
+	target := bc.GetHeaderByNumber(head)
+	if target == nil {
+		target = &types.Header{Number: new(big.Int).SetUint64(head)}
+	}
+	return bc.verifyReorg(current, target, head, func(uint64) (common.Hash, bool) {
+		return common.Hash{}, false
+	})
+}
+
+// verifySideChain checks whether a side chain ending in the given header could
+// become canonical without violating the reorg restrictions. It's used to avoid
+// reprocessing forbidden side chains before the actual reorg would reject them.
+func (bc *BlockChain) verifySideChain(head *types.Header) error {
+	if !bc.reorgGuarded() {
+		return nil
+	}
+	var (
+		current = bc.CurrentBlock().Header()
+		limit   = bc.MaxReorgDepth()
+		hashes  = make(map[uint64]common.Hash)
+	)
+	// Walk back the side chain until reaching the canonical chain, bailing out
+	// as soon as the depth limit is surely exceeded
+	ancestor := head
+	for ancestor != nil && rawdb.ReadCanonicalHash(bc.db, ancestor.Number.Uint64()) != ancestor.Hash() {
+		number := ancestor.Number.Uint64()
+		if limit > 0 && number+limit < current.Number.Uint64() {
+			break
+		}
+		hashes[number] = ancestor.Hash()
+		ancestor = bc.GetHeader(ancestor.ParentHash, number-1)
+	}
+	if ancestor == nil {
+		return nil // Unknown ancestry, leave it to the import to fail
+	}
+	number := ancestor.Number.Uint64()
+	if rawdb.ReadCanonicalHash(bc.db, number) != ancestor.Hash() {
+		number-- // Walk aborted above the fork point, anything below is too deep
+	}
+	if number >= current.Number.Uint64() {
+		return nil // Side chain extends the canonical one, no reorg
+	}
+	return bc.verifyReorg(current, head, number, func(n uint64) (common.Hash, bool) {
+		hash, ok := hashes[n]
+		return hash, ok
+	})
+}
+
+// SubscribeChainReorgRejectedEvent registers a subscription of
+// ChainReorgRejectedEvent.
+func (bc *BlockChain) SubscribeChainReorgRejectedEvent(ch chan<- ChainReorgRejectedEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgRejectFeed.Subscribe(ch))
+}
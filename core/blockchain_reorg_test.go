@@ -0,0 +1,144 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/ioncdb"
+	"github.com/ionchain/ionchain-core/params"
+)
+
+// newReorgTestChain creates a blockchain with a canonical chain of the given
+// length and returns it along with the canonical blocks.
+func newReorgTestChain(t *testing.T, db ioncdb.Database, length int) (*BlockChain, []*types.Block) {
+	genesis := newTestGenesis(db, common.HexToAddress("0xf00d"))
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, chainTestEngine{}, db, length, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.HexToAddress("0xaa"))
+	})
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, chainTestEngine{}, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return chain, blocks
+}
+
+// forkReorgTestChain creates a side chain forking off the given block, longer
+// than the canonical chain so that it's preferred by total difficulty.
+func forkReorgTestChain(db ioncdb.Database, parent *types.Block, length int) []*types.Block {
+	blocks, _ := GenerateChain(params.TestChainConfig, parent, chainTestEngine{}, db, length, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.HexToAddress("0xbb"))
+	})
+	return blocks
+}
+
+// Tests that reorgs and head rewinds dropping more blocks than the maximum reorg
+// depth are refused, while shallower ones go through.
+func TestReorgDepthLimit(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	chain, blocks := newReorgTestChain(t, db, 8)
+	defer chain.Stop()
+
+	chain.SetMaxReorgDepth(3)
+
+	// A heavier fork dropping 6 canonical blocks must be rejected
+	deep := forkReorgTestChain(db, blocks[1], 10)
+	if _, err := chain.InsertChain(deep); !errors.Is(err, ErrReorgTooDeep) {
+		t.Fatalf("deep reorg error mismatch: have %v, want %v", err, ErrReorgTooDeep)
+	}
+	if head := chain.CurrentBlock().Hash(); head != blocks[7].Hash() {
+		t.Fatalf("head changed after rejected reorg: have %x, want %x", head, blocks[7].Hash())
+	}
+	// Rewinding the head beyond the limit must be rejected just the same
+	if err := chain.SetHead(2); !errors.Is(err, ErrReorgTooDeep) {
+		t.Fatalf("deep rewind error mismatch: have %v, want %v", err, ErrReorgTooDeep)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 8 {
+		t.Fatalf("head rewound despite the limit: have #%d, want #%d", head, 8)
+	}
+	// A heavier fork dropping 2 canonical blocks must be accepted
+	shallow := forkReorgTestChain(db, blocks[5], 4)
+	if _, err := chain.InsertChain(shallow); err != nil {
+		t.Fatalf("failed to import shallow reorg: %v", err)
+	}
+	if head := chain.CurrentBlock().Hash(); head != shallow[3].Hash() {
+		t.Fatalf("head mismatch after shallow reorg: have %x, want %x", head, shallow[3].Hash())
+	}
+	if err := chain.SetHead(8); err != nil {
+		t.Fatalf("failed to rewind within the limit: %v", err)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 8 {
+		t.Fatalf("head mismatch after rewind: have #%d, want #%d", head, 8)
+	}
+}
+
+// Tests that pinned checkpoints can't be reorged or rewound away, and that they
+// survive a restart.
+func TestReorgCheckpoints(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	chain, blocks := newReorgTestChain(t, db, 8)
+
+	chain.AddCheckpoint(4, blocks[3].Hash())
+
+	// A fork below the checkpoint would drop it, reject both reorg and rewind
+	fork := forkReorgTestChain(db, blocks[1], 10)
+	if _, err := chain.InsertChain(fork); !errors.Is(err, ErrCheckpointConflict) {
+		t.Fatalf("checkpoint reorg error mismatch: have %v, want %v", err, ErrCheckpointConflict)
+	}
+	if err := chain.SetHead(3); !errors.Is(err, ErrCheckpointConflict) {
+		t.Fatalf("checkpoint rewind error mismatch: have %v, want %v", err, ErrCheckpointConflict)
+	}
+	if head := chain.CurrentBlock().Hash(); head != blocks[7].Hash() {
+		t.Fatalf("head changed despite the checkpoint: have %x, want %x", head, blocks[7].Hash())
+	}
+	// Rewinds and reorgs above the checkpoint are fine
+	if err := chain.SetHead(6); err != nil {
+		t.Fatalf("failed to rewind above the checkpoint: %v", err)
+	}
+	chain.Stop()
+
+	// Reopen the chain and ensure the checkpoint is still enforced
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, chainTestEngine{}, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if checkpoints := chain.Checkpoints(); len(checkpoints) != 1 || checkpoints[0] != (Checkpoint{Number: 4, Hash: blocks[3].Hash()}) {
+		t.Fatalf("checkpoints not persisted: have %v", checkpoints)
+	}
+	if err := chain.SetHead(2); !errors.Is(err, ErrCheckpointConflict) {
+		t.Fatalf("checkpoint rewind error mismatch after restart: have %v, want %v", err, ErrCheckpointConflict)
+	}
+	if !chain.RemoveCheckpoint(4) {
+		t.Fatalf("failed to remove checkpoint")
+	}
+	if err := chain.SetHead(2); err != nil {
+		t.Fatalf("failed to rewind after removing the checkpoint: %v", err)
+	}
+	if checkpoints := rawdb.ReadReorgCheckpoints(db); len(checkpoints) != 0 {
+		t.Fatalf("removed checkpoint still persisted: %v", checkpoints)
+	}
+}
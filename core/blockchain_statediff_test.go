@@ -0,0 +1,171 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/consensus"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/crypto"
+	"github.com/ionchain/ionchain-core/ioncdb"
+	"github.com/ionchain/ionchain-core/params"
+	"github.com/ionchain/ionchain-core/rlp"
+	"github.com/ionchain/ionchain-core/trie"
+)
+
+// chainTestEngine is a consensus engine accepting any block, for tests which
+// need a full blockchain without the proof of stake machinery.
+type chainTestEngine struct {
+	witnessTestEngine
+}
+
+func (chainTestEngine) VerifyHeader(consensus.ChainHeaderReader, *types.Header, bool) error {
+	return nil
+}
+
+func (chainTestEngine) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	results := make(chan error, len(headers))
+	for range headers {
+		results <- nil
+	}
+	return make(chan struct{}), results
+}
+
+func (chainTestEngine) VerifyUncles(consensus.ChainReader, *types.Block) error {
+	return nil
+}
+
+func (chainTestEngine) Prepare(consensus.ChainHeaderReader, *types.Header) error {
+	return nil
+}
+
+func (chainTestEngine) CalcDifficulty(consensus.ChainHeaderReader, uint64, *types.Header) *big.Int {
+	return big.NewInt(1)
+}
+
+func (e chainTestEngine) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	e.Finalize(chain, header, statedb, txs, uncles)
+	return types.NewBlock(header, txs, uncles, receipts, new(trie.Trie)), nil
+}
+
+// newTestGenesis commits a genesis block funding the given account and holding
+// the parallel test contracts.
+func newTestGenesis(db ioncdb.Database, funded common.Address) *types.Block {
+	gspec := &Genesis{
+		Config:     params.TestChainConfig,
+		Difficulty: big.NewInt(1),
+		BaseTarget: big.NewInt(1),
+		GasLimit:   10000000,
+		Alloc: GenesisAlloc{
+			funded:           {Balance: big.NewInt(params.Ether)},
+			parallelCounter:  {Balance: big.NewInt(0), Code: common.FromHex("0x60005460010160005500")},
+			parallelRegistry: {Balance: big.NewInt(0), Code: common.FromHex("0x33335500")},
+		},
+	}
+	return gspec.MustCommit(db)
+}
+
+// Tests that the reverse state diffs recorded during block import roll the head
+// state back to the exact state of every older block, and that reconstructions
+// deeper than the configured limit are refused.
+func TestHistoricStateAt(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		signer  = types.NewEIP155Signer(params.TestChainConfig.ChainID)
+		targets = []common.Address{sender, parallelCounter, parallelRegistry, parallelSink}
+		db      = rawdb.NewMemoryDatabase()
+		genesis = newTestGenesis(db, sender)
+	)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, chainTestEngine{}, db, 8, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.HexToAddress("0xc01bba5e"))
+		for j, to := range []common.Address{parallelSink, parallelCounter, parallelRegistry} {
+			if (i+j)%2 == 0 {
+				continue
+			}
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), to, big.NewInt(int64(i+1)), 100000, big.NewInt(1), nil), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	cache := &CacheConfig{
+		TrieCleanLimit:    16,
+		TrieDirtyDisabled: true, // keep all tries around to compare against
+		SnapshotLimit:     16,
+		SnapshotWait:      true,
+		StateDiffs:        true,
+		StateDiffLimit:    6,
+	}
+	chain, err := NewBlockChain(db, cache, params.TestChainConfig, chainTestEngine{}, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Every imported block must have a decodable diff reverting onto its parent
+	for _, block := range blocks {
+		blob := rawdb.ReadStateDiff(db, block.Hash(), block.NumberU64())
+		if len(blob) == 0 {
+			t.Fatalf("block #%d: state diff missing", block.NumberU64())
+		}
+		diff := new(state.StateDiff)
+		if err := rlp.DecodeBytes(blob, diff); err != nil {
+			t.Fatalf("block #%d: failed to decode state diff: %v", block.NumberU64(), err)
+		}
+		parent := chain.GetHeaderByHash(block.ParentHash())
+		if diff.Parent != parent.Root || diff.Root != block.Root() {
+			t.Fatalf("block #%d: state diff roots mismatch: have %x->%x, want %x->%x", block.NumberU64(), diff.Root, diff.Parent, block.Root(), parent.Root)
+		}
+	}
+	// Reconstruct the historical states and compare them with the archived ones
+	for number := uint64(2); number <= 8; number++ {
+		header := chain.GetHeaderByNumber(number)
+		historic, err := chain.HistoricStateAt(header)
+		if err != nil {
+			t.Fatalf("block #%d: failed to reconstruct state: %v", number, err)
+		}
+		archived, err := chain.StateAt(header.Root)
+		if err != nil {
+			t.Fatalf("block #%d: failed to open archived state: %v", number, err)
+		}
+		for _, addr := range targets {
+			if have, want := historic.GetBalance(addr), archived.GetBalance(addr); have.Cmp(want) != 0 {
+				t.Errorf("block #%d, %x: balance mismatch: have %v, want %v", number, addr, have, want)
+			}
+			if have, want := historic.GetNonce(addr), archived.GetNonce(addr); have != want {
+				t.Errorf("block #%d, %x: nonce mismatch: have %d, want %d", number, addr, have, want)
+			}
+			for _, slot := range []common.Hash{{}, common.BytesToHash(sender.Bytes())} {
+				if have, want := historic.GetState(addr, slot), archived.GetState(addr, slot); have != want {
+					t.Errorf("block #%d, %x: slot %x mismatch: have %x, want %x", number, addr, slot, have, want)
+				}
+			}
+		}
+	}
+	// Reconstructions beyond the limit must be rejected
+	if _, err := chain.HistoricStateAt(chain.GetHeaderByNumber(1)); err == nil {
+		t.Fatalf("reconstruction beyond the state diff limit succeeded")
+	}
+}
@@ -51,6 +51,13 @@ type ChainIndexerBackend interface {
 	Prune(threshold uint64) error
 }
 
+// ChainIndexerRollbacker is implemented by the backends which delete the data of
+// the sections rolled back by a reorg. Other backends leave it in the database.
+type ChainIndexerRollbacker interface {
+	// Rollback deletes the data of a processed section with the given head.
+	Rollback(section uint64, head common.Hash) error
+}
+
 // ChainIndexerChain interface is used for connecting the indexer to a blockchain
 type ChainIndexerChain interface {
 	// CurrentHeader retrieves the latest locally known header.
@@ -485,11 +492,28 @@ func (c *ChainIndexer) setValidSections(sections uint64) {
 	// Remove any reorged sections, caching the valids in the mean time
 	for c.storedSections > sections {
 		c.storedSections--
+		c.rollbackSection(c.storedSections)
 		c.removeSectionHead(c.storedSections)
 	}
 	c.storedSections = sections // needed if new > old
 }
 
+// rollbackSection lets the backend delete the data of a reorged section, if it
+// supports doing so.
+func (c *ChainIndexer) rollbackSection(section uint64) {
+	backend, ok := c.backend.(ChainIndexerRollbacker)
+	if !ok {
+		return
+	}
+	head := c.SectionHead(section)
+	if head == (common.Hash{}) {
+		return
+	}
+	if err := backend.Rollback(section, head); err != nil {
+		c.log.Error("Failed to roll back section", "section", section, "head", head, "err", err)
+	}
+}
+
 // SectionHead retrieves the last block hash of a processed section from the
 // index database.
 func (c *ChainIndexer) SectionHead(section uint64) common.Hash {
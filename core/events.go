@@ -41,3 +41,12 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ChainReorgRejectedEvent is posted when a reorg is refused for exceeding the
+// maximum reorg depth or crossing a pinned checkpoint.
+type ChainReorgRejectedEvent struct {
+	OldHead *types.Header // Canonical head retained
+	NewHead *types.Header // Head of the rejected chain
+	Depth   uint64        // Number of canonical blocks the reorg would have dropped
+	Err     error         // Reason of the rejection
+}
@@ -44,15 +44,21 @@ func NewEVMBlockContext(header *types.Header, chain ChainContext, author *common
 	} else {
 		beneficiary = *author
 	}
+	var baseTarget *big.Int
+	if header.BaseTarget != nil {
+		baseTarget = new(big.Int).Set(header.BaseTarget)
+	}
 	return vm.BlockContext{
-		CanTransfer: CanTransfer,
-		Transfer:    Transfer,
-		GetHash:     GetHashFn(header, chain),
-		Coinbase:    beneficiary,
-		BlockNumber: new(big.Int).Set(header.Number),
-		Time:        new(big.Int).SetUint64(header.Time),
-		Difficulty:  new(big.Int).Set(header.Difficulty),
-		GasLimit:    header.GasLimit,
+		CanTransfer:            CanTransfer,
+		Transfer:               Transfer,
+		GetHash:                GetHashFn(header, chain),
+		GetGenerationSignature: GetGenerationSignatureFn(header, chain),
+		Coinbase:               beneficiary,
+		BlockNumber:            new(big.Int).Set(header.Number),
+		Time:                   new(big.Int).SetUint64(header.Time),
+		Difficulty:             new(big.Int).Set(header.Difficulty),
+		BaseTarget:             baseTarget,
+		GasLimit:               header.GasLimit,
 	}
 }
 
@@ -98,6 +104,45 @@ func GetHashFn(ref *types.Header, chain ChainContext) func(n uint64) common.Hash
 	}
 }
 
+// GetGenerationSignatureFn returns a GetGenerationSignatureFunc which retrieves
+// the generation signatures of the ancestors of a header by number.
+func GetGenerationSignatureFn(ref *types.Header, chain ChainContext) func(n uint64) []byte {
+	// Cache will fill up with the ancestor headers [refHash.p, refHash.pp, ...]
+	var cache []*types.Header
+
+	return func(n uint64) []byte {
+		if n >= ref.Number.Uint64() {
+			return nil
+		}
+		if idx := ref.Number.Uint64() - n - 1; idx < uint64(len(cache)) {
+			return common.CopyBytes(cache[idx].GenerationSignature)
+		}
+		// No luck in the cache, continue iterating from the last header we know
+		hash, number := ref.ParentHash, ref.Number.Uint64()-1
+		if len(cache) > 0 {
+			last := cache[len(cache)-1]
+			if last.Number.Uint64() == 0 {
+				return nil
+			}
+			hash, number = last.ParentHash, last.Number.Uint64()-1
+		}
+		for {
+			header := chain.GetHeader(hash, number)
+			if header == nil {
+				return nil
+			}
+			cache = append(cache, header)
+			if n == number {
+				return common.CopyBytes(header.GenerationSignature)
+			}
+			if number == 0 {
+				return nil
+			}
+			hash, number = header.ParentHash, number-1
+		}
+	}
+}
+
 // CanTransfer checks whether there are enough funds in the address' account to make a transfer.
 // This does not take the necessary gas in to account to make the transfer valid.
 func CanTransfer(db vm.StateDB, addr common.Address, amount *big.Int) bool {
@@ -0,0 +1,165 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/metrics"
+	"github.com/ionchain/ionchain-core/params"
+)
+
+var (
+	parallelMergedMeter     = metrics.NewRegisteredMeter("chain/parallel/merged", nil)
+	parallelReexecutedMeter = metrics.NewRegisteredMeter("chain/parallel/reexecuted", nil)
+)
+
+// speculation is the outcome of executing a transaction on a private copy of
+// the pre-block state, concurrently with the other transactions of the block.
+type speculation struct {
+	state    *state.StateDB   // Private state the transaction was executed on
+	accesses *state.AccessSet // Accounts and slots accessed during execution
+	msg      types.Message    // Message derived from the transaction
+	receipt  *types.Receipt   // Receipt of the execution, without block-wide fields
+	err      error            // Any error that occurred during execution
+	done     chan struct{}    // Closed when the execution finished
+}
+
+// applyTransactionsParallel applies the transactions of a block to the state,
+// executing them optimistically in parallel: every transaction is run on its own
+// copy of the pre-block state, tracking the values it accesses. The results are
+// then merged into the state in block order, as long as none of the values read
+// were modified by a preceding transaction. Conflicting transactions (and those
+// that can't be merged otherwise) are re-executed serially on the live state.
+//
+// The outcome is identical to that of applying the transactions one by one, but
+// only post-Byzantium blocks are supported, as intermediate state roots can't be
+// produced for speculative executions.
+func applyTransactionsParallel(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, blockHash common.Hash, txs types.Transactions, usedGas *uint64, cfg vm.Config) (types.Receipts, []*types.Log, error) {
+	var (
+		signer = types.MakeSigner(config, header.Number)
+		specs  = make([]*speculation, len(txs))
+		tasks  = make(chan int, len(txs))
+		abort  int32
+	)
+	// Copy the pre-block state for each transaction before touching it
+	for i := range txs {
+		specs[i] = &speculation{
+			state:    statedb.Copy(),
+			accesses: state.NewAccessSet(),
+			done:     make(chan struct{}),
+		}
+		tasks <- i
+	}
+	close(tasks)
+
+	// Start executing the transactions speculatively
+	workers := runtime.NumCPU()
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	var pending sync.WaitGroup
+	pending.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer pending.Done()
+			for i := range tasks {
+				spec := specs[i]
+				if atomic.LoadInt32(&abort) == 0 {
+					spec.speculate(config, bc, author, header, blockHash, txs[i], i, signer, cfg)
+				} else {
+					spec.err = errInsertionInterrupted
+				}
+				close(spec.done)
+			}
+		}()
+	}
+	defer pending.Wait()
+	defer atomic.StoreInt32(&abort, 1)
+
+	// Merge the results in block order, re-executing any conflicting transactions
+	var (
+		receipts types.Receipts
+		allLogs  []*types.Log
+		writes   = state.NewAccessSet()
+		vmenv    = vm.NewEVM(NewEVMBlockContext(header, bc, author), vm.TxContext{}, statedb, config, cfg)
+	)
+	for i, tx := range txs {
+		spec := specs[i]
+		<-spec.done
+
+		statedb.Prepare(tx.Hash(), blockHash, i)
+		if spec.err == nil && !spec.accesses.Conflicts(writes) && gp.Gas() >= spec.msg.Gas() && statedb.MergeTransaction(spec.state, spec.accesses) {
+			if err := gp.SubGas(spec.receipt.GasUsed); err != nil {
+				return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			statedb.Finalise(true)
+			*usedGas += spec.receipt.GasUsed
+
+			receipt := spec.receipt
+			receipt.CumulativeGasUsed = *usedGas
+			receipt.Logs = statedb.GetLogs(tx.Hash())
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			receipt.BlockHash = statedb.BlockHash()
+			receipt.TransactionIndex = uint(statedb.TxIndex())
+
+			receipts = append(receipts, receipt)
+			allLogs = append(allLogs, receipt.Logs...)
+			writes.Merge(spec.accesses)
+			parallelMergedMeter.Mark(1)
+			continue
+		}
+		// Speculation failed, execute the transaction on the live state
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, nil, err
+		}
+		accesses := state.NewAccessSet()
+		statedb.TrackAccesses(accesses)
+		receipt, err := applyTransaction(msg, config, bc, author, gp, statedb, header, tx, usedGas, vmenv)
+		statedb.TrackAccesses(nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+		writes.Merge(accesses)
+		parallelReexecutedMeter.Mark(1)
+	}
+	return receipts, allLogs, nil
+}
+
+// speculate executes a transaction on the private state of the speculation.
+func (s *speculation) speculate(config *params.ChainConfig, bc ChainContext, author *common.Address, header *types.Header, blockHash common.Hash, tx *types.Transaction, index int, signer types.Signer, cfg vm.Config) {
+	s.msg, s.err = tx.AsMessage(signer)
+	if s.err != nil {
+		return
+	}
+	s.state.TrackAccesses(s.accesses)
+	s.state.Prepare(tx.Hash(), blockHash, index)
+
+	// The block context caches block hashes internally, so it can't be shared
+	vmenv := vm.NewEVM(NewEVMBlockContext(header, bc, author), vm.TxContext{}, s.state, config, cfg)
+	s.receipt, s.err = applyTransaction(s.msg, config, bc, author, new(GasPool).AddGas(header.GasLimit), s.state, header, tx, new(uint64), vmenv)
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/consensus"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/crypto"
+	"github.com/ionchain/ionchain-core/params"
+)
+
+// parallelTestChain is a chain context without any headers, sufficient for
+// executing transactions with an explicit author.
+type parallelTestChain struct{}
+
+func (parallelTestChain) Engine() consensus.Engine                    { return nil }
+func (parallelTestChain) GetHeader(common.Hash, uint64) *types.Header { return nil }
+
+var (
+	parallelCounter  = common.HexToAddress("0xc0")                  // slot[0]++
+	parallelRegistry = common.HexToAddress("0xc1")                  // slot[caller] = caller
+	parallelSuicider = common.HexToAddress("0xc2")                  // selfdestruct(caller)
+	parallelLogger   = common.HexToAddress("0xc3")                  // log0()
+	parallelSink     = common.HexToAddress("0x1111111111111111111") // plain value receiver
+)
+
+// newParallelTestState creates a pre-block state with a few funded accounts
+// and some contracts exercising storage, logs and self destructs.
+func newParallelTestState(t *testing.T, keys []*ecdsa.PrivateKey) *state.StateDB {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	for _, key := range keys {
+		statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(params.Ether))
+	}
+	statedb.SetCode(parallelCounter, common.FromHex("0x60005460010160005500"))
+	statedb.SetCode(parallelRegistry, common.FromHex("0x33335500"))
+	statedb.SetCode(parallelSuicider, common.FromHex("0x33ff"))
+	statedb.SetCode(parallelLogger, common.FromHex("0x60006000a000"))
+	statedb.SetState(parallelCounter, common.Hash{}, common.BigToHash(big.NewInt(7)))
+	statedb.AddBalance(parallelSuicider, big.NewInt(12345))
+
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	statedb, _ = state.New(root, statedb.Database(), nil)
+	return statedb
+}
+
+// Tests that executing the transactions of a block in parallel results in the
+// exact same receipts and state as executing them serially.
+func TestParallelExecutionEquivalence(t *testing.T) {
+	var (
+		keys   = make([]*ecdsa.PrivateKey, 12)
+		nonces = make([]uint64, 12)
+		signer = types.NewEIP155Signer(params.TestChainConfig.ChainID)
+		miner  = common.HexToAddress("0xc01bba5e")
+		txs    types.Transactions
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	send := func(from int, to *common.Address, value int64, data []byte) {
+		var tx *types.Transaction
+		if to == nil {
+			tx = types.NewContractCreation(nonces[from], big.NewInt(value), 100000, big.NewInt(1), data)
+		} else {
+			tx = types.NewTransaction(nonces[from], *to, big.NewInt(value), 100000, big.NewInt(1), data)
+		}
+		signed, err := types.SignTx(tx, signer, keys[from])
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		txs = append(txs, signed)
+		nonces[from]++
+	}
+	fresh := common.HexToAddress("0xdead")
+	send(0, &parallelSink, 1, nil)                           // independent transfer
+	send(1, &parallelSink, 2, nil)                           // conflicts on the receiver
+	send(2, &parallelCounter, 0, nil)                        // storage update
+	send(3, &parallelCounter, 0, nil)                        // conflicting storage update
+	send(4, &parallelRegistry, 0, nil)                       // storage update
+	send(5, &parallelRegistry, 0, nil)                       // disjoint storage slot
+	send(6, &parallelLogger, 0, nil)                         // log emission
+	send(7, nil, 0, common.FromHex("0x00"))                  // contract creation
+	send(8, &parallelSuicider, 0, nil)                       // self destruct
+	send(9, &parallelSuicider, 5, nil)                       // resurrect via value transfer
+	send(10, &fresh, 0, nil)                                 // touch of an empty account
+	send(11, &parallelLogger, 0, common.FromHex("0xc0ffee")) // another log emission
+	send(0, &parallelSink, 3, nil)                           // sender nonce conflict
+	send(4, &parallelRegistry, 0, nil)                       // sender and slot conflict
+
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		Coinbase:   miner,
+		GasLimit:   10000000,
+		Difficulty: big.NewInt(1),
+		Time:       1,
+		BaseTarget: big.NewInt(1),
+	}
+	blockHash := common.HexToHash("0xb10c")
+
+	// Execute the transactions serially and in parallel on identical states
+	serialState := newParallelTestState(t, keys)
+	var (
+		serialReceipts types.Receipts
+		serialGas      uint64
+		serialPool     = new(GasPool).AddGas(header.GasLimit)
+	)
+	for i, tx := range txs {
+		serialState.Prepare(tx.Hash(), blockHash, i)
+		receipt, err := ApplyTransaction(params.TestChainConfig, parallelTestChain{}, &miner, serialPool, serialState, header, tx, &serialGas, vm.Config{})
+		if err != nil {
+			t.Fatalf("tx %d: serial execution failed: %v", i, err)
+		}
+		serialReceipts = append(serialReceipts, receipt)
+	}
+	parallelState := newParallelTestState(t, keys)
+	var (
+		parallelGas  uint64
+		parallelPool = new(GasPool).AddGas(header.GasLimit)
+	)
+	parallelReceipts, _, err := applyTransactionsParallel(params.TestChainConfig, parallelTestChain{}, &miner, parallelPool, parallelState, header, blockHash, txs, &parallelGas, vm.Config{})
+	if err != nil {
+		t.Fatalf("parallel execution failed: %v", err)
+	}
+	// Ensure the two executions are indistinguishable
+	if serialGas != parallelGas {
+		t.Errorf("gas used mismatch: serial %d, parallel %d", serialGas, parallelGas)
+	}
+	if serialPool.Gas() != parallelPool.Gas() {
+		t.Errorf("gas pool mismatch: serial %d, parallel %d", serialPool.Gas(), parallelPool.Gas())
+	}
+	if len(serialReceipts) != len(parallelReceipts) {
+		t.Fatalf("receipt count mismatch: serial %d, parallel %d", len(serialReceipts), len(parallelReceipts))
+	}
+	for i := range serialReceipts {
+		if !reflect.DeepEqual(serialReceipts[i], parallelReceipts[i]) {
+			t.Errorf("receipt %d mismatch:\nserial:   %+v\nparallel: %+v", i, serialReceipts[i], parallelReceipts[i])
+		}
+	}
+	if serial, parallel := serialState.IntermediateRoot(true), parallelState.IntermediateRoot(true); serial != parallel {
+		t.Errorf("state root mismatch: serial %x, parallel %x", serial, parallel)
+	}
+	if serial, parallel := serialState.GetState(parallelCounter, common.Hash{}), parallelState.GetState(parallelCounter, common.Hash{}); serial != parallel || serial.Big().Uint64() != 9 {
+		t.Errorf("counter mismatch: serial %x, parallel %x", serial, parallel)
+	}
+}
@@ -18,6 +18,8 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
 	"math/big"
 
 	"github.com/ionchain/ionchain-core/common"
@@ -175,3 +177,166 @@ func DeleteBloombits(db ioncdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// ReadCallTraceIndexHead retrieves the hash of the latest block whose call traces
+// have been indexed.
+func ReadCallTraceIndexHead(db ioncdb.KeyValueReader) common.Hash {
+	data, _ := db.Get(callTraceIndexHeadKey)
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteCallTraceIndexHead stores the hash of the latest block whose call traces
+// have been indexed.
+func WriteCallTraceIndexHead(db ioncdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Put(callTraceIndexHeadKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store the call trace index head", "err", err)
+	}
+}
+
+// ReadCallTraces retrieves the encoded flat call traces of a block.
+func ReadCallTraces(db ioncdb.KeyValueReader, hash common.Hash, number uint64) []byte {
+	data, _ := db.Get(callTracesKey(number, hash))
+	return data
+}
+
+// WriteCallTraces stores the encoded flat call traces of a block.
+func WriteCallTraces(db ioncdb.KeyValueWriter, hash common.Hash, number uint64, traces []byte) {
+	if err := db.Put(callTracesKey(number, hash), traces); err != nil {
+		log.Crit("Failed to store call traces", "err", err)
+	}
+}
+
+// DeleteCallTraces deletes the flat call traces of a block.
+func DeleteCallTraces(db ioncdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(callTracesKey(number, hash)); err != nil {
+		log.Crit("Failed to delete call traces", "err", err)
+	}
+}
+
+// WriteCallTraceAddress marks a block as containing call traces involving the
+// given address. Entries are keyed by number only, so the traces of the canonical
+// block must be checked to filter out ones of blocks reorged meanwhile.
+func WriteCallTraceAddress(db ioncdb.KeyValueWriter, address common.Address, number uint64) {
+	if err := db.Put(callTraceAddrKey(address, number), nil); err != nil {
+		log.Crit("Failed to store call trace address index", "err", err)
+	}
+}
+
+// DeleteCallTraceAddress removes the mark of a block containing call traces
+// involving the given address.
+func DeleteCallTraceAddress(db ioncdb.KeyValueWriter, address common.Address, number uint64) {
+	if err := db.Delete(callTraceAddrKey(address, number)); err != nil {
+		log.Crit("Failed to delete call trace address index", "err", err)
+	}
+}
+
+// ReadCallTraceAddressBlocks retrieves the numbers of the blocks within the given
+// range (both inclusive) containing call traces involving the given address.
+func ReadCallTraceAddressBlocks(db ioncdb.Iteratee, address common.Address, from, to uint64) []uint64 {
+	prefix := append(callTraceAddrPrefix, address.Bytes()...)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var numbers []uint64
+	for it.Next() {
+		if len(it.Key()) != len(prefix)+8 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(it.Key()[len(prefix):])
+		if number > to {
+			break
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers
+}
+
+// LogPosition locates a log within the chain.
+type LogPosition struct {
+	Block    uint64 // Number of the block containing the log
+	TxIndex  uint32 // Index of the transaction emitting the log in the block
+	LogIndex uint32 // Index of the log in the block
+}
+
+// logPositionSize is the size of an encoded log position.
+const logPositionSize = 16
+
+// AddressLogTerm returns the log index term of the logs emitted by an address.
+func AddressLogTerm(address common.Address) []byte {
+	return append([]byte{'a'}, address.Bytes()...)
+}
+
+// TopicLogTerm returns the log index term of the logs having the given topic at
+// position i, which must be below 4.
+func TopicLogTerm(i int, topic common.Hash) []byte {
+	return append([]byte{'0' + byte(i)}, topic.Bytes()...)
+}
+
+// ReadLogIndex retrieves the positions of the logs matching a term within the
+// section with the given head, in chain order. No positions are returned if no
+// log of the section matches the term.
+func ReadLogIndex(db ioncdb.KeyValueReader, term []byte, section uint64, head common.Hash) ([]LogPosition, error) {
+	data, _ := db.Get(logIndexKey(term, section, head))
+	if len(data)%logPositionSize != 0 {
+		return nil, fmt.Errorf("invalid log index entry size %d", len(data))
+	}
+	positions := make([]LogPosition, len(data)/logPositionSize)
+	for i := range positions {
+		entry := data[i*logPositionSize:]
+		positions[i] = LogPosition{
+			Block:    binary.BigEndian.Uint64(entry),
+			TxIndex:  binary.BigEndian.Uint32(entry[8:]),
+			LogIndex: binary.BigEndian.Uint32(entry[12:]),
+		}
+	}
+	return positions, nil
+}
+
+// WriteLogIndex stores the positions of the logs matching a term within the
+// section with the given head.
+func WriteLogIndex(db ioncdb.KeyValueWriter, term []byte, section uint64, head common.Hash, positions []LogPosition) {
+	data := make([]byte, len(positions)*logPositionSize)
+	for i, pos := range positions {
+		entry := data[i*logPositionSize:]
+		binary.BigEndian.PutUint64(entry, pos.Block)
+		binary.BigEndian.PutUint32(entry[8:], pos.TxIndex)
+		binary.BigEndian.PutUint32(entry[12:], pos.LogIndex)
+	}
+	if err := db.Put(logIndexKey(term, section, head), data); err != nil {
+		log.Crit("Failed to store log index", "err", err)
+	}
+}
+
+// DeleteLogIndex removes the positions of the logs matching a term within the
+// section with the given head.
+func DeleteLogIndex(db ioncdb.KeyValueWriter, term []byte, section uint64, head common.Hash) {
+	if err := db.Delete(logIndexKey(term, section, head)); err != nil {
+		log.Crit("Failed to delete log index", "err", err)
+	}
+}
+
+// HasLogIndexSection checks whether the log index of the section with the given
+// head is complete.
+func HasLogIndexSection(db ioncdb.KeyValueReader, section uint64, head common.Hash) bool {
+	has, _ := db.Has(logIndexKey(nil, section, head))
+	return has
+}
+
+// WriteLogIndexSection marks the log index of the section with the given head as
+// complete.
+func WriteLogIndexSection(db ioncdb.KeyValueWriter, section uint64, head common.Hash) {
+	if err := db.Put(logIndexKey(nil, section, head), nil); err != nil {
+		log.Crit("Failed to store log index section marker", "err", err)
+	}
+}
+
+// DeleteLogIndexSection removes the completion marker of the log index of the
+// section with the given head.
+func DeleteLogIndexSection(db ioncdb.KeyValueWriter, section uint64, head common.Hash) {
+	if err := db.Delete(logIndexKey(nil, section, head)); err != nil {
+		log.Crit("Failed to delete log index section marker", "err", err)
+	}
+}
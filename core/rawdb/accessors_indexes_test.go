@@ -0,0 +1,109 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+)
+
+// Tests that the log index positions of a term can be stored and retrieved, and
+// are kept apart by term, section and section head.
+func TestLogIndexStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		head     = common.HexToHash("0x01")
+		address  = AddressLogTerm(common.HexToAddress("0x0a"))
+		topic    = TopicLogTerm(0, common.HexToHash("0x0b"))
+		shifted  = TopicLogTerm(1, common.HexToHash("0x0b"))
+		position = []LogPosition{
+			{Block: 4096, TxIndex: 0, LogIndex: 0},
+			{Block: 4100, TxIndex: 2, LogIndex: 5},
+			{Block: 8191, TxIndex: 1<<32 - 1, LogIndex: 1<<32 - 1},
+		}
+	)
+	WriteLogIndex(db, address, 1, head, position)
+	WriteLogIndex(db, topic, 1, head, position[1:])
+
+	tests := []struct {
+		term    []byte
+		section uint64
+		head    common.Hash
+		want    []LogPosition
+	}{
+		{address, 1, head, position},
+		{topic, 1, head, position[1:]},
+		{shifted, 1, head, nil},
+		{address, 0, head, nil},
+		{address, 1, common.HexToHash("0x02"), nil},
+	}
+	for i, tt := range tests {
+		have, err := ReadLogIndex(db, tt.term, tt.section, tt.head)
+		if err != nil {
+			t.Fatalf("test %d: failed to read log index: %v", i, err)
+		}
+		if len(have) != len(tt.want) || (len(have) > 0 && !reflect.DeepEqual(have, tt.want)) {
+			t.Errorf("test %d: positions mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	DeleteLogIndex(db, address, 1, head)
+	if have, _ := ReadLogIndex(db, address, 1, head); len(have) != 0 {
+		t.Errorf("deleted positions retrieved: %v", have)
+	}
+	if have, _ := ReadLogIndex(db, topic, 1, head); !reflect.DeepEqual(have, position[1:]) {
+		t.Errorf("positions of other term mismatch after delete: have %v, want %v", have, position[1:])
+	}
+	// Corrupt entries must be reported rather than misread
+	db.Put(logIndexKey(address, 1, head), make([]byte, logPositionSize+1))
+	if _, err := ReadLogIndex(db, address, 1, head); err == nil {
+		t.Errorf("corrupt log index entry accepted")
+	}
+}
+
+// Tests that the completion marker of a log index section gates on the section
+// head, and doesn't collide with the postings of any term.
+func TestLogIndexSectionMarker(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		head   = common.HexToHash("0x01")
+		reorg  = common.HexToHash("0x02")
+		topic  = TopicLogTerm(0, common.HexToHash("0x0b"))
+		marked = func(section uint64, head common.Hash) bool { return HasLogIndexSection(db, section, head) }
+	)
+	WriteLogIndex(db, topic, 1, head, []LogPosition{{Block: 4096}})
+	if marked(1, head) {
+		t.Fatalf("section marked complete by its postings")
+	}
+	WriteLogIndexSection(db, 1, head)
+	if !marked(1, head) {
+		t.Fatalf("completed section not marked")
+	}
+	if marked(0, head) || marked(2, head) || marked(1, reorg) {
+		t.Fatalf("marker leaked to other sections or heads")
+	}
+	DeleteLogIndexSection(db, 1, head)
+	if marked(1, head) {
+		t.Fatalf("deleted section marker retrieved")
+	}
+	if have, _ := ReadLogIndex(db, topic, 1, head); len(have) != 1 {
+		t.Fatalf("postings deleted along with the section marker")
+	}
+}
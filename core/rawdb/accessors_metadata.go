@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"encoding/binary"
 	"encoding/json"
 
 	"github.com/ionchain/ionchain-core/common"
@@ -79,3 +80,61 @@ func WriteChainConfig(db ioncdb.KeyValueWriter, hash common.Hash, cfg *params.Ch
 		log.Crit("Failed to store chain config", "err", err)
 	}
 }
+
+// ReadTraceJobs retrieves the JSON encoded specification and progress of all the
+// chain tracing jobs.
+func ReadTraceJobs(db ioncdb.Iteratee) [][]byte {
+	it := db.NewIterator(traceJobPrefix, nil)
+	defer it.Release()
+
+	var jobs [][]byte
+	for it.Next() {
+		jobs = append(jobs, common.CopyBytes(it.Value()))
+	}
+	return jobs
+}
+
+// WriteTraceJob stores the JSON encoded specification and progress of a chain
+// tracing job.
+func WriteTraceJob(db ioncdb.KeyValueWriter, id string, job []byte) {
+	if err := db.Put(traceJobKey(id), job); err != nil {
+		log.Crit("Failed to store trace job", "err", err)
+	}
+}
+
+// DeleteTraceJob deletes a chain tracing job.
+func DeleteTraceJob(db ioncdb.KeyValueWriter, id string) {
+	if err := db.Delete(traceJobKey(id)); err != nil {
+		log.Crit("Failed to delete trace job", "err", err)
+	}
+}
+
+// ReadReorgCheckpoints retrieves all the blocks pinned against chain reorgs.
+func ReadReorgCheckpoints(db ioncdb.Iteratee) map[uint64]common.Hash {
+	it := db.NewIterator(reorgCheckpointPrefix, nil)
+	defer it.Release()
+
+	checkpoints := make(map[uint64]common.Hash)
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(reorgCheckpointPrefix)+8 || len(it.Value()) != common.HashLength {
+			continue
+		}
+		checkpoints[binary.BigEndian.Uint64(key[len(reorgCheckpointPrefix):])] = common.BytesToHash(it.Value())
+	}
+	return checkpoints
+}
+
+// WriteReorgCheckpoint stores a block pinned against chain reorgs.
+func WriteReorgCheckpoint(db ioncdb.KeyValueWriter, number uint64, hash common.Hash) {
+	if err := db.Put(reorgCheckpointKey(number), hash.Bytes()); err != nil {
+		log.Crit("Failed to store reorg checkpoint", "err", err)
+	}
+}
+
+// DeleteReorgCheckpoint deletes the reorg checkpoint at the given height.
+func DeleteReorgCheckpoint(db ioncdb.KeyValueWriter, number uint64) {
+	if err := db.Delete(reorgCheckpointKey(number)); err != nil {
+		log.Crit("Failed to delete reorg checkpoint", "err", err)
+	}
+}
@@ -94,3 +94,44 @@ func DeleteTrieNode(db ioncdb.KeyValueWriter, hash common.Hash) {
 		log.Crit("Failed to delete trie node", "err", err)
 	}
 }
+
+// ReadStateDiff retrieves the reverse state diff of a block, which can be used
+// to roll the flat state of the block back to the state of its parent.
+func ReadStateDiff(db ioncdb.KeyValueReader, hash common.Hash, number uint64) []byte {
+	data, _ := db.Get(stateDiffKey(number, hash))
+	return data
+}
+
+// WriteStateDiff stores the reverse state diff of a block.
+func WriteStateDiff(db ioncdb.KeyValueWriter, hash common.Hash, number uint64, diff []byte) {
+	if err := db.Put(stateDiffKey(number, hash), diff); err != nil {
+		log.Crit("Failed to store state diff", "err", err)
+	}
+}
+
+// DeleteStateDiff deletes the reverse state diff of a block.
+func DeleteStateDiff(db ioncdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(stateDiffKey(number, hash)); err != nil {
+		log.Crit("Failed to delete state diff", "err", err)
+	}
+}
+
+// ReadBlockWitness retrieves the encoded stateless witness of a block.
+func ReadBlockWitness(db ioncdb.KeyValueReader, hash common.Hash, number uint64) []byte {
+	data, _ := db.Get(blockWitnessKey(number, hash))
+	return data
+}
+
+// WriteBlockWitness stores the encoded stateless witness of a block.
+func WriteBlockWitness(db ioncdb.KeyValueWriter, hash common.Hash, number uint64, witness []byte) {
+	if err := db.Put(blockWitnessKey(number, hash), witness); err != nil {
+		log.Crit("Failed to store block witness", "err", err)
+	}
+}
+
+// DeleteBlockWitness deletes the stateless witness of a block.
+func DeleteBlockWitness(db ioncdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockWitnessKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block witness", "err", err)
+	}
+}
@@ -127,6 +127,24 @@ func NewDatabaseWithFreezer(db ioncdb.KeyValueStore, freezer string, namespace s
 	if err != nil {
 		return nil, err
 	}
+	return newDatabaseWithAncients(db, frdb)
+}
+
+// NewDatabaseWithBlobFreezer creates a high level database on top of a given key-
+// value data store with a freezer moving immutable chain segments into a blob
+// store (e.g. a remote object storage shared by multiple nodes).
+func NewDatabaseWithBlobFreezer(db ioncdb.KeyValueStore, store BlobStore, namespace string) (ioncdb.Database, error) {
+	// Create the idle freezer instance
+	frdb, err := newBlobFreezer(store, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return newDatabaseWithAncients(db, frdb)
+}
+
+// newDatabaseWithAncients validates that the key-value store and the freezer are
+// compatible with each other and combines them into a single database.
+func newDatabaseWithAncients(db ioncdb.KeyValueStore, frdb *freezer) (ioncdb.Database, error) {
 	// Since the freezer can be stored separately from the user's key-value database,
 	// there's a fairly high probability that the user requests invalid combinations
 	// of the freezer and database. Ensure that we don't shoot ourselves in the foot
@@ -238,6 +256,21 @@ func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer
 	return frdb, nil
 }
 
+// NewLevelDBDatabaseWithBlobFreezer creates a persistent key-value database with
+// a freezer moving immutable chain segments into the given blob store.
+func NewLevelDBDatabaseWithBlobFreezer(file string, cache int, handles int, store BlobStore, namespace string) (ioncdb.Database, error) {
+	kvdb, err := leveldb.New(file, cache, handles, namespace)
+	if err != nil {
+		return nil, err
+	}
+	frdb, err := NewDatabaseWithBlobFreezer(kvdb, store, namespace)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
 type counter uint64
 
 func (c counter) String() string {
@@ -291,6 +324,12 @@ func InspectDatabase(db ioncdb.Database) error {
 		txLookups       stat
 		accountSnaps    stat
 		storageSnaps    stat
+		stateDiffs      stat
+		witnesses       stat
+		callTraces      stat
+		callTraceAddrs  stat
+		logIndex        stat
+		traceJobs       stat
 		preimages       stat
 		bloomBits       stat
 		cliqueSnaps     stat
@@ -343,6 +382,20 @@ func InspectDatabase(db ioncdb.Database) error {
 			accountSnaps.Add(size)
 		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
 			storageSnaps.Add(size)
+		case bytes.HasPrefix(key, stateDiffPrefix) && len(key) == (len(stateDiffPrefix)+8+common.HashLength):
+			stateDiffs.Add(size)
+		case bytes.HasPrefix(key, blockWitnessPrefix) && len(key) == (len(blockWitnessPrefix)+8+common.HashLength):
+			witnesses.Add(size)
+		case bytes.HasPrefix(key, callTracesPrefix) && len(key) == (len(callTracesPrefix)+8+common.HashLength):
+			callTraces.Add(size)
+		case bytes.HasPrefix(key, callTraceAddrPrefix) && len(key) == (len(callTraceAddrPrefix)+common.AddressLength+8):
+			callTraceAddrs.Add(size)
+		case bytes.HasPrefix(key, logIndexPrefix) && (len(key) == (len(logIndexPrefix)+8+common.HashLength) || len(key) == (len(logIndexPrefix)+1+common.AddressLength+8+common.HashLength) || len(key) == (len(logIndexPrefix)+1+common.HashLength+8+common.HashLength)):
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, traceJobPrefix):
+			traceJobs.Add(size)
+		case bytes.HasPrefix(key, reorgCheckpointPrefix) && len(key) == len(reorgCheckpointPrefix)+8:
+			metadata.Add(size)
 		case bytes.HasPrefix(key, preimagePrefix) && len(key) == (len(preimagePrefix)+common.HashLength):
 			preimages.Add(size)
 		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
@@ -395,11 +448,17 @@ func InspectDatabase(db ioncdb.Database) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Log index", logIndex.Size(), logIndex.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
 		{"Key-Value store", "Account snapshot", accountSnaps.Size(), accountSnaps.Count()},
 		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
+		{"Key-Value store", "State diffs", stateDiffs.Size(), stateDiffs.Count()},
+		{"Key-Value store", "Block witnesses", witnesses.Size(), witnesses.Count()},
+		{"Key-Value store", "Call traces", callTraces.Size(), callTraces.Count()},
+		{"Key-Value store", "Call trace address index", callTraceAddrs.Size(), callTraceAddrs.Count()},
+		{"Key-Value store", "Trace jobs", traceJobs.Size(), traceJobs.Count()},
 		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
 		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
 		{"Ancient store", "Headers", ancientHeadersSize.String(), ancients.String()},
@@ -59,6 +59,39 @@ const (
 	freezerBatchLimit = 30000
 )
 
+// ancientTable is the storage backend of a single data category within the
+// freezer (e.g. headers or bodies). Tables are append-only and may only be
+// shortened from the head via truncate.
+type ancientTable interface {
+	// Append injects a binary blob at the end of the table.
+	Append(item uint64, blob []byte) error
+
+	// Retrieve looks up the binary blob stored at the given item number.
+	Retrieve(item uint64) ([]byte, error)
+
+	// Sync pushes any pending data out to the backing storage.
+	Sync() error
+
+	// Close releases all resources held by the table.
+	Close() error
+
+	// has returns an indicator whether the specified number data exists.
+	has(number uint64) bool
+
+	// count returns the number of items stored in the table.
+	count() uint64
+
+	// size returns the total data size in the table.
+	size() (uint64, error)
+
+	// truncate discards any recent data above the provided threshold number.
+	truncate(items uint64) error
+}
+
+// ancientTableOpener creates (or opens) the backing table for a single data
+// category of the freezer.
+type ancientTableOpener func(name string, readMeter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, disableSnappy bool) (ancientTable, error)
+
 // freezer is an memory mapped append-only database to store immutable chain data
 // into flat files:
 //
@@ -73,8 +106,9 @@ type freezer struct {
 	frozen    uint64 // Number of blocks already frozen
 	threshold uint64 // Number of recent blocks not to freeze (params.FullImmutabilityThreshold apart from tests)
 
-	tables       map[string]*freezerTable // Data tables for storing everything
-	instanceLock fileutil.Releaser        // File-system lock to prevent double opens
+	tables       map[string]ancientTable // Data tables for storing everything
+	instanceLock fileutil.Releaser       // File-system lock or writer lease to prevent concurrent writers
+	readonly     bool                    // Whether another instance owns the tables (shared blob stores only)
 
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
 
@@ -85,12 +119,6 @@ type freezer struct {
 // newFreezer creates a chain freezer that moves ancient chain data into
 // append-only flat file containers.
 func newFreezer(datadir string, namespace string) (*freezer, error) {
-	// Create the initial freezer object
-	var (
-		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
-		writeMeter = metrics.NewRegisteredMeter(namespace+"ancient/write", nil)
-		sizeGauge  = metrics.NewRegisteredGauge(namespace+"ancient/size", nil)
-	)
 	// Ensure the datadir is not a symbolic link if it exists.
 	if info, err := os.Lstat(datadir); !os.IsNotExist(err) {
 		if info.Mode()&os.ModeSymlink != 0 {
@@ -104,21 +132,77 @@ func newFreezer(datadir string, namespace string) (*freezer, error) {
 	if err != nil {
 		return nil, err
 	}
+	opener := func(name string, readMeter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, disableSnappy bool) (ancientTable, error) {
+		return newTable(datadir, name, readMeter, writeMeter, sizeGauge, disableSnappy)
+	}
+	freezer, err := newFreezerWithTables(opener, lock, false, namespace)
+	if err != nil {
+		lock.Release()
+		return nil, err
+	}
+	log.Info("Opened ancient database", "database", datadir)
+	return freezer, nil
+}
+
+// newBlobFreezer creates a chain freezer that moves ancient chain data into
+// immutable segments stored in the given blob store.
+//
+// The store may be shared by multiple nodes, but only the one holding its writer
+// lease freezes data into it. All others open it read-only, serving whatever the
+// store contained on startup and keeping their ancient data in the key-value
+// store.
+func newBlobFreezer(store BlobStore, namespace string) (*freezer, error) {
+	lease, err := acquireBlobLease(store)
+	switch {
+	case err == errBlobStoreLocked:
+		log.Warn("Ancient blob database locked by another writer, opening read-only", "store", store)
+	case err != nil:
+		return nil, err
+	}
+	opener := func(name string, readMeter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, disableSnappy bool) (ancientTable, error) {
+		return newBlobTable(store, lease, name, readMeter, writeMeter, sizeGauge, blobSegmentItems, disableSnappy)
+	}
+	// Avoid storing a typed nil into the lock interface of read-only freezers
+	var lock fileutil.Releaser
+	if lease != nil {
+		lock = lease
+	}
+	freezer, err := newFreezerWithTables(opener, lock, lease == nil, namespace)
+	if err != nil {
+		if lease != nil {
+			lease.Release()
+		}
+		return nil, err
+	}
+	log.Info("Opened ancient blob database", "store", store, "readonly", lease == nil)
+	return freezer, nil
+}
+
+// newFreezerWithTables opens all the supported data tables via the given opener
+// and assembles them into a consistent chain freezer. A read-only freezer never
+// truncates its tables nor freezes new data into them.
+func newFreezerWithTables(opener ancientTableOpener, lock fileutil.Releaser, readonly bool, namespace string) (*freezer, error) {
+	// Create the initial freezer object
+	var (
+		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
+		writeMeter = metrics.NewRegisteredMeter(namespace+"ancient/write", nil)
+		sizeGauge  = metrics.NewRegisteredGauge(namespace+"ancient/size", nil)
+	)
 	// Open all the supported data tables
 	freezer := &freezer{
 		threshold:    params.FullImmutabilityThreshold,
-		tables:       make(map[string]*freezerTable),
+		tables:       make(map[string]ancientTable),
 		instanceLock: lock,
+		readonly:     readonly,
 		trigger:      make(chan chan struct{}),
 		quit:         make(chan struct{}),
 	}
 	for name, disableSnappy := range freezerNoSnappy {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, disableSnappy)
+		table, err := opener(name, readMeter, writeMeter, sizeGauge, disableSnappy)
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
 			}
-			lock.Release()
 			return nil, err
 		}
 		freezer.tables[name] = table
@@ -127,10 +211,8 @@ func newFreezer(datadir string, namespace string) (*freezer, error) {
 		for _, table := range freezer.tables {
 			table.Close()
 		}
-		lock.Release()
 		return nil, err
 	}
-	log.Info("Opened ancient database", "database", datadir)
 	return freezer, nil
 }
 
@@ -144,8 +226,10 @@ func (f *freezer) Close() error {
 				errs = append(errs, err)
 			}
 		}
-		if err := f.instanceLock.Release(); err != nil {
-			errs = append(errs, err)
+		if f.instanceLock != nil {
+			if err := f.instanceLock.Release(); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	})
 	if errs != nil {
@@ -191,6 +275,9 @@ func (f *freezer) AncientSize(kind string) (uint64, error) {
 // injection will be rejected. But if two injections with same number happen at
 // the same time, we can get into the trouble.
 func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+	if f.readonly {
+		return errBlobReadOnly
+	}
 	// Ensure the binary blobs we are appending is continuous with freezer.
 	if atomic.LoadUint64(&f.frozen) != number {
 		return errOutOrderInsertion
@@ -233,6 +320,9 @@ func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td
 
 // TruncateAncients discards any recent data above the provided threshold number.
 func (f *freezer) TruncateAncients(items uint64) error {
+	if f.readonly {
+		return errBlobReadOnly
+	}
 	if atomic.LoadUint64(&f.frozen) <= items {
 		return nil
 	}
@@ -265,6 +355,11 @@ func (f *freezer) Sync() error {
 // This functionality is deliberately broken off from block importing to avoid
 // incurring additional data shuffling delays on block propagation.
 func (f *freezer) freeze(db ioncdb.KeyValueStore) {
+	if f.readonly {
+		log.Info("Ancient database is read-only, not freezing")
+		<-f.quit
+		return
+	}
 	nfdb := &nofreezedb{KeyValueStore: db}
 
 	var (
@@ -457,11 +552,16 @@ func (f *freezer) freeze(db ioncdb.KeyValueStore) {
 func (f *freezer) repair() error {
 	min := uint64(math.MaxUint64)
 	for _, table := range f.tables {
-		items := atomic.LoadUint64(&table.items)
+		items := table.count()
 		if min > items {
 			min = items
 		}
 	}
+	if f.readonly {
+		// Leave the tables to their writer, just hide the unaligned items
+		atomic.StoreUint64(&f.frozen, min)
+		return nil
+	}
 	for _, table := range f.tables {
 		if err := table.truncate(min); err != nil {
 			return err
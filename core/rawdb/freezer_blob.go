@@ -0,0 +1,475 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/snappy"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/log"
+	"github.com/ionchain/ionchain-core/metrics"
+	"github.com/ionchain/ionchain-core/rlp"
+)
+
+const (
+	// blobSegmentItems is the number of items bundled into a single immutable
+	// segment of a blob backed freezer table.
+	blobSegmentItems = 2048
+
+	// blobSegmentCache is the number of decoded segments kept in memory per table.
+	blobSegmentCache = 16
+)
+
+// ErrBlobNotFound is returned by a BlobStore if the requested blob does not exist.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobInfo describes a single blob contained in a BlobStore.
+type BlobInfo struct {
+	Name string // Slash separated name of the blob
+	Size int64  // Size of the blob in bytes
+}
+
+// BlobStore is a minimal object storage abstraction that a chain freezer can use
+// to persist immutable ancient chain segments outside of the local disk (e.g. in
+// a cloud bucket shared across multiple nodes).
+//
+// Implementations must make Put atomic: a concurrent or subsequent Get must see
+// either the old or the new content of a blob, never a partial write.
+type BlobStore interface {
+	// Get retrieves the content of a blob, or ErrBlobNotFound if it's missing.
+	Get(name string) ([]byte, error)
+
+	// Put creates or replaces a blob with the given content.
+	Put(name string, blob []byte) error
+
+	// Delete removes a blob from the store. Deleting a missing blob is not an error.
+	Delete(name string) error
+
+	// List returns all the blobs whose name starts with the given prefix.
+	List(prefix string) ([]BlobInfo, error)
+}
+
+// blobSegment is the storage format of a segment of a blob freezer table. Sealed
+// segments always contain exactly the configured number of items, the head
+// segment carries the items appended since the last seal.
+type blobSegment struct {
+	Number uint64
+	Items  [][]byte
+}
+
+// blobTable is a freezer table storing its items in fixed size immutable segments
+// inside a BlobStore. Items appended after the last full segment are kept in
+// memory and persisted as a mutable head blob upon Sync.
+type blobTable struct {
+	// WARNING: The `items` field is accessed atomically. On 32 bit platforms, only
+	// 64-bit aligned fields can be atomic. The struct is guaranteed to be so aligned,
+	// so take advantage of that (https://golang.org/pkg/sync/atomic/#pkg-note-BUG).
+	items uint64 // Number of items stored in the table
+
+	store         BlobStore
+	lease         *blobLease // Writer lease of the store, nil if opened read-only
+	name          string
+	segmentItems  uint64     // Number of items in a sealed segment
+	noCompression bool       // if true, disables snappy compression. Note: does not work retroactively
+	pending       [][]byte   // Items not yet part of a sealed segment
+	sealedBytes   uint64     // Total size of all the sealed segments
+	segments      *lru.Cache // Cache of recently accessed sealed segments
+	dirty         bool       // Whether the pending items changed since the last sync
+	closed        bool
+
+	readMeter  metrics.Meter // Meter for measuring the effective amount of data read
+	writeMeter metrics.Meter // Meter for measuring the effective amount of data written
+	sizeGauge  metrics.Gauge // Gauge for tracking the combined size of all freezer tables
+
+	logger log.Logger   // Logger with blob store and table name ambedded
+	lock   sync.RWMutex // Mutex protecting the pending items and segment set
+}
+
+// newBlobTable opens a blob backed freezer table, recovering all sealed segments
+// and the last synced head segment from the store. Without a writer lease the
+// table is read-only and leaves the store untouched, even while repairing.
+func newBlobTable(store BlobStore, lease *blobLease, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, segmentItems uint64, noCompression bool) (*blobTable, error) {
+	cache, _ := lru.New(blobSegmentCache)
+	tab := &blobTable{
+		store:         store,
+		lease:         lease,
+		name:          name,
+		segmentItems:  segmentItems,
+		noCompression: noCompression,
+		segments:      cache,
+		readMeter:     readMeter,
+		writeMeter:    writeMeter,
+		sizeGauge:     sizeGauge,
+		logger:        log.New("store", store, "table", name),
+	}
+	if err := tab.repair(); err != nil {
+		return nil, err
+	}
+	size, _ := tab.sizeNolock()
+	tab.sizeGauge.Inc(int64(size))
+	return tab, nil
+}
+
+// segmentName returns the blob name of the sealed segment with the given number.
+func (t *blobTable) segmentName(number uint64) string {
+	if t.noCompression {
+		return fmt.Sprintf("%s/%08d.rseg", t.name, number)
+	}
+	return fmt.Sprintf("%s/%08d.cseg", t.name, number)
+}
+
+// headName returns the blob name of the unsealed head segment.
+func (t *blobTable) headName() string {
+	if t.noCompression {
+		return fmt.Sprintf("%s/head.rseg", t.name)
+	}
+	return fmt.Sprintf("%s/head.cseg", t.name)
+}
+
+// repair scans the blob store for the longest contiguous run of sealed segments
+// and loads the head segment on top if it continues that run. Anything else is
+// left over from a crash or truncation and gets discarded.
+func (t *blobTable) repair() error {
+	infos, err := t.store.List(t.name + "/")
+	if err != nil {
+		return err
+	}
+	sizes := make(map[string]int64)
+	for _, info := range infos {
+		sizes[info.Name] = info.Size
+	}
+	var sealed uint64
+	for {
+		size, ok := sizes[t.segmentName(sealed)]
+		if !ok {
+			break
+		}
+		delete(sizes, t.segmentName(sealed))
+		t.sealedBytes += uint64(size)
+		sealed++
+	}
+	delete(sizes, t.headName())
+
+	// Drop any dangling segments beyond a gap, they can't be reached anymore
+	dangling := make([]string, 0, len(sizes))
+	for name := range sizes {
+		if strings.HasSuffix(name, ".rseg") == t.noCompression {
+			dangling = append(dangling, name)
+		}
+	}
+	sort.Strings(dangling)
+	if len(dangling) > 0 && t.lease == nil {
+		t.logger.Debug("Leaving dangling segments to the writer", "count", len(dangling))
+		dangling = nil
+	}
+	for _, name := range dangling {
+		t.logger.Warn("Deleting dangling segment", "name", name)
+		if err := t.store.Delete(name); err != nil {
+			return err
+		}
+	}
+	// Load the head segment if it continues the sealed ones
+	blob, err := t.store.Get(t.headName())
+	switch {
+	case err == ErrBlobNotFound:
+	case err != nil:
+		return err
+	default:
+		head, err := t.decode(blob)
+		if err != nil {
+			return err
+		}
+		if head.Number == sealed && uint64(len(head.Items)) < t.segmentItems {
+			t.pending = head.Items
+		} else {
+			t.logger.Warn("Discarding stale head segment", "segment", head.Number, "sealed", sealed)
+		}
+	}
+	t.items = sealed*t.segmentItems + uint64(len(t.pending))
+	t.logger.Debug("Chain freezer blob table opened", "items", t.items, "size", common.StorageSize(t.sealedBytes))
+	return nil
+}
+
+// writable returns an error if the table may not modify the store.
+func (t *blobTable) writable() error {
+	if t.lease == nil {
+		return errBlobReadOnly
+	}
+	return t.lease.check()
+}
+
+// encode serializes and optionally compresses a segment.
+func (t *blobTable) encode(segment *blobSegment) ([]byte, error) {
+	blob, err := rlp.EncodeToBytes(segment)
+	if err != nil {
+		return nil, err
+	}
+	if !t.noCompression {
+		blob = snappy.Encode(nil, blob)
+	}
+	return blob, nil
+}
+
+// decode decompresses and deserializes a segment.
+func (t *blobTable) decode(blob []byte) (*blobSegment, error) {
+	if !t.noCompression {
+		var err error
+		if blob, err = snappy.Decode(nil, blob); err != nil {
+			return nil, err
+		}
+	}
+	segment := new(blobSegment)
+	if err := rlp.DecodeBytes(blob, segment); err != nil {
+		return nil, err
+	}
+	return segment, nil
+}
+
+// loadSegment retrieves a sealed segment either from the cache or from the store.
+// The caller must hold at least the read lock.
+func (t *blobTable) loadSegment(number uint64) (*blobSegment, error) {
+	if cached, ok := t.segments.Get(number); ok {
+		return cached.(*blobSegment), nil
+	}
+	blob, err := t.store.Get(t.segmentName(number))
+	if err != nil {
+		return nil, err
+	}
+	segment, err := t.decode(blob)
+	if err != nil {
+		return nil, err
+	}
+	if segment.Number != number || uint64(len(segment.Items)) != t.segmentItems {
+		return nil, fmt.Errorf("corrupt segment %d: number %d, items %d", number, segment.Number, len(segment.Items))
+	}
+	t.readMeter.Mark(int64(len(blob)))
+	t.segments.Add(number, segment)
+	return segment, nil
+}
+
+// Append injects a binary blob at the end of the table. Whenever a segment fills
+// up, it is sealed and uploaded to the blob store.
+func (t *blobTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.closed {
+		return errClosed
+	}
+	if err := t.writable(); err != nil {
+		return err
+	}
+	if items := atomic.LoadUint64(&t.items); items != item {
+		return fmt.Errorf("appending unexpected item: want %d, have %d", items, item)
+	}
+	t.pending = append(t.pending, common.CopyBytes(blob))
+	t.dirty = true
+	atomic.AddUint64(&t.items, 1)
+
+	if uint64(len(t.pending)) < t.segmentItems {
+		return nil
+	}
+	segment := &blobSegment{Number: item / t.segmentItems, Items: t.pending}
+	enc, err := t.encode(segment)
+	if err != nil {
+		return err
+	}
+	if err := t.store.Put(t.segmentName(segment.Number), enc); err != nil {
+		// Keep the items pending, the freezer will repair the item count
+		return err
+	}
+	t.pending = nil
+	t.sealedBytes += uint64(len(enc))
+	t.segments.Add(segment.Number, segment)
+
+	t.writeMeter.Mark(int64(len(enc)))
+	t.sizeGauge.Inc(int64(len(enc)))
+	return nil
+}
+
+// Retrieve looks up the binary blob stored at the given item number.
+func (t *blobTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.closed {
+		return nil, errClosed
+	}
+	if atomic.LoadUint64(&t.items) <= item {
+		return nil, errOutOfBounds
+	}
+	number := item / t.segmentItems
+	if sealed := (atomic.LoadUint64(&t.items) - uint64(len(t.pending))) / t.segmentItems; number == sealed {
+		return common.CopyBytes(t.pending[item%t.segmentItems]), nil
+	}
+	segment, err := t.loadSegment(number)
+	if err != nil {
+		return nil, err
+	}
+	return common.CopyBytes(segment.Items[item%t.segmentItems]), nil
+}
+
+// has returns an indicator whether the specified number data exists in the table.
+func (t *blobTable) has(number uint64) bool {
+	return atomic.LoadUint64(&t.items) > number
+}
+
+// count returns the number of items stored in the table.
+func (t *blobTable) count() uint64 {
+	return atomic.LoadUint64(&t.items)
+}
+
+// size returns the total data size of the table.
+func (t *blobTable) size() (uint64, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.sizeNolock()
+}
+
+// sizeNolock returns the total data size of the table without obtaining the
+// mutex first. Pending items are accounted with their uncompressed size.
+func (t *blobTable) sizeNolock() (uint64, error) {
+	total := t.sealedBytes
+	for _, item := range t.pending {
+		total += uint64(len(item))
+	}
+	return total, nil
+}
+
+// truncate discards any recent data above the provided threshold number. If the
+// threshold falls into a sealed segment, that segment is reopened as the head.
+func (t *blobTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	existing := atomic.LoadUint64(&t.items)
+	if existing <= items {
+		return nil
+	}
+	if err := t.writable(); err != nil {
+		return err
+	}
+	log := t.logger.Debug
+	if existing > items+1 {
+		log = t.logger.Warn // Only loud warn if we delete multiple items
+	}
+	log("Truncating freezer table", "items", existing, "limit", items)
+
+	oldSize, _ := t.sizeNolock()
+	sealed := (existing - uint64(len(t.pending))) / t.segmentItems
+	keep := items / t.segmentItems
+
+	if keep < sealed {
+		// Reopen the segment containing the new head, then drop everything after
+		segment, err := t.loadSegment(keep)
+		if err != nil {
+			return err
+		}
+		pending := make([][]byte, items%t.segmentItems)
+		copy(pending, segment.Items)
+		t.pending, t.dirty = pending, true
+		atomic.StoreUint64(&t.items, items)
+
+		// Persist the new head before removing the sealed segments
+		if err := t.syncNolock(); err != nil {
+			return err
+		}
+		for number := sealed; number > keep; number-- {
+			if err := t.deleteSegment(number - 1); err != nil {
+				return err
+			}
+		}
+	} else {
+		t.pending, t.dirty = t.pending[:items%t.segmentItems], true
+		atomic.StoreUint64(&t.items, items)
+	}
+
+	newSize, _ := t.sizeNolock()
+	t.sizeGauge.Dec(int64(oldSize - newSize))
+	return nil
+}
+
+// deleteSegment removes a sealed segment from the store and the local caches.
+func (t *blobTable) deleteSegment(number uint64) error {
+	infos, err := t.store.List(t.segmentName(number))
+	if err != nil {
+		return err
+	}
+	if err := t.store.Delete(t.segmentName(number)); err != nil {
+		return err
+	}
+	for _, info := range infos {
+		t.sealedBytes -= uint64(info.Size)
+	}
+	t.segments.Remove(number)
+	return nil
+}
+
+// Sync uploads the items not yet part of a sealed segment as the head segment.
+func (t *blobTable) Sync() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.closed {
+		return errClosed
+	}
+	return t.syncNolock()
+}
+
+// syncNolock uploads the head segment without obtaining the mutex first.
+func (t *blobTable) syncNolock() error {
+	if !t.dirty {
+		return nil
+	}
+	if err := t.writable(); err != nil {
+		return err
+	}
+	sealed := (atomic.LoadUint64(&t.items) - uint64(len(t.pending))) / t.segmentItems
+	enc, err := t.encode(&blobSegment{Number: sealed, Items: t.pending})
+	if err != nil {
+		return err
+	}
+	if err := t.store.Put(t.headName(), enc); err != nil {
+		return err
+	}
+	t.writeMeter.Mark(int64(len(enc)))
+	t.dirty = false
+	return nil
+}
+
+// Close flushes the pending items and releases the table.
+func (t *blobTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.closed {
+		return nil
+	}
+	err := t.syncNolock()
+	t.closed = true
+	t.pending = nil
+	t.segments.Purge()
+	return err
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dirBlobStore is a BlobStore keeping every blob as a file inside a local
+// directory. It's mostly useful for testing and as a reference implementation
+// for remote object stores.
+type dirBlobStore struct {
+	root string
+}
+
+// NewDirBlobStore creates a blob store backed by the files of a local directory.
+func NewDirBlobStore(root string) (BlobStore, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &dirBlobStore{root: root}, nil
+}
+
+// String implements fmt.Stringer, returning the root of the store.
+func (s *dirBlobStore) String() string {
+	return "dir://" + s.root
+}
+
+// path converts a slash separated blob name into a file system path.
+func (s *dirBlobStore) path(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(name))
+}
+
+// Get retrieves the content of a blob, or ErrBlobNotFound if it's missing.
+func (s *dirBlobStore) Get(name string) ([]byte, error) {
+	blob, err := ioutil.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	return blob, err
+}
+
+// Put creates or replaces a blob. The content is written into a temporary file
+// first and moved into place afterwards to keep the update atomic.
+func (s *dirBlobStore) Put(name string, blob []byte) error {
+	path := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(blob); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Delete removes a blob from the store.
+func (s *dirBlobStore) Delete(name string) error {
+	if err := os.Remove(s.path(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns all the blobs whose name starts with the given prefix.
+func (s *dirBlobStore) List(prefix string) ([]BlobInfo, error) {
+	var infos []BlobInfo
+	err := filepath.Walk(s.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+			infos = append(infos, BlobInfo{Name: name, Size: info.Size()})
+		}
+		return nil
+	})
+	return infos, err
+}
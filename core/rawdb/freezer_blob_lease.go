@@ -0,0 +1,217 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ionchain/ionchain-core/log"
+	"github.com/ionchain/ionchain-core/rlp"
+)
+
+const (
+	// blobLeaseName is the name of the blob holding the writer lease of a store.
+	// Table blobs are all nested under their table name, so it can't collide.
+	blobLeaseName = "LEASE"
+
+	// blobLeaseTTL is the time a writer lease stays valid without being renewed.
+	blobLeaseTTL = 2 * time.Minute
+
+	// blobLeaseRenewal is the frequency at which the writer renews its lease.
+	blobLeaseRenewal = blobLeaseTTL / 4
+)
+
+// blobLeaseSettle is the time to wait after taking over a recently expired lease
+// before checking that no other node claimed it concurrently (variable to allow
+// tests to lower it).
+var blobLeaseSettle = 5 * time.Second
+
+var (
+	// errBlobStoreLocked is returned if the writer lease of a blob store is held
+	// by another node.
+	errBlobStoreLocked = errors.New("blob store locked by another writer")
+
+	// errBlobReadOnly is returned if a blob freezer opened without the writer
+	// lease is asked to modify the store.
+	errBlobReadOnly = errors.New("blob store opened read-only")
+
+	// errBlobLeaseLost is returned if the writer lease expired or was taken over
+	// by another node since the store was opened.
+	errBlobLeaseLost = errors.New("blob store writer lease lost")
+)
+
+// blobLeaseRecord is the storage format of a writer lease.
+type blobLeaseRecord struct {
+	Owner  string // Random identifier of the lease holder
+	Expiry uint64 // Unix time in seconds after which the lease may be taken over
+}
+
+// blobLease is an exclusive writer lease over a BlobStore. Only the holder of the
+// lease may append, truncate or repair the tables in the store, all other nodes
+// sharing the store must open it read-only.
+//
+// The lease is advisory: it relies on every writer going through it and on the
+// clocks of the nodes being roughly in sync. A writer stops modifying the store
+// a full renewal period before its lease expires, so a node failing to renew
+// never overlaps with the next holder.
+type blobLease struct {
+	store  BlobStore
+	owner  string
+	expiry time.Time // Local view of the lease expiry, updated on renewals
+	lost   bool      // Whether another node took over the lease
+
+	quit chan struct{}
+	done chan struct{}
+	lock sync.RWMutex
+}
+
+// acquireBlobLease claims the writer lease of a blob store, failing with
+// errBlobStoreLocked if another node holds an unexpired one.
+func acquireBlobLease(store BlobStore) (*blobLease, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	lease := &blobLease{
+		store: store,
+		owner: hex.EncodeToString(id),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	current, err := lease.read()
+	if err != nil {
+		return nil, err
+	}
+	now := uint64(time.Now().Unix())
+	if current != nil && current.Expiry > now {
+		return nil, errBlobStoreLocked
+	}
+	// Nodes waiting for a writer to go away race for its lease as soon as it
+	// expires. Absent and long expired leases have nobody waiting on them, so
+	// only settle on takeovers to keep the startup of a lone writer fast.
+	contended := current != nil && current.Expiry+uint64(blobLeaseTTL/time.Second) > now
+
+	if err := lease.write(); err != nil {
+		return nil, err
+	}
+	// Puts are last-writer-wins, make sure a concurrent claim didn't override us
+	if contended {
+		time.Sleep(blobLeaseSettle)
+	}
+	if current, err = lease.read(); err != nil {
+		return nil, err
+	}
+	if current == nil || current.Owner != lease.owner {
+		return nil, errBlobStoreLocked
+	}
+	go lease.loop()
+	return lease, nil
+}
+
+// read retrieves the lease currently stored, or nil if there's none.
+func (l *blobLease) read() (*blobLeaseRecord, error) {
+	blob, err := l.store.Get(blobLeaseName)
+	if err == ErrBlobNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	record := new(blobLeaseRecord)
+	if err := rlp.DecodeBytes(blob, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// write stores the lease with a fresh expiry.
+func (l *blobLease) write() error {
+	expiry := time.Now().Add(blobLeaseTTL)
+	blob, err := rlp.EncodeToBytes(&blobLeaseRecord{Owner: l.owner, Expiry: uint64(expiry.Unix())})
+	if err != nil {
+		return err
+	}
+	if err := l.store.Put(blobLeaseName, blob); err != nil {
+		return err
+	}
+	l.lock.Lock()
+	l.expiry = expiry
+	l.lock.Unlock()
+	return nil
+}
+
+// loop periodically renews the lease until it's released or taken over.
+func (l *blobLease) loop() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(blobLeaseRenewal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			current, err := l.read()
+			if err != nil {
+				log.Warn("Failed to read blob store lease", "err", err)
+				continue
+			}
+			if current == nil || current.Owner != l.owner {
+				log.Error("Blob store writer lease taken over, stopping writes")
+				l.lock.Lock()
+				l.lost = true
+				l.lock.Unlock()
+				return
+			}
+			if err := l.write(); err != nil {
+				log.Warn("Failed to renew blob store lease", "err", err)
+			}
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+// check returns an error if the lease may not be relied upon for writing anymore.
+func (l *blobLease) check() error {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	if l.lost || time.Now().Add(blobLeaseRenewal).After(l.expiry) {
+		return errBlobLeaseLost
+	}
+	return nil
+}
+
+// Release implements fileutil.Releaser, stopping the renewals and giving up the
+// lease if it's still held.
+func (l *blobLease) Release() error {
+	close(l.quit)
+	<-l.done
+
+	current, err := l.read()
+	if err != nil {
+		return err
+	}
+	if current == nil || current.Owner != l.owner {
+		return nil
+	}
+	return l.store.Delete(blobLeaseName)
+}
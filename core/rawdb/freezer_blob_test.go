@@ -0,0 +1,294 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ionchain/ionchain-core/ioncdb/memorydb"
+	"github.com/ionchain/ionchain-core/metrics"
+	"github.com/ionchain/ionchain-core/rlp"
+)
+
+func init() {
+	blobLeaseSettle = 0
+}
+
+// openBlobTestTable opens a blob table with tiny segments over a directory store,
+// holding the writer lease of the store for the duration of the test.
+func openBlobTestTable(t *testing.T, dir string, noCompression bool) *blobTable {
+	store, err := NewDirBlobStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+	lease, err := acquireBlobLease(store)
+	if err != nil {
+		t.Fatalf("failed to acquire writer lease: %v", err)
+	}
+	table, err := newBlobTable(store, lease, "test", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 4, noCompression)
+	if err != nil {
+		t.Fatalf("failed to open blob table: %v", err)
+	}
+	return table
+}
+
+// closeBlobTestTable closes a blob table and releases its writer lease.
+func closeBlobTestTable(t *testing.T, table *blobTable) {
+	if err := table.Close(); err != nil {
+		t.Fatalf("failed to close table: %v", err)
+	}
+	if err := table.lease.Release(); err != nil {
+		t.Fatalf("failed to release writer lease: %v", err)
+	}
+}
+
+// openBlobTestFreezer opens a blob freezer over the given store, running the
+// freeze loop on an empty database so the freezer can be closed.
+func openBlobTestFreezer(t *testing.T, store BlobStore) *freezer {
+	f, err := newBlobFreezer(store, "")
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	go f.freeze(memorydb.New())
+	return f
+}
+
+func checkBlobItems(t *testing.T, table *blobTable, items int) {
+	if have := table.count(); have != uint64(items) {
+		t.Fatalf("item count mismatch: have %d, want %d", have, items)
+	}
+	for i := 0; i < items; i++ {
+		blob, err := table.Retrieve(uint64(i))
+		if err != nil {
+			t.Fatalf("item %d: failed to retrieve: %v", i, err)
+		}
+		if want := bytes.Repeat([]byte{byte(i)}, i+1); !bytes.Equal(blob, want) {
+			t.Fatalf("item %d: content mismatch: have %x, want %x", i, blob, want)
+		}
+	}
+	if _, err := table.Retrieve(uint64(items)); err != errOutOfBounds {
+		t.Fatalf("out of bounds retrieval error mismatch: have %v, want %v", err, errOutOfBounds)
+	}
+}
+
+// Tests that items are sealed into segments, survive a reopen and that the
+// table can be truncated back into a previously sealed segment.
+func TestBlobTableAppendReopenTruncate(t *testing.T) {
+	for _, noCompression := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "blobfreezer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		table := openBlobTestTable(t, dir, noCompression)
+		for i := 0; i < 10; i++ {
+			if err := table.Append(uint64(i), bytes.Repeat([]byte{byte(i)}, i+1)); err != nil {
+				t.Fatalf("item %d: failed to append: %v", i, err)
+			}
+		}
+		if err := table.Append(20, []byte{0x20}); err == nil {
+			t.Fatalf("out of order append succeeded")
+		}
+		checkBlobItems(t, table, 10)
+		closeBlobTestTable(t, table)
+		// Reopen and ensure both the sealed and head segments were recovered
+		table = openBlobTestTable(t, dir, noCompression)
+		checkBlobItems(t, table, 10)
+
+		// Truncate into the first sealed segment and ensure it's reopened
+		if err := table.truncate(3); err != nil {
+			t.Fatalf("failed to truncate table: %v", err)
+		}
+		checkBlobItems(t, table, 3)
+		closeBlobTestTable(t, table)
+		table = openBlobTestTable(t, dir, noCompression)
+		checkBlobItems(t, table, 3)
+
+		// Appending after the truncation should continue seamlessly
+		for i := 3; i < 6; i++ {
+			if err := table.Append(uint64(i), bytes.Repeat([]byte{byte(i)}, i+1)); err != nil {
+				t.Fatalf("item %d: failed to append: %v", i, err)
+			}
+		}
+		checkBlobItems(t, table, 6)
+		closeBlobTestTable(t, table)
+	}
+}
+
+// Tests that a blob backed freezer keeps its tables aligned and reports the
+// same data as it was fed with.
+func TestBlobFreezer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobfreezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewDirBlobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := openBlobTestFreezer(t, store)
+	defer f.Close()
+
+	for i := uint64(0); i < 5; i++ {
+		hash := bytes.Repeat([]byte{byte(i)}, 32)
+		if err := f.AppendAncient(i, hash, []byte{1}, []byte{2}, []byte{3}, []byte{4}); err != nil {
+			t.Fatalf("block %d: failed to append: %v", i, err)
+		}
+	}
+	if frozen, _ := f.Ancients(); frozen != 5 {
+		t.Fatalf("frozen count mismatch: have %d, want %d", frozen, 5)
+	}
+	if err := f.TruncateAncients(2); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	for name, table := range f.tables {
+		if table.count() != 2 {
+			t.Fatalf("table %s: item count mismatch: have %d, want %d", name, table.count(), 2)
+		}
+	}
+	if hash, err := f.Ancient(freezerHashTable, 1); err != nil || !bytes.Equal(hash, bytes.Repeat([]byte{1}, 32)) {
+		t.Fatalf("hash mismatch: have %x, %v", hash, err)
+	}
+}
+
+// Tests that only one of the freezers sharing a blob store may write into it,
+// and that the others neither truncate nor repair the tables of the writer.
+func TestBlobFreezerSharedStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobfreezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewDirBlobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := openBlobTestFreezer(t, store)
+	for i := uint64(0); i < 5; i++ {
+		hash := bytes.Repeat([]byte{byte(i)}, 32)
+		if err := writer.AppendAncient(i, hash, []byte{1}, []byte{2}, []byte{3}, []byte{4}); err != nil {
+			t.Fatalf("block %d: failed to append: %v", i, err)
+		}
+	}
+	if err := writer.Sync(); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	// Leave a dangling segment and an unaligned table behind, as after a crash
+	if err := store.Put(freezerHashTable+"/00000007.rseg", []byte{0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.tables[freezerHashTable].Append(5, bytes.Repeat([]byte{5}, 32)); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if err := writer.tables[freezerHashTable].Sync(); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	// A second freezer must open read-only and leave the store untouched
+	reader := openBlobTestFreezer(t, store)
+	if !reader.readonly {
+		t.Fatalf("second freezer opened writable")
+	}
+	if frozen, _ := reader.Ancients(); frozen != 5 {
+		t.Fatalf("reader frozen count mismatch: have %d, want %d", frozen, 5)
+	}
+	if have := reader.tables[freezerHashTable].count(); have != 6 {
+		t.Fatalf("reader repaired the hash table: have %d items, want %d", have, 6)
+	}
+	if _, err := store.Get(freezerHashTable + "/00000007.rseg"); err != nil {
+		t.Fatalf("reader deleted dangling segment: %v", err)
+	}
+	if err := reader.AppendAncient(5, bytes.Repeat([]byte{5}, 32), []byte{1}, []byte{2}, []byte{3}, []byte{4}); err != errBlobReadOnly {
+		t.Fatalf("reader append error mismatch: have %v, want %v", err, errBlobReadOnly)
+	}
+	if err := reader.TruncateAncients(1); err != errBlobReadOnly {
+		t.Fatalf("reader truncate error mismatch: have %v, want %v", err, errBlobReadOnly)
+	}
+	if hash, err := reader.Ancient(freezerHashTable, 4); err != nil || !bytes.Equal(hash, bytes.Repeat([]byte{4}, 32)) {
+		t.Fatalf("reader hash mismatch: have %x, %v", hash, err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Fatalf("failed to close reader: %v", err)
+	}
+	// Once the writer lets go, the next freezer takes over and repairs the store
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	next := openBlobTestFreezer(t, store)
+	defer next.Close()
+
+	if next.readonly {
+		t.Fatalf("freezer opened read-only after the writer released the store")
+	}
+	if frozen, _ := next.Ancients(); frozen != 5 {
+		t.Fatalf("frozen count mismatch: have %d, want %d", frozen, 5)
+	}
+	if _, err := store.Get(freezerHashTable + "/00000007.rseg"); err != ErrBlobNotFound {
+		t.Fatalf("dangling segment not deleted: %v", err)
+	}
+}
+
+// Tests that claiming the writer lease only settles when taking over a recently
+// expired lease, which other nodes may be racing for.
+func TestBlobLeaseSettle(t *testing.T) {
+	defer func(settle time.Duration) { blobLeaseSettle = settle }(blobLeaseSettle)
+	blobLeaseSettle = 500 * time.Millisecond
+
+	now := uint64(time.Now().Unix())
+	tests := []struct {
+		expiry  uint64 // expiry of the stored lease, 0 if there's none
+		settles bool
+	}{
+		{0, false},
+		{now - uint64(2*blobLeaseTTL/time.Second), false},
+		{now - 1, true},
+	}
+	for i, tt := range tests {
+		dir, err := ioutil.TempDir("", "blobstore")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		store, err := NewDirBlobStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.expiry != 0 {
+			blob, _ := rlp.EncodeToBytes(&blobLeaseRecord{Owner: "previous", Expiry: tt.expiry})
+			if err := store.Put(blobLeaseName, blob); err != nil {
+				t.Fatal(err)
+			}
+		}
+		start := time.Now()
+		lease, err := acquireBlobLease(store)
+		if err != nil {
+			t.Fatalf("test %d: failed to acquire lease: %v", i, err)
+		}
+		if settled := time.Since(start) >= blobLeaseSettle; settled != tt.settles {
+			t.Errorf("test %d: settle mismatch: have %v, want %v", i, settled, tt.settles)
+		}
+		lease.Release()
+	}
+}
@@ -603,6 +603,11 @@ func (t *freezerTable) has(number uint64) bool {
 	return atomic.LoadUint64(&t.items) > number
 }
 
+// count returns the number of items stored in the freezer table.
+func (t *freezerTable) count() uint64 {
+	return atomic.LoadUint64(&t.items)
+}
+
 // size returns the total data size in the freezer table.
 func (t *freezerTable) size() (uint64, error) {
 	t.lock.RLock()
@@ -63,6 +63,9 @@ var (
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
+	// callTraceIndexHeadKey tracks the latest block whose call traces have been indexed.
+	callTraceIndexHeadKey = []byte("CallTraceIndexHead")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -77,12 +80,21 @@ var (
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	codePrefix            = []byte("c") // codePrefix + code hash -> account code
+	stateDiffPrefix       = []byte("D") // stateDiffPrefix + num (uint64 big endian) + hash -> reverse state diff
+	blockWitnessPrefix    = []byte("W") // blockWitnessPrefix + num (uint64 big endian) + hash -> block witness
+	callTracesPrefix      = []byte("T") // callTracesPrefix + num (uint64 big endian) + hash -> flat call traces
+	callTraceAddrPrefix   = []byte("A") // callTraceAddrPrefix + address + num (uint64 big endian) -> empty
+	logIndexPrefix        = []byte("P") // logIndexPrefix + term (address or topic) + section (uint64 big endian) + hash -> log positions (no term marks a complete section)
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
+	traceJobPrefix = []byte("trace-job-")       // traceJobPrefix + job id -> trace job specification and progress
+
+	reorgCheckpointPrefix = []byte("reorg-checkpoint-") // reorgCheckpointPrefix + num (uint64 big endian) -> operator pinned block hash
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	LogIndexPrefix       = []byte("iL") // LogIndexPrefix is the data table of the log index chain indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -165,6 +177,26 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// stateDiffKey = stateDiffPrefix + num (uint64 big endian) + hash
+func stateDiffKey(number uint64, hash common.Hash) []byte {
+	return append(append(stateDiffPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// blockWitnessKey = blockWitnessPrefix + num (uint64 big endian) + hash
+func blockWitnessKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockWitnessPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// callTracesKey = callTracesPrefix + num (uint64 big endian) + hash
+func callTracesKey(number uint64, hash common.Hash) []byte {
+	return append(append(callTracesPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// callTraceAddrKey = callTraceAddrPrefix + address + num (uint64 big endian)
+func callTraceAddrKey(address common.Address, number uint64) []byte {
+	return append(append(callTraceAddrPrefix, address.Bytes()...), encodeBlockNumber(number)...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
@@ -195,6 +227,13 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// logIndexKey = logIndexPrefix + term + section (uint64 big endian) + hash
+func logIndexKey(term []byte, section uint64, hash common.Hash) []byte {
+	key := append(append(append([]byte{}, logIndexPrefix...), term...), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(logIndexPrefix)+len(term):], section)
+	return append(key, hash.Bytes()...)
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
@@ -214,6 +253,16 @@ func IsCodeKey(key []byte) (bool, []byte) {
 	return false, nil
 }
 
+// traceJobKey = traceJobPrefix + id
+func traceJobKey(id string) []byte {
+	return append(traceJobPrefix, id...)
+}
+
+// reorgCheckpointKey = reorgCheckpointPrefix + num (uint64 big endian)
+func reorgCheckpointKey(number uint64) []byte {
+	return append(reorgCheckpointPrefix, encodeBlockNumber(number)...)
+}
+
 // configKey = configPrefix + hash
 func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
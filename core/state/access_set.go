@@ -0,0 +1,263 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/ionchain/ionchain-core/common"
+)
+
+// AccessSet records the accounts and storage slots read and written while
+// executing transactions on a state. It is used to decide whether a transaction
+// executed speculatively on an older state would have behaved identically on
+// the current one.
+//
+// Balance increases that are not accompanied by any read of the account (e.g.
+// fee payments to the coinbase) are tracked separately as credits, since they
+// commute with each other and can be replayed as deltas.
+type AccessSet struct {
+	accountReads  map[common.Address]struct{}
+	accountWrites map[common.Address]struct{}
+	credits       map[common.Address]*big.Int // Balance before the first credit
+	storageReads  map[common.Address]map[common.Hash]struct{}
+	storageWrites map[common.Address]map[common.Hash]struct{}
+	storageResets map[common.Address]struct{}
+	dirties       map[common.Address]struct{} // Accounts finalised after modification
+}
+
+// NewAccessSet creates an empty access set.
+func NewAccessSet() *AccessSet {
+	return &AccessSet{
+		accountReads:  make(map[common.Address]struct{}),
+		accountWrites: make(map[common.Address]struct{}),
+		credits:       make(map[common.Address]*big.Int),
+		storageReads:  make(map[common.Address]map[common.Hash]struct{}),
+		storageWrites: make(map[common.Address]map[common.Hash]struct{}),
+		storageResets: make(map[common.Address]struct{}),
+		dirties:       make(map[common.Address]struct{}),
+	}
+}
+
+// readAccount marks an account as read. It's a noop on a nil set, so the state
+// can call it unconditionally.
+func (a *AccessSet) readAccount(addr common.Address) {
+	if a != nil {
+		a.accountReads[addr] = struct{}{}
+	}
+}
+
+// writeAccount marks an account as both read and overwritten.
+func (a *AccessSet) writeAccount(addr common.Address) {
+	if a != nil {
+		a.accountReads[addr] = struct{}{}
+		a.accountWrites[addr] = struct{}{}
+	}
+}
+
+// resetAccount marks an account as overwritten, dropping its entire storage.
+func (a *AccessSet) resetAccount(addr common.Address) {
+	if a != nil {
+		a.writeAccount(addr)
+		a.storageResets[addr] = struct{}{}
+	}
+}
+
+// creditAccount marks a balance increase of an account, remembering the balance
+// it had before the first credit.
+func (a *AccessSet) creditAccount(addr common.Address, balance *big.Int) {
+	if a != nil {
+		if _, ok := a.credits[addr]; !ok {
+			a.credits[addr] = new(big.Int).Set(balance)
+		}
+	}
+}
+
+// readSlot marks a storage slot as read.
+func (a *AccessSet) readSlot(addr common.Address, slot common.Hash) {
+	if a != nil {
+		slots := a.storageReads[addr]
+		if slots == nil {
+			slots = make(map[common.Hash]struct{})
+			a.storageReads[addr] = slots
+		}
+		slots[slot] = struct{}{}
+	}
+}
+
+// writeSlot marks a storage slot as both read and overwritten.
+func (a *AccessSet) writeSlot(addr common.Address, slot common.Hash) {
+	if a != nil {
+		a.readSlot(addr, slot)
+
+		slots := a.storageWrites[addr]
+		if slots == nil {
+			slots = make(map[common.Hash]struct{})
+			a.storageWrites[addr] = slots
+		}
+		slots[slot] = struct{}{}
+	}
+}
+
+// markDirty marks an account as modified during a finalised transaction.
+func (a *AccessSet) markDirty(addr common.Address) {
+	if a != nil {
+		a.dirties[addr] = struct{}{}
+	}
+}
+
+// creditOnly returns whether the only modification done to an account was an
+// increase of its balance.
+func (a *AccessSet) creditOnly(addr common.Address) bool {
+	if _, ok := a.accountWrites[addr]; ok {
+		return false
+	}
+	_, ok := a.credits[addr]
+	return ok
+}
+
+// Conflicts returns whether any of the values read by the set were modified by
+// the writes of another one, i.e. whether the transactions tracked by a could
+// observe different data if executed after the ones tracked by writes.
+func (a *AccessSet) Conflicts(writes *AccessSet) bool {
+	for addr := range a.accountReads {
+		if _, ok := writes.accountWrites[addr]; ok {
+			return true
+		}
+		if _, ok := writes.credits[addr]; ok {
+			return true
+		}
+	}
+	for addr, slots := range a.storageReads {
+		if _, ok := writes.storageResets[addr]; ok {
+			return true
+		}
+		if written, ok := writes.storageWrites[addr]; ok {
+			for slot := range slots {
+				if _, ok := written[slot]; ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Merge adds all the accesses of another set into this one. The balances of the
+// credits already tracked by the set are retained.
+func (a *AccessSet) Merge(other *AccessSet) {
+	for addr := range other.accountReads {
+		a.accountReads[addr] = struct{}{}
+	}
+	for addr := range other.accountWrites {
+		a.accountWrites[addr] = struct{}{}
+	}
+	for addr, balance := range other.credits {
+		a.creditAccount(addr, balance)
+	}
+	for addr, slots := range other.storageReads {
+		for slot := range slots {
+			a.readSlot(addr, slot)
+		}
+	}
+	for addr, slots := range other.storageWrites {
+		for slot := range slots {
+			a.writeSlot(addr, slot)
+		}
+	}
+	for addr := range other.storageResets {
+		a.storageResets[addr] = struct{}{}
+	}
+	for addr := range other.dirties {
+		a.dirties[addr] = struct{}{}
+	}
+}
+
+// TrackAccesses starts recording all the state accesses into the given set. A
+// nil set stops the tracking.
+func (s *StateDB) TrackAccesses(set *AccessSet) {
+	s.accesses = set
+}
+
+// MergeTransaction applies the effects of a single finalised transaction, which
+// was executed on src (a copy of an earlier version of s) while tracking its
+// accesses into set. The caller is responsible for ensuring that the accesses
+// don't conflict with the changes done to s since the copy.
+//
+// The method returns false without modifying s if it can't faithfully replay the
+// transaction (i.e. some modification was not captured by the access set).
+func (s *StateDB) MergeTransaction(src *StateDB, set *AccessSet) bool {
+	// Ensure all the modifications are accounted for and gather them in a
+	// deterministic order
+	addrs := make([]common.Address, 0, len(set.dirties))
+	for addr := range set.dirties {
+		if _, ok := set.accountReads[addr]; !ok && !set.creditOnly(addr) {
+			return false
+		}
+		if set.creditOnly(addr) {
+			if obj := src.stateObjects[addr]; obj != nil && obj.Balance().Cmp(set.credits[addr]) < 0 {
+				return false
+			}
+		}
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	for _, addr := range addrs {
+		obj := src.stateObjects[addr]
+		if obj == nil {
+			continue // Touched but nonexistent precompile, see Finalise
+		}
+		if set.creditOnly(addr) {
+			s.AddBalance(addr, new(big.Int).Sub(obj.Balance(), set.credits[addr]))
+		} else if obj.suicided {
+			s.Suicide(addr)
+			continue
+		} else {
+			if _, ok := set.storageResets[addr]; ok {
+				s.CreateAccount(addr)
+			}
+			dst := s.GetOrNewStateObject(addr)
+			if dst.Nonce() != obj.Nonce() {
+				s.SetNonce(addr, obj.Nonce())
+			}
+			if dst.Balance().Cmp(obj.Balance()) != 0 {
+				s.SetBalance(addr, obj.Balance())
+			}
+			if !bytes.Equal(dst.CodeHash(), obj.CodeHash()) {
+				s.SetCode(addr, obj.Code(src.db))
+			}
+			s.AddBalance(addr, new(big.Int)) // Touch the account for empty-deletion
+		}
+		if obj.deleted {
+			continue
+		}
+		for slot := range set.storageWrites[addr] {
+			s.SetState(addr, slot, obj.GetState(src.db, slot))
+		}
+	}
+	for _, log := range src.GetLogs(src.thash) {
+		s.AddLog(log)
+	}
+	for hash, preimage := range src.preimages {
+		s.AddPreimage(hash, preimage)
+	}
+	return true
+}
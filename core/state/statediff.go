@@ -0,0 +1,339 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/state/snapshot"
+	"github.com/ionchain/ionchain-core/rlp"
+	"github.com/ionchain/ionchain-core/trie"
+)
+
+// errHistoricCommit is returned if a state reconstructed from reverse diffs is
+// attempted to be committed. Such states have no backing trie.
+var errHistoricCommit = errors.New("historic state cannot be committed")
+
+// StateDiff is the reverse state diff of a single block: the flat snapshot values
+// of every account and storage slot modified by the block, as they were before
+// the block was applied. Applying it on top of the block's state yields the state
+// of its parent.
+type StateDiff struct {
+	Parent   common.Hash    // State root before the block (the state the diff reverts to)
+	Root     common.Hash    // State root after the block (the state the diff applies on)
+	Accounts []*DiffAccount // Modified accounts, sorted by hash
+}
+
+// DiffAccount is the pre-block value of a single account and its modified slots.
+type DiffAccount struct {
+	Hash    common.Hash    // Hash of the account address
+	Data    []byte         // Slim RLP of the account before the block, empty if missing
+	Storage []*DiffStorage // Modified storage slots, sorted by hash
+}
+
+// DiffStorage is the pre-block value of a single storage slot.
+type DiffStorage struct {
+	Hash  common.Hash // Hash of the storage slot key
+	Value []byte      // RLP of the slot value before the block, empty if missing
+}
+
+// RecordReverseDiff enables the collection of a reverse state diff during the
+// next commit. Diffs can only be gathered if the state is backed by a snapshot.
+func (s *StateDB) RecordReverseDiff() {
+	s.diffRecording = true
+}
+
+// ReverseDiff returns the reverse state diff gathered during the last commit, or
+// nil if none was recorded.
+func (s *StateDB) ReverseDiff() *StateDiff {
+	return s.reverseDiff
+}
+
+// priorReader retrieves the flat values of the parent state of a commit. It
+// prefers the parent snapshot layer, falling back to the parent tries if the
+// snapshot is unable to serve the request (e.g. still being generated).
+type priorReader struct {
+	snap   snapshot.Snapshot
+	triedb *trie.Database
+	root   common.Hash
+	tries  map[common.Hash]*trie.Trie
+}
+
+// openTrie opens (and caches) a raw trie, keyed by hashed paths.
+func (r *priorReader) openTrie(root common.Hash) (*trie.Trie, error) {
+	if tr, ok := r.tries[root]; ok {
+		return tr, nil
+	}
+	tr, err := trie.New(root, r.triedb)
+	if err != nil {
+		return nil, err
+	}
+	r.tries[root] = tr
+	return tr, nil
+}
+
+// account retrieves the slim RLP of an account in the parent state.
+func (r *priorReader) account(hash common.Hash) ([]byte, error) {
+	if data, err := r.snap.AccountRLP(hash); err == nil {
+		return data, nil
+	}
+	tr, err := r.openTrie(r.root)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := tr.TryGet(hash[:])
+	if err != nil || len(enc) == 0 {
+		return nil, err
+	}
+	var acc Account
+	if err := rlp.DecodeBytes(enc, &acc); err != nil {
+		return nil, err
+	}
+	return snapshot.SlimAccountRLP(acc.Nonce, acc.Balance, acc.Root, acc.CodeHash), nil
+}
+
+// storageTrie opens the storage trie of an account in the parent state, or nil
+// if the account did not exist.
+func (r *priorReader) storageTrie(data []byte) (*trie.Trie, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	acc, err := snapshot.FullAccount(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.openTrie(common.BytesToHash(acc.Root))
+}
+
+// storage retrieves the RLP value of a storage slot in the parent state.
+func (r *priorReader) storage(hash common.Hash, data []byte, slot common.Hash) ([]byte, error) {
+	if value, err := r.snap.Storage(hash, slot); err == nil {
+		return value, nil
+	}
+	tr, err := r.storageTrie(data)
+	if err != nil || tr == nil {
+		return nil, err
+	}
+	return tr.TryGet(slot[:])
+}
+
+// buildReverseDiff assembles the reverse diff of the pending snapshot changes,
+// reading the pre-commit values from the parent state.
+func (s *StateDB) buildReverseDiff(parent, root common.Hash) (*StateDiff, error) {
+	reader := &priorReader{
+		snap:   s.snap,
+		triedb: s.db.TrieDB(),
+		root:   parent,
+		tries:  make(map[common.Hash]*trie.Trie),
+	}
+	var (
+		accounts = make(map[common.Hash][]byte)
+		storage  = make(map[common.Hash]map[common.Hash][]byte)
+	)
+	track := func(hash common.Hash) ([]byte, error) {
+		if data, ok := accounts[hash]; ok {
+			return data, nil
+		}
+		data, err := reader.account(hash)
+		if err != nil {
+			return nil, err
+		}
+		accounts[hash] = data
+		storage[hash] = make(map[common.Hash][]byte)
+		return data, nil
+	}
+	// Destructed accounts lose their entire storage, record all of it
+	for hash := range s.snapDestructs {
+		data, err := track(hash)
+		if err != nil {
+			return nil, err
+		}
+		tr, err := reader.storageTrie(data)
+		if err != nil {
+			return nil, err
+		}
+		if tr == nil {
+			continue
+		}
+		it := trie.NewIterator(tr.NodeIterator(nil))
+		for it.Next() {
+			storage[hash][common.BytesToHash(it.Key)] = common.CopyBytes(it.Value)
+		}
+		if it.Err != nil {
+			return nil, it.Err
+		}
+	}
+	for hash := range s.snapAccounts {
+		if _, err := track(hash); err != nil {
+			return nil, err
+		}
+	}
+	for hash, slots := range s.snapStorage {
+		data, err := track(hash)
+		if err != nil {
+			return nil, err
+		}
+		for slot := range slots {
+			if _, ok := storage[hash][slot]; ok {
+				continue
+			}
+			value, err := reader.storage(hash, data, slot)
+			if err != nil {
+				return nil, err
+			}
+			storage[hash][slot] = value
+		}
+	}
+	// Flatten the collected values into a deterministic diff
+	diff := &StateDiff{Parent: parent, Root: root}
+	for hash, data := range accounts {
+		acc := &DiffAccount{Hash: hash, Data: data}
+		for slot, value := range storage[hash] {
+			acc.Storage = append(acc.Storage, &DiffStorage{Hash: slot, Value: value})
+		}
+		sort.Slice(acc.Storage, func(i, j int) bool {
+			return bytes.Compare(acc.Storage[i].Hash[:], acc.Storage[j].Hash[:]) < 0
+		})
+		diff.Accounts = append(diff.Accounts, acc)
+	}
+	sort.Slice(diff.Accounts, func(i, j int) bool {
+		return bytes.Compare(diff.Accounts[i].Hash[:], diff.Accounts[j].Hash[:]) < 0
+	})
+	return diff, nil
+}
+
+// historicLayer is a read only snapshot layer presenting an old state, created
+// by rolling a newer snapshot back through a series of reverse diffs.
+type historicLayer struct {
+	root     common.Hash
+	base     snapshot.Snapshot
+	accounts map[common.Hash][]byte
+	storage  map[common.Hash]map[common.Hash][]byte
+}
+
+// newHistoricLayer creates a snapshot layer for the parent state of the oldest
+// diff. The diffs must be ordered from oldest to newest, the newest applying to
+// the state of the base snapshot.
+func newHistoricLayer(base snapshot.Snapshot, diffs []*StateDiff) (*historicLayer, error) {
+	layer := &historicLayer{
+		root:     base.Root(),
+		base:     base,
+		accounts: make(map[common.Hash][]byte),
+		storage:  make(map[common.Hash]map[common.Hash][]byte),
+	}
+	for i, diff := range diffs {
+		// Ensure the diffs form a contiguous chain onto the base snapshot
+		next := base.Root()
+		if i+1 < len(diffs) {
+			next = diffs[i+1].Parent
+		}
+		if diff.Root != next {
+			return nil, fmt.Errorf("state diff gap: %x != %x", diff.Root, next)
+		}
+		// The oldest diff touching an item holds its value at the target state
+		for _, acc := range diff.Accounts {
+			if _, ok := layer.accounts[acc.Hash]; !ok {
+				layer.accounts[acc.Hash] = acc.Data
+			}
+			slots := layer.storage[acc.Hash]
+			if slots == nil {
+				slots = make(map[common.Hash][]byte)
+				layer.storage[acc.Hash] = slots
+			}
+			for _, slot := range acc.Storage {
+				if _, ok := slots[slot.Hash]; !ok {
+					slots[slot.Hash] = slot.Value
+				}
+			}
+		}
+	}
+	if len(diffs) > 0 {
+		layer.root = diffs[0].Parent
+	}
+	return layer, nil
+}
+
+// Root returns the root hash of the state the layer represents.
+func (l *historicLayer) Root() common.Hash {
+	return l.root
+}
+
+// Account directly retrieves the account associated with a particular hash in
+// the snapshot slim data format.
+func (l *historicLayer) Account(hash common.Hash) (*snapshot.Account, error) {
+	data, err := l.AccountRLP(hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 { // can be both nil and []byte{}
+		return nil, nil
+	}
+	account := new(snapshot.Account)
+	if err := rlp.DecodeBytes(data, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// AccountRLP directly retrieves the account RLP associated with a particular
+// hash in the snapshot slim data format.
+func (l *historicLayer) AccountRLP(hash common.Hash) ([]byte, error) {
+	if data, ok := l.accounts[hash]; ok {
+		return data, nil
+	}
+	return l.base.AccountRLP(hash)
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account.
+func (l *historicLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	// Accounts missing from the old state can't have storage, whatever the
+	// newer layers contain
+	if data, ok := l.accounts[accountHash]; ok {
+		if len(data) == 0 {
+			return nil, nil
+		}
+		if value, ok := l.storage[accountHash][storageHash]; ok {
+			return value, nil
+		}
+	}
+	return l.base.Storage(accountHash, storageHash)
+}
+
+// NewHistoric creates a read only state of an old block, reconstructed from the
+// flat snapshot of a newer block and the reverse diffs of the blocks in between,
+// ordered from oldest to newest. The state has no backing trie, so it can not be
+// committed and can't produce proofs.
+func NewHistoric(db Database, base snapshot.Snapshot, diffs []*StateDiff) (*StateDB, error) {
+	layer, err := newHistoricLayer(base, diffs)
+	if err != nil {
+		return nil, err
+	}
+	sdb, err := New(common.Hash{}, db, nil)
+	if err != nil {
+		return nil, err
+	}
+	sdb.snap, sdb.snapOnly = layer, true
+	sdb.snapDestructs = make(map[common.Hash]struct{})
+	sdb.snapAccounts = make(map[common.Hash][]byte)
+	sdb.snapStorage = make(map[common.Hash]map[common.Hash][]byte)
+	return sdb, nil
+}
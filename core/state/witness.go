@@ -0,0 +1,166 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/crypto"
+	"github.com/ionchain/ionchain-core/ioncdb"
+	"github.com/ionchain/ionchain-core/ioncdb/memorydb"
+	"github.com/ionchain/ionchain-core/trie"
+)
+
+// errNotTrieNode is returned by the node recorder for non trie node lookups.
+var errNotTrieNode = errors.New("not a trie node")
+
+// nodeRecorder is a key-value store serving the trie nodes of a trie database,
+// retaining a copy of every node retrieved through it. Writes are accepted but
+// never reach the source database.
+type nodeRecorder struct {
+	ioncdb.KeyValueStore // Scratch store absorbing any writes
+
+	source *trie.Database
+	nodes  map[common.Hash][]byte
+	lock   sync.Mutex
+}
+
+// Has retrieves if a trie node is present in the source database.
+func (r *nodeRecorder) Has(key []byte) (bool, error) {
+	if _, err := r.Get(key); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Get retrieves a trie node from the source database, recording it.
+func (r *nodeRecorder) Get(key []byte) ([]byte, error) {
+	if len(key) != common.HashLength {
+		return nil, errNotTrieNode
+	}
+	hash := common.BytesToHash(key)
+	blob, err := r.source.Node(hash)
+	if err != nil {
+		return nil, err
+	}
+	r.lock.Lock()
+	r.nodes[hash] = blob
+	r.lock.Unlock()
+
+	return blob, nil
+}
+
+// RecordingDatabase is a read only state database which retains all the trie
+// nodes and contract codes accessed through it. It can be used to gather the
+// minimal set of data needed to replay a state transition without access to
+// the full state.
+type RecordingDatabase struct {
+	Database // Database resolving tries through the node recorder
+
+	source Database
+	nodes  *nodeRecorder
+	codes  map[common.Hash][]byte
+	lock   sync.Mutex
+}
+
+// NewRecordingDatabase creates a state database on top of an existing one,
+// recording all the data accessed. Any modifications done to states opened
+// through the recording database are not persisted.
+func NewRecordingDatabase(source Database) *RecordingDatabase {
+	recorder := &nodeRecorder{
+		KeyValueStore: memorydb.New(),
+		source:        source.TrieDB(),
+		nodes:         make(map[common.Hash][]byte),
+	}
+	return &RecordingDatabase{
+		Database: NewDatabase(rawdb.NewDatabase(recorder)),
+		source:   source,
+		nodes:    recorder,
+		codes:    make(map[common.Hash][]byte),
+	}
+}
+
+// ContractCode retrieves a particular contract's code, recording it.
+func (db *RecordingDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	code, err := db.source.ContractCode(addrHash, codeHash)
+	if err != nil {
+		return nil, err
+	}
+	db.lock.Lock()
+	db.codes[codeHash] = code
+	db.lock.Unlock()
+
+	return code, nil
+}
+
+// ContractCodeSize retrieves a particular contracts code's size. The code itself
+// is recorded as it's needed to prove the size.
+func (db *RecordingDatabase) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(addrHash, codeHash)
+	return len(code), err
+}
+
+// Nodes returns all the trie nodes accessed so far, sorted by hash.
+func (db *RecordingDatabase) Nodes() [][]byte {
+	db.nodes.lock.Lock()
+	defer db.nodes.lock.Unlock()
+
+	return sortedBlobs(db.nodes.nodes)
+}
+
+// Codes returns all the contract codes accessed so far, sorted by hash.
+func (db *RecordingDatabase) Codes() [][]byte {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return sortedBlobs(db.codes)
+}
+
+// sortedBlobs flattens a set of hash addressed blobs into a list, ordered by
+// their hashes.
+func sortedBlobs(blobs map[common.Hash][]byte) [][]byte {
+	hashes := make([]common.Hash, 0, len(blobs))
+	for hash := range blobs {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+	list := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		list[i] = blobs[hash]
+	}
+	return list
+}
+
+// NewWitnessDatabase creates an in-memory state database populated with the
+// given trie nodes and contract codes, as gathered by a RecordingDatabase.
+func NewWitnessDatabase(nodes, codes [][]byte) Database {
+	db := rawdb.NewMemoryDatabase()
+	for _, node := range nodes {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	for _, code := range codes {
+		rawdb.WriteCode(db, crypto.Keccak256Hash(code), code)
+	}
+	return NewDatabase(db)
+}
@@ -67,6 +67,16 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	//if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
 	//	misc.ApplyDAOHardFork(statedb)
 	//}
+	// Execute the transactions optimistically in parallel if enabled. Tracers
+	// expect to see every transaction executed once, in order, so skip it then.
+	if p.bc.cacheConfig.ParallelExec && !cfg.Debug && p.config.IsByzantium(header.Number) && len(block.Transactions()) > 1 {
+		receipts, allLogs, err := applyTransactionsParallel(p.config, p.bc, nil, gp, statedb, header, block.Hash(), block.Transactions(), usedGas, cfg)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles())
+		return receipts, allLogs, *usedGas, nil
+	}
 	blockContext := NewEVMBlockContext(header, p.bc, nil)
 	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, p.config, cfg)
 	// Iterate over and process the individual transactions
@@ -94,14 +104,7 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 	txContext := NewEVMTxContext(msg)
 	// Add addresses to access list if applicable
 	if config.IsYoloV2(header.Number) {
-		statedb.AddAddressToAccessList(msg.From())
-		if dst := msg.To(); dst != nil {
-			statedb.AddAddressToAccessList(*dst)
-			// If it's a create-tx, the destination will be added inside evm.create
-		}
-		for _, addr := range evm.ActivePrecompiles() {
-			statedb.AddAddressToAccessList(addr)
-		}
+		statedb.PrepareAccessList(msg.From(), msg.To(), evm.ActivePrecompiles(), nil)
 	}
 
 	// Update the evm with the new transaction context.
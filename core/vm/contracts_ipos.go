@@ -0,0 +1,139 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/crypto"
+	"github.com/ionchain/ionchain-core/params"
+)
+
+var (
+	// IPosStakingAddress is the address of the IPos staking precompile, right
+	// next to the staking contract itself.
+	IPosStakingAddress = common.HexToAddress("0x0000000000000000000000000000000000000101")
+
+	// IPosContractAddress is the address of the staking contract the IPos
+	// consensus engine derives the mint power of block producers from.
+	IPosContractAddress = common.HexToAddress("0x0000000000000000000000000000000000000100")
+)
+
+// Method selectors of the IPos staking precompile, following the Solidity ABI.
+var (
+	iposMintPowerSelector           = crypto.Keccak256([]byte("mintPower(address)"))[:4]
+	iposBaseTargetSelector          = crypto.Keccak256([]byte("baseTarget()"))[:4]
+	iposGenerationSignatureSelector = crypto.Keccak256([]byte("generationSignature(uint256)"))[:4]
+)
+
+var errIPosStakingInvalidInputLength = errors.New("invalid input length")
+
+// iposStaking is a precompile exposing the consensus level staking data of the
+// IPos engine to contracts, through the Solidity ABI methods:
+//   - mintPower(address) returns the effective mint power of an address
+//   - baseTarget() returns the base target of the current block
+//   - generationSignature(uint256) returns the generation signature of one of the
+//     256 most recent blocks, or zero for any other block
+//
+// Calls to any other method revert without consuming gas, like calls to a
+// Solidity contract without a fallback function.
+//
+// Unlike the other precompiles it needs access to the block context and state,
+// so it's bound to the EVM executing it.
+type iposStaking struct {
+	evm *EVM
+}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *iposStaking) RequiredGas(input []byte) uint64 {
+	if len(input) < 4 {
+		return 0
+	}
+	switch {
+	case bytes.Equal(input[:4], iposMintPowerSelector):
+		return params.IPosMintPowerGas
+	case bytes.Equal(input[:4], iposBaseTargetSelector):
+		return params.IPosBaseTargetGas
+	case bytes.Equal(input[:4], iposGenerationSignatureSelector):
+		return params.IPosGenerationSignatureGas
+	default:
+		return 0
+	}
+}
+
+// Run dispatches the call to the requested method.
+func (c *iposStaking) Run(input []byte) ([]byte, error) {
+	if len(input) < 4 {
+		return nil, ErrExecutionReverted
+	}
+	method, args := input[:4], input[4:]
+	switch {
+	case bytes.Equal(method, iposMintPowerSelector):
+		if len(args) != 32 {
+			return nil, errIPosStakingInvalidInputLength
+		}
+		return c.mintPower(common.BytesToAddress(args))
+
+	case bytes.Equal(method, iposBaseTargetSelector):
+		if len(args) != 0 {
+			return nil, errIPosStakingInvalidInputLength
+		}
+		if c.evm.Context.BaseTarget == nil {
+			return common.LeftPadBytes(nil, 32), nil
+		}
+		return common.LeftPadBytes(c.evm.Context.BaseTarget.Bytes(), 32), nil
+
+	case bytes.Equal(method, iposGenerationSignatureSelector):
+		if len(args) != 32 {
+			return nil, errIPosStakingInvalidInputLength
+		}
+		return c.generationSignature(new(big.Int).SetBytes(args)), nil
+	}
+	return nil, ErrExecutionReverted
+}
+
+// mintPower queries the staking contract for the mint power of an address, in
+// the same units the IPos engine weighs block producers by.
+func (c *iposStaking) mintPower(addr common.Address) ([]byte, error) {
+	input := append(common.CopyBytes(iposMintPowerSelector), common.LeftPadBytes(addr.Bytes(), 32)...)
+	ret, _, err := c.evm.StaticCall(AccountRef(IPosStakingAddress), IPosContractAddress, input, params.IPosMintPowerGas)
+	if err != nil {
+		return nil, err
+	}
+	power := new(big.Int).SetBytes(ret)
+	power.Div(power, big.NewInt(params.Ether))
+	return common.LeftPadBytes(power.Bytes(), 32), nil
+}
+
+// generationSignature returns the generation signature of the given block, if
+// it's one of the 256 blocks preceding the current one.
+func (c *iposStaking) generationSignature(number *big.Int) []byte {
+	var (
+		current = c.evm.Context.BlockNumber.Uint64()
+		lower   uint64
+	)
+	if current > 256 {
+		lower = current - 256
+	}
+	if !number.IsUint64() || number.Uint64() < lower || number.Uint64() >= current || c.evm.Context.GetGenerationSignature == nil {
+		return common.LeftPadBytes(nil, 32)
+	}
+	return common.LeftPadBytes(c.evm.Context.GetGenerationSignature(number.Uint64()), 32)
+}
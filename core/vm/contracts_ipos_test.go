@@ -0,0 +1,122 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/params"
+)
+
+// newIPosTestEVM creates an EVM at the given block, with the staking precompile
+// activated at block 10 and a staking contract reporting 5 ether of stake for
+// every address.
+func newIPosTestEVM(t *testing.T, number uint64) *EVM {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	// PUSH8 5 ether, PUSH1 0, MSTORE, PUSH1 32, PUSH1 0, RETURN
+	statedb.SetCode(IPosContractAddress, common.FromHex("0x674563918244f4000060005260206000f3"))
+
+	config := *params.TestChainConfig
+	config.IPosStakingBlock = big.NewInt(10)
+
+	context := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		GetGenerationSignature: func(n uint64) []byte {
+			return []byte{byte(n), 0xff}
+		},
+		BlockNumber: new(big.Int).SetUint64(number),
+		BaseTarget:  big.NewInt(0x1234),
+	}
+	return NewEVM(context, TxContext{}, statedb, &config, Config{})
+}
+
+// iposCall assembles the input of a staking precompile call.
+func iposCall(selector []byte, args ...[]byte) []byte {
+	input := common.CopyBytes(selector)
+	for _, arg := range args {
+		input = append(input, common.LeftPadBytes(arg, 32)...)
+	}
+	return input
+}
+
+// Tests that the staking precompile is only reachable from its fork block on.
+func TestIPosStakingActivation(t *testing.T) {
+	caller := AccountRef(common.HexToAddress("0xc0ffee"))
+	input := iposCall(iposBaseTargetSelector)
+
+	if _, ok := newIPosTestEVM(t, 9).precompile(IPosStakingAddress); ok {
+		t.Fatalf("staking precompile active before its fork block")
+	}
+	ret, _, err := newIPosTestEVM(t, 9).Call(caller, IPosStakingAddress, input, 100000, new(big.Int))
+	if err != nil || len(ret) != 0 {
+		t.Fatalf("call before fork block: have %x, %v, want empty result", ret, err)
+	}
+	evm := newIPosTestEVM(t, 10)
+	if _, ok := evm.precompile(IPosStakingAddress); !ok {
+		t.Fatalf("staking precompile inactive at its fork block")
+	}
+	var found bool
+	for _, addr := range evm.ActivePrecompiles() {
+		found = found || addr == IPosStakingAddress
+	}
+	if !found {
+		t.Fatalf("staking precompile missing from the active precompiles")
+	}
+}
+
+// Tests that the staking precompile dispatches the ABI methods, charges their
+// gas and reverts calls to unknown methods without consuming gas.
+func TestIPosStakingDispatch(t *testing.T) {
+	caller := AccountRef(common.HexToAddress("0xc0ffee"))
+	tests := []struct {
+		name  string
+		input []byte
+		gas   uint64 // Gas used if the call succeeds
+		want  []byte
+		err   error
+	}{
+		{"baseTarget", iposCall(iposBaseTargetSelector), params.IPosBaseTargetGas, common.LeftPadBytes([]byte{0x12, 0x34}, 32), nil},
+		{"generationSignature", iposCall(iposGenerationSignatureSelector, []byte{99}), params.IPosGenerationSignatureGas, common.LeftPadBytes([]byte{99, 0xff}, 32), nil},
+		{"generationSignatureFuture", iposCall(iposGenerationSignatureSelector, []byte{100}), params.IPosGenerationSignatureGas, make([]byte, 32), nil},
+		{"mintPower", iposCall(iposMintPowerSelector, common.HexToAddress("0xf00d").Bytes()), params.IPosMintPowerGas, common.LeftPadBytes([]byte{5}, 32), nil},
+		{"unknownMethod", iposCall([]byte{0xde, 0xad, 0xbe, 0xef}), 0, nil, ErrExecutionReverted},
+		{"shortInput", []byte{0x01}, 0, nil, ErrExecutionReverted},
+	}
+	for _, tt := range tests {
+		evm := newIPosTestEVM(t, 100)
+		ret, left, err := evm.Call(caller, IPosStakingAddress, tt.input, 100000, new(big.Int))
+		if err != tt.err {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if !bytes.Equal(ret, tt.want) {
+			t.Errorf("%s: result mismatch: have %x, want %x", tt.name, ret, tt.want)
+		}
+		if used := 100000 - left; used != tt.gas {
+			t.Errorf("%s: gas used mismatch: have %d, want %d", tt.name, used, tt.gas)
+		}
+	}
+}
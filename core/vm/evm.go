@@ -40,21 +40,35 @@ type (
 	// GetHashFunc returns the n'th block hash in the blockchain
 	// and is used by the BLOCKHASH EVM op code.
 	GetHashFunc func(uint64) common.Hash
+	// GetGenerationSignatureFunc returns the IPos generation signature of the
+	// n'th block in the blockchain and is used by the IPos staking precompile.
+	GetGenerationSignatureFunc func(uint64) []byte
 )
 
 // ActivePrecompiles returns the addresses of the precompiles enabled with the current
 // configuration
 func (evm *EVM) ActivePrecompiles() []common.Address {
+	return ActivePrecompiles(evm.chainRules)
+}
+
+// ActivePrecompiles returns the addresses of the precompiles enabled by the given
+// chain rules.
+func ActivePrecompiles(rules params.Rules) []common.Address {
+	var precompiles []common.Address
 	switch {
-	case evm.chainRules.IsYoloV2:
-		return PrecompiledAddressesYoloV2
-	case evm.chainRules.IsIstanbul:
-		return PrecompiledAddressesIstanbul
-	case evm.chainRules.IsByzantium:
-		return PrecompiledAddressesByzantium
+	case rules.IsYoloV2:
+		precompiles = PrecompiledAddressesYoloV2
+	case rules.IsIstanbul:
+		precompiles = PrecompiledAddressesIstanbul
+	case rules.IsByzantium:
+		precompiles = PrecompiledAddressesByzantium
 	default:
-		return PrecompiledAddressesHomestead
+		precompiles = PrecompiledAddressesHomestead
+	}
+	if rules.IsIPosStaking {
+		precompiles = append(append([]common.Address{}, precompiles...), IPosStakingAddress)
 	}
+	return precompiles
 }
 
 func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
@@ -70,6 +84,9 @@ func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 		precompiles = PrecompiledContractsHomestead
 	}
 	p, ok := precompiles[addr]
+	if !ok && evm.chainRules.IsIPosStaking && addr == IPosStakingAddress {
+		return &iposStaking{evm: evm}, true
+	}
 	return p, ok
 }
 
@@ -101,6 +118,8 @@ type BlockContext struct {
 	Transfer TransferFunc
 	// GetHash returns the hash corresponding to n
 	GetHash GetHashFunc
+	// GetGenerationSignature returns the generation signature corresponding to n
+	GetGenerationSignature GetGenerationSignatureFunc
 
 	// Block information
 	Coinbase    common.Address // Provides information for COINBASE
@@ -108,6 +127,7 @@ type BlockContext struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+	BaseTarget  *big.Int       // Provides the IPos base target for the staking precompile
 }
 
 // TxContext provides the EVM with information about a transaction.
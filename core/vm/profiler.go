@@ -0,0 +1,255 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+)
+
+// ProfileEntry is the aggregated cost of a set of executed instructions.
+type ProfileEntry struct {
+	Count uint64        `json:"count"` // Number of instructions executed
+	Gas   uint64        `json:"gas"`   // Gas spent by the instructions themselves
+	Time  time.Duration `json:"time"`  // Wall time spent in nanoseconds
+}
+
+// ProfilePcEntry is the aggregated cost of the instruction at a program counter.
+type ProfilePcEntry struct {
+	Op string `json:"op"`
+	ProfileEntry
+}
+
+// ProfileResult is the execution profile assembled by the Profiler.
+type ProfileResult struct {
+	Gas       uint64                                        `json:"gas"`
+	Time      time.Duration                                 `json:"time"`
+	Opcodes   map[string]*ProfileEntry                      `json:"opcodes"`
+	Contracts map[common.Address]*ProfileEntry              `json:"contracts"`
+	Pcs       map[common.Address]map[uint64]*ProfilePcEntry `json:"pcs"`
+}
+
+// profileStep is an executed instruction, along with the entries it's accounted in.
+type profileStep struct {
+	gas     uint64 // Gas available before the instruction
+	cost    uint64 // Gas cost of the instruction as reported by the interpreter
+	entries [4]*ProfileEntry
+}
+
+// profileFrame is a call frame being executed.
+type profileFrame struct {
+	stack    string       // Folded call stack of the frame, by code address
+	pending  *profileStep // Last instruction executed in the frame, cost still unknown
+	childGas uint64       // Gas spent by the frames called by the pending instruction
+	total    uint64       // Gas spent by the frame and its callees
+}
+
+// Profiler is an EVM tracer attributing the gas and the wall time spent to the
+// opcodes, contracts and program counters executing them. The program counters
+// are tracked per code address, so they can be mapped back to the source with
+// the source maps of the compiler.
+//
+// Gas is attributed exclusively: the gas forwarded to a call is accounted in the
+// instructions of the callee, and only the remainder in the call itself. Time is
+// attributed to an instruction up until the next one starts executing.
+//
+// Besides the JSON result, the profile can be written as folded call stacks for
+// flame graph tools.
+type Profiler struct {
+	result *ProfileResult
+	stacks map[string]*ProfileEntry // Profile by folded call stack
+
+	frames   []*profileFrame
+	last     *profileStep // Last instruction executed, with its time unsettled
+	lastTime time.Time    // Time the last instruction started executing
+}
+
+// NewProfiler creates a new EVM profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		result: &ProfileResult{
+			Opcodes:   make(map[string]*ProfileEntry),
+			Contracts: make(map[common.Address]*ProfileEntry),
+			Pcs:       make(map[common.Address]map[uint64]*ProfilePcEntry),
+		},
+		stacks: make(map[string]*ProfileEntry),
+	}
+}
+
+// CaptureStart implements the Tracer interface, it's a no-op.
+func (p *Profiler) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the Tracer interface, accounting the instruction.
+func (p *Profiler) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, rStack *ReturnStack, rData []byte, contract *Contract, depth int, err error) error {
+	now := time.Now()
+	p.settleTime(now)
+
+	// Settle the frames returned from, and the previous instruction of this frame
+	for len(p.frames) > depth {
+		p.exitFrame()
+	}
+	if len(p.frames) == depth {
+		frame := p.frames[depth-1]
+		if step := frame.pending; step != nil {
+			var spent uint64
+			if step.gas > gas {
+				spent = step.gas - gas
+			}
+			if spent > frame.childGas {
+				p.settleGas(frame, step, spent-frame.childGas)
+			}
+			frame.childGas = 0
+		}
+	}
+	// Enter the called frame, if any, and account the instruction in it
+	addr := contract.Address()
+	if contract.CodeAddr != nil {
+		addr = *contract.CodeAddr
+	}
+	if len(p.frames) < depth {
+		stack := addr.Hex()
+		if len(p.frames) > 0 {
+			stack = p.frames[len(p.frames)-1].stack + ";" + stack
+		}
+		p.frames = append(p.frames, &profileFrame{stack: stack})
+	}
+	frame := p.frames[len(p.frames)-1]
+
+	step := &profileStep{gas: gas, cost: cost}
+
+	if step.entries[0] = p.result.Opcodes[op.String()]; step.entries[0] == nil {
+		step.entries[0] = new(ProfileEntry)
+		p.result.Opcodes[op.String()] = step.entries[0]
+	}
+	if step.entries[1] = p.result.Contracts[addr]; step.entries[1] == nil {
+		step.entries[1] = new(ProfileEntry)
+		p.result.Contracts[addr] = step.entries[1]
+	}
+	pcs := p.result.Pcs[addr]
+	if pcs == nil {
+		pcs = make(map[uint64]*ProfilePcEntry)
+		p.result.Pcs[addr] = pcs
+	}
+	if pcs[pc] == nil {
+		pcs[pc] = &ProfilePcEntry{Op: op.String()}
+	}
+	step.entries[2] = &pcs[pc].ProfileEntry
+
+	folded := fmt.Sprintf("%s;%s@%d", frame.stack, op, pc)
+	if step.entries[3] = p.stacks[folded]; step.entries[3] == nil {
+		step.entries[3] = new(ProfileEntry)
+		p.stacks[folded] = step.entries[3]
+	}
+	for _, entry := range step.entries {
+		entry.Count++
+	}
+	frame.pending = step
+	p.last, p.lastTime = step, now
+	return nil
+}
+
+// CaptureFault implements the Tracer interface, it's a no-op.
+func (p *Profiler) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, rStack *ReturnStack, contract *Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the Tracer interface, settling the remaining frames.
+func (p *Profiler) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	p.settleTime(time.Now())
+	for len(p.frames) > 0 {
+		p.exitFrame()
+	}
+	return nil
+}
+
+// settleTime accounts the time elapsed since the last instruction started.
+func (p *Profiler) settleTime(now time.Time) {
+	if p.last == nil {
+		return
+	}
+	elapsed := now.Sub(p.lastTime)
+	for _, entry := range p.last.entries {
+		entry.Time += elapsed
+	}
+	p.result.Time += elapsed
+	p.last = nil
+}
+
+// settleGas accounts the gas spent by an instruction of a frame.
+func (p *Profiler) settleGas(frame *profileFrame, step *profileStep, gas uint64) {
+	for _, entry := range step.entries {
+		entry.Gas += gas
+	}
+	frame.total += gas
+	p.result.Gas += gas
+}
+
+// exitFrame settles the last instruction of the innermost frame with its reported
+// cost, as there's no subsequent instruction to measure against, and accounts the
+// gas spent by the frame in its caller.
+func (p *Profiler) exitFrame() {
+	frame := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:len(p.frames)-1]
+
+	if frame.pending != nil {
+		p.settleGas(frame, frame.pending, frame.pending.cost)
+	}
+	if len(p.frames) > 0 {
+		parent := p.frames[len(p.frames)-1]
+		parent.childGas += frame.total
+		parent.total += frame.total
+	}
+}
+
+// Result returns the profile assembled so far.
+func (p *Profiler) Result() *ProfileResult {
+	return p.result
+}
+
+// WriteFolded writes the profile as folded call stacks, one line per stack with
+// the instruction being the leaf, weighted by either "gas" or "time".
+func (p *Profiler) WriteFolded(w io.Writer, weight string) error {
+	if weight != "gas" && weight != "time" {
+		return fmt.Errorf("unknown profile weight %q", weight)
+	}
+	stacks := make([]string, 0, len(p.stacks))
+	for stack := range p.stacks {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	var out strings.Builder
+	for _, stack := range stacks {
+		value := p.stacks[stack].Gas
+		if weight == "time" {
+			value = uint64(p.stacks[stack].Time)
+		}
+		if value > 0 {
+			fmt.Fprintf(&out, "%s %d\n", stack, value)
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/params"
+)
+
+// Tests that the profiler attributes the gas exclusively to the instructions
+// spending it, the gas forwarded to a call being accounted in the callee, and
+// that the wall time is attributed to exactly one instruction at a time.
+func TestProfilerAttribution(t *testing.T) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	var (
+		caller = common.HexToAddress("0xc0ffee")
+		outer  = common.HexToAddress("0xaa")
+		inner  = common.HexToAddress("0xbb")
+	)
+	// CALL(GAS, 0xbb, 0, 0, 0, 0, 0), POP, STOP
+	statedb.SetCode(outer, common.FromHex("0x6000600060006000600060bb5af15000"))
+	// SSTORE(0, 1), STOP
+	statedb.SetCode(inner, common.FromHex("0x600160005500"))
+
+	profiler := NewProfiler()
+	context := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(1),
+	}
+	evm := NewEVM(context, TxContext{}, statedb, params.TestChainConfig, Config{Debug: true, Tracer: profiler})
+
+	gas := uint64(100000)
+	_, left, err := evm.Call(AccountRef(caller), outer, nil, gas, new(big.Int))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	result := profiler.Result()
+
+	// The gas must add up to the gas used, split exclusively
+	if result.Gas != gas-left {
+		t.Errorf("total gas mismatch: have %d, want %d", result.Gas, gas-left)
+	}
+	var sum uint64
+	for _, entry := range result.Contracts {
+		sum += entry.Gas
+	}
+	if sum != result.Gas {
+		t.Errorf("contract gas doesn't add up: have %d, want %d", sum, result.Gas)
+	}
+	if have, want := result.Contracts[inner].Gas, 3+3+params.SstoreSetGasEIP2200; have != want {
+		t.Errorf("callee gas mismatch: have %d, want %d", have, want)
+	}
+	if have, want := result.Opcodes["CALL"].Gas, params.CallGasEIP150; have != want {
+		t.Errorf("call gas mismatch: have %d, want %d", have, want)
+	}
+	if have, want := result.Opcodes["SSTORE"].Gas, params.SstoreSetGasEIP2200; have != want {
+		t.Errorf("sstore gas mismatch: have %d, want %d", have, want)
+	}
+	if have := result.Opcodes["PUSH1"].Count; have != 8 {
+		t.Errorf("push count mismatch: have %d, want 8", have)
+	}
+	if pc := result.Pcs[inner][4]; pc == nil || pc.Op != "SSTORE" || pc.Gas != params.SstoreSetGasEIP2200 {
+		t.Errorf("sstore pc entry mismatch: have %+v", pc)
+	}
+	// The time must be attributed to exactly one instruction at a time
+	var elapsed time.Duration
+	for _, entry := range result.Opcodes {
+		elapsed += entry.Time
+	}
+	if result.Time <= 0 || elapsed != result.Time {
+		t.Errorf("opcode time doesn't add up: have %v, want %v", elapsed, result.Time)
+	}
+	// The folded stacks must nest the callee under the caller
+	var folded strings.Builder
+	if err := profiler.WriteFolded(&folded, "gas"); err != nil {
+		t.Fatalf("failed to write folded stacks: %v", err)
+	}
+	want := fmt.Sprintf("%s;%s;SSTORE@4 %d\n", outer.Hex(), inner.Hex(), params.SstoreSetGasEIP2200)
+	if !strings.Contains(folded.String(), want) {
+		t.Errorf("folded stacks miss %q:\n%s", want, folded.String())
+	}
+	if err := profiler.WriteFolded(&folded, "memory"); err == nil {
+		t.Errorf("unknown weight accepted")
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/golang/snappy"
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/consensus"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/params"
+	"github.com/ionchain/ionchain-core/rlp"
+	"github.com/ionchain/ionchain-core/trie"
+)
+
+// errMissingWitnessParent is returned if a witness doesn't contain the header
+// of the parent of the block it's supposed to verify.
+var errMissingWitnessParent = errors.New("witness missing parent header")
+
+// Witness is the data needed to verify a block without access to the chain
+// database: the trie nodes and contract codes of the parent state accessed while
+// executing the block, and the ancestor headers it referenced.
+type Witness struct {
+	Headers []*types.Header // Ancestor headers accessed, the parent included
+	Codes   [][]byte        // Contract codes accessed, sorted by hash
+	Nodes   [][]byte        // Pre-state trie nodes accessed, sorted by hash
+}
+
+// EncodeCompact serializes the witness into its RLP form, snappy compressed.
+func (w *Witness) EncodeCompact() ([]byte, error) {
+	blob, err := rlp.EncodeToBytes(w)
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, blob), nil
+}
+
+// DecodeWitness parses a witness from its compact encoding.
+func DecodeWitness(blob []byte) (*Witness, error) {
+	blob, err := snappy.Decode(nil, blob)
+	if err != nil {
+		return nil, err
+	}
+	witness := new(Witness)
+	if err := rlp.DecodeBytes(blob, witness); err != nil {
+		return nil, err
+	}
+	return witness, nil
+}
+
+// chainReader is the chain access needed to execute and finalize a block.
+type chainReader interface {
+	consensus.ChainHeaderReader
+
+	// Engine retrieves the chain's consensus engine.
+	Engine() consensus.Engine
+}
+
+// headerRecorder is a chain reader retaining all the headers retrieved.
+type headerRecorder struct {
+	chainReader
+	headers map[common.Hash]*types.Header
+}
+
+// record retains a header if it exists.
+func (r *headerRecorder) record(header *types.Header) *types.Header {
+	if header != nil {
+		r.headers[header.Hash()] = header
+	}
+	return header
+}
+
+// GetHeader retrieves a block header by hash and number, recording it.
+func (r *headerRecorder) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return r.record(r.chainReader.GetHeader(hash, number))
+}
+
+// GetHeaderByNumber retrieves a block header by number, recording it.
+func (r *headerRecorder) GetHeaderByNumber(number uint64) *types.Header {
+	return r.record(r.chainReader.GetHeaderByNumber(number))
+}
+
+// GetHeaderByHash retrieves a block header by hash, recording it.
+func (r *headerRecorder) GetHeaderByHash(hash common.Hash) *types.Header {
+	return r.record(r.chainReader.GetHeaderByHash(hash))
+}
+
+// witnessChain is a chain reader serving the ancestor headers of a witness. All
+// lookups are anchored to the parent of the verified block via the hash chain,
+// so the headers can't be forged.
+type witnessChain struct {
+	config  *params.ChainConfig
+	engine  consensus.Engine
+	parent  *types.Header
+	headers map[common.Hash]*types.Header
+}
+
+func (c *witnessChain) Config() *params.ChainConfig  { return c.config }
+func (c *witnessChain) Engine() consensus.Engine     { return c.engine }
+func (c *witnessChain) CurrentHeader() *types.Header { return c.parent }
+
+// GetHeader retrieves a header of the witness by hash and number.
+func (c *witnessChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+// GetHeaderByHash retrieves a header of the witness by hash.
+func (c *witnessChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.headers[hash]
+}
+
+// GetHeaderByNumber retrieves an ancestor header of the witness by number.
+func (c *witnessChain) GetHeaderByNumber(number uint64) *types.Header {
+	header := c.parent
+	for header != nil && header.Number.Uint64() > number {
+		header = c.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return header
+}
+
+// executeBlock applies the transactions of a block and finalizes it on top of
+// the given state, returning the receipts and the gas used.
+func executeBlock(config *params.ChainConfig, chain chainReader, engine consensus.Engine, block *types.Block, statedb *state.StateDB) (types.Receipts, uint64, error) {
+	var (
+		receipts types.Receipts
+		usedGas  uint64
+		header   = block.Header()
+		gp       = new(GasPool).AddGas(block.GasLimit())
+	)
+	for i, tx := range block.Transactions() {
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, err := ApplyTransaction(config, chain, nil, gp, statedb, header, tx, &usedGas, vm.Config{})
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	engine.Finalize(chain, header, statedb, block.Transactions(), block.Uncles())
+	return receipts, usedGas, nil
+}
+
+// ExecuteStateless verifies a block using only the data contained in its
+// witness, without access to any chain or state database. It returns the state
+// root resulting from the execution, or an error if the witness is incomplete or
+// the block is invalid.
+//
+// Note, only the state transition is verified, the consensus rules of the header
+// are left to the caller.
+func ExecuteStateless(config *params.ChainConfig, engine consensus.Engine, block *types.Block, witness *Witness) (common.Hash, error) {
+	chain := &witnessChain{
+		config:  config,
+		engine:  engine,
+		headers: make(map[common.Hash]*types.Header, len(witness.Headers)),
+	}
+	for _, header := range witness.Headers {
+		chain.headers[header.Hash()] = header
+	}
+	if chain.parent = chain.GetHeader(block.ParentHash(), block.NumberU64()-1); chain.parent == nil {
+		return common.Hash{}, errMissingWitnessParent
+	}
+	statedb, err := state.New(chain.parent.Root, state.NewWitnessDatabase(witness.Nodes, witness.Codes), nil)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("incomplete witness: %v", err)
+	}
+	receipts, usedGas, err := executeBlock(config, chain, engine, block, statedb)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	root := statedb.IntermediateRoot(config.IsEIP158(block.Number()))
+	if err := statedb.Error(); err != nil {
+		return common.Hash{}, fmt.Errorf("incomplete witness: %v", err)
+	}
+	// Validate the outcome the same way as the block validator does
+	header := block.Header()
+	if header.GasUsed != usedGas {
+		return root, fmt.Errorf("invalid gas used (remote: %d local: %d)", header.GasUsed, usedGas)
+	}
+	if rbloom := types.CreateBloom(receipts); rbloom != header.Bloom {
+		return root, fmt.Errorf("invalid bloom (remote: %x  local: %x)", header.Bloom, rbloom)
+	}
+	if receiptSha := types.DeriveSha(receipts, trie.NewStackTrie(nil)); receiptSha != header.ReceiptHash {
+		return root, fmt.Errorf("invalid receipt root hash (remote: %x local: %x)", header.ReceiptHash, receiptSha)
+	}
+	if header.Root != root {
+		return root, fmt.Errorf("invalid merkle root (remote: %x local: %x)", header.Root, root)
+	}
+	return root, nil
+}
+
+// recordWitness re-executes a block on top of its parent state, gathering all
+// the data needed to verify it statelessly.
+func (bc *BlockChain) recordWitness(block *types.Block, parent *types.Header) (*Witness, error) {
+	db := state.NewRecordingDatabase(bc.stateCache)
+	statedb, err := state.New(parent.Root, db, nil)
+	if err != nil {
+		return nil, err
+	}
+	chain := &headerRecorder{
+		chainReader: bc,
+		headers:     map[common.Hash]*types.Header{parent.Hash(): parent},
+	}
+	if _, _, err := executeBlock(bc.chainConfig, chain, bc.engine, block, statedb); err != nil {
+		return nil, err
+	}
+	// The root calculation also needs the trie nodes touched by the updates
+	statedb.IntermediateRoot(bc.chainConfig.IsEIP158(block.Number()))
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+	witness := &Witness{
+		Codes: db.Codes(),
+		Nodes: db.Nodes(),
+	}
+	for _, header := range chain.headers {
+		witness.Headers = append(witness.Headers, header)
+	}
+	sort.Slice(witness.Headers, func(i, j int) bool {
+		return witness.Headers[i].Number.Cmp(witness.Headers[j].Number) > 0
+	})
+	return witness, nil
+}
+
+// writeWitness stores the witness of a block in its compact encoding.
+func (bc *BlockChain) writeWitness(block *types.Block, witness *Witness) error {
+	blob, err := witness.EncodeCompact()
+	if err != nil {
+		return err
+	}
+	rawdb.WriteBlockWitness(bc.db, block.Hash(), block.NumberU64(), blob)
+	return nil
+}
+
+// BlockWitness returns the stateless witness of a block. If none was recorded
+// during import, it's regenerated as long as the parent state is available.
+func (bc *BlockChain) BlockWitness(block *types.Block) (*Witness, error) {
+	if blob := rawdb.ReadBlockWitness(bc.db, block.Hash(), block.NumberU64()); len(blob) > 0 {
+		return DecodeWitness(blob)
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis block has no witness")
+	}
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	return bc.recordWitness(block, parent)
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/consensus"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/crypto"
+	"github.com/ionchain/ionchain-core/params"
+	"github.com/ionchain/ionchain-core/trie"
+)
+
+// witnessTestEngine is a consensus engine only capable of finalizing blocks,
+// crediting the fees to the coinbase without any block rewards.
+type witnessTestEngine struct {
+	consensus.Engine
+}
+
+func (witnessTestEngine) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+func (witnessTestEngine) Finalize(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+	header.Root = statedb.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+}
+
+// Tests that a witness recorded while executing a block is sufficient to verify
+// it without a database, and that incomplete witnesses are rejected.
+func TestStatelessExecution(t *testing.T) {
+	var (
+		keys   = make([]*ecdsa.PrivateKey, 3)
+		signer = types.NewEIP155Signer(params.TestChainConfig.ChainID)
+		engine = witnessTestEngine{}
+		txs    types.Transactions
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	for i, to := range []common.Address{parallelCounter, parallelRegistry, parallelSuicider} {
+		tx, _ := types.SignTx(types.NewTransaction(0, to, big.NewInt(1), 100000, big.NewInt(1), nil), signer, keys[i])
+		txs = append(txs, tx)
+	}
+	pre := newParallelTestState(t, keys)
+	parent := &types.Header{
+		Number:     big.NewInt(0),
+		Root:       pre.IntermediateRoot(true),
+		Difficulty: big.NewInt(1),
+		BaseTarget: big.NewInt(1),
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     big.NewInt(1),
+		Coinbase:   common.HexToAddress("0xc01bba5e"),
+		GasLimit:   10000000,
+		Difficulty: big.NewInt(1),
+		Time:       1,
+		BaseTarget: big.NewInt(1),
+	}
+	// Execute the block on a recording database to assemble it and its witness
+	db := state.NewRecordingDatabase(pre.Database())
+	statedb, err := state.New(parent.Root, db, nil)
+	if err != nil {
+		t.Fatalf("failed to open recording state: %v", err)
+	}
+	chain := &witnessChain{
+		config:  params.TestChainConfig,
+		engine:  engine,
+		parent:  parent,
+		headers: map[common.Hash]*types.Header{parent.Hash(): parent},
+	}
+	receipts, usedGas, err := executeBlock(params.TestChainConfig, chain, engine, types.NewBlockWithHeader(header).WithBody(txs, nil), statedb)
+	if err != nil {
+		t.Fatalf("failed to execute block: %v", err)
+	}
+	header.GasUsed = usedGas
+	header.Root = statedb.IntermediateRoot(true)
+	block := types.NewBlock(header, txs, nil, receipts, new(trie.Trie))
+
+	witness := &Witness{Headers: []*types.Header{parent}, Codes: db.Codes(), Nodes: db.Nodes()}
+	blob, err := witness.EncodeCompact()
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	if witness, err = DecodeWitness(blob); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	// Verify the block statelessly, with both the full and a truncated witness
+	root, err := ExecuteStateless(params.TestChainConfig, engine, block, witness)
+	if err != nil {
+		t.Fatalf("stateless execution failed: %v", err)
+	}
+	if root != block.Root() {
+		t.Fatalf("state root mismatch: have %x, want %x", root, block.Root())
+	}
+	partial := &Witness{Headers: witness.Headers, Codes: witness.Codes, Nodes: witness.Nodes[1:]}
+	if _, err := ExecuteStateless(params.TestChainConfig, engine, block, partial); err == nil {
+		t.Fatalf("stateless execution succeeded with incomplete witness")
+	}
+	if _, err := ExecuteStateless(params.TestChainConfig, engine, block, &Witness{Codes: witness.Codes, Nodes: witness.Nodes}); err != errMissingWitnessParent {
+		t.Fatalf("missing parent error mismatch: have %v, want %v", err, errMissingWitnessParent)
+	}
+}
@@ -20,6 +20,7 @@ package graphql
 import (
 	"context"
 	"errors"
+	"math/big"
 	"time"
 
 	"github.com/ionchain/ionchain-core"
@@ -29,8 +30,10 @@ import (
 	"github.com/ionchain/ionchain-core/core/state"
 	"github.com/ionchain/ionchain-core/core/types"
 	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/crypto"
 	"github.com/ionchain/ionchain-core/internal/ioncapi"
 	"github.com/ionchain/ionchain-core/ionc/filters"
+	"github.com/ionchain/ionchain-core/params"
 	"github.com/ionchain/ionchain-core/rlp"
 	"github.com/ionchain/ionchain-core/rpc"
 )
@@ -39,6 +42,12 @@ var (
 	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
 )
 
+// Method selectors of the IPos staking contract, following the Solidity ABI.
+var (
+	stakingMintPowerSelector = crypto.Keccak256([]byte("mintPower(address)"))[:4]
+	stakingBalancesSelector  = crypto.Keccak256([]byte("balances(address)"))[:4]
+)
+
 // Account represents an IonChain account at a particular block.
 type Account struct {
 	backend       ioncapi.Backend
@@ -119,6 +128,10 @@ func (l *Log) Data(ctx context.Context) hexutil.Bytes {
 	return l.log.Data
 }
 
+func (l *Log) Removed(ctx context.Context) bool {
+	return l.log.Removed
+}
+
 // Transaction represents an IonChain transaction.
 // backend and hash are mandatory; all others will be fetched when required.
 type Transaction struct {
@@ -477,6 +490,30 @@ func (b *Block) Difficulty(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*header.Difficulty), nil
 }
 
+func (b *Block) BaseTarget(ctx context.Context) (hexutil.Big, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil || header.BaseTarget == nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*header.BaseTarget), nil
+}
+
+func (b *Block) GenerationSignature(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return header.GenerationSignature, nil
+}
+
+func (b *Block) BlockSignature(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return header.BlockSignature, nil
+}
+
 func (b *Block) Timestamp(ctx context.Context) (hexutil.Uint64, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
@@ -816,6 +853,77 @@ func (b *Block) EstimateGas(ctx context.Context, args struct {
 	return gas, err
 }
 
+func (b *Block) Staking(ctx context.Context) (*Staking, error) {
+	if b.numberOrHash == nil {
+		_, err := b.resolveHeader(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Staking{
+		backend:       b.backend,
+		blockNrOrHash: *b.numberOrHash,
+	}, nil
+}
+
+// Staking represents the IPos staking data at a particular block, as recorded
+// by the staking contract.
+type Staking struct {
+	backend       ioncapi.Backend
+	blockNrOrHash rpc.BlockNumberOrHash
+}
+
+// call invokes a method of the staking contract taking a single address, and
+// returns its result as an integer.
+func (s *Staking) call(ctx context.Context, selector []byte, address common.Address) (*big.Int, error) {
+	var (
+		to   = vm.IPosContractAddress
+		data = hexutil.Bytes(append(common.CopyBytes(selector), common.LeftPadBytes(address.Bytes(), 32)...))
+	)
+	result, err := ioncapi.DoCall(ctx, s.backend, ioncapi.CallArgs{To: &to, Data: &data}, s.blockNrOrHash, nil, vm.Config{}, 5*time.Second, s.backend.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	if result.Failed() {
+		return nil, result.Err
+	}
+	return new(big.Int).SetBytes(result.ReturnData), nil
+}
+
+func (s *Staking) Contract(ctx context.Context) *Account {
+	return &Account{
+		backend:       s.backend,
+		address:       vm.IPosContractAddress,
+		blockNrOrHash: s.blockNrOrHash,
+	}
+}
+
+// MintPower returns the mint power of an address in the same units the IPos
+// engine weighs the block producers by, i.e. whole ions deposited.
+func (s *Staking) MintPower(ctx context.Context, args struct{ Address common.Address }) (hexutil.Big, error) {
+	power, err := s.call(ctx, stakingMintPowerSelector, args.Address)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*power.Div(power, big.NewInt(params.Ether))), nil
+}
+
+func (s *Staking) Deposit(ctx context.Context, args struct{ Address common.Address }) (hexutil.Big, error) {
+	deposit, err := s.call(ctx, stakingBalancesSelector, args.Address)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*deposit), nil
+}
+
+func (s *Staking) TotalDeposits(ctx context.Context) (hexutil.Big, error) {
+	state, _, err := s.backend.StateAndHeaderByNumberOrHash(ctx, s.blockNrOrHash)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*state.GetBalance(vm.IPosContractAddress)), nil
+}
+
 type Pending struct {
 	backend ioncapi.Backend
 }
@@ -883,6 +991,7 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 // Resolver is the top-level object in the GraphQL hierarchy.
 type Resolver struct {
 	backend ioncapi.Backend
+	events  *filters.EventSystem // Event system feeding the subscriptions
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -948,6 +1057,13 @@ func (r *Resolver) Blocks(ctx context.Context, args struct {
 	return ret, nil
 }
 
+func (r *Resolver) Staking(ctx context.Context, args BlockNumberArgs) *Staking {
+	return &Staking{
+		backend:       r.backend,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
 func (r *Resolver) Pending(ctx context.Context) *Pending {
 	return &Pending{r.backend}
 }
@@ -1076,3 +1192,115 @@ func (r *Resolver) Syncing() (*SyncState, error) {
 	// Otherwise gather the block sync stats
 	return &SyncState{progress}, nil
 }
+
+// NewBlocks sends a notification each time a new block is appended to the chain,
+// including chain reorganizations.
+func (r *Resolver) NewBlocks(ctx context.Context) <-chan *Block {
+	var (
+		headers = make(chan *types.Header)
+		sub     = r.events.SubscribeNewHeads(headers)
+		blocks  = make(chan *Block)
+	)
+	go func() {
+		defer close(blocks)
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case header := <-headers:
+				numberOrHash := rpc.BlockNumberOrHashWithHash(header.Hash(), true)
+				block := &Block{
+					backend:      r.backend,
+					numberOrHash: &numberOrHash,
+					hash:         header.Hash(),
+					header:       header,
+				}
+				select {
+				case blocks <- block:
+				case <-ctx.Done():
+					return
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks
+}
+
+// NewLogs sends a notification for each log included in new imported blocks and
+// matching the given filter criteria. Logs removed by a reorganization are sent
+// again, as reported by their removed field.
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	var crit ionchain.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	matched := make(chan []*types.Log)
+	sub, err := r.events.SubscribeLogs(crit, matched)
+	if err != nil {
+		return nil, err
+	}
+	logs := make(chan *Log)
+	go func() {
+		defer close(logs)
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case batch := <-matched:
+				for _, log := range batch {
+					select {
+					case logs <- &Log{backend: r.backend, transaction: &Transaction{backend: r.backend, hash: log.TxHash}, log: log}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
+
+// NewPendingTransactions sends a notification each time a transaction enters the
+// transaction pool.
+func (r *Resolver) NewPendingTransactions(ctx context.Context) <-chan *Transaction {
+	var (
+		hashes = make(chan []common.Hash)
+		sub    = r.events.SubscribePendingTxs(hashes)
+		txs    = make(chan *Transaction)
+	)
+	go func() {
+		defer close(txs)
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case batch := <-hashes:
+				for _, hash := range batch {
+					// Resolve the transaction from the pool, it may be gone already
+					tx := r.backend.GetPoolTransaction(hash)
+					if tx == nil {
+						continue
+					}
+					select {
+					case txs <- &Transaction{backend: r.backend, hash: hash, tx: tx}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return txs
+}
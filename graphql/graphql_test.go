@@ -0,0 +1,276 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/event"
+	"github.com/ionchain/ionchain-core/internal/ioncapi"
+	"github.com/ionchain/ionchain-core/node"
+	"github.com/ionchain/ionchain-core/params"
+	"github.com/ionchain/ionchain-core/rpc"
+)
+
+// testBackend serves a single block whose state holds a staking contract, and
+// the chain events fed by the tests. Anything else is left unimplemented.
+type testBackend struct {
+	ioncapi.Backend
+
+	db     state.Database
+	root   common.Hash
+	header *types.Header
+
+	txsFeed, chainFeed, logsFeed, rmLogsFeed, pendingLogsFeed event.Feed
+}
+
+// newTestBackend creates a backend whose staking contract returns the given
+// value from every call, holding the given deposits.
+func newTestBackend(t *testing.T, value, deposits *big.Int) *testBackend {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, db, nil)
+
+	// PUSH32 value, PUSH1 0, MSTORE, PUSH1 32, PUSH1 0, RETURN
+	code := append(append([]byte{byte(vm.PUSH32)}, common.LeftPadBytes(value.Bytes(), 32)...), common.FromHex("0x60005260206000f3")...)
+	statedb.SetCode(vm.IPosContractAddress, code)
+	statedb.SetBalance(vm.IPosContractAddress, deposits)
+
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := db.TrieDB().Commit(root, false, nil); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	return &testBackend{
+		db:     db,
+		root:   root,
+		header: &types.Header{Number: big.NewInt(1), Root: root, Difficulty: big.NewInt(1), GasLimit: 10000000},
+	}
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+func (b *testBackend) RPCGasCap() uint64                { return 25000000 }
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	statedb, err := state.New(b.root, b.db, nil)
+	return statedb, b.header, err
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	if vmConfig == nil {
+		vmConfig = new(vm.Config)
+	}
+	context := core.NewEVMBlockContext(header, nil, &common.Address{})
+	return vm.NewEVM(context, core.NewEVMTxContext(msg), state, b.ChainConfig(), *vmConfig), func() error { return nil }, nil
+}
+
+func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.txsFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return b.chainFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return b.logsFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
+	return b.rmLogsFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return b.pendingLogsFeed.Subscribe(ch)
+}
+
+// newTestNode starts a node serving GraphQL over the backend on a random port.
+func newTestNode(t *testing.T, backend *testBackend) *node.Node {
+	stack, err := node.New(&node.Config{HTTPHost: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	if err := New(stack, backend, false, nil, nil); err != nil {
+		t.Fatalf("failed to create GraphQL service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	return stack
+}
+
+func TestSchemaParse(t *testing.T) {
+	if _, err := graphql.ParseSchema(schema, new(Resolver)); err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+}
+
+// Tests that the staking data is served from the contract at the requested block.
+func TestGraphQLStaking(t *testing.T) {
+	deposits := new(big.Int).Mul(big.NewInt(7), big.NewInt(params.Ether))
+	backend := newTestBackend(t, new(big.Int).Mul(big.NewInt(5), big.NewInt(params.Ether)), deposits)
+
+	stack := newTestNode(t, backend)
+	defer stack.Close()
+
+	query := `{"query": "{ staking { mintPower(address: \"0x00000000000000000000000000000000000000aa\") totalDeposits } }"}`
+	res, err := http.Post(stack.HTTPEndpoint()+"/graphql", "application/json", strings.NewReader(query))
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status mismatch: have %d, want %d: %s", res.StatusCode, http.StatusOK, body)
+	}
+	var result struct {
+		Data struct {
+			Staking struct {
+				MintPower     hexutil.Big `json:"mintPower"`
+				TotalDeposits hexutil.Big `json:"totalDeposits"`
+			} `json:"staking"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("failed to decode response %s: %v", body, err)
+	}
+	if power := result.Data.Staking.MintPower.ToInt(); power.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("mint power mismatch: have %v, want 5", power)
+	}
+	if total := result.Data.Staking.TotalDeposits.ToInt(); total.Cmp(deposits) != 0 {
+		t.Errorf("total deposits mismatch: have %v, want %v", total, deposits)
+	}
+}
+
+// Tests that new blocks are streamed over the graphql-ws protocol until the
+// operation is stopped, and that the node's shutdown tears the connection and
+// the event system down.
+func TestGraphQLSubscription(t *testing.T) {
+	backend := newTestBackend(t, new(big.Int), new(big.Int))
+	stack := newTestNode(t, backend)
+	defer stack.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-ws"}}
+	conn, _, err := dialer.Dial(strings.Replace(stack.HTTPEndpoint(), "http", "ws", 1)+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	messages := make(chan *wsMessage)
+	go func() {
+		defer close(messages)
+		for {
+			msg := new(wsMessage)
+			if err := conn.ReadJSON(msg); err != nil {
+				return
+			}
+			if msg.Type != wsConnectionKeepAlive {
+				messages <- msg
+			}
+		}
+	}()
+	next := func() *wsMessage {
+		select {
+		case msg := <-messages:
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for message")
+			return nil
+		}
+	}
+	conn.WriteJSON(&wsMessage{Type: wsConnectionInit})
+	if msg := next(); msg == nil || msg.Type != wsConnectionAck {
+		t.Fatalf("connection not acknowledged: %v", msg)
+	}
+	conn.WriteJSON(&wsMessage{ID: "1", Type: wsStart, Payload: json.RawMessage(`{"query": "subscription { newBlocks { number hash } }"}`)})
+
+	// Feed blocks until the subscription is installed and one gets through
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(42), Difficulty: big.NewInt(1)})
+
+	var msg *wsMessage
+	for i := 0; msg == nil && i < 50; i++ {
+		backend.chainFeed.Send(core.ChainEvent{Block: block, Hash: block.Hash()})
+		select {
+		case msg = <-messages:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	if msg == nil || msg.ID != "1" || msg.Type != wsData {
+		t.Fatalf("block not notified: %v", msg)
+	}
+	var data struct {
+		Data struct {
+			NewBlocks struct {
+				Number hexutil.Uint64 `json:"number"`
+				Hash   common.Hash    `json:"hash"`
+			} `json:"newBlocks"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(msg.Payload, &data); err != nil {
+		t.Fatalf("failed to decode notification %s: %v", msg.Payload, err)
+	}
+	if data.Data.NewBlocks.Number != 42 || data.Data.NewBlocks.Hash != block.Hash() {
+		t.Errorf("block mismatch: have #%d [%x], want #42 [%x]", data.Data.NewBlocks.Number, data.Data.NewBlocks.Hash, block.Hash())
+	}
+	// Stop the operation, skipping any block notified in the meantime
+	conn.WriteJSON(&wsMessage{ID: "1", Type: wsStop})
+	for {
+		if msg = next(); msg == nil || msg.Type != wsData {
+			break
+		}
+	}
+	if msg == nil || msg.ID != "1" || msg.Type != wsComplete {
+		t.Fatalf("operation not completed: %v", msg)
+	}
+	// Start another operation and check that the shutdown ends everything
+	conn.WriteJSON(&wsMessage{ID: "2", Type: wsStart, Payload: json.RawMessage(`{"query": "subscription { newBlocks { number } }"}`)})
+	stack.Close()
+
+	timeout := time.After(5 * time.Second)
+	for open := true; open; {
+		select {
+		case _, open = <-messages:
+		case <-timeout:
+			t.Fatalf("connection not closed by the shutdown")
+		}
+	}
+	if n := backend.chainFeed.Send(core.ChainEvent{Block: block, Hash: block.Hash()}); n != 0 {
+		t.Errorf("event system still subscribed after shutdown: %d subscribers", n)
+	}
+}
@@ -34,6 +34,7 @@ const schema string = `
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an IonChain account at a particular block.
@@ -67,6 +68,9 @@ const schema string = `
         data: Bytes!
         # Transaction is the transaction that generated this log entry.
         transaction: Transaction!
+        # Removed is true if this log was reverted due to a chain reorganisation.
+        # It is only ever set for logs delivered by subscriptions.
+        removed: Boolean!
     }
 
     # Transaction is an IonChain transaction.
@@ -148,8 +152,6 @@ const schema string = `
         hash: Bytes32!
         # Parent is the parent block of this block.
         parent: Block
-        # Nonce is the block nonce, an 8 byte sequence determined by the miner.
-        nonce: Bytes!
         # TransactionsRoot is the keccak256 hash of the root of the trie of transactions in this block.
         transactionsRoot: Bytes32!
         # TransactionCount is the number of transactions in this block. if
@@ -172,10 +174,16 @@ const schema string = `
         # LogsBloom is a bloom filter that can be used to check if a block may
         # contain log entries matching a filter.
         logsBloom: Bytes!
-        # MixHash is the hash that was used as an input to the PoW process.
-        mixHash: Bytes32!
-        # Difficulty is a measure of the difficulty of mining this block.
+        # Difficulty is a measure of the difficulty of forging this block.
         difficulty: BigInt!
+        # BaseTarget is the IPos base target of this block, scaling the hit
+        # threshold a forger must meet with its mint power.
+        baseTarget: BigInt!
+        # GenerationSignature is the IPos generation signature of this block,
+        # chained from the one of its parent and the forger's public key.
+        generationSignature: Bytes!
+        # BlockSignature is the signature of the forger over the block header.
+        blockSignature: Bytes!
         # TotalDifficulty is the sum of all difficulty values up to and including
         # this block.
         totalDifficulty: BigInt!
@@ -209,6 +217,22 @@ const schema string = `
         # EstimateGas estimates the amount of gas that will be required for
         # successful execution of a transaction at the current block's state.
         estimateGas(data: CallData!): Long!
+        # Staking fetches the IPos staking data at the current block's state.
+        staking: Staking!
+    }
+
+    # Staking is the IPos staking data at a particular block, as recorded by
+    # the staking contract.
+    type Staking {
+        # Contract is the staking contract account.
+        contract: Account!
+        # MintPower is the mint power of an account, in the units the IPos
+        # consensus engine weighs the forgers by.
+        mintPower(address: Address!): BigInt!
+        # Deposit is the amount, in wei, an account has deposited for staking.
+        deposit(address: Address!): BigInt!
+        # TotalDeposits is the amount, in wei, held by the staking contract.
+        totalDeposits: BigInt!
     }
 
     # CallData represents the data associated with a local contract call.
@@ -316,10 +340,24 @@ const schema string = `
         syncing: SyncState
         # ChainID returns the current chain ID for transaction replay protection.
         chainID: BigInt!
+        # Staking returns the IPos staking data at the given block, or at the
+        # most recent known block if not supplied.
+        staking(block: Long): Staking!
     }
 
     type Mutation {
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscription notifies of chain events, over the GraphQL WebSocket protocol.
+    type Subscription {
+        # NewBlocks notifies of each block appended to the chain, including the
+        # ones imported by chain reorganisations.
+        newBlocks: Block!
+        # NewLogs notifies of each log matching the filter in the imported blocks.
+        newLogs(filter: BlockFilterCriteria!): Log!
+        # NewPendingTransactions notifies of each transaction entering the pool.
+        newPendingTransactions: Transaction!
+    }
 `
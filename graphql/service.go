@@ -20,16 +20,23 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/gorilla/websocket"
 	"github.com/ionchain/ionchain-core/internal/ioncapi"
+	"github.com/ionchain/ionchain-core/ionc/filters"
 	"github.com/ionchain/ionchain-core/node"
 	"github.com/graph-gophers/graphql-go"
 )
 
 type handler struct {
 	Schema *graphql.Schema
+	ws     *wsHandler
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		h.ws.ServeHTTP(w, r)
+		return
+	}
 	var params struct {
 		Query         string                 `json:"query"`
 		OperationName string                 `json:"operationName"`
@@ -55,27 +62,53 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// New constructs a new GraphQL service instance.
-func New(stack *node.Node, backend ioncapi.Backend, cors, vhosts []string) error {
+// service tears down the subscriptions of the GraphQL handler when the node stops.
+type service struct {
+	events *filters.EventSystem
+	ws     *wsHandler
+}
+
+// Start implements node.Lifecycle, the handler is served by the node's HTTP server.
+func (s *service) Start() error {
+	return nil
+}
+
+// Stop implements node.Lifecycle, closing the WebSocket connections and stopping
+// the event system feeding the subscriptions.
+func (s *service) Stop() error {
+	s.ws.close()
+	s.events.Stop()
+	return nil
+}
+
+// New constructs a new GraphQL service instance. Light mode needs to be set if
+// the backend is a light client, so that subscriptions retrieve the logs on demand.
+func New(stack *node.Node, backend ioncapi.Backend, lightMode bool, cors, vhosts []string) error {
 	if backend == nil {
 		panic("missing backend")
 	}
 	// check if http server with given endpoint exists and enable graphQL on it
-	return newHandler(stack, backend, cors, vhosts)
+	return newHandler(stack, backend, lightMode, cors, vhosts)
 }
 
-// newHandler returns a new `http.Handler` that will answer GraphQL queries.
+// newHandler returns a new `http.Handler` that will answer GraphQL queries, and
+// subscriptions over WebSocket connections.
 // It additionally exports an interactive query browser on the / endpoint.
-func newHandler(stack *node.Node, backend ioncapi.Backend, cors, vhosts []string) error {
-	q := Resolver{backend}
-
+func newHandler(stack *node.Node, backend ioncapi.Backend, lightMode bool, cors, vhosts []string) error {
+	q := Resolver{
+		backend: backend,
+		events:  filters.NewEventSystem(backend, lightMode),
+	}
 	s, err := graphql.ParseSchema(schema, &q)
 	if err != nil {
+		q.events.Stop()
 		return err
 	}
-	h := handler{Schema: s}
+	h := handler{Schema: s, ws: newWSHandler(s, cors)}
 	handler := node.NewHTTPHandlerStack(h, cors, vhosts)
 
+	stack.RegisterLifecycle(&service{events: q.events, ws: h.ws})
+
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
 	stack.RegisterHandler("GraphQL", "/graphql", handler)
 	stack.RegisterHandler("GraphQL", "/graphql/", handler)
@@ -0,0 +1,289 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/ionchain/ionchain-core/log"
+)
+
+const (
+	wsReadLimit         = 1024 * 1024      // Maximum size of a client message
+	wsWriteTimeout      = 10 * time.Second // Maximum time to send a message to the client
+	wsKeepAliveInterval = 30 * time.Second // Interval of the keep-alive messages
+	wsMaxOperations     = 128              // Maximum number of concurrent operations per connection
+)
+
+// Message types of the graphql-ws protocol, as defined by subscriptions-transport-ws.
+const (
+	wsConnectionInit      = "connection_init"
+	wsConnectionAck       = "connection_ack"
+	wsConnectionError     = "connection_error"
+	wsConnectionKeepAlive = "ka"
+	wsConnectionTerminate = "connection_terminate"
+	wsStart               = "start"
+	wsStop                = "stop"
+	wsData                = "data"
+	wsError               = "error"
+	wsComplete            = "complete"
+)
+
+// wsMessage is a message of the graphql-ws protocol.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsHandler serves GraphQL operations, most notably subscriptions, over WebSocket
+// connections speaking the graphql-ws protocol.
+type wsHandler struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+
+	conns  map[*wsConn]struct{} // Connections being served
+	closed bool                 // Whether the handler was closed, no connection may be served
+	lock   sync.Mutex
+	wg     sync.WaitGroup
+}
+
+// newWSHandler creates a GraphQL WebSocket handler accepting connections from
+// the given origins, or only from the serving host if none are given.
+func newWSHandler(schema *graphql.Schema, origins []string) *wsHandler {
+	return &wsHandler{
+		schema: schema,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{"graphql-ws"},
+			CheckOrigin:  wsOriginValidator(origins),
+		},
+		conns: make(map[*wsConn]struct{}),
+	}
+}
+
+// wsOriginValidator returns a function verifying the origin during the WebSocket
+// upgrade. Requests without an origin don't come from browsers and are accepted.
+func wsOriginValidator(origins []string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, allowed := range origins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL WebSocket upgrade failed", "err", err)
+		return
+	}
+	c := newWSConn(h.schema, conn)
+
+	h.lock.Lock()
+	if h.closed {
+		h.lock.Unlock()
+		conn.Close()
+		return
+	}
+	h.conns[c] = struct{}{}
+	h.wg.Add(1)
+	h.lock.Unlock()
+
+	defer func() {
+		h.lock.Lock()
+		delete(h.conns, c)
+		h.lock.Unlock()
+		h.wg.Done()
+	}()
+	c.serve(r.Context())
+}
+
+// close terminates all the connections being served, stopping their operations,
+// and rejects any new ones. It blocks until the connections are torn down.
+func (h *wsHandler) close() {
+	h.lock.Lock()
+	h.closed = true
+	for c := range h.conns {
+		c.conn.Close()
+	}
+	h.lock.Unlock()
+
+	h.wg.Wait()
+}
+
+// wsConn is a GraphQL WebSocket connection, running any number of operations
+// concurrently.
+type wsConn struct {
+	schema *graphql.Schema
+	conn   *websocket.Conn
+
+	ops     map[string]context.CancelFunc // Cancellers of the running operations by id
+	opsLock sync.Mutex
+	opsWg   sync.WaitGroup
+
+	writeLock sync.Mutex
+}
+
+func newWSConn(schema *graphql.Schema, conn *websocket.Conn) *wsConn {
+	conn.SetReadLimit(wsReadLimit)
+	return &wsConn{
+		schema: schema,
+		conn:   conn,
+		ops:    make(map[string]context.CancelFunc),
+	}
+}
+
+// serve reads the client messages until the connection is terminated, cancelling
+// all the running operations afterwards.
+func (c *wsConn) serve(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		c.opsWg.Wait()
+		c.conn.Close()
+	}()
+	go c.keepAlive(ctx)
+
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case wsConnectionInit:
+			c.send(&wsMessage{Type: wsConnectionAck})
+			c.send(&wsMessage{Type: wsConnectionKeepAlive})
+
+		case wsConnectionTerminate:
+			return
+
+		case wsStart:
+			c.start(ctx, msg.ID, msg.Payload)
+
+		case wsStop:
+			c.opsLock.Lock()
+			if stop, ok := c.ops[msg.ID]; ok {
+				stop()
+			}
+			c.opsLock.Unlock()
+
+		default:
+			c.sendError(wsConnectionError, msg.ID, fmt.Errorf("unknown message type %q", msg.Type))
+		}
+	}
+}
+
+// start runs an operation, sending its results to the client until it finishes
+// or gets stopped.
+func (c *wsConn) start(ctx context.Context, id string, payload json.RawMessage) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.Unmarshal(payload, &params); err != nil {
+		c.sendError(wsError, id, err)
+		return
+	}
+	c.opsLock.Lock()
+	defer c.opsLock.Unlock()
+
+	if _, ok := c.ops[id]; ok {
+		c.sendError(wsError, id, fmt.Errorf("operation %q already running", id))
+		return
+	}
+	if len(c.ops) >= wsMaxOperations {
+		c.sendError(wsError, id, fmt.Errorf("too many operations (max %d)", wsMaxOperations))
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	responses, err := c.schema.Subscribe(ctx, params.Query, params.OperationName, params.Variables)
+	if err != nil {
+		cancel()
+		c.sendError(wsError, id, err)
+		return
+	}
+	c.ops[id] = cancel
+	c.opsWg.Add(1)
+
+	go func() {
+		defer c.opsWg.Done()
+		defer func() {
+			c.opsLock.Lock()
+			delete(c.ops, id)
+			c.opsLock.Unlock()
+			cancel()
+		}()
+		for response := range responses {
+			data, err := json.Marshal(response)
+			if err != nil {
+				c.sendError(wsError, id, err)
+				return
+			}
+			c.send(&wsMessage{ID: id, Type: wsData, Payload: data})
+		}
+		c.send(&wsMessage{ID: id, Type: wsComplete})
+	}()
+}
+
+// keepAlive periodically sends keep-alive messages until the context is cancelled.
+func (c *wsConn) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(wsKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.send(&wsMessage{Type: wsConnectionKeepAlive})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sendError sends an error message with the given type to the client.
+func (c *wsConn) sendError(typ string, id string, err error) {
+	payload, _ := json.Marshal(map[string]string{"message": err.Error()})
+	c.send(&wsMessage{ID: id, Type: typ, Payload: payload})
+}
+
+// send writes a message to the client. Write failures are ignored, they'll end
+// the connection via the read loop.
+func (c *wsConn) send(msg *wsMessage) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	c.conn.WriteJSON(msg)
+}
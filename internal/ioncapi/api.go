@@ -34,6 +34,7 @@ import (
 	"github.com/ionchain/ionchain-core/common/hexutil"
 	"github.com/ionchain/ionchain-core/common/math"
 	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/state"
 	"github.com/ionchain/ionchain-core/core/types"
 	"github.com/ionchain/ionchain-core/core/vm"
 	"github.com/ionchain/ionchain-core/crypto"
@@ -756,6 +757,9 @@ type CallArgs struct {
 	GasPrice *hexutil.Big    `json:"gasPrice"`
 	Value    *hexutil.Big    `json:"value"`
 	Data     *hexutil.Bytes  `json:"data"`
+
+	// AccessList is warmed up prior to the execution if EIP-2929 is in effect
+	AccessList *types.AccessList `json:"accessList"`
 }
 
 // ToMessage converts CallArgs to the Message type used by the core evm
@@ -797,13 +801,13 @@ func (args *CallArgs) ToMessage(globalGasCap uint64) types.Message {
 	return msg
 }
 
-// account indicates the overriding fields of account during the execution of
-// a message call.
+// OverrideAccount indicates the overriding fields of account during the execution
+// of a message call.
 // Note, state and stateDiff can't be specified at the same time. If state is
 // set, message execution will only use the data in the given state. Otherwise
 // if statDiff is set, all diff will be applied first and then execute the call
 // message.
-type account struct {
+type OverrideAccount struct {
 	Nonce     *hexutil.Uint64              `json:"nonce"`
 	Code      *hexutil.Bytes               `json:"code"`
 	Balance   **hexutil.Big                `json:"balance"`
@@ -811,7 +815,7 @@ type account struct {
 	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
 }
 
-func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides map[common.Address]account, vmCfg vm.Config, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
+func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides StateOverride, vmCfg vm.Config, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
 	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
@@ -819,32 +823,8 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 		return nil, err
 	}
 	// Override the fields of specified contracts before execution.
-	for addr, account := range overrides {
-		// Override account nonce.
-		if account.Nonce != nil {
-			state.SetNonce(addr, uint64(*account.Nonce))
-		}
-		// Override account(contract) code.
-		if account.Code != nil {
-			state.SetCode(addr, *account.Code)
-		}
-		// Override account balance.
-		if account.Balance != nil {
-			state.SetBalance(addr, (*big.Int)(*account.Balance))
-		}
-		if account.State != nil && account.StateDiff != nil {
-			return nil, fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
-		}
-		// Replace entire state if caller requires.
-		if account.State != nil {
-			state.SetStorage(addr, *account.State)
-		}
-		// Apply state diff into specified accounts.
-		if account.StateDiff != nil {
-			for key, value := range *account.StateDiff {
-				state.SetState(addr, key, value)
-			}
-		}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
 	}
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -860,10 +840,17 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 
 	// Get a new instance of the EVM.
 	msg := args.ToMessage(globalGasCap)
-	evm, vmError, err := b.GetEVM(ctx, msg, state, header)
+	evm, vmError, err := b.GetEVM(ctx, msg, state, header, nil)
 	if err != nil {
 		return nil, err
 	}
+	if b.ChainConfig().IsYoloV2(header.Number) {
+		var list types.AccessList
+		if args.AccessList != nil {
+			list = *args.AccessList
+		}
+		state.PrepareAccessList(msg.From(), msg.To(), evm.ActivePrecompiles(), list)
+	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
 	go func() {
@@ -888,6 +875,41 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 	return result, nil
 }
 
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
+
+// Apply overrides the fields of the specified accounts in the state.
+func (diff StateOverride) Apply(state *state.StateDB) error {
+	for addr, account := range diff {
+		// Override account nonce.
+		if account.Nonce != nil {
+			state.SetNonce(addr, uint64(*account.Nonce))
+		}
+		// Override account(contract) code.
+		if account.Code != nil {
+			state.SetCode(addr, *account.Code)
+		}
+		// Override account balance.
+		if account.Balance != nil {
+			state.SetBalance(addr, (*big.Int)(*account.Balance))
+		}
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+		}
+		// Replace entire state if caller requires.
+		if account.State != nil {
+			state.SetStorage(addr, *account.State)
+		}
+		// Apply state diff into specified accounts.
+		if account.StateDiff != nil {
+			for key, value := range *account.StateDiff {
+				state.SetState(addr, key, value)
+			}
+		}
+	}
+	return nil
+}
+
 func newRevertError(result *core.ExecutionResult) *revertError {
 	reason, errUnpack := abi.UnpackRevert(result.Revert())
 	err := errors.New("execution reverted")
@@ -924,8 +946,8 @@ func (e *revertError) ErrorData() interface{} {
 //
 // Note, this function doesn't make and changes in the state/blockchain and is
 // useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *map[common.Address]account) (hexutil.Bytes, error) {
-	var accounts map[common.Address]account
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Bytes, error) {
+	var accounts StateOverride
 	if overrides != nil {
 		accounts = *overrides
 	}
@@ -940,6 +962,282 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOr
 	return result.Return(), result.Err
 }
 
+// BundleCall is a single step of a call bundle, either a message call or a
+// signed transaction.
+type BundleCall struct {
+	CallArgs
+	Raw *hexutil.Bytes `json:"raw"` // RLP encoded signed transaction, overriding the call fields
+}
+
+// BlockOverrides are the block context fields a simulated call may override.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Time       *hexutil.Uint64 `json:"time"`
+	Coinbase   *common.Address `json:"coinbase"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	BaseTarget *hexutil.Big    `json:"baseTarget"`
+}
+
+// Apply overrides the fields of the block context.
+func (o *BlockOverrides) Apply(ctx *vm.BlockContext) {
+	if o == nil {
+		return
+	}
+	if o.Number != nil {
+		ctx.BlockNumber = o.Number.ToInt()
+	}
+	if o.Time != nil {
+		ctx.Time = new(big.Int).SetUint64(uint64(*o.Time))
+	}
+	if o.Coinbase != nil {
+		ctx.Coinbase = *o.Coinbase
+	}
+	if o.GasLimit != nil {
+		ctx.GasLimit = uint64(*o.GasLimit)
+	}
+	if o.Difficulty != nil {
+		ctx.Difficulty = o.Difficulty.ToInt()
+	}
+	if o.BaseTarget != nil {
+		ctx.BaseTarget = o.BaseTarget.ToInt()
+	}
+}
+
+// MakeHeader returns a copy of the header with the fields overridden, used to
+// derive the chain rules of the simulated block.
+func (o *BlockOverrides) MakeHeader(header *types.Header) *types.Header {
+	header = types.CopyHeader(header)
+	if o == nil {
+		return header
+	}
+	if o.Number != nil {
+		header.Number = new(big.Int).Set(o.Number.ToInt())
+	}
+	if o.Time != nil {
+		header.Time = uint64(*o.Time)
+	}
+	if o.Coinbase != nil {
+		header.Coinbase = *o.Coinbase
+	}
+	if o.GasLimit != nil {
+		header.GasLimit = uint64(*o.GasLimit)
+	}
+	if o.Difficulty != nil {
+		header.Difficulty = new(big.Int).Set(o.Difficulty.ToInt())
+	}
+	if o.BaseTarget != nil {
+		header.BaseTarget = new(big.Int).Set(o.BaseTarget.ToInt())
+	}
+	return header
+}
+
+// BlockAncestors resolves the ancestors of a simulated block whose number was
+// overridden. The block descends from the one the simulation runs on, any blocks
+// in between the two don't exist.
+type BlockAncestors struct {
+	ctx     context.Context
+	b       Backend
+	headers map[uint64]*types.Header
+	oldest  *types.Header
+}
+
+// NewBlockAncestors creates the ancestry of a simulated block on top of parent.
+func NewBlockAncestors(ctx context.Context, b Backend, parent *types.Header) *BlockAncestors {
+	return &BlockAncestors{
+		ctx:     ctx,
+		b:       b,
+		headers: map[uint64]*types.Header{parent.Number.Uint64(): parent},
+		oldest:  parent,
+	}
+}
+
+// header retrieves the ancestor with the given number, or nil if there's none.
+func (a *BlockAncestors) header(n uint64) *types.Header {
+	if header, ok := a.headers[n]; ok {
+		return header
+	}
+	for a.oldest.Number.Uint64() > n && a.oldest.Number.Sign() > 0 {
+		parent, err := a.b.HeaderByHash(a.ctx, a.oldest.ParentHash)
+		if parent == nil || err != nil {
+			return nil
+		}
+		a.headers[parent.Number.Uint64()] = parent
+		a.oldest = parent
+	}
+	return a.headers[n]
+}
+
+// GetHash implements vm.GetHashFunc.
+func (a *BlockAncestors) GetHash(n uint64) common.Hash {
+	if header := a.header(n); header != nil {
+		return header.Hash()
+	}
+	return common.Hash{}
+}
+
+// GetGenerationSignature implements vm.GetGenerationSignatureFunc.
+func (a *BlockAncestors) GetGenerationSignature(n uint64) []byte {
+	if header := a.header(n); header != nil {
+		return common.CopyBytes(header.GenerationSignature)
+	}
+	return nil
+}
+
+// BundleCallResult is the outcome of a single step of a call bundle.
+type BundleCallResult struct {
+	TransactionHash *common.Hash   `json:"transactionHash,omitempty"`
+	ReturnData      hexutil.Bytes  `json:"returnData"`
+	GasUsed         hexutil.Uint64 `json:"gasUsed"`
+	Logs            []*types.Log   `json:"logs"`
+	Error           string         `json:"error,omitempty"`
+	RevertReason    string         `json:"revertReason,omitempty"`
+}
+
+// DoCallMany executes an ordered list of calls and signed transactions on top of
+// the state of the given block, each one seeing the state changes of the ones
+// before it. Nothing is committed.
+func DoCallMany(ctx context.Context, b Backend, calls []BundleCall, blockNrOrHash rpc.BlockNumberOrHash, blockOverrides *BlockOverrides, overrides StateOverride, timeout time.Duration, globalGasCap uint64) ([]*BundleCallResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call bundle finished", "runtime", time.Since(start)) }(time.Now())
+
+	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	// Setup context so it may be cancelled the bundle has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	// The calls run in a block derived from the requested one, if its number is
+	// overridden the rules and the ancestors follow the overridden number
+	var (
+		config    = b.ChainConfig()
+		simHeader = blockOverrides.MakeHeader(header)
+		number    = simHeader.Number
+		ancestors *BlockAncestors
+		gp        = new(core.GasPool).AddGas(math.MaxUint64)
+		results   = make([]*BundleCallResult, len(calls))
+	)
+	if number.Cmp(header.Number) != 0 {
+		ancestors = NewBlockAncestors(ctx, b, header)
+	}
+	signer := types.MakeSigner(config, number)
+
+	for i, call := range calls {
+		var (
+			msg    types.Message
+			result = &BundleCallResult{Logs: []*types.Log{}}
+			thash  = common.BigToHash(big.NewInt(int64(i + 1))) // Log key of plain calls
+		)
+		results[i] = result
+		if call.Raw != nil {
+			tx := new(types.Transaction)
+			if err := rlp.DecodeBytes(*call.Raw, tx); err != nil {
+				return nil, fmt.Errorf("call %d: %v", i, err)
+			}
+			if msg, err = tx.AsMessage(signer); err != nil {
+				return nil, fmt.Errorf("call %d: %v", i, err)
+			}
+			thash = tx.Hash()
+			result.TransactionHash = &thash
+		} else {
+			msg = call.ToMessage(globalGasCap)
+		}
+		state.Prepare(thash, common.Hash{}, i)
+
+		evm, vmError, err := b.GetEVM(ctx, msg, state, simHeader, nil)
+		if err != nil {
+			return nil, err
+		}
+		blockOverrides.Apply(&evm.Context)
+		if ancestors != nil {
+			evm.Context.GetHash = ancestors.GetHash
+			evm.Context.GetGenerationSignature = ancestors.GetGenerationSignature
+		}
+		if config.IsYoloV2(number) {
+			var list types.AccessList
+			if call.AccessList != nil && call.Raw == nil {
+				list = *call.AccessList
+			}
+			state.PrepareAccessList(msg.From(), msg.To(), evm.ActivePrecompiles(), list)
+		}
+
+		// Wait for the context to be done and cancel the evm. Even if the
+		// EVM has finished, cancelling may be done (repeatedly)
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		snap := state.Snapshot()
+		res, err := core.ApplyMessage(evm, msg, gp)
+		close(done)
+
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		// Calls failing consensus checks are reported without affecting the
+		// state (the gas may already be bought), the remaining ones still run
+		if err != nil {
+			state.RevertToSnapshot(snap)
+			result.Error = err.Error()
+			continue
+		}
+		result.ReturnData, result.GasUsed = res.Return(), hexutil.Uint64(res.UsedGas)
+		if res.Err != nil {
+			result.Error = res.Err.Error()
+		}
+		if len(res.Revert()) > 0 {
+			result.ReturnData = res.Revert()
+			if reason, err := abi.UnpackRevert(res.Revert()); err == nil {
+				result.RevertReason = reason
+			}
+		}
+		if logs := state.GetLogs(thash); logs != nil {
+			result.Logs = logs
+		}
+		if result.TransactionHash == nil {
+			for _, l := range result.Logs {
+				l.TxHash = common.Hash{}
+			}
+		}
+		state.Finalise(config.IsEIP158(number))
+	}
+	return results, nil
+}
+
+// CallMany executes an ordered list of calls and signed transactions on top of
+// the state of the given block, each one seeing the state changes of the ones
+// before it, and returns their results.
+//
+// Additionally, the caller can override the context of the simulated block and
+// the fields of any accounts.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to simulate transaction bundles.
+func (s *PublicBlockChainAPI) CallMany(ctx context.Context, calls []BundleCall, blockNrOrHash rpc.BlockNumberOrHash, blockOverrides *BlockOverrides, overrides *StateOverride) ([]*BundleCallResult, error) {
+	var accounts StateOverride
+	if overrides != nil {
+		accounts = *overrides
+	}
+	return DoCallMany(ctx, s.b, calls, blockNrOrHash, blockOverrides, accounts, 5*time.Second, s.b.RPCGasCap())
+}
+
 func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap uint64) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
@@ -1046,9 +1344,142 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash
 			return 0, fmt.Errorf("gas required exceeds allowance (%d)", cap)
 		}
 	}
+	// The execution was cheapened by the warm access list, account for its cost
+	if args.AccessList != nil {
+		header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+		if err != nil {
+			return 0, err
+		}
+		if header != nil && b.ChainConfig().IsYoloV2(header.Number) {
+			hi += accessListGas(*args.AccessList)
+		}
+	}
 	return hexutil.Uint64(hi), nil
 }
 
+// accessListGas returns the intrinsic gas cost of warming up an access list.
+func accessListGas(list types.AccessList) uint64 {
+	return uint64(len(list))*params.TxAccessListAddressGas + uint64(list.StorageKeys())*params.TxAccessListStorageKeyGas
+}
+
+// accessListResult returns an optional accesslist
+// Its the result of the `eth_createAccessList` RPC call.
+// It contains an error if the transaction itself failed.
+type accessListResult struct {
+	Accesslist *types.AccessList `json:"accessList"`
+	Error      string            `json:"error,omitempty"`
+	GasUsed    hexutil.Uint64    `json:"gasUsed"`
+}
+
+// CreateAccessList creates an access list for the given transaction. If the
+// access list creation fails an error is returned. If the transaction itself
+// fails, a vmErr is returned in the result.
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*accessListResult, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	// Bound the repeated executions like a single call, they're just as unmetered
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	acl, gasUsed, vmerr, err := AccessList(ctx, s.b, bNrOrHash, args)
+	if err != nil {
+		return nil, err
+	}
+	result := &accessListResult{Accesslist: &acl, GasUsed: hexutil.Uint64(gasUsed)}
+	if vmerr != nil {
+		result.Error = vmerr.Error()
+	}
+	return result, nil
+}
+
+// AccessList creates an access list for the given transaction by executing it
+// repeatedly with the access list of the previous run warmed up, until the list
+// doesn't change anymore. If EIP-2929 is in effect, the returned gas used includes
+// the intrinsic cost of the access list. The execution is aborted once the context
+// is done.
+func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args CallArgs) (acl types.AccessList, gasUsed uint64, vmErr error, err error) {
+	// Retrieve the execution context
+	db, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if db == nil || err != nil {
+		return nil, 0, nil, err
+	}
+	if args.From == nil {
+		args.From = new(common.Address)
+	}
+	// Retrieve the precompiles since they don't need to be added to the access
+	// list, nor do the sender and the destination
+	var (
+		from = *args.From
+		to   common.Address
+	)
+	if args.To != nil {
+		to = *args.To
+	} else {
+		to = crypto.CreateAddress(from, db.GetNonce(from))
+	}
+	evm, _, err := b.GetEVM(ctx, args.ToMessage(b.RPCGasCap()), db.Copy(), header, nil)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	precompiles := evm.ActivePrecompiles()
+
+	// Create an initial tracer
+	prevTracer := vm.NewAccessListTracer(nil, from, to, precompiles)
+	if args.AccessList != nil {
+		prevTracer = vm.NewAccessListTracer(*args.AccessList, from, to, precompiles)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, nil, fmt.Errorf("execution aborted: %v", err)
+		}
+		// Retrieve the current access list to expand
+		accessList := prevTracer.AccessList()
+		log.Trace("Creating access list", "input", accessList)
+
+		// Copy the original db so we don't modify it
+		statedb := db.Copy()
+		msg := args.ToMessage(b.RPCGasCap())
+
+		// Apply the transaction with the access list tracer
+		tracer := vm.NewAccessListTracer(accessList, from, to, precompiles)
+		config := vm.Config{Tracer: tracer, Debug: true}
+		vmenv, _, err := b.GetEVM(ctx, msg, statedb, header, &config)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if b.ChainConfig().IsYoloV2(header.Number) {
+			statedb.PrepareAccessList(msg.From(), msg.To(), precompiles, accessList)
+		}
+		// Cancel the execution if the context is done before it finishes
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				vmenv.Cancel()
+			case <-done:
+			}
+		}()
+		res, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+		close(done)
+		if vmenv.Cancelled() {
+			return nil, 0, nil, fmt.Errorf("execution aborted: %v", ctx.Err())
+		}
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("failed to apply transaction: %v", err)
+		}
+		if tracer.Equal(prevTracer) {
+			gasUsed := res.UsedGas
+			if b.ChainConfig().IsYoloV2(header.Number) {
+				gasUsed += accessListGas(accessList)
+			}
+			return accessList, gasUsed, res.Err, nil
+		}
+		prevTracer = tracer
+	}
+}
+
 // EstimateGas returns an estimate of the amount of gas needed to execute the
 // given transaction against the current pending block.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
@@ -0,0 +1,336 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ioncapi
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/consensus"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/crypto"
+	"github.com/ionchain/ionchain-core/params"
+	"github.com/ionchain/ionchain-core/rpc"
+)
+
+// callTestBackend is a Backend serving a short header chain on top of an empty
+// state, only implementing the methods needed to simulate calls.
+type callTestBackend struct {
+	Backend
+
+	config  *params.ChainConfig
+	headers []*types.Header
+	state   *state.StateDB
+}
+
+func newCallTestBackend(t *testing.T, config *params.ChainConfig, length int) *callTestBackend {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	b := &callTestBackend{config: config, state: statedb}
+	for i := 0; i < length; i++ {
+		header := &types.Header{
+			Number:              big.NewInt(int64(i)),
+			Difficulty:          big.NewInt(1),
+			BaseTarget:          big.NewInt(1),
+			GasLimit:            params.GenesisGasLimit,
+			Time:                uint64(i * 10),
+			GenerationSignature: []byte{byte(i)},
+		}
+		if i > 0 {
+			header.ParentHash = b.headers[i-1].Hash()
+		}
+		b.headers = append(b.headers, header)
+	}
+	return b
+}
+
+func (b *callTestBackend) head() *types.Header { return b.headers[len(b.headers)-1] }
+
+func (b *callTestBackend) ChainConfig() *params.ChainConfig { return b.config }
+
+func (b *callTestBackend) RPCGasCap() uint64 { return 0 }
+
+func (b *callTestBackend) Engine() consensus.Engine { return nil }
+
+func (b *callTestBackend) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if number < uint64(len(b.headers)) && b.headers[number].Hash() == hash {
+		return b.headers[number]
+	}
+	return nil
+}
+
+func (b *callTestBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	for _, header := range b.headers {
+		if header.Hash() == hash {
+			return header, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *callTestBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	return b.head(), nil
+}
+
+func (b *callTestBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	return b.state.Copy(), b.head(), nil
+}
+
+func (b *callTestBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	if vmConfig == nil {
+		vmConfig = new(vm.Config)
+	}
+	context := core.NewEVMBlockContext(header, b, &header.Coinbase)
+	return vm.NewEVM(context, core.NewEVMTxContext(msg), state, b.config, *vmConfig), func() error { return nil }, nil
+}
+
+// Tests that a call failing the consensus checks after its gas was bought
+// doesn't leak any state changes into the later calls of a bundle.
+func TestCallManyRevertsFailedCalls(t *testing.T) {
+	var (
+		b       = newCallTestBackend(t, params.TestChainConfig, 4)
+		sender  = common.HexToAddress("0x1000")
+		probe   = common.HexToAddress("0x2000")
+		balance = (*hexutil.Big)(big.NewInt(1000000))
+		gas     = hexutil.Uint64(100000)
+		code    = hexutil.Bytes(common.FromHex("0x3331600052" + "60206000f3")) // BALANCE(CALLER), return it
+	)
+	overrides := StateOverride{
+		sender: {Balance: &balance},
+		probe:  {Code: &code},
+	}
+	calls := []BundleCall{
+		// Can afford the gas, but not the value transfer
+		{CallArgs: CallArgs{From: &sender, To: &probe, Gas: &gas, GasPrice: (*hexutil.Big)(big.NewInt(1)), Value: (*hexutil.Big)(big.NewInt(1000000))}},
+		{CallArgs: CallArgs{From: &sender, To: &probe, Gas: &gas}},
+	}
+	results, err := DoCallMany(context.Background(), b, calls, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil, overrides, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to execute bundle: %v", err)
+	}
+	if results[0].Error == "" {
+		t.Fatalf("unaffordable call succeeded")
+	}
+	if results[1].Error != "" {
+		t.Fatalf("probe call failed: %v", results[1].Error)
+	}
+	if have := new(big.Int).SetBytes(results[1].ReturnData); have.Cmp(balance.ToInt()) != 0 {
+		t.Errorf("sender balance mismatch after failed call: have %v, want %v", have, balance.ToInt())
+	}
+}
+
+// Tests that overriding the number of the simulated block switches the chain
+// rules and the block hashes accordingly.
+func TestCallManyNumberOverride(t *testing.T) {
+	config := *params.TestChainConfig
+	config.IstanbulBlock = big.NewInt(5)
+	config.MuirGlacierBlock = nil
+
+	var (
+		b      = newCallTestBackend(t, &config, 4)
+		probe  = common.HexToAddress("0x2000")
+		chain  = common.HexToAddress("0x3000")
+		number = hexutil.Bytes(common.FromHex("0x4360005260034060205260044060405260606000f3")) // NUMBER, BLOCKHASH(3), BLOCKHASH(4)
+		chid   = hexutil.Bytes(common.FromHex("0x4660005260206000f3"))                         // CHAINID, Istanbul only
+	)
+	overrides := StateOverride{
+		probe: {Code: &number},
+		chain: {Code: &chid},
+	}
+	calls := []BundleCall{
+		{CallArgs: CallArgs{To: &probe}},
+		{CallArgs: CallArgs{To: &chain}},
+	}
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+
+	// Without overrides the calls run in the head block, before Istanbul
+	results, err := DoCallMany(context.Background(), b, calls, latest, nil, overrides, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to execute bundle: %v", err)
+	}
+	if have := new(big.Int).SetBytes(results[0].ReturnData[:32]); have.Uint64() != 3 {
+		t.Errorf("block number mismatch: have %v, want 3", have)
+	}
+	if have := common.BytesToHash(results[0].ReturnData[32:64]); have != (common.Hash{}) {
+		t.Errorf("current block hash available: %x", have)
+	}
+	if results[1].Error == "" {
+		t.Errorf("CHAINID executed before Istanbul")
+	}
+	// Overriding the number must activate Istanbul and expose the real parent
+	results, err = DoCallMany(context.Background(), b, calls, latest, &BlockOverrides{Number: (*hexutil.Big)(big.NewInt(5))}, overrides, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to execute bundle: %v", err)
+	}
+	if have := new(big.Int).SetBytes(results[0].ReturnData[:32]); have.Uint64() != 5 {
+		t.Errorf("block number mismatch: have %v, want 5", have)
+	}
+	if have, want := common.BytesToHash(results[0].ReturnData[32:64]), b.head().Hash(); have != want {
+		t.Errorf("parent hash mismatch: have %x, want %x", have, want)
+	}
+	if have := common.BytesToHash(results[0].ReturnData[64:]); have != (common.Hash{}) {
+		t.Errorf("skipped block hash available: %x", have)
+	}
+	if results[1].Error != "" {
+		t.Fatalf("CHAINID failed after Istanbul: %v", results[1].Error)
+	}
+	if have := new(big.Int).SetBytes(results[1].ReturnData); have.Cmp(config.ChainID) != 0 {
+		t.Errorf("chain id mismatch: have %v, want %v", have, config.ChainID)
+	}
+}
+
+// yoloV2TestConfig returns a copy of the test chain config with EIP-2929 active.
+func yoloV2TestConfig() *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.YoloV2Block = big.NewInt(0)
+	return &config
+}
+
+// accessListSet flattens an access list for order independent comparisons.
+func accessListSet(list types.AccessList) map[common.Address]map[common.Hash]bool {
+	set := make(map[common.Address]map[common.Hash]bool)
+	for _, tuple := range list {
+		set[tuple.Address] = make(map[common.Hash]bool)
+		for _, key := range tuple.StorageKeys {
+			set[tuple.Address][key] = true
+		}
+	}
+	return set
+}
+
+// Tests that access lists are grown until the execution with the list warmed up
+// doesn't touch anything new, leaving out the sender, the destination and the
+// precompiles.
+func TestAccessList(t *testing.T) {
+	var (
+		b        = newCallTestBackend(t, yoloV2TestConfig(), 4)
+		contract = common.HexToAddress("0xc0")
+		probed   = common.HexToAddress("0xb0")
+		gated    = common.HexToAddress("0xb1")
+		slot     = common.BigToHash(big.NewInt(1))
+		gas      = hexutil.Uint64(100000)
+	)
+	// SLOAD(1), BALANCE(0x02), BALANCE(0xb0), STATICCALL(0x04). If the remaining
+	// gas is above 77600, which is only the case if 0xb0 was warm, BALANCE(0xb1).
+	b.state.SetCode(contract, common.FromHex("0x600154506002315060b03150600060006000600060045afa5062012f505a11602357005b60b1315000"))
+
+	tests := []struct {
+		args CallArgs
+		want types.AccessList
+	}{
+		// The gated address is only reached once the probed one was warmed up
+		{
+			args: CallArgs{To: &contract, Gas: &gas},
+			want: types.AccessList{{Address: contract, StorageKeys: []common.Hash{slot}}, {Address: probed}, {Address: gated}},
+		},
+		// Contract creations exclude the address being created, but not its slots.
+		// PUSH1 5, SLOAD, POP, PUSH1 0xb0, BALANCE, POP, STOP
+		{
+			args: CallArgs{Gas: &gas, Data: (*hexutil.Bytes)(&[]byte{0x60, 0x05, 0x54, 0x50, 0x60, 0xb0, 0x31, 0x50, 0x00})},
+			want: types.AccessList{{Address: crypto.CreateAddress(common.Address{}, 0), StorageKeys: []common.Hash{common.BigToHash(big.NewInt(5))}}, {Address: probed}},
+		},
+	}
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	for i, tt := range tests {
+		list, gasUsed, vmErr, err := AccessList(context.Background(), b, latest, tt.args)
+		if err != nil || vmErr != nil {
+			t.Fatalf("test %d: failed to create access list: %v, %v", i, err, vmErr)
+		}
+		if have, want := accessListSet(list), accessListSet(tt.want); !reflect.DeepEqual(have, want) {
+			t.Errorf("test %d: access list mismatch: have %v, want %v", i, list, tt.want)
+		}
+		// Running with the created list must reproduce it and cost the same
+		args := tt.args
+		args.AccessList = &list
+		again, againUsed, _, err := AccessList(context.Background(), b, latest, args)
+		if err != nil {
+			t.Fatalf("test %d: failed to recreate access list: %v", i, err)
+		}
+		if !reflect.DeepEqual(accessListSet(again), accessListSet(list)) || againUsed != gasUsed {
+			t.Errorf("test %d: access list not converged: have %v using %d, want %v using %d", i, again, againUsed, list, gasUsed)
+		}
+	}
+}
+
+// Tests that the access list creation is aborted once its context is done, even
+// in the middle of an execution.
+func TestAccessListCancel(t *testing.T) {
+	var (
+		b        = newCallTestBackend(t, yoloV2TestConfig(), 4)
+		contract = common.HexToAddress("0xc0")
+		gas      = hexutil.Uint64(1 << 40)
+	)
+	b.state.SetCode(contract, common.FromHex("0x5b600056")) // JUMPDEST, PUSH1 0, JUMP
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, _, _, err := AccessList(ctx, b, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), CallArgs{To: &contract, Gas: &gas})
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatalf("endless execution succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("execution not aborted")
+	}
+}
+
+// Tests that gas estimations only account for the intrinsic cost of the access
+// list once EIP-2929 is in effect.
+func TestEstimateGasAccessList(t *testing.T) {
+	var (
+		to   = common.HexToAddress("0xee")
+		gas  = hexutil.Uint64(50000)
+		list = types.AccessList{{Address: common.HexToAddress("0xb0"), StorageKeys: []common.Hash{{0x01}, {0x02}}}}
+	)
+	tests := []struct {
+		config *params.ChainConfig
+		list   *types.AccessList
+		want   uint64
+	}{
+		{params.TestChainConfig, nil, params.TxGas},
+		{params.TestChainConfig, &list, params.TxGas},
+		{yoloV2TestConfig(), nil, params.TxGas},
+		{yoloV2TestConfig(), &list, params.TxGas + params.TxAccessListAddressGas + 2*params.TxAccessListStorageKeyGas},
+	}
+	for i, tt := range tests {
+		b := newCallTestBackend(t, tt.config, 4)
+		have, err := DoEstimateGas(context.Background(), b, CallArgs{To: &to, Gas: &gas, AccessList: tt.list}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), 0)
+		if err != nil {
+			t.Fatalf("test %d: failed to estimate gas: %v", i, err)
+		}
+		if uint64(have) != tt.want {
+			t.Errorf("test %d: gas mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
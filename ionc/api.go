@@ -264,6 +264,32 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// AddCheckpoint pins a block, refusing any future chain reorg or head rewind
+// which would drop it or make a different block canonical at its height. The
+// checkpoint is persisted in the database until removed.
+func (api *PrivateAdminAPI) AddCheckpoint(number hexutil.Uint64, hash common.Hash) bool {
+	api.eth.BlockChain().AddCheckpoint(uint64(number), hash)
+	return true
+}
+
+// RemoveCheckpoint unpins the checkpoint at the given height, returning whether
+// there was any.
+func (api *PrivateAdminAPI) RemoveCheckpoint(number hexutil.Uint64) bool {
+	return api.eth.BlockChain().RemoveCheckpoint(uint64(number))
+}
+
+// Checkpoints returns the blocks pinned against chain reorgs.
+func (api *PrivateAdminAPI) Checkpoints() []core.Checkpoint {
+	return api.eth.BlockChain().Checkpoints()
+}
+
+// SetMaxReorgDepth sets the maximum number of canonical blocks a chain reorg may
+// drop, zero disabling the limit.
+func (api *PrivateAdminAPI) SetMaxReorgDepth(depth hexutil.Uint64) bool {
+	api.eth.BlockChain().SetMaxReorgDepth(uint64(depth))
+	return true
+}
+
 // PublicDebugAPI is the collection of IonChain full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -351,6 +377,27 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 	return results, nil
 }
 
+// GetBlockWitness returns the stateless witness of a block in its compact (snappy
+// compressed RLP) encoding. Witnesses not recorded during import are regenerated
+// if the parent state is still available.
+func (api *PrivateDebugAPI) GetBlockWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		return nil, errors.New("pending block has no witness")
+	}
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+	witness, err := api.eth.blockchain.BlockWitness(block)
+	if err != nil {
+		return nil, err
+	}
+	return witness.EncodeCompact()
+}
+
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
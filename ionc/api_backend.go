@@ -146,7 +146,7 @@ func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.B
 	if header == nil {
 		return nil, nil, errors.New("header not found")
 	}
-	stateDb, err := b.ionc.BlockChain().StateAt(header.Root)
+	stateDb, err := b.stateAtHeader(header)
 	return stateDb, header, err
 }
 
@@ -165,12 +165,22 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 		if blockNrOrHash.RequireCanonical && b.ionc.blockchain.GetCanonicalHash(header.Number.Uint64()) != hash {
 			return nil, nil, errors.New("hash is not currently canonical")
 		}
-		stateDb, err := b.ionc.BlockChain().StateAt(header.Root)
+		stateDb, err := b.stateAtHeader(header)
 		return stateDb, header, err
 	}
 	return nil, nil, errors.New("invalid arguments; neither block nor hash specified")
 }
 
+// stateAtHeader returns the state of the given block. If the state trie was
+// already pruned, it's reconstructed from the reverse state diffs if available.
+func (b *EthAPIBackend) stateAtHeader(header *types.Header) (*state.StateDB, error) {
+	stateDb, err := b.ionc.BlockChain().StateAt(header.Root)
+	if err != nil && b.ionc.config.StateDiffs {
+		return b.ionc.BlockChain().HistoricStateAt(header)
+	}
+	return stateDb, err
+}
+
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
 	return b.ionc.blockchain.GetReceiptsByHash(hash), nil
 }
@@ -191,12 +201,14 @@ func (b *EthAPIBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
 	return b.ionc.blockchain.GetTdByHash(hash)
 }
 
-func (b *EthAPIBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header) (*vm.EVM, func() error, error) {
+func (b *EthAPIBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
 	vmError := func() error { return nil }
-
+	if vmConfig == nil {
+		vmConfig = b.ionc.blockchain.GetVMConfig()
+	}
 	txContext := core.NewEVMTxContext(msg)
 	context := core.NewEVMBlockContext(header, b.ionc.BlockChain(), nil)
-	return vm.NewEVM(context, txContext, state, b.ionc.blockchain.Config(), *b.ionc.blockchain.GetVMConfig()), vmError, nil
+	return vm.NewEVM(context, txContext, state, b.ionc.blockchain.Config(), *vmConfig), vmError, nil
 }
 
 func (b *EthAPIBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
@@ -309,6 +321,14 @@ func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, sections
 }
 
+func (b *EthAPIBackend) LogIndexStatus() (uint64, uint64) {
+	if b.ionc.logIndexer == nil {
+		return 0, 0
+	}
+	sections, _, _ := b.ionc.logIndexer.Sections()
+	return params.BloomBitsBlocks, sections
+}
+
 func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.ionc.bloomRequests)
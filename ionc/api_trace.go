@@ -0,0 +1,374 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/ionc/tracers"
+	"github.com/ionchain/ionchain-core/rpc"
+)
+
+// maxTraceFilterBlocks is the maximum number of blocks a trace filter without
+// any address criteria may span (variable to allow tests to lower it).
+var maxTraceFilterBlocks = uint64(10000)
+
+// PrivateTraceAPI is the collection of Parity style call tracing APIs, serving
+// flattened call traces from the call trace index, or by re-executing blocks if
+// they are not indexed.
+type PrivateTraceAPI struct {
+	eth   *IonChain
+	debug *PrivateDebugAPI
+}
+
+// NewPrivateTraceAPI creates a new API definition for the call tracing methods
+// of the IonChain service.
+func NewPrivateTraceAPI(eth *IonChain) *PrivateTraceAPI {
+	return &PrivateTraceAPI{eth: eth, debug: NewPrivateDebugAPI(eth)}
+}
+
+// blockByNumber retrieves a canonical block by number, resolving the pending
+// and latest tags to the current head.
+func (api *PrivateTraceAPI) blockByNumber(number rpc.BlockNumber) (*types.Block, error) {
+	var block *types.Block
+	if number == rpc.PendingBlockNumber || number == rpc.LatestBlockNumber {
+		block = api.eth.blockchain.CurrentBlock()
+	} else {
+		block = api.eth.blockchain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return block, nil
+}
+
+// blockCallTraces returns the flattened call traces of a block, re-executing it
+// if it's not indexed.
+func (api *PrivateTraceAPI) blockCallTraces(block *types.Block) ([]*callTrace, error) {
+	traces, err := readCallTraces(api.eth.ChainDb(), block)
+	if err != errCallTracesNotIndexed {
+		return traces, err
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, err := api.debug.computeStateDB(parent, defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	return traceBlockCalls(api.eth.blockchain, block, statedb)
+}
+
+// formatTraces converts the flat call traces of a block into the Parity format.
+func formatTraces(traces []*callTrace, block *types.Block) []*parityTrace {
+	results := make([]*parityTrace, len(traces))
+	for i, trace := range traces {
+		results[i] = newParityTrace(trace, block)
+	}
+	return results
+}
+
+// Block returns the flattened call traces of all the transactions in a block.
+func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]*parityTrace, error) {
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	traces, err := api.blockCallTraces(block)
+	if err != nil {
+		return nil, err
+	}
+	return formatTraces(traces, block), nil
+}
+
+// Transaction returns the flattened call traces of a single transaction.
+func (api *PrivateTraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]*parityTrace, error) {
+	tx, blockHash, _, index := rawdb.ReadTransaction(api.eth.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	block := api.eth.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	// Serve the traces from the index if available, otherwise execute up to the
+	// transaction only
+	traces, err := readCallTraces(api.eth.ChainDb(), block)
+	if err == errCallTracesNotIndexed {
+		msg, vmctx, statedb, err := api.debug.computeTxEnv(block, int(index), defaultTraceReexec)
+		if err != nil {
+			return nil, err
+		}
+		if _, traces, err = traceMessageCalls(api.eth.blockchain.Config(), vmctx, msg, statedb, index); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	var results []*parityTrace
+	for _, trace := range traces {
+		if trace.TxIndex == index {
+			results = append(results, newParityTrace(trace, block))
+		}
+	}
+	return results, nil
+}
+
+// TraceFilterArgs are the criteria of a trace filter.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// matches returns whether a trace satisfies the address criteria of the filter.
+func (args *TraceFilterArgs) matches(trace *callTrace) bool {
+	contains := func(addrs []common.Address, addr common.Address) bool {
+		if len(addrs) == 0 {
+			return true
+		}
+		for _, a := range addrs {
+			if a == addr {
+				return true
+			}
+		}
+		return false
+	}
+	return contains(args.FromAddress, trace.From) && contains(args.ToAddress, trace.To)
+}
+
+// resolveTraceFilterBlock converts a block number of a trace filter into an
+// absolute one, resolving the latest tag to the head of the call trace index.
+func resolveTraceFilterBlock(number rpc.BlockNumber, head uint64) (uint64, error) {
+	switch {
+	case number == rpc.PendingBlockNumber:
+		return 0, errors.New("pending block not indexed")
+	case number == rpc.LatestBlockNumber:
+		return head, nil
+	case number < 0:
+		return 0, fmt.Errorf("invalid block number %d", number)
+	}
+	return uint64(number), nil
+}
+
+// Filter returns the flattened call traces within a block range matching the
+// given address criteria. It's served exclusively from the call trace index, so
+// the whole range must already be indexed.
+//
+// The range ends at the head of the index by default. Without a starting block,
+// it spans the whole index if filtering by address, or the most blocks allowed
+// otherwise.
+func (api *PrivateTraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]*parityTrace, error) {
+	var (
+		db   = api.eth.ChainDb()
+		head = api.eth.blockchain.GetHeaderByHash(rawdb.ReadCallTraceIndexHead(db))
+		from uint64
+		to   uint64
+		err  error
+	)
+	if head == nil {
+		return nil, errors.New("call trace index not available")
+	}
+	to = head.Number.Uint64()
+	if args.ToBlock != nil {
+		if to, err = resolveTraceFilterBlock(*args.ToBlock, head.Number.Uint64()); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case args.FromBlock != nil:
+		if from, err = resolveTraceFilterBlock(*args.FromBlock, head.Number.Uint64()); err != nil {
+			return nil, err
+		}
+	case len(args.FromAddress) == 0 && len(args.ToAddress) == 0 && to >= maxTraceFilterBlocks:
+		from = to - maxTraceFilterBlocks + 1
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if to > head.Number.Uint64() {
+		return nil, fmt.Errorf("block range %d-%d not indexed, index head #%d", from, to, head.Number)
+	}
+	// Gather the candidate blocks, using the address index if possible
+	var numbers []uint64
+	if len(args.FromAddress) > 0 || len(args.ToAddress) > 0 {
+		seen := make(map[uint64]struct{})
+		for _, addr := range append(append([]common.Address{}, args.FromAddress...), args.ToAddress...) {
+			for _, number := range rawdb.ReadCallTraceAddressBlocks(db, addr, from, to) {
+				if _, ok := seen[number]; !ok {
+					seen[number] = struct{}{}
+					numbers = append(numbers, number)
+				}
+			}
+		}
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	} else {
+		if to-from >= maxTraceFilterBlocks {
+			return nil, fmt.Errorf("block range too large without address criteria, limit %d", maxTraceFilterBlocks)
+		}
+		for number := from; number <= to; number++ {
+			numbers = append(numbers, number)
+		}
+	}
+	// Filter the traces of the canonical blocks, skipping and limiting as requested
+	var (
+		results []*parityTrace
+		skipped uint64
+	)
+	for _, number := range numbers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := api.eth.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		traces, err := readCallTraces(db, block)
+		if err == errCallTracesNotIndexed && number == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("block #%d: %v", number, err)
+		}
+		for _, trace := range traces {
+			if !args.matches(trace) {
+				continue
+			}
+			if args.After != nil && skipped < *args.After {
+				skipped++
+				continue
+			}
+			results = append(results, newParityTrace(trace, block))
+			if args.Count != nil && uint64(len(results)) >= *args.Count {
+				return results, nil
+			}
+		}
+	}
+	return results, nil
+}
+
+// traceReplayResult is the outcome of replaying a single transaction.
+type traceReplayResult struct {
+	Output          hexutil.Bytes                         `json:"output"`
+	StateDiff       map[common.Address]*parityAccountDiff `json:"stateDiff"`
+	Trace           []*parityTrace                        `json:"trace"`
+	VmTrace         interface{}                           `json:"vmTrace"`
+	TransactionHash common.Hash                           `json:"transactionHash"`
+}
+
+// ReplayBlockTransactions re-executes all the transactions of a block, returning
+// the requested kinds of traces for each. The supported trace types are "trace"
+// for the flattened call traces and "stateDiff" for the modified state.
+func (api *PrivateTraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string) ([]*traceReplayResult, error) {
+	var withTrace, withDiff bool
+	for _, kind := range traceTypes {
+		switch kind {
+		case "trace":
+			withTrace = true
+		case "stateDiff":
+			withDiff = true
+		default:
+			return nil, fmt.Errorf("unsupported trace type %q", kind)
+		}
+	}
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, err := api.debug.computeStateDB(parent, defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		config  = api.eth.blockchain.Config()
+		signer  = types.MakeSigner(config, block.Number())
+		vmctx   = core.NewEVMBlockContext(block.Header(), api.eth.blockchain, nil)
+		results = make([]*traceReplayResult, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = &traceReplayResult{TransactionHash: tx.Hash()}
+
+		// Gather the state diff on a copy, the call traces advance the state
+		if withDiff {
+			diffdb := statedb.Copy()
+			tracer, err := tracers.NewTracer("prestateTracer", diffdb, json.RawMessage(`{"diffMode":true}`))
+			if err != nil {
+				return nil, err
+			}
+			vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(msg), diffdb, config, vm.Config{Debug: true, Tracer: tracer})
+			if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+				return nil, fmt.Errorf("tracing failed: %v", err)
+			}
+			blob, err := tracer.GetResult()
+			if err != nil {
+				return nil, err
+			}
+			if results[i].StateDiff, err = newParityStateDiff(blob); err != nil {
+				return nil, err
+			}
+			// The tracer reports the sender's balance after the gas purchase, use
+			// the actual one prior to the transaction instead
+			if account := results[i].StateDiff[msg.From()]; account != nil {
+				pre, post := statedb.GetBalance(msg.From()), diffdb.GetBalance(msg.From())
+				account.Balance = parityFieldDiff((*hexutil.Big)(pre), (*hexutil.Big)(post), true, true, pre.Cmp(post) != 0)
+			}
+		}
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		result, traces, err := traceMessageCalls(config, vmctx, msg, statedb, uint64(i))
+		if err != nil {
+			return nil, err
+		}
+		statedb.Finalise(config.IsEIP158(block.Number()))
+
+		results[i].Output = result.Return()
+		if withTrace {
+			results[i].Trace = formatTraces(traces, block)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,303 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/consensus"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/crypto"
+	"github.com/ionchain/ionchain-core/ioncdb"
+	"github.com/ionchain/ionchain-core/params"
+	"github.com/ionchain/ionchain-core/rpc"
+	"github.com/ionchain/ionchain-core/trie"
+)
+
+// traceTestEngine is a consensus engine accepting any block and crediting no
+// rewards, for tests which need a full blockchain without the proof of stake
+// machinery.
+type traceTestEngine struct {
+	consensus.Engine
+}
+
+func (traceTestEngine) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+func (traceTestEngine) VerifyHeader(consensus.ChainHeaderReader, *types.Header, bool) error {
+	return nil
+}
+
+func (traceTestEngine) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	results := make(chan error, len(headers))
+	for range headers {
+		results <- nil
+	}
+	return make(chan struct{}), results
+}
+
+func (traceTestEngine) VerifyUncles(consensus.ChainReader, *types.Block) error {
+	return nil
+}
+
+func (traceTestEngine) Prepare(consensus.ChainHeaderReader, *types.Header) error {
+	return nil
+}
+
+func (traceTestEngine) CalcDifficulty(consensus.ChainHeaderReader, uint64, *types.Header) *big.Int {
+	return big.NewInt(1)
+}
+
+func (traceTestEngine) Finalize(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+	header.Root = statedb.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+}
+
+func (e traceTestEngine) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	e.Finalize(chain, header, statedb, txs, uncles)
+	return types.NewBlock(header, txs, uncles, receipts, new(trie.Trie)), nil
+}
+
+var (
+	traceTestKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	traceTestSender   = crypto.PubkeyToAddress(traceTestKey.PublicKey)
+	traceTestSigner   = types.NewEIP155Signer(params.TestChainConfig.ChainID)
+	traceTestForward  = common.HexToAddress("0xf0") // contract forwarding its call value to traceTestSink
+	traceTestSink     = common.HexToAddress("0x5c")
+	traceTestReceiver = common.HexToAddress("0xee")
+)
+
+// traceTestGenesis commits a genesis block funding traceTestSender and holding
+// the forwarding contract.
+func traceTestGenesis(db ioncdb.Database) *types.Block {
+	// PUSH1 0, PUSH1 0, PUSH1 0, PUSH1 0, CALLVALUE, PUSH20 sink, GAS, CALL, STOP
+	code := append(append(common.FromHex("0x60006000600060003473"), traceTestSink.Bytes()...), common.FromHex("0x5af100")...)
+	genesis := &core.Genesis{
+		Config:     params.TestChainConfig,
+		Difficulty: big.NewInt(1),
+		BaseTarget: big.NewInt(1),
+		GasLimit:   10000000,
+		Alloc: core.GenesisAlloc{
+			traceTestSender:  {Balance: big.NewInt(params.Ether)},
+			traceTestForward: {Code: code, Balance: new(big.Int)},
+		},
+	}
+	return genesis.MustCommit(db)
+}
+
+// traceTestBlocks generates blocks on top of the given parent, sending a value of
+// the block number to the forwarding contract in even blocks and to
+// traceTestReceiver in odd ones, or to traceTestReceiver only if plain is set.
+func traceTestBlocks(db ioncdb.Database, parent *types.Block, n int, plain bool) []*types.Block {
+	blocks, _ := core.GenerateChain(params.TestChainConfig, parent, traceTestEngine{}, db, n, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.HexToAddress("0xc0"))
+		to := traceTestReceiver
+		if !plain && gen.Number().Uint64()%2 == 0 {
+			to = traceTestForward
+		}
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(traceTestSender), to, gen.Number(), 100000, big.NewInt(1), nil), traceTestSigner, traceTestKey)
+		gen.AddTx(tx)
+	})
+	return blocks
+}
+
+// newTraceTestChain creates an IonChain service around a chain of the given
+// length generated by traceTestBlocks, without any networking or mining.
+func newTraceTestChain(t *testing.T, length int) *IonChain {
+	db := rawdb.NewMemoryDatabase()
+	genesis := traceTestGenesis(db)
+
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, traceTestEngine{}, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(traceTestBlocks(db, genesis, length, false)); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	config := DefaultConfig
+	eth := &IonChain{config: &config, blockchain: chain, chainDb: db, engine: traceTestEngine{}}
+	eth.APIBackend = &EthAPIBackend{ionc: eth}
+	return eth
+}
+
+// indexTestCallTraces runs a call trace indexer over the chain until it reaches
+// the head, returning it for reuse.
+func indexTestCallTraces(eth *IonChain, idx *callTraceIndexer) *callTraceIndexer {
+	if idx == nil {
+		idx = newCallTraceIndexer(eth.blockchain, eth.chainDb)
+		idx.head = eth.blockchain.Genesis().Header()
+	}
+	idx.index(make(chan struct{}))
+	return idx
+}
+
+// Tests that the trace filter serves the range, address, skip and limit criteria
+// from the call trace index.
+func TestTraceFilter(t *testing.T) {
+	defer func(limit uint64) { maxTraceFilterBlocks = limit }(maxTraceFilterBlocks)
+	maxTraceFilterBlocks = 8
+
+	eth := newTraceTestChain(t, 12)
+	defer eth.blockchain.Stop()
+
+	api := NewPrivateTraceAPI(eth)
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{}); err == nil {
+		t.Fatalf("filter succeeded without a call trace index")
+	}
+	indexTestCallTraces(eth, nil)
+
+	var (
+		number = func(n int64) *rpc.BlockNumber { bn := rpc.BlockNumber(n); return &bn }
+		count  = func(n uint64) *uint64 { return &n }
+	)
+	type trace struct {
+		block   uint64
+		from    common.Address
+		to      common.Address
+		address []uint64
+	}
+	tests := []struct {
+		args TraceFilterArgs
+		want []trace
+		fail bool
+	}{
+		// Address criteria span the whole index
+		{
+			args: TraceFilterArgs{ToAddress: []common.Address{traceTestSink}},
+			want: []trace{
+				{2, traceTestForward, traceTestSink, []uint64{0}}, {4, traceTestForward, traceTestSink, []uint64{0}},
+				{6, traceTestForward, traceTestSink, []uint64{0}}, {8, traceTestForward, traceTestSink, []uint64{0}},
+				{10, traceTestForward, traceTestSink, []uint64{0}}, {12, traceTestForward, traceTestSink, []uint64{0}},
+			},
+		},
+		{
+			args: TraceFilterArgs{FromBlock: number(3), ToBlock: number(6), FromAddress: []common.Address{traceTestForward}, ToAddress: []common.Address{traceTestSink}},
+			want: []trace{{4, traceTestForward, traceTestSink, []uint64{0}}, {6, traceTestForward, traceTestSink, []uint64{0}}},
+		},
+		// Ranges without address criteria are capped, defaulting to the last blocks
+		{
+			args: TraceFilterArgs{FromBlock: number(3), ToBlock: number(5)},
+			want: []trace{
+				{3, traceTestSender, traceTestReceiver, []uint64{}},
+				{4, traceTestSender, traceTestForward, []uint64{}}, {4, traceTestForward, traceTestSink, []uint64{0}},
+				{5, traceTestSender, traceTestReceiver, []uint64{}},
+			},
+		},
+		{
+			args: TraceFilterArgs{FromBlock: number(1), ToBlock: number(8)},
+			want: []trace{
+				{1, traceTestSender, traceTestReceiver, []uint64{}},
+				{2, traceTestSender, traceTestForward, []uint64{}}, {2, traceTestForward, traceTestSink, []uint64{0}},
+				{3, traceTestSender, traceTestReceiver, []uint64{}},
+				{4, traceTestSender, traceTestForward, []uint64{}}, {4, traceTestForward, traceTestSink, []uint64{0}},
+				{5, traceTestSender, traceTestReceiver, []uint64{}},
+				{6, traceTestSender, traceTestForward, []uint64{}}, {6, traceTestForward, traceTestSink, []uint64{0}},
+				{7, traceTestSender, traceTestReceiver, []uint64{}},
+				{8, traceTestSender, traceTestForward, []uint64{}}, {8, traceTestForward, traceTestSink, []uint64{0}},
+			},
+		},
+		{args: TraceFilterArgs{FromBlock: number(1), ToBlock: number(9)}, fail: true},
+		{
+			args: TraceFilterArgs{After: count(9)}, // blocks 5-12
+			want: []trace{
+				{11, traceTestSender, traceTestReceiver, []uint64{}},
+				{12, traceTestSender, traceTestForward, []uint64{}}, {12, traceTestForward, traceTestSink, []uint64{0}},
+			},
+		},
+		{
+			args: TraceFilterArgs{FromAddress: []common.Address{traceTestSender}, ToAddress: []common.Address{traceTestReceiver}, After: count(2)},
+			want: []trace{
+				{5, traceTestSender, traceTestReceiver, []uint64{}}, {7, traceTestSender, traceTestReceiver, []uint64{}},
+				{9, traceTestSender, traceTestReceiver, []uint64{}}, {11, traceTestSender, traceTestReceiver, []uint64{}},
+			},
+		},
+		{
+			args: TraceFilterArgs{ToBlock: number(int64(rpc.LatestBlockNumber)), FromAddress: []common.Address{traceTestForward}, After: count(1), Count: count(2)},
+			want: []trace{{4, traceTestForward, traceTestSink, []uint64{0}}, {6, traceTestForward, traceTestSink, []uint64{0}}},
+		},
+		// Ranges must be resolvable and within the index
+		{args: TraceFilterArgs{FromBlock: number(int64(rpc.LatestBlockNumber)), ToAddress: []common.Address{traceTestSink}}, want: []trace{{12, traceTestForward, traceTestSink, []uint64{0}}}},
+		{args: TraceFilterArgs{ToBlock: number(int64(rpc.PendingBlockNumber))}, fail: true},
+		{args: TraceFilterArgs{FromBlock: number(6), ToBlock: number(5)}, fail: true},
+		{args: TraceFilterArgs{FromBlock: number(12), ToBlock: number(13)}, fail: true},
+	}
+
+	for i, tt := range tests {
+		results, err := api.Filter(context.Background(), tt.args)
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: failure mismatch: have %v, want fail %v", i, err, tt.fail)
+			continue
+		}
+		var have []trace
+		for _, result := range results {
+			action := result.Action.(*parityCallAction)
+			have = append(have, trace{result.BlockNumber, action.From, action.To, result.TraceAddress})
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: traces mismatch:\nhave %v\nwant %v", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that the call trace indexer rewinds onto a reorged chain, dropping the
+// traces and address marks of the stale blocks.
+func TestCallTraceIndexerReorg(t *testing.T) {
+	eth := newTraceTestChain(t, 6)
+	defer eth.blockchain.Stop()
+
+	idx := indexTestCallTraces(eth, nil)
+	stale := eth.blockchain.CurrentBlock()
+	if have := rawdb.ReadCallTraceAddressBlocks(eth.chainDb, traceTestSink, 0, 10); !reflect.DeepEqual(have, []uint64{2, 4, 6}) {
+		t.Fatalf("sink blocks mismatch: have %v, want %v", have, []uint64{2, 4, 6})
+	}
+	// Reorg onto a longer fork from block 3 without any forwarded calls
+	fork := traceTestBlocks(eth.chainDb, eth.blockchain.GetBlockByNumber(3), 4, true)
+	if _, err := eth.blockchain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	indexTestCallTraces(eth, idx)
+
+	if head := rawdb.ReadCallTraceIndexHead(eth.chainDb); head != fork[len(fork)-1].Hash() {
+		t.Fatalf("index head mismatch: have %x, want %x", head, fork[len(fork)-1].Hash())
+	}
+	if have := rawdb.ReadCallTraceAddressBlocks(eth.chainDb, traceTestSink, 0, 10); !reflect.DeepEqual(have, []uint64{2}) {
+		t.Errorf("sink blocks mismatch: have %v, want %v", have, []uint64{2})
+	}
+	if have := rawdb.ReadCallTraceAddressBlocks(eth.chainDb, traceTestForward, 0, 10); !reflect.DeepEqual(have, []uint64{2}) {
+		t.Errorf("forwarder blocks mismatch: have %v, want %v", have, []uint64{2})
+	}
+	if have := rawdb.ReadCallTraceAddressBlocks(eth.chainDb, traceTestReceiver, 0, 10); !reflect.DeepEqual(have, []uint64{1, 3, 4, 5, 6, 7}) {
+		t.Errorf("receiver blocks mismatch: have %v, want %v", have, []uint64{1, 3, 4, 5, 6, 7})
+	}
+	if blob := rawdb.ReadCallTraces(eth.chainDb, stale.Hash(), stale.NumberU64()); len(blob) != 0 {
+		t.Errorf("traces of stale head retained")
+	}
+	for _, block := range fork {
+		traces, err := readCallTraces(eth.chainDb, block)
+		if err != nil || len(traces) != 1 || traces[0].To != traceTestReceiver {
+			t.Errorf("block #%d: traces mismatch: have %v, %v", block.NumberU64(), traces, err)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -44,6 +45,10 @@ import (
 	"github.com/ionchain/ionchain-core/trie"
 )
 
+// errChainTraceAborted is returned if chain tracing was interrupted before
+// reaching the end of the requested range.
+var errChainTraceAborted = errors.New("chain tracing aborted")
+
 const (
 	// defaultTraceTimeout is the amount of time a single transaction can execute
 	// by default before being forcefully aborted.
@@ -58,9 +63,17 @@ const (
 // TraceConfig holds extra parameters to trace functions.
 type TraceConfig struct {
 	*vm.LogConfig
-	Tracer  *string
-	Timeout *string
-	Reexec  *uint64
+	Tracer       *string
+	TracerConfig json.RawMessage // Options of native tracers
+	Timeout      *string
+	Reexec       *uint64
+}
+
+// TraceCallConfig holds extra parameters to call tracing functions.
+type TraceCallConfig struct {
+	TraceConfig
+	StateOverrides *ioncapi.StateOverride
+	BlockOverrides *ioncapi.BlockOverrides
 }
 
 // StdTraceConfig holds extra parameters to standard-json trace functions.
@@ -103,7 +116,15 @@ type txTraceTask struct {
 // TraceChain returns the structured logs created during the execution of EVM
 // between two blocks (excluding start) and returns them as a JSON object.
 func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.BlockNumber, config *TraceConfig) (*rpc.Subscription, error) {
-	// Fetch the block interval that we want to trace
+	from, to, err := api.blockRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return api.traceChain(ctx, from, to, config)
+}
+
+// blockRange fetches the block interval that we want to trace.
+func (api *PrivateDebugAPI) blockRange(start, end rpc.BlockNumber) (*types.Block, *types.Block, error) {
 	var from, to *types.Block
 
 	switch start {
@@ -124,15 +145,50 @@ func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.Block
 	}
 	// Trace the chain if we've found all our blocks
 	if from == nil {
-		return nil, fmt.Errorf("starting block #%d not found", start)
+		return nil, nil, fmt.Errorf("starting block #%d not found", start)
 	}
 	if to == nil {
-		return nil, fmt.Errorf("end block #%d not found", end)
+		return nil, nil, fmt.Errorf("end block #%d not found", end)
 	}
 	if from.Number().Cmp(to.Number()) >= 0 {
-		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", end, start)
+		return nil, nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", end, start)
 	}
-	return api.traceChain(ctx, from, to, config)
+	return from, to, nil
+}
+
+// StartTraceJob starts tracing the blocks after start up to end in the background,
+// writing the traces of each block with transactions as a JSON line into a file.
+// Unlike TraceChain, the job outlives the connection and is resumed from its last
+// checkpoint after a restart.
+func (api *PrivateDebugAPI) StartTraceJob(ctx context.Context, start, end rpc.BlockNumber, config *TraceJobConfig) (*TraceJob, error) {
+	from, to, err := api.blockRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	if to.NumberU64() > api.eth.blockchain.CurrentBlock().NumberU64() {
+		return nil, errors.New("trace jobs can't include the pending block")
+	}
+	return api.eth.traceJobs.submit(from, to, config)
+}
+
+// TraceJobStatus retrieves the status and progress of a trace job.
+func (api *PrivateDebugAPI) TraceJobStatus(id string) (*TraceJob, error) {
+	return api.eth.traceJobs.status(id)
+}
+
+// CancelTraceJob stops a running trace job, keeping the traces written so far.
+func (api *PrivateDebugAPI) CancelTraceJob(id string) error {
+	return api.eth.traceJobs.cancel(id)
+}
+
+// ListTraceJobs retrieves all the trace jobs, running or not.
+func (api *PrivateDebugAPI) ListTraceJobs() []*TraceJob {
+	return api.eth.traceJobs.list()
+}
+
+// RemoveTraceJob deletes a trace job which is not running, along with its output.
+func (api *PrivateDebugAPI) RemoveTraceJob(id string) error {
+	return api.eth.traceJobs.remove(id)
 }
 
 // traceChain configures a new tracer according to the provided configuration, and
@@ -146,6 +202,24 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 	}
 	sub := notifier.CreateSubscription()
 
+	// Stream the blocks with transactions to the user, and the last one to signal completion
+	notify := func(result *blockTraceResult) {
+		if len(result.Traces) > 0 || uint64(result.Block) == end.NumberU64() {
+			notifier.Notify(sub.ID, result)
+		}
+	}
+	if err := api.traceBlocks(ctx, start, end, config, notifier.Closed(), notify, nil); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// traceBlocks traces all the blocks between start (exclusive) and end concurrently
+// in the background, delivering the results to the sink in order. Any error
+// preventing the tracing from starting is returned, the outcome of the tracing
+// itself is delivered to the optional done callback, errChainTraceAborted if the
+// closed channel was closed before reaching the end.
+func (api *PrivateDebugAPI) traceBlocks(ctx context.Context, start, end *types.Block, config *TraceConfig, closed <-chan interface{}, sink func(*blockTraceResult), done func(error)) error {
 	// Ensure we have a valid starting state before doing any work
 	origin := start.NumberU64()
 	database := state.NewDatabaseWithConfig(api.eth.ChainDb(), &trie.Config{Cache: 16, Preimages: true})
@@ -153,7 +227,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 	if number := start.NumberU64(); number > 0 {
 		start = api.eth.blockchain.GetBlock(start.ParentHash(), start.NumberU64()-1)
 		if start == nil {
-			return nil, fmt.Errorf("parent block #%d not found", number-1)
+			return fmt.Errorf("parent block #%d not found", number-1)
 		}
 	}
 	statedb, err := state.New(start.Root(), database, nil)
@@ -177,9 +251,9 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 		if err != nil {
 			switch err.(type) {
 			case *trie.MissingNodeError:
-				return nil, errors.New("required historical state unavailable")
+				return errors.New("required historical state unavailable")
 			default:
-				return nil, err
+				return err
 			}
 		}
 	}
@@ -220,7 +294,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 				// Stream the result back to the user or abort on teardown
 				select {
 				case results <- task:
-				case <-notifier.Closed():
+				case <-closed:
 					return
 				}
 			}
@@ -229,12 +303,12 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 	// Start a goroutine to feed all the blocks into the tracers
 	begin := time.Now()
 
+	var failed error // Outcome of the tracing, set before the results are closed
 	go func() {
 		var (
 			logged time.Time
 			number uint64
 			traced uint64
-			failed error
 			proot  common.Hash
 		)
 		// Ensure everything is properly cleaned up on any exit path
@@ -250,13 +324,16 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 			default:
 				log.Info("Chain tracing finished", "start", start.NumberU64(), "end", end.NumberU64(), "transactions", traced, "elapsed", time.Since(begin))
 			}
+			if failed == nil && number <= end.NumberU64() {
+				failed = errChainTraceAborted
+			}
 			close(results)
 		}()
 		// Feed all the blocks both into the tracer, as well as fast process concurrently
 		for number = start.NumberU64() + 1; number <= end.NumberU64(); number++ {
 			// Stop tracing if interruption was requested
 			select {
-			case <-notifier.Closed():
+			case <-closed:
 				return
 			default:
 			}
@@ -282,7 +359,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 
 				select {
 				case tasks <- &blockTraceTask{statedb: statedb.Copy(), block: block, rootref: proot, results: make([]*txTraceResult, len(txs))}:
-				case <-notifier.Closed():
+				case <-closed:
 					return
 				}
 				traced += uint64(len(txs))
@@ -318,11 +395,11 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 		}
 	}()
 
-	// Keep reading the trace results and stream the to the user
+	// Keep reading the trace results and deliver them in order
 	go func() {
 		var (
-			done = make(map[uint64]*blockTraceResult)
-			next = origin + 1
+			pending = make(map[uint64]*blockTraceResult)
+			next    = origin + 1
 		)
 		for res := range results {
 			// Queue up next received result
@@ -331,22 +408,23 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 				Hash:   res.block.Hash(),
 				Traces: res.results,
 			}
-			done[uint64(result.Block)] = result
+			pending[uint64(result.Block)] = result
 
 			// Dereference any paret tries held in memory by this task
 			database.TrieDB().Dereference(res.rootref)
 
-			// Stream completed traces to the user, aborting on the first error
-			for result, ok := done[next]; ok; result, ok = done[next] {
-				if len(result.Traces) > 0 || next == end.NumberU64() {
-					notifier.Notify(sub.ID, result)
-				}
-				delete(done, next)
+			// Deliver the completed traces in order
+			for result, ok := pending[next]; ok; result, ok = pending[next] {
+				sink(result)
+				delete(pending, next)
 				next++
 			}
 		}
+		if done != nil {
+			done(failed)
+		}
 	}()
-	return sub, nil
+	return nil
 }
 
 // TraceBlockByNumber returns the structured logs created during the execution of
@@ -743,7 +821,10 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 // TraceCall lets you trace a given eth_call. It collects the structured logs created during the execution of EVM
 // if the given transaction was added on top of the provided block and returns them as a JSON object.
 // You can provide -2 as a block number to trace on top of the pending block.
-func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ioncapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceConfig) (interface{}, error) {
+//
+// Additionally, the caller can override the fields of any accounts and the
+// context of the block the call is executed in.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ioncapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
 	// First try to retrieve the state
 	statedb, header, err := api.eth.APIBackend.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if err != nil {
@@ -766,19 +847,49 @@ func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ioncapi.CallArgs
 		if err != nil {
 			return nil, err
 		}
+		header = block.Header()
 	}
+	// Apply the customized state and block rules, if any
+	var (
+		traceConfig    *TraceConfig
+		blockOverrides *ioncapi.BlockOverrides
+	)
+	if config != nil {
+		if config.StateOverrides != nil {
+			if err := config.StateOverrides.Apply(statedb); err != nil {
+				return nil, err
+			}
+		}
+		blockOverrides, traceConfig = config.BlockOverrides, &config.TraceConfig
+	}
+	// The call runs in a block derived from the requested one, if its number is
+	// overridden the rules and the ancestors follow the overridden number
+	simHeader := blockOverrides.MakeHeader(header)
 
+	author, _ := api.eth.blockchain.Engine().Author(header) // Sealed by the original header only
+	vmctx := core.NewEVMBlockContext(simHeader, api.eth.blockchain, &author)
+	blockOverrides.Apply(&vmctx)
+	if simHeader.Number.Cmp(header.Number) != 0 {
+		ancestors := ioncapi.NewBlockAncestors(ctx, api.eth.APIBackend, header)
+		vmctx.GetHash, vmctx.GetGenerationSignature = ancestors.GetHash, ancestors.GetGenerationSignature
+	}
 	// Execute the trace
 	msg := args.ToMessage(api.eth.APIBackend.RPCGasCap())
-	vmctx := core.NewEVMBlockContext(header, api.eth.blockchain, nil)
-	return api.traceTx(ctx, msg, vmctx, statedb, config)
+	if chainConfig := api.eth.blockchain.Config(); chainConfig.IsYoloV2(simHeader.Number) {
+		var list types.AccessList
+		if args.AccessList != nil {
+			list = *args.AccessList
+		}
+		statedb.PrepareAccessList(msg.From(), msg.To(), vm.ActivePrecompiles(chainConfig.Rules(simHeader.Number)), list)
+	}
+	return api.traceTx(ctx, msg, vmctx, statedb, traceConfig)
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
-	// Assemble the structured logger or the JavaScript tracer
+	// Assemble the structured logger or the JavaScript or native tracer
 	var (
 		tracer    vm.Tracer
 		err       error
@@ -793,15 +904,25 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
+		// Constuct the JavaScript or native tracer to execute with
+		if !api.eth.config.RPCTracerCustom && !tracers.IsBuiltin(*config.Tracer) {
+			return nil, tracers.ErrCustomTracerDisabled
+		}
+		if tracer, err = tracers.NewTracer(*config.Tracer, statedb, config.TracerConfig); err != nil {
 			return nil, err
 		}
+		if jst, ok := tracer.(*tracers.Tracer); ok {
+			jst.SetLimits(tracers.Limits{
+				Steps:  api.eth.config.RPCTracerSteps,
+				Memory: api.eth.config.RPCTracerMemory,
+				Output: api.eth.config.RPCTracerOutput,
+			})
+		}
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			tracer.(tracers.ResultTracer).Stop(tracers.TimeoutError(uint64(timeout / time.Millisecond)))
 		}()
 		defer cancel()
 
@@ -833,7 +954,7 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 			StructLogs:  ioncapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *tracers.Tracer:
+	case tracers.ResultTracer:
 		return tracer.GetResult()
 
 	default:
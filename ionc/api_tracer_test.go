@@ -0,0 +1,102 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/internal/ioncapi"
+	"github.com/ionchain/ionchain-core/rpc"
+)
+
+// Tests that calls traced with block overrides run in the overridden block, with
+// the ancestors following its number.
+func TestTraceCallBlockOverrides(t *testing.T) {
+	eth := newTraceTestChain(t, 6)
+	defer eth.blockchain.Stop()
+
+	var (
+		api   = NewPrivateDebugAPI(eth)
+		probe = common.HexToAddress("0x9b")
+		head  = eth.blockchain.CurrentBlock()
+	)
+	// Return NUMBER, TIMESTAMP, COINBASE, BLOCKHASH(7), BLOCKHASH(6), BLOCKHASH(5)
+	code := hexutil.Bytes(common.FromHex("0x43600052426020524160405260074060605260064060805260054060a05260c06000f3"))
+	state := ioncapi.StateOverride{probe: {Code: &code}}
+
+	var (
+		number   = hexutil.Big(*big.NewInt(8))
+		time     = hexutil.Uint64(1234567)
+		coinbase = common.HexToAddress("0xcb")
+	)
+	tests := []struct {
+		overrides *ioncapi.BlockOverrides
+		want      []common.Hash
+	}{
+		// Without overrides the call runs in the requested block
+		{
+			nil,
+			[]common.Hash{
+				common.BigToHash(head.Number()), common.BigToHash(new(big.Int).SetUint64(head.Time())), common.BytesToHash(head.Coinbase().Bytes()),
+				{}, {}, eth.blockchain.GetBlockByNumber(5).Hash(),
+			},
+		},
+		// Overriding the number exposes the requested block as an ancestor, but
+		// nothing in between
+		{
+			&ioncapi.BlockOverrides{Number: &number, Time: &time, Coinbase: &coinbase},
+			[]common.Hash{
+				common.BigToHash(big.NewInt(8)), common.BigToHash(big.NewInt(1234567)), common.BytesToHash(coinbase.Bytes()),
+				{}, head.Hash(), eth.blockchain.GetBlockByNumber(5).Hash(),
+			},
+		},
+		// Overriding other fields only keeps the ancestors of the requested block
+		{
+			&ioncapi.BlockOverrides{Coinbase: &coinbase},
+			[]common.Hash{
+				common.BigToHash(head.Number()), common.BigToHash(new(big.Int).SetUint64(head.Time())), common.BytesToHash(coinbase.Bytes()),
+				{}, {}, eth.blockchain.GetBlockByNumber(5).Hash(),
+			},
+		},
+	}
+	for i, tt := range tests {
+		result, err := api.TraceCall(context.Background(), ioncapi.CallArgs{To: &probe}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &TraceCallConfig{
+			StateOverrides: &state,
+			BlockOverrides: tt.overrides,
+		})
+		if err != nil {
+			t.Fatalf("test %d: failed to trace call: %v", i, err)
+		}
+		res := result.(*ioncapi.ExecutionResult)
+		if res.Failed {
+			t.Fatalf("test %d: call failed", i)
+		}
+		output := common.FromHex(res.ReturnValue)
+		if len(output) != 32*len(tt.want) {
+			t.Fatalf("test %d: output length mismatch: have %d, want %d", i, len(output), 32*len(tt.want))
+		}
+		for j, want := range tt.want {
+			if have := common.BytesToHash(output[32*j : 32*(j+1)]); have != want {
+				t.Errorf("test %d, word %d: mismatch: have %x, want %x", i, j, have, want)
+			}
+		}
+	}
+}
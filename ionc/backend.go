@@ -71,8 +71,12 @@ type IonChain struct {
 
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
+	logIndexer        *core.ChainIndexer             // Log indexer of addresses and topics, if enabled
 	closeBloomHandler chan struct{}
 
+	callTraceIndexer *callTraceIndexer // Call trace indexer following the canonical chain, if enabled
+	traceJobs        *traceJobManager  // Chain tracing jobs running in the background
+
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
@@ -169,12 +173,20 @@ func New(stack *node.Node, config *Config) (*IonChain, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			StateDiffs:          config.StateDiffs,
+			StateDiffLimit:      config.StateDiffLimit,
+			ParallelExec:        config.ParallelExec,
+			Witnesses:           config.Witnesses,
 		}
 	)
 	ionc.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, ionc.engine, vmConfig, ionc.shouldPreserve, &config.TxLookupLimit)
 	if err != nil {
 		return nil, err
 	}
+	ionc.blockchain.SetMaxReorgDepth(config.MaxReorgDepth)
+	for _, checkpoint := range config.ReorgCheckpoints {
+		ionc.blockchain.AddCheckpoint(checkpoint.Number, checkpoint.Hash)
+	}
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -182,6 +194,18 @@ func New(stack *node.Node, config *Config) (*IonChain, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	ionc.bloomIndexer.Start(ionc.blockchain)
+	if config.LogIndex {
+		ionc.logIndexer = NewLogIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms)
+		ionc.logIndexer.Start(logIndexChain{ionc.blockchain})
+	}
+	if config.CallTraceIndex {
+		ionc.callTraceIndexer = newCallTraceIndexer(ionc.blockchain, chainDb)
+	}
+	traceJobDir := config.TraceJobDir
+	if traceJobDir == "" {
+		traceJobDir = "tracejobs"
+	}
+	ionc.traceJobs = newTraceJobManager(ionc, chainDb, stack.ResolvePath(traceJobDir))
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
@@ -220,6 +244,7 @@ func New(stack *node.Node, config *Config) (*IonChain, error) {
 	stack.RegisterAPIs(ionc.APIs())
 	stack.RegisterProtocols(ionc.Protocols())
 	stack.RegisterLifecycle(ionc)
+	ionc.registerHealthChecks(stack)
 	return ionc, nil
 }
 
@@ -278,7 +303,7 @@ func (s *IonChain) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
+			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, s.config.RPCLogsRange, s.config.RPCLogsBackfill),
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -293,6 +318,10 @@ func (s *IonChain) APIs() []rpc.API {
 			Namespace: "debug",
 			Version:   "1.0",
 			Service:   NewPrivateDebugAPI(s),
+		}, {
+			Namespace: "trace",
+			Version:   "1.0",
+			Service:   NewPrivateTraceAPI(s),
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -487,6 +516,13 @@ func (s *IonChain) Start() error {
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers(params.BloomBitsBlocks)
 
+	// Start indexing the call traces if requested
+	if s.callTraceIndexer != nil {
+		s.callTraceIndexer.start()
+	}
+	// Resume the trace jobs interrupted by the last shutdown
+	s.traceJobs.start()
+
 	// Figure out a max peers count based on the server limits
 	maxPeers := s.p2pServer.MaxPeers
 	if s.config.LightServ > 0 {
@@ -507,8 +543,15 @@ func (s *IonChain) Stop() error {
 	s.protocolManager.Stop()
 
 	// Then stop everything else.
+	s.traceJobs.stop()
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
+	if s.logIndexer != nil {
+		s.logIndexer.Close()
+	}
+	if s.callTraceIndexer != nil {
+		s.callTraceIndexer.stop()
+	}
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.blockchain.Stop()
@@ -0,0 +1,331 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/ionc/tracers"
+	"github.com/ionchain/ionchain-core/params"
+)
+
+// callTrace is a single call of a transaction in flattened form, as stored by
+// the call trace indexer. Internal value transfers are calls too.
+type callTrace struct {
+	Type         string         // Trace type: call, create or suicide
+	CallType     string         // Call opcode for calls: call, callcode, delegatecall or staticcall
+	From         common.Address // Caller, creator or self destructed contract
+	To           common.Address // Callee, created contract or refund address
+	Value        *big.Int       // Value transferred or balance refunded
+	Gas          uint64         // Gas allowance of the call
+	GasUsed      uint64         // Gas used by the call
+	Input        []byte         // Call data or contract init code
+	Output       []byte         // Return data or created contract code
+	Error        string         // Failure reason, if the call failed
+	Subtraces    uint64         // Number of direct inner calls
+	TraceAddress []uint64       // Position of the call in the call tree
+	TxIndex      uint64         // Index of the transaction within its block
+}
+
+// callTracerFrame is a call as reported by the call tracer.
+type callTracerFrame struct {
+	Type    string             `json:"type"`
+	From    common.Address     `json:"from"`
+	To      *common.Address    `json:"to"`
+	Value   *hexutil.Big       `json:"value"`
+	Gas     hexutil.Uint64     `json:"gas"`
+	GasUsed hexutil.Uint64     `json:"gasUsed"`
+	Input   hexutil.Bytes      `json:"input"`
+	Output  hexutil.Bytes      `json:"output"`
+	Error   string             `json:"error"`
+	Calls   []*callTracerFrame `json:"calls"`
+}
+
+// flattenCallFrame appends the given call and all its inner calls in depth first
+// order to the flat trace list.
+func flattenCallFrame(frame *callTracerFrame, txIndex uint64, address []uint64, traces []*callTrace) []*callTrace {
+	trace := &callTrace{
+		From:         frame.From,
+		Value:        new(big.Int),
+		Gas:          uint64(frame.Gas),
+		GasUsed:      uint64(frame.GasUsed),
+		Input:        frame.Input,
+		Output:       frame.Output,
+		Error:        frame.Error,
+		Subtraces:    uint64(len(frame.Calls)),
+		TraceAddress: address,
+		TxIndex:      txIndex,
+	}
+	if frame.To != nil {
+		trace.To = *frame.To
+	}
+	if frame.Value != nil {
+		trace.Value = frame.Value.ToInt()
+	}
+	switch frame.Type {
+	case "CREATE", "CREATE2":
+		trace.Type = "create"
+	case "SELFDESTRUCT":
+		trace.Type = "suicide"
+	default:
+		trace.Type, trace.CallType = "call", strings.ToLower(frame.Type)
+	}
+	traces = append(traces, trace)
+	for i, call := range frame.Calls {
+		child := make([]uint64, len(address)+1)
+		copy(child, address)
+		child[len(address)] = uint64(i)
+		traces = flattenCallFrame(call, txIndex, child, traces)
+	}
+	return traces
+}
+
+// traceMessageCalls executes a message on top of the given state with the call
+// tracer, returning the execution result and the flattened call traces.
+func traceMessageCalls(config *params.ChainConfig, vmctx vm.BlockContext, msg core.Message, statedb *state.StateDB, txIndex uint64) (*core.ExecutionResult, []*callTrace, error) {
+	tracer, err := tracers.NewTracer("callTracer", statedb, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(msg), statedb, config, vm.Config{Debug: true, Tracer: tracer})
+	result, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	blob, err := tracer.GetResult()
+	if err != nil {
+		return nil, nil, err
+	}
+	frame := new(callTracerFrame)
+	if err := json.Unmarshal(blob, frame); err != nil {
+		return nil, nil, err
+	}
+	return result, flattenCallFrame(frame, txIndex, []uint64{}, nil), nil
+}
+
+// traceBlockCalls executes all the transactions of a block on top of the state
+// of its parent, returning their flattened call traces. The state is left at the
+// end of the transaction executions, without any block finalization applied.
+func traceBlockCalls(chain *core.BlockChain, block *types.Block, statedb *state.StateDB) ([]*callTrace, error) {
+	var (
+		config = chain.Config()
+		signer = types.MakeSigner(config, block.Number())
+		vmctx  = core.NewEVMBlockContext(block.Header(), chain, nil)
+		traces = []*callTrace{}
+	)
+	for i, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, err
+		}
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		_, txTraces, err := traceMessageCalls(config, vmctx, msg, statedb, uint64(i))
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, txTraces...)
+
+		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+		statedb.Finalise(config.IsEIP158(block.Number()))
+	}
+	return traces, nil
+}
+
+// Parity style call trace formats
+
+type parityCallAction struct {
+	CallType string         `json:"callType"`
+	From     common.Address `json:"from"`
+	Gas      hexutil.Uint64 `json:"gas"`
+	Input    hexutil.Bytes  `json:"input"`
+	To       common.Address `json:"to"`
+	Value    *hexutil.Big   `json:"value"`
+}
+
+type parityCreateAction struct {
+	From  common.Address `json:"from"`
+	Gas   hexutil.Uint64 `json:"gas"`
+	Init  hexutil.Bytes  `json:"init"`
+	Value *hexutil.Big   `json:"value"`
+}
+
+type paritySuicideAction struct {
+	Address       common.Address `json:"address"`
+	Balance       *hexutil.Big   `json:"balance"`
+	RefundAddress common.Address `json:"refundAddress"`
+}
+
+type parityCallResult struct {
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Output  hexutil.Bytes  `json:"output"`
+}
+
+type parityCreateResult struct {
+	Address common.Address `json:"address"`
+	Code    hexutil.Bytes  `json:"code"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+}
+
+// parityTrace is a flat call trace in the format of the Parity trace module.
+type parityTrace struct {
+	Action              interface{}  `json:"action"`
+	BlockHash           common.Hash  `json:"blockHash"`
+	BlockNumber         uint64       `json:"blockNumber"`
+	Error               string       `json:"error,omitempty"`
+	Result              interface{}  `json:"result"`
+	Subtraces           uint64       `json:"subtraces"`
+	TraceAddress        []uint64     `json:"traceAddress"`
+	TransactionHash     *common.Hash `json:"transactionHash"`
+	TransactionPosition *uint64      `json:"transactionPosition"`
+	Type                string       `json:"type"`
+}
+
+// newParityTrace converts a flat call trace of a block into the Parity format.
+func newParityTrace(trace *callTrace, block *types.Block) *parityTrace {
+	result := &parityTrace{
+		BlockHash:    block.Hash(),
+		BlockNumber:  block.NumberU64(),
+		Error:        trace.Error,
+		Subtraces:    trace.Subtraces,
+		TraceAddress: trace.TraceAddress,
+		Type:         trace.Type,
+	}
+	if txs := block.Transactions(); trace.TxIndex < uint64(len(txs)) {
+		hash, index := txs[trace.TxIndex].Hash(), trace.TxIndex
+		result.TransactionHash, result.TransactionPosition = &hash, &index
+	}
+	value := (*hexutil.Big)(trace.Value)
+	switch trace.Type {
+	case "create":
+		result.Action = &parityCreateAction{From: trace.From, Gas: hexutil.Uint64(trace.Gas), Init: trace.Input, Value: value}
+		if trace.Error == "" {
+			result.Result = &parityCreateResult{Address: trace.To, Code: trace.Output, GasUsed: hexutil.Uint64(trace.GasUsed)}
+		}
+	case "suicide":
+		result.Action = &paritySuicideAction{Address: trace.From, Balance: value, RefundAddress: trace.To}
+	default:
+		result.Action = &parityCallAction{CallType: trace.CallType, From: trace.From, Gas: hexutil.Uint64(trace.Gas), Input: trace.Input, To: trace.To, Value: value}
+		if trace.Error == "" {
+			result.Result = &parityCallResult{GasUsed: hexutil.Uint64(trace.GasUsed), Output: trace.Output}
+		}
+	}
+	return result
+}
+
+// parityAccountDiff is the change of an account made by a transaction in the
+// format of the Parity trace module. Every field is either "=" if unchanged, or
+// an object keyed by "+" if created, "-" if deleted, or "*" if modified.
+type parityAccountDiff struct {
+	Balance interface{}                 `json:"balance"`
+	Nonce   interface{}                 `json:"nonce"`
+	Code    interface{}                 `json:"code"`
+	Storage map[common.Hash]interface{} `json:"storage"`
+}
+
+// prestateDiffAccount is an account as reported by the prestate tracer in diff mode.
+type prestateDiffAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// parityFieldDiff formats the change of a single field.
+func parityFieldDiff(pre, post interface{}, existed, exists, changed bool) interface{} {
+	switch {
+	case !existed:
+		return map[string]interface{}{"+": post}
+	case !exists:
+		return map[string]interface{}{"-": pre}
+	case changed:
+		return map[string]interface{}{"*": map[string]interface{}{"from": pre, "to": post}}
+	}
+	return "="
+}
+
+// newParityStateDiff converts the result of the prestate tracer in diff mode into
+// the Parity state diff format.
+func newParityStateDiff(blob json.RawMessage) (map[common.Address]*parityAccountDiff, error) {
+	var diff struct {
+		Pre  map[common.Address]*prestateDiffAccount `json:"pre"`
+		Post map[common.Address]*prestateDiffAccount `json:"post"`
+	}
+	if err := json.Unmarshal(blob, &diff); err != nil {
+		return nil, err
+	}
+	accounts := make(map[common.Address]struct{})
+	for addr := range diff.Pre {
+		accounts[addr] = struct{}{}
+	}
+	for addr := range diff.Post {
+		accounts[addr] = struct{}{}
+	}
+	result := make(map[common.Address]*parityAccountDiff)
+	for addr := range accounts {
+		pre, existed := diff.Pre[addr]
+		post, exists := diff.Post[addr]
+		if !existed {
+			pre = &prestateDiffAccount{Balance: new(hexutil.Big), Code: hexutil.Bytes{}}
+		}
+		if pre.Balance == nil {
+			pre.Balance = new(hexutil.Big)
+		}
+		if pre.Code == nil {
+			pre.Code = hexutil.Bytes{}
+		}
+		if !exists {
+			post = new(prestateDiffAccount)
+		}
+		// The post state only holds the modified fields, fill in the rest
+		balance, nonce, code := post.Balance, post.Nonce, post.Code
+		if balance == nil {
+			balance = pre.Balance
+		}
+		if nonce == 0 {
+			nonce = pre.Nonce
+		}
+		if code == nil {
+			code = pre.Code
+		}
+		account := &parityAccountDiff{
+			Balance: parityFieldDiff(pre.Balance, balance, existed, exists, balance.ToInt().Cmp(pre.Balance.ToInt()) != 0),
+			Nonce:   parityFieldDiff(hexutil.Uint64(pre.Nonce), hexutil.Uint64(nonce), existed, exists, nonce != pre.Nonce),
+			Code:    parityFieldDiff(pre.Code, code, existed, exists, string(code) != string(pre.Code)),
+			Storage: make(map[common.Hash]interface{}),
+		}
+		for key, val := range post.Storage {
+			prev, ok := pre.Storage[key]
+			account.Storage[key] = parityFieldDiff(prev, val, existed && ok, true, prev != val)
+		}
+		if !exists {
+			for key, val := range pre.Storage {
+				account.Storage[key] = parityFieldDiff(val, nil, true, false, true)
+			}
+		}
+		result[addr] = account
+	}
+	return result, nil
+}
@@ -0,0 +1,295 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/consensus"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/ioncdb"
+	"github.com/ionchain/ionchain-core/log"
+	"github.com/ionchain/ionchain-core/rlp"
+	"github.com/ionchain/ionchain-core/trie"
+)
+
+// errCallTracesNotIndexed is returned if the call traces of a block are requested
+// from the index, but they were not indexed yet.
+var errCallTracesNotIndexed = errors.New("call traces not indexed")
+
+// callTraceIndexer is a background process following the canonical chain and
+// storing the flattened call traces of every block, along with an index of the
+// blocks each address was involved in.
+//
+// Blocks are traced by re-executing them on top of their parent state. While
+// catching up with the chain, the state is carried over from block to block in
+// memory, since historical states are usually pruned away.
+type callTraceIndexer struct {
+	chain *core.BlockChain
+	db    ioncdb.Database
+
+	database state.Database  // Private state database used while catching up
+	statedb  *state.StateDB  // Post state of the last indexed block, if carried over
+	head     *types.Header   // Last indexed block
+	proot    common.Hash     // Root of the carried over state referenced in the private database
+	quit     chan chan error // Termination channel to stop the indexer
+}
+
+// newCallTraceIndexer creates a call trace indexer for the given chain.
+func newCallTraceIndexer(chain *core.BlockChain, db ioncdb.Database) *callTraceIndexer {
+	return &callTraceIndexer{
+		chain:    chain,
+		db:       db,
+		database: state.NewDatabaseWithConfig(db, &trie.Config{Cache: 16}),
+		quit:     make(chan chan error),
+	}
+}
+
+// start launches the indexing goroutine.
+func (idx *callTraceIndexer) start() {
+	go idx.loop()
+}
+
+// stop terminates the indexing goroutine, waiting for any block being indexed.
+func (idx *callTraceIndexer) stop() error {
+	errc := make(chan error)
+	idx.quit <- errc
+	return <-errc
+}
+
+// loop indexes the canonical chain whenever a new head arrives.
+func (idx *callTraceIndexer) loop() {
+	heads := make(chan core.ChainHeadEvent, 10)
+	sub := idx.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	// Resume from the last indexed block if it's still available
+	if hash := rawdb.ReadCallTraceIndexHead(idx.db); hash != (common.Hash{}) {
+		idx.head = idx.chain.GetHeaderByHash(hash)
+	}
+	if idx.head == nil {
+		idx.head = idx.chain.Genesis().Header()
+	}
+	var (
+		interrupt = make(chan struct{})
+		done      = make(chan struct{})
+		running   bool
+	)
+	run := func() {
+		running = true
+		go func() {
+			idx.index(interrupt)
+			done <- struct{}{}
+		}()
+	}
+	run()
+	for {
+		select {
+		case <-heads:
+			// Index the new blocks, unless still busy with the previous ones
+			if !running {
+				run()
+			}
+		case <-done:
+			running = false
+
+		case errc := <-idx.quit:
+			close(interrupt)
+			if running {
+				<-done
+			}
+			errc <- nil
+			return
+		}
+	}
+}
+
+// index processes all the canonical blocks above the last indexed one, until
+// reaching the chain head or being interrupted.
+func (idx *callTraceIndexer) index(interrupt chan struct{}) {
+	var (
+		start   = time.Now()
+		logged  = time.Now()
+		indexed int
+	)
+	for {
+		select {
+		case <-interrupt:
+			return
+		default:
+		}
+		next, err := idx.next()
+		if err != nil {
+			log.Error("Failed to rewind call trace index", "err", err)
+			return
+		}
+		if next == nil {
+			break
+		}
+		if err := idx.process(next); err != nil {
+			log.Error("Failed to index call traces", "number", next.NumberU64(), "hash", next.Hash(), "err", err)
+			return
+		}
+		indexed++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing call traces", "number", next.NumberU64(), "blocks", indexed, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if indexed > 1 {
+		log.Info("Indexed call traces", "number", idx.head.Number, "blocks", indexed, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
+
+// next returns the next canonical block to index, or nil if the index is up to
+// date. If the last indexed block was reorged out, the index is rewound to the
+// common ancestor, discarding the traces and address marks of the stale blocks.
+func (idx *callTraceIndexer) next() (*types.Block, error) {
+	for rawdb.ReadCanonicalHash(idx.db, idx.head.Number.Uint64()) != idx.head.Hash() {
+		number := idx.head.Number.Uint64()
+		parent := idx.chain.GetHeader(idx.head.ParentHash, number-1)
+		if parent == nil {
+			return nil, fmt.Errorf("missing parent of #%d [%x..]", idx.head.Number, idx.head.Hash().Bytes()[:4])
+		}
+		batch := idx.db.NewBatch()
+		if blob := rawdb.ReadCallTraces(idx.db, idx.head.Hash(), number); len(blob) > 0 {
+			var traces []*callTrace
+			if err := rlp.DecodeBytes(blob, &traces); err != nil {
+				return nil, err
+			}
+			for _, trace := range traces {
+				rawdb.DeleteCallTraceAddress(batch, trace.From, number)
+				rawdb.DeleteCallTraceAddress(batch, trace.To, number)
+			}
+		}
+		rawdb.DeleteCallTraces(batch, idx.head.Hash(), number)
+		rawdb.WriteCallTraceIndexHead(batch, parent.Hash())
+		if err := batch.Write(); err != nil {
+			return nil, err
+		}
+		idx.head = parent
+		idx.release()
+	}
+	if idx.head.Number.Uint64() >= idx.chain.CurrentBlock().NumberU64() {
+		return nil, nil
+	}
+	return idx.chain.GetBlockByNumber(idx.head.Number.Uint64() + 1), nil
+}
+
+// process traces a single block and stores its flattened call traces.
+func (idx *callTraceIndexer) process(block *types.Block) error {
+	if block == nil {
+		return errors.New("block not found")
+	}
+	parent := idx.chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	// Use the chain's own state if still available, or carry over our own
+	statedb, err := idx.chain.StateAt(parent.Root)
+	carried := err != nil
+	if carried {
+		if idx.statedb == nil || idx.proot != parent.Root {
+			idx.release()
+			if idx.statedb, err = state.New(parent.Root, idx.database, nil); err != nil {
+				return fmt.Errorf("required historical state unavailable: %v", err)
+			}
+		}
+		statedb = idx.statedb
+	}
+	traces, err := traceBlockCalls(idx.chain, block, statedb)
+	if err != nil {
+		idx.release()
+		return err
+	}
+	if carried {
+		if err := idx.carry(block, statedb); err != nil {
+			idx.release()
+			return err
+		}
+	} else {
+		idx.release()
+	}
+	blob, err := rlp.EncodeToBytes(traces)
+	if err != nil {
+		return err
+	}
+	batch := idx.db.NewBatch()
+	rawdb.WriteCallTraces(batch, block.Hash(), block.NumberU64(), blob)
+	for _, trace := range traces {
+		rawdb.WriteCallTraceAddress(batch, trace.From, block.NumberU64())
+		rawdb.WriteCallTraceAddress(batch, trace.To, block.NumberU64())
+	}
+	rawdb.WriteCallTraceIndexHead(batch, block.Hash())
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	idx.head = block.Header()
+	return nil
+}
+
+// carry finalizes the block on top of the carried over state and commits it into
+// the private state database, so it can serve as the parent state of the next
+// block.
+func (idx *callTraceIndexer) carry(block *types.Block, statedb *state.StateDB) error {
+	header := block.Header()
+	idx.chain.Engine().Finalize(idx.chain, header, statedb, block.Transactions(), block.Uncles())
+
+	root, err := statedb.Commit(idx.chain.Config().IsEIP158(block.Number()))
+	if err != nil {
+		return err
+	}
+	if root != block.Root() {
+		return fmt.Errorf("state root mismatch: have %x, want %x", root, block.Root())
+	}
+	if err := statedb.Reset(root); err != nil {
+		return err
+	}
+	idx.database.TrieDB().Reference(root, common.Hash{})
+	if idx.proot != (common.Hash{}) {
+		idx.database.TrieDB().Dereference(idx.proot)
+	}
+	idx.statedb, idx.proot = statedb, root
+	return nil
+}
+
+// release drops any carried over state, once the chain's own states suffice.
+func (idx *callTraceIndexer) release() {
+	if idx.proot != (common.Hash{}) {
+		idx.database.TrieDB().Dereference(idx.proot)
+	}
+	idx.statedb, idx.proot = nil, common.Hash{}
+}
+
+// readCallTraces retrieves the indexed call traces of a block.
+func readCallTraces(db ioncdb.KeyValueReader, block *types.Block) ([]*callTrace, error) {
+	blob := rawdb.ReadCallTraces(db, block.Hash(), block.NumberU64())
+	if len(blob) == 0 {
+		return nil, errCallTracesNotIndexed
+	}
+	var traces []*callTrace
+	if err := rlp.DecodeBytes(blob, &traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
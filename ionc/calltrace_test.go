@@ -0,0 +1,156 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+)
+
+// Tests that nested calls are flattened depth first, with their positions in
+// the call tree and their value transfers.
+func TestFlattenCallFrame(t *testing.T) {
+	frame := new(callTracerFrame)
+	err := json.Unmarshal([]byte(`{
+		"type": "CALL", "from": "0x00000000000000000000000000000000000000aa", "to": "0x00000000000000000000000000000000000000bb", "value": "0x10",
+		"calls": [
+			{"type": "DELEGATECALL", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000cc",
+				"calls": [{"type": "CREATE2", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000dd", "value": "0x1"}]},
+			{"type": "STATICCALL", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000cc", "error": "out of gas"},
+			{"type": "SELFDESTRUCT", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000aa", "value": "0x5"}
+		]
+	}`), frame)
+	if err != nil {
+		t.Fatalf("failed to decode frame: %v", err)
+	}
+	traces := flattenCallFrame(frame, 3, []uint64{}, nil)
+
+	want := []struct {
+		kind, callType string
+		from, to       byte
+		value          int64
+		subtraces      uint64
+		address        []uint64
+		err            string
+	}{
+		{"call", "call", 0xaa, 0xbb, 0x10, 3, []uint64{}, ""},
+		{"call", "delegatecall", 0xbb, 0xcc, 0, 1, []uint64{0}, ""},
+		{"create", "", 0xbb, 0xdd, 1, 0, []uint64{0, 0}, ""},
+		{"call", "staticcall", 0xbb, 0xcc, 0, 0, []uint64{1}, "out of gas"},
+		{"suicide", "", 0xbb, 0xaa, 5, 0, []uint64{2}, ""},
+	}
+	if len(traces) != len(want) {
+		t.Fatalf("trace count mismatch: have %d, want %d", len(traces), len(want))
+	}
+	for i, trace := range traces {
+		w := want[i]
+		if trace.Type != w.kind || trace.CallType != w.callType {
+			t.Errorf("trace %d: type mismatch: have %s/%s, want %s/%s", i, trace.Type, trace.CallType, w.kind, w.callType)
+		}
+		if trace.From != common.BytesToAddress([]byte{w.from}) || trace.To != common.BytesToAddress([]byte{w.to}) {
+			t.Errorf("trace %d: addresses mismatch: have %x->%x, want %x->%x", i, trace.From, trace.To, w.from, w.to)
+		}
+		if trace.Value.Int64() != w.value {
+			t.Errorf("trace %d: value mismatch: have %v, want %d", i, trace.Value, w.value)
+		}
+		if trace.Subtraces != w.subtraces || !reflect.DeepEqual(trace.TraceAddress, w.address) {
+			t.Errorf("trace %d: position mismatch: have %d subtraces at %v, want %d at %v", i, trace.Subtraces, trace.TraceAddress, w.subtraces, w.address)
+		}
+		if trace.Error != w.err || trace.TxIndex != 3 {
+			t.Errorf("trace %d: error or tx index mismatch: have %q in tx %d, want %q in tx 3", i, trace.Error, trace.TxIndex, w.err)
+		}
+	}
+}
+
+// Tests that the prestate tracer's diff output is converted into Parity state
+// diffs, filling in the fields the post state leaves out.
+func TestParityStateDiff(t *testing.T) {
+	diff, err := newParityStateDiff(json.RawMessage(`{
+		"pre": {
+			"0x00000000000000000000000000000000000000aa": {"balance": "0x10", "nonce": 1},
+			"0x00000000000000000000000000000000000000bb": {"balance": "0x5", "code": "0x6000", "storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000001",
+				"0x0000000000000000000000000000000000000000000000000000000000000002": "0x0000000000000000000000000000000000000000000000000000000000000002"
+			}},
+			"0x00000000000000000000000000000000000000dd": {"balance": "0x7", "storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000003"
+			}}
+		},
+		"post": {
+			"0x00000000000000000000000000000000000000aa": {"balance": "0x8", "nonce": 2},
+			"0x00000000000000000000000000000000000000bb": {"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000004",
+				"0x0000000000000000000000000000000000000000000000000000000000000003": "0x0000000000000000000000000000000000000000000000000000000000000005"
+			}},
+			"0x00000000000000000000000000000000000000cc": {"balance": "0x3", "code": "0x6001"}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("failed to convert state diff: %v", err)
+	}
+	blob, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatalf("failed to encode state diff: %v", err)
+	}
+	var have map[string]interface{}
+	if err := json.Unmarshal(blob, &have); err != nil {
+		t.Fatal(err)
+	}
+	var want map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"0x00000000000000000000000000000000000000aa": {
+			"balance": {"*": {"from": "0x10", "to": "0x8"}},
+			"nonce": {"*": {"from": "0x1", "to": "0x2"}},
+			"code": "=",
+			"storage": {}
+		},
+		"0x00000000000000000000000000000000000000bb": {
+			"balance": "=",
+			"nonce": "=",
+			"code": "=",
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": {"*": {
+					"from": "0x0000000000000000000000000000000000000000000000000000000000000001",
+					"to": "0x0000000000000000000000000000000000000000000000000000000000000004"
+				}},
+				"0x0000000000000000000000000000000000000000000000000000000000000003": {"+": "0x0000000000000000000000000000000000000000000000000000000000000005"}
+			}
+		},
+		"0x00000000000000000000000000000000000000cc": {
+			"balance": {"+": "0x3"},
+			"nonce": {"+": "0x0"},
+			"code": {"+": "0x6001"},
+			"storage": {}
+		},
+		"0x00000000000000000000000000000000000000dd": {
+			"balance": {"-": "0x7"},
+			"nonce": {"-": "0x0"},
+			"code": {"-": "0x"},
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": {"-": "0x0000000000000000000000000000000000000000000000000000000000000003"}
+			}
+		}
+	}`), &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("state diff mismatch:\nhave %s\nwant %v", blob, want)
+	}
+}
@@ -68,6 +68,11 @@ var DefaultConfig = Config{
 	RPCGasCap:   25000000,
 	GPO:         DefaultFullGPOConfig,
 	RPCTxFeeCap: 1, // 1 ether
+
+	RPCTracerSteps:  10000000,
+	RPCTracerMemory: 64 * 1024 * 1024,
+	RPCTracerOutput: 16 * 1024 * 1024,
+	RPCLogsBackfill: 10000,
 }
 
 func init() {
@@ -94,14 +99,26 @@ type Config struct {
 	// for nodes to connect to.
 	DiscoveryURLs []string
 
-	NoPruning  bool // Whether to disable pruning and flush everything to disk
-	NoPrefetch bool // Whether to disable prefetching and only load state on demand
+	NoPruning      bool   // Whether to disable pruning and flush everything to disk
+	NoPrefetch     bool   // Whether to disable prefetching and only load state on demand
+	StateDiffs     bool   // Whether to record reverse state diffs to serve pruned historical state
+	StateDiffLimit uint64 // Maximum number of blocks to serve pruned historical state for (0 = default)
+
+	ParallelExec bool // Whether to execute block transactions optimistically in parallel
+	Witnesses    bool // Whether to record stateless witnesses of the imported blocks
+
+	CallTraceIndex bool // Whether to index the flattened call traces of the canonical chain
+	LogIndex       bool // Whether to index the logs of the canonical chain by address and topic
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
+	// Reorg protection options
+	MaxReorgDepth    uint64            `toml:",omitempty"` // Maximum number of canonical blocks a reorg may drop (0 = unlimited)
+	ReorgCheckpoints []core.Checkpoint `toml:",omitempty"` // Blocks no reorg may replace or drop
+
 	// Light client options
 	LightServ    int  `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightIngress int  `toml:",omitempty"` // Incoming bandwidth limit for light servers
@@ -156,6 +173,28 @@ type Config struct {
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64 `toml:",omitempty"`
 
+	// RPCTracerCustom allows tracing with JavaScript code submitted over RPC,
+	// rather than only the tracers built into the node.
+	RPCTracerCustom bool `toml:",omitempty"`
+
+	// Resource limits of every JavaScript trace served over RPC (0 = no limit):
+	// the number of steps, and the size of the tracer state and result in bytes.
+	RPCTracerSteps  uint64 `toml:",omitempty"`
+	RPCTracerMemory uint64 `toml:",omitempty"`
+	RPCTracerOutput uint64 `toml:",omitempty"`
+
+	// TraceJobDir is the directory the outputs of the chain tracing jobs are
+	// confined to, "tracejobs" in the data directory if empty.
+	TraceJobDir string `toml:",omitempty"`
+
+	// RPCLogsRange is the maximum number of blocks a log query over RPC may span
+	// (0 = no limit).
+	RPCLogsRange uint64 `toml:",omitempty"`
+
+	// RPCLogsBackfill is the maximum number of blocks a log subscription may
+	// backfill logs of (0 = no limit).
+	RPCLogsBackfill uint64 `toml:",omitempty"`
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
 
@@ -31,6 +31,7 @@ import (
 	"github.com/ionchain/ionchain-core/core/types"
 	"github.com/ionchain/ionchain-core/event"
 	"github.com/ionchain/ionchain-core/ioncdb"
+	"github.com/ionchain/ionchain-core/log"
 	"github.com/ionchain/ionchain-core/rpc"
 )
 
@@ -59,15 +60,22 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+	logsRange uint64 // Maximum block range of the log queries, 0 if unlimited
+
+	backfillRange uint64 // Maximum block range of log subscription backfills, 0 if unlimited
 }
 
-// NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
+// NewPublicFilterAPI returns a new PublicFilterAPI instance. Log queries over a
+// range of more than logsRange blocks are refused, and so are log subscriptions
+// backfilling more than backfillRange blocks, unless the limit is zero.
+func NewPublicFilterAPI(backend Backend, lightMode bool, logsRange, backfillRange uint64) *PublicFilterAPI {
 	api := &PublicFilterAPI{
-		backend: backend,
-		chainDb: backend.ChainDb(),
-		events:  NewEventSystem(backend, lightMode),
-		filters: make(map[rpc.ID]*filter),
+		backend:       backend,
+		chainDb:       backend.ChainDb(),
+		events:        NewEventSystem(backend, lightMode),
+		filters:       make(map[rpc.ID]*filter),
+		logsRange:     logsRange,
+		backfillRange: backfillRange,
 	}
 	go api.timeoutLoop()
 
@@ -234,6 +242,12 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 }
 
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
+//
+// If the criteria start at a block number, the matching logs of the chain since that
+// block are sent first, followed by the new logs without gaps or duplicates. Logs of
+// blocks dropped by a reorg are sent again with the removed property set to true.
+// Subscriptions starting further back than the backfill limit of the node allows
+// are refused.
 func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
@@ -249,26 +263,155 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	if err != nil {
 		return nil, err
 	}
+	var backfill *logBackfill
+	if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 {
+		// Subscribed to the new logs first, so no block falls between the two
+		if backfill, err = api.newLogBackfill(ctx, crit); err != nil {
+			logsSub.Unsubscribe()
+			return nil, err
+		}
+	}
+	go api.streamLogs(notifier, rpcSub, logsSub, matchedLogs, backfill)
+	return rpcSub, nil
+}
 
-	go func() {
+// streamLogs sends the new logs of a log subscription to the client. If the
+// subscription is backfilled, the new logs are buffered until the backfill is
+// done, and the logs already sent by it are dropped.
+func (api *PublicFilterAPI) streamLogs(notifier *rpc.Notifier, rpcSub *rpc.Subscription, logsSub *Subscription, matchedLogs chan []*types.Log, backfill *logBackfill) {
+	defer logsSub.Unsubscribe()
 
-		for {
-			select {
-			case logs := <-matchedLogs:
-				for _, log := range logs {
-					notifier.Notify(rpcSub.ID, &log)
-				}
-			case <-rpcSub.Err(): // client send an unsubscribe request
-				logsSub.Unsubscribe()
-				return
-			case <-notifier.Closed(): // connection dropped
-				logsSub.Unsubscribe()
+	var (
+		buffered   []*types.Log
+		backfilled chan error
+	)
+	if backfill != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		backfilled = make(chan error, 1)
+		go func() { backfilled <- api.backfillLogs(ctx, notifier, rpcSub.ID, backfill) }()
+	}
+	for {
+		select {
+		case logs := <-matchedLogs:
+			if backfilled != nil {
+				buffered = append(buffered, logs...)
+				continue
+			}
+			if backfill != nil {
+				logs = backfill.live(logs)
+			}
+			for _, log := range logs {
+				notifier.Notify(rpcSub.ID, &log)
+			}
+		case err := <-backfilled:
+			if err != nil {
+				log.Debug("Log subscription backfill failed", "id", rpcSub.ID, "err", err)
 				return
 			}
+			backfilled = nil
+			for _, log := range backfill.live(buffered) {
+				notifier.Notify(rpcSub.ID, &log)
+			}
+			buffered = nil
+		case <-rpcSub.Err(): // client send an unsubscribe request
+			return
+		case <-notifier.Closed(): // connection dropped
+			return
 		}
-	}()
+	}
+}
 
-	return rpcSub, nil
+// backfillRecentBlocks is the number of blocks below the end of a log backfill
+// whose logs may also arrive as new logs, either because they were imported while
+// backfilling or because they were reorged.
+const backfillRecentBlocks = 128
+
+// backfillBatchBlocks is the number of blocks whose logs a backfill gathers at once
+// before sending them.
+var backfillBatchBlocks = uint64(1024)
+
+// logBackfill records the logs sent by the backfill of a log subscription.
+type logBackfill struct {
+	crit       FilterCriteria
+	begin, end uint64                   // First and last block of the backfill
+	sent       map[common.Hash]struct{} // Recent blocks whose logs were sent
+}
+
+// newLogBackfill sets up the backfill of a log subscription, from the starting
+// block of the criteria up to the current head. Backfills spanning more blocks
+// than allowed are refused.
+func (api *PublicFilterAPI) newLogBackfill(ctx context.Context, crit FilterCriteria) (*logBackfill, error) {
+	header, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if header == nil || err != nil {
+		return nil, fmt.Errorf("no current block: %v", err)
+	}
+	backfill := &logBackfill{
+		crit:  crit,
+		begin: crit.FromBlock.Uint64(),
+		end:   header.Number.Uint64(),
+		sent:  make(map[common.Hash]struct{}),
+	}
+	if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 && crit.ToBlock.Uint64() < backfill.end {
+		backfill.end = crit.ToBlock.Uint64()
+	}
+	if limit := api.backfillRange; limit > 0 && backfill.end >= backfill.begin && backfill.end-backfill.begin >= limit {
+		return nil, &rangeLimitError{blocks: backfill.end - backfill.begin + 1, limit: limit}
+	}
+	return backfill, nil
+}
+
+// backfillLogs sends the logs of a backfill once the subscription is active,
+// gathering them in batches of blocks so the sending paces the gathering.
+func (api *PublicFilterAPI) backfillLogs(ctx context.Context, notifier *rpc.Notifier, id rpc.ID, backfill *logBackfill) error {
+	select {
+	case <-notifier.Active():
+	case <-notifier.Closed():
+		return errors.New("connection closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	for begin := backfill.begin; begin <= backfill.end; begin += backfillBatchBlocks {
+		end := begin + backfillBatchBlocks - 1
+		if end > backfill.end || end < begin {
+			end = backfill.end
+		}
+		filter := NewRangeFilter(api.backend, int64(begin), int64(end), backfill.crit.Addresses, backfill.crit.Topics)
+
+		logs, err := filter.Logs(ctx)
+		if err != nil {
+			return err
+		}
+		for _, log := range logs {
+			if log.BlockNumber+backfillRecentBlocks > backfill.end {
+				backfill.sent[log.BlockHash] = struct{}{}
+			}
+			if err := notifier.Notify(id, &log); err != nil {
+				return err
+			}
+		}
+		if end == backfill.end {
+			break
+		}
+	}
+	return nil
+}
+
+// live filters the new logs of a backfilled subscription, dropping the logs the
+// backfill already sent and the removal of logs it didn't send.
+func (b *logBackfill) live(logs []*types.Log) []*types.Log {
+	var ret []*types.Log
+	for _, log := range logs {
+		if log.BlockNumber > b.end || log.BlockNumber+backfillRecentBlocks <= b.end {
+			ret = append(ret, log)
+			continue
+		}
+		if _, sent := b.sent[log.BlockHash]; sent == log.Removed {
+			ret = append(ret, log)
+		}
+	}
+	return ret
 }
 
 // FilterCriteria represents a request to create a new filter.
@@ -340,6 +483,7 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 		}
 		// Construct the range filter
 		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
+		filter.rangeLimit = api.logsRange
 	}
 	// Run the filter and return all the logs
 	logs, err := filter.Logs(ctx)
@@ -395,6 +539,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 		}
 		// Construct the range filter
 		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
+		filter.rangeLimit = api.logsRange
 	}
 	// Run the filter and return all the logs
 	logs, err := filter.Logs(ctx)
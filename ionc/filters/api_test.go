@@ -0,0 +1,207 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/rpc"
+)
+
+// Tests that the new logs of a backfilled subscription are handed over without
+// repeating the logs the backfill sent, and that only the removal of those is
+// passed on.
+func TestLogBackfillLive(t *testing.T) {
+	var (
+		sentHash   = common.Hash{0x01} // recent block whose logs were sent
+		unsentHash = common.Hash{0x02} // recent block without sent logs, e.g. imported during the backfill
+	)
+	backfill := &logBackfill{end: 1000, sent: map[common.Hash]struct{}{sentHash: {}}}
+
+	tests := []struct {
+		log  types.Log
+		pass bool
+	}{
+		// Logs past the backfill are new
+		{types.Log{BlockNumber: 1001}, true},
+		{types.Log{BlockNumber: 1001, Removed: true}, true},
+		// Logs of recent blocks are new unless the backfill sent them
+		{types.Log{BlockNumber: 1000, BlockHash: sentHash}, false},
+		{types.Log{BlockNumber: 999, BlockHash: unsentHash}, true},
+		{types.Log{BlockNumber: 1000 - backfillRecentBlocks + 1, BlockHash: sentHash}, false},
+		// Removals of recent blocks only matter if the backfill sent their logs
+		{types.Log{BlockNumber: 1000, BlockHash: sentHash, Removed: true}, true},
+		{types.Log{BlockNumber: 999, BlockHash: unsentHash, Removed: true}, false},
+		// Older blocks are beyond the reach of the dedup, everything passes
+		{types.Log{BlockNumber: 1000 - backfillRecentBlocks, BlockHash: unsentHash}, true},
+		{types.Log{BlockNumber: 1000 - backfillRecentBlocks, BlockHash: unsentHash, Removed: true}, true},
+	}
+	for i, tt := range tests {
+		log := tt.log
+		if have := len(backfill.live([]*types.Log{&log})) == 1; have != tt.pass {
+			t.Errorf("test %d: pass mismatch: have %v, want %v", i, have, tt.pass)
+		}
+	}
+}
+
+// newTestFilterClient serves the filter API of a backend in process.
+func newTestFilterClient(t *testing.T, b *testBackend, logsRange, backfillRange uint64) (*rpc.Client, func()) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", NewPublicFilterAPI(b, false, logsRange, backfillRange)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	return client, func() {
+		client.Close()
+		server.Stop()
+	}
+}
+
+// Tests that a log subscription backfills the chain in batches, and hands over to
+// the new logs without gaps or duplicates.
+func TestLogsSubscriptionBackfill(t *testing.T) {
+	defer func(batch uint64) { backfillBatchBlocks = batch }(backfillBatchBlocks)
+	backfillBatchBlocks = 7
+
+	b := newTestBackend(t, 5*testSectionSize, 2, 4)
+	b.stall = make(chan struct{})
+
+	client, stop := newTestFilterClient(t, b, 0, 0)
+	defer stop()
+
+	logs := make(chan types.Log, 100)
+	sub, err := client.EthSubscribe(context.Background(), logs, "logs", map[string]interface{}{
+		"fromBlock": "0x0",
+		"address":   []common.Address{testAddress},
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Deliver new logs while the backfill is stalled, repeating the last block
+	var (
+		last = b.logs[b.headers[len(b.headers)-1].Hash()][0][1]
+		next = &types.Log{Address: testAddress, Topics: []common.Hash{testTopic}, BlockNumber: last.BlockNumber + 1, BlockHash: common.Hash{0xaa}, TxHash: common.Hash{0xff}}
+	)
+	b.logsFeed.Send([]*types.Log{last, next})
+	close(b.stall)
+
+	var want []uint64
+	for number := uint64(0); number <= last.BlockNumber; number += 3 {
+		want = append(want, number)
+	}
+	want = append(want, next.BlockNumber)
+
+	for i, number := range want {
+		select {
+		case log := <-logs:
+			if log.BlockNumber != number || log.Removed {
+				t.Fatalf("log %d mismatch: have block %d (removed %v), want block %d", i, log.BlockNumber, log.Removed, number)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for log %d", i)
+		}
+	}
+	// The removal of a backfilled log must get through, without anything else
+	removed := *last
+	removed.Removed = true
+	b.rmLogsFeed.Send(core.RemovedLogsEvent{Logs: []*types.Log{&removed}})
+
+	select {
+	case log := <-logs:
+		if log.BlockNumber != last.BlockNumber || !log.Removed {
+			t.Fatalf("removed log mismatch: have block %d (removed %v), want block %d removed", log.BlockNumber, log.Removed, last.BlockNumber)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for removed log")
+	}
+	select {
+	case log := <-logs:
+		t.Fatalf("unexpected log of block %d", log.BlockNumber)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Tests that log subscriptions backfilling more blocks than allowed are refused.
+func TestLogsSubscriptionBackfillRange(t *testing.T) {
+	b := newTestBackend(t, 5*testSectionSize, 2, 4)
+
+	client, stop := newTestFilterClient(t, b, 0, 10)
+	defer stop()
+
+	for _, tt := range []struct {
+		from string
+		ok   bool
+	}{
+		{"0x0", false},
+		{"0x1d", false},
+		{"0x1e", true},
+		{"0x30", true}, // Past the head, nothing to backfill
+		{"latest", true},
+	} {
+		logs := make(chan types.Log)
+		sub, err := client.EthSubscribe(context.Background(), logs, "logs", map[string]interface{}{"fromBlock": tt.from})
+		if (err == nil) != tt.ok {
+			t.Errorf("from %s: error mismatch: have %v, want ok %v", tt.from, err, tt.ok)
+		}
+		if err == nil {
+			sub.Unsubscribe()
+		}
+	}
+}
+
+// Tests that log queries spanning more blocks than allowed are refused with the
+// limit exceeded code, and that the ones spanning exactly the limit pass.
+func TestGetLogsRange(t *testing.T) {
+	b := newTestBackend(t, 5*testSectionSize, 2, 4)
+
+	client, stop := newTestFilterClient(t, b, 10, 0)
+	defer stop()
+
+	for _, tt := range []struct {
+		from, to string
+		ok       bool
+	}{
+		{"0x0", "0x9", true},
+		{"0x0", "0xa", false},
+		{"0x5", "0x5", true},
+		{"0x9", "0x5", true}, // Empty range
+		{"0x1e", "latest", true},
+		{"0x1d", "latest", false},
+		{"latest", "latest", true},
+	} {
+		var logs []map[string]interface{}
+		err := client.Call(&logs, "eth_getLogs", map[string]interface{}{"fromBlock": tt.from, "toBlock": tt.to})
+		if tt.ok {
+			if err != nil {
+				t.Errorf("range %s-%s: unexpected error: %v", tt.from, tt.to, err)
+			}
+			continue
+		}
+		if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != -32005 {
+			t.Errorf("range %s-%s: error mismatch: have %v, want code -32005", tt.from, tt.to, err)
+		}
+	}
+}
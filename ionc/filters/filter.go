@@ -19,17 +19,23 @@ package filters
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/ionchain/ionchain-core/common"
 	"github.com/ionchain/ionchain-core/core"
 	"github.com/ionchain/ionchain-core/core/bloombits"
+	"github.com/ionchain/ionchain-core/core/rawdb"
 	"github.com/ionchain/ionchain-core/core/types"
 	"github.com/ionchain/ionchain-core/ioncdb"
 	"github.com/ionchain/ionchain-core/event"
 	"github.com/ionchain/ionchain-core/rpc"
 )
 
+// logIndexTopics is the number of topic positions covered by the log index.
+const logIndexTopics = 4
+
 type Backend interface {
 	ChainDb() ioncdb.Database
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
@@ -44,9 +50,22 @@ type Backend interface {
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
 
 	BloomStatus() (uint64, uint64)
+	LogIndexStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// rangeLimitError is returned if a log query spans more blocks than allowed.
+type rangeLimitError struct {
+	blocks, limit uint64
+}
+
+func (e *rangeLimitError) Error() string {
+	return fmt.Sprintf("block range too large (%d > %d)", e.blocks, e.limit)
+}
+
+// ErrorCode returns the JSON-RPC error code of exceeded limits.
+func (e *rangeLimitError) ErrorCode() int { return -32005 }
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -57,6 +76,7 @@ type Filter struct {
 
 	block      common.Hash // Block hash if filtering a single block
 	begin, end int64       // Range interval if filtering multiple blocks
+	rangeLimit uint64      // Maximum number of blocks in the range, 0 if unlimited
 
 	matcher *bloombits.Matcher
 }
@@ -142,18 +162,33 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
-	// Gather all indexed logs, and finish with non indexed ones
-	var (
-		logs []*types.Log
-		err  error
-	)
+	if f.rangeLimit > 0 && end >= uint64(f.begin) && end-uint64(f.begin) >= f.rangeLimit {
+		return nil, &rangeLimitError{blocks: end - uint64(f.begin) + 1, limit: f.rangeLimit}
+	}
+	// Gather the logs from the log index if the filter has criteria to look up,
+	// continue with the bloom indexed logs, and finish with non indexed ones
+	var logs []*types.Log
+	if size, sections := f.backend.LogIndexStatus(); f.hasCriteria() {
+		if indexed := sections * size; indexed > uint64(f.begin) {
+			last := end
+			if indexed <= end {
+				last = indexed - 1
+			}
+			found, err := f.logIndexLogs(ctx, size, last)
+			logs = append(logs, found...)
+			if err != nil {
+				return logs, err
+			}
+		}
+	}
 	size, sections := f.backend.BloomStatus()
 	if indexed := sections * size; indexed > uint64(f.begin) {
-		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
-		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+		last := end
+		if indexed <= end {
+			last = indexed - 1
 		}
+		found, err := f.indexedLogs(ctx, last)
+		logs = append(logs, found...)
 		if err != nil {
 			return logs, err
 		}
@@ -163,6 +198,151 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	return logs, err
 }
 
+// hasCriteria reports whether the filter restricts the emitting addresses or any
+// of the topics, which the log index can resolve.
+func (f *Filter) hasCriteria() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, topics := range f.topics {
+		if len(topics) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// logIndexLogs returns the logs matching the filter criteria based on the log
+// index of the sections of the given size. It stops at the first section whose
+// index doesn't match the canonical chain, which is left to the other indices.
+func (f *Filter) logIndexLogs(ctx context.Context, size, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for f.begin <= int64(end) {
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+		section := uint64(f.begin) / size
+		head := rawdb.ReadCanonicalHash(f.db, (section+1)*size-1)
+		if !rawdb.HasLogIndexSection(f.db, section, head) {
+			return logs, nil
+		}
+		positions, err := f.logIndexPositions(section, head)
+		if err != nil {
+			return logs, err
+		}
+		last := (section+1)*size - 1
+		if last > end {
+			last = end
+		}
+		for i := 0; i < len(positions); {
+			number := positions[i].Block
+			if number < uint64(f.begin) {
+				i++
+				continue
+			}
+			if number > last {
+				break
+			}
+			// Gather the matching positions of the block and pull their logs
+			j := i
+			for j < len(positions) && positions[j].Block == number {
+				j++
+			}
+			found, err := f.positionLogs(ctx, number, positions[i:j])
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+			i = j
+		}
+		f.begin = int64(last) + 1
+	}
+	return logs, nil
+}
+
+// logIndexPositions returns the positions of the logs of a section matching all
+// criteria of the filter, in chain order.
+func (f *Filter) logIndexPositions(section uint64, head common.Hash) ([]rawdb.LogPosition, error) {
+	var clauses [][][]byte
+	if len(f.addresses) > 0 {
+		terms := make([][]byte, len(f.addresses))
+		for i, address := range f.addresses {
+			terms[i] = rawdb.AddressLogTerm(address)
+		}
+		clauses = append(clauses, terms)
+	}
+	for i, topics := range f.topics {
+		if len(topics) == 0 {
+			continue
+		}
+		if i >= logIndexTopics {
+			// Logs have no more topics than indexed, nothing can match
+			return nil, nil
+		}
+		terms := make([][]byte, len(topics))
+		for j, topic := range topics {
+			terms[j] = rawdb.TopicLogTerm(i, topic)
+		}
+		clauses = append(clauses, terms)
+	}
+	var matches map[rawdb.LogPosition]struct{}
+	for _, terms := range clauses {
+		// Any term of a clause matches, all clauses must match
+		union := make(map[rawdb.LogPosition]struct{})
+		for _, term := range terms {
+			positions, err := rawdb.ReadLogIndex(f.db, term, section, head)
+			if err != nil {
+				return nil, err
+			}
+			for _, pos := range positions {
+				if _, ok := matches[pos]; ok || matches == nil {
+					union[pos] = struct{}{}
+				}
+			}
+		}
+		matches = union
+		if len(matches) == 0 {
+			return nil, nil
+		}
+	}
+	positions := make([]rawdb.LogPosition, 0, len(matches))
+	for pos := range matches {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Block != positions[j].Block {
+			return positions[i].Block < positions[j].Block
+		}
+		return positions[i].LogIndex < positions[j].LogIndex
+	})
+	return positions, nil
+}
+
+// positionLogs retrieves the logs at the given positions within a block.
+func (f *Filter) positionLogs(ctx context.Context, number uint64, positions []rawdb.LogPosition) ([]*types.Log, error) {
+	header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if header == nil || err != nil {
+		return nil, err
+	}
+	logsList, err := f.backend.GetLogs(ctx, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	var unfiltered []*types.Log
+	for _, logs := range logsList {
+		unfiltered = append(unfiltered, logs...)
+	}
+	var logs []*types.Log
+	for _, pos := range positions {
+		if int(pos.LogIndex) < len(unfiltered) {
+			logs = append(logs, unfiltered[pos.LogIndex])
+		}
+	}
+	// Double check the positions against the criteria, the full logs are cheap to filter
+	return filterLogs(logs, nil, nil, f.addresses, f.topics), nil
+}
+
 // indexedLogs returns the logs matching the filter criteria based on the bloom
 // bits indexed available locally or via the network.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
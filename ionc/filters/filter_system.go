@@ -102,6 +102,9 @@ type EventSystem struct {
 	pendingLogsCh chan []*types.Log          // Channel to receive new log event
 	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
 	chainCh       chan core.ChainEvent       // Channel to receive new chain event
+	quit          chan struct{}              // Channel to request the event loop to terminate
+	done          chan struct{}              // Channel closed when the event loop terminated
+	stopOnce      sync.Once
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -109,7 +112,7 @@ type EventSystem struct {
 // work loop holds its own index that is used to forward events to filters.
 //
 // The returned manager has a loop that needs to be stopped with the Stop function
-// or by stopping the given mux.
+// or by stopping the backend event feeds.
 func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 	m := &EventSystem{
 		backend:       backend,
@@ -121,6 +124,8 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		pendingLogsCh: make(chan []*types.Log, logsChanSize),
 		chainCh:       make(chan core.ChainEvent, chainEvChanSize),
+		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
 	}
 
 	// Subscribe events
@@ -164,6 +169,8 @@ func (sub *Subscription) Unsubscribe() {
 			select {
 			case sub.es.uninstall <- sub.f:
 				break uninstallLoop
+			case <-sub.es.done:
+				break uninstallLoop // Already uninstalled when the loop terminated
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
@@ -177,13 +184,25 @@ func (sub *Subscription) Unsubscribe() {
 	})
 }
 
-// subscribe installs the subscription in the event broadcast loop.
+// subscribe installs the subscription in the event broadcast loop. Subscriptions
+// created after the loop terminated are ended right away.
 func (es *EventSystem) subscribe(sub *subscription) *Subscription {
-	es.install <- sub
-	<-sub.installed
+	select {
+	case es.install <- sub:
+		<-sub.installed
+	case <-es.done:
+		close(sub.err)
+	}
 	return &Subscription{ID: sub.id, f: sub, es: es}
 }
 
+// Stop terminates the event loop, ending all the subscriptions. It blocks until
+// the loop has terminated.
+func (es *EventSystem) Stop() {
+	es.stopOnce.Do(func() { close(es.quit) })
+	<-es.done
+}
+
 // SubscribeLogs creates a subscription that will write all logs matching the
 // given criteria to the given logs channel. Default value for the from and to
 // block is "latest". If the fromBlock > toBlock an error is returned.
@@ -441,6 +460,10 @@ func (es *EventSystem) lightFilterLogs(header *types.Header, addresses []common.
 
 // eventLoop (un)installs filters and processes mux events.
 func (es *EventSystem) eventLoop() {
+	index := make(filterIndex)
+	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
+		index[i] = make(map[rpc.ID]*subscription)
+	}
 	// Ensure all subscriptions get cleaned up
 	defer func() {
 		es.txsSub.Unsubscribe()
@@ -448,12 +471,19 @@ func (es *EventSystem) eventLoop() {
 		es.rmLogsSub.Unsubscribe()
 		es.pendingLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
-	}()
 
-	index := make(filterIndex)
-	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
-		index[i] = make(map[rpc.ID]*subscription)
-	}
+		// End the installed filters, the mined and pending logs ones are indexed twice
+		ended := make(map[rpc.ID]bool)
+		for _, filters := range index {
+			for id, f := range filters {
+				if !ended[id] {
+					close(f.err)
+					ended[id] = true
+				}
+			}
+		}
+		close(es.done)
+	}()
 
 	for {
 		select {
@@ -489,6 +519,8 @@ func (es *EventSystem) eventLoop() {
 			close(f.err)
 
 		// System stopped
+		case <-es.quit:
+			return
 		case <-es.txsSub.Err():
 			return
 		case <-es.logsSub.Err():
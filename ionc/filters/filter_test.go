@@ -0,0 +1,297 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/bloombits"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/event"
+	"github.com/ionchain/ionchain-core/ioncdb"
+	"github.com/ionchain/ionchain-core/rpc"
+)
+
+// testSectionSize is the section size of the log and bloom indices of the test
+// chain, the smallest the bloom bits generator supports.
+const testSectionSize = 8
+
+// testBackend is a Backend serving a chain with its log index and bloom bits,
+// and feeding the events sent by the tests.
+type testBackend struct {
+	db            ioncdb.Database
+	headers       []*types.Header
+	logs          map[common.Hash][][]*types.Log
+	bloomBits     map[uint64][][]byte // bloom bits of the bloom indexed sections
+	logSections   uint64              // number of log indexed sections
+	bloomSections uint64              // number of bloom indexed sections
+
+	stall chan struct{} // if set, log retrievals wait for it to be closed
+
+	txFeed      event.Feed
+	chainFeed   event.Feed
+	logsFeed    event.Feed
+	rmLogsFeed  event.Feed
+	pendingFeed event.Feed
+}
+
+func (b *testBackend) ChainDb() ioncdb.Database { return b.db }
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber {
+		return b.headers[len(b.headers)-1], nil
+	}
+	if number < 0 || int(number) >= len(b.headers) {
+		return nil, nil
+	}
+	return b.headers[number], nil
+}
+
+func (b *testBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	for _, header := range b.headers {
+		if header.Hash() == hash {
+			return header, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return nil, nil
+}
+
+func (b *testBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
+	if b.stall != nil {
+		select {
+		case <-b.stall:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return b.logs[hash], nil
+}
+
+func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.txFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return b.chainFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
+	return b.rmLogsFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return b.logsFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return b.pendingFeed.Subscribe(ch)
+}
+
+func (b *testBackend) BloomStatus() (uint64, uint64) { return testSectionSize, b.bloomSections }
+
+func (b *testBackend) LogIndexStatus() (uint64, uint64) { return testSectionSize, b.logSections }
+
+func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	requests := make(chan chan *bloombits.Retrieval)
+
+	go session.Multiplex(16, 0, requests)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case request := <-requests:
+				task := <-request
+				task.Bitsets = make([][]byte, len(task.Sections))
+				for i, section := range task.Sections {
+					task.Bitsets[i] = b.bloomBits[section][task.Bit]
+				}
+				request <- task
+			}
+		}
+	}()
+}
+
+var (
+	testAddress = common.HexToAddress("0x0a")
+	testOther   = common.HexToAddress("0x0b")
+	testTopic   = common.HexToHash("0x0c")
+)
+
+// newTestBackend creates a chain of the given length in which every block has a
+// log of testOther, and every third block has a log of testAddress with testTopic
+// after it. The sections are indexed by the log index first, by bloom bits
+// second, and the rest is left unindexed.
+func newTestBackend(t *testing.T, length int, logSections, bloomSections uint64) *testBackend {
+	b := &testBackend{
+		db:            rawdb.NewMemoryDatabase(),
+		logs:          make(map[common.Hash][][]*types.Log),
+		bloomBits:     make(map[uint64][][]byte),
+		logSections:   logSections,
+		bloomSections: bloomSections,
+	}
+	var (
+		gen      *bloombits.Generator
+		postings = make(map[uint64]map[string][]rawdb.LogPosition)
+	)
+	for i := 0; i < length; i++ {
+		number := uint64(i)
+
+		logs := []*types.Log{{Address: testOther}}
+		if i%3 == 0 {
+			logs = append(logs, &types.Log{Address: testAddress, Topics: []common.Hash{testTopic}})
+		}
+		receipt := &types.Receipt{Logs: logs}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+		header := &types.Header{Number: big.NewInt(int64(i)), Bloom: receipt.Bloom}
+		if i > 0 {
+			header.ParentHash = b.headers[i-1].Hash()
+		}
+		hash := header.Hash()
+		for j, log := range logs {
+			log.BlockNumber, log.BlockHash, log.TxHash, log.Index = number, hash, common.Hash{0xff}, uint(j)
+		}
+		b.headers = append(b.headers, header)
+		b.logs[hash] = [][]*types.Log{logs}
+		rawdb.WriteCanonicalHash(b.db, hash, number)
+
+		// Index the logs of the log indexed sections, by address and topic
+		section := number / testSectionSize
+		if section < logSections {
+			if postings[section] == nil {
+				postings[section] = make(map[string][]rawdb.LogPosition)
+			}
+			for j, log := range logs {
+				pos := rawdb.LogPosition{Block: number, LogIndex: uint32(j)}
+				terms := [][]byte{rawdb.AddressLogTerm(log.Address)}
+				for k, topic := range log.Topics {
+					terms = append(terms, rawdb.TopicLogTerm(k, topic))
+				}
+				for _, term := range terms {
+					postings[section][string(term)] = append(postings[section][string(term)], pos)
+				}
+			}
+		}
+		// Generate the bloom bits of the bloom indexed sections
+		if section < bloomSections {
+			if number%testSectionSize == 0 {
+				gen, _ = bloombits.NewGenerator(testSectionSize)
+			}
+			gen.AddBloom(uint(number%testSectionSize), header.Bloom)
+			if number%testSectionSize == testSectionSize-1 {
+				bits := make([][]byte, types.BloomBitLength)
+				for bit := range bits {
+					bits[bit], _ = gen.Bitset(uint(bit))
+				}
+				b.bloomBits[section] = bits
+			}
+		}
+	}
+	// Store the log index under the section heads and mark the sections complete
+	for section, terms := range postings {
+		head := b.headers[(section+1)*testSectionSize-1].Hash()
+		for term, positions := range terms {
+			rawdb.WriteLogIndex(b.db, []byte(term), section, head, positions)
+		}
+		rawdb.WriteLogIndexSection(b.db, section, head)
+	}
+	return b
+}
+
+// checkFilterLogs runs a range filter and checks that it returns the logs of
+// testAddress of every third block in the range exactly once, in order.
+func checkFilterLogs(t *testing.T, b *testBackend, begin, end int64, addresses []common.Address, topics [][]common.Hash) {
+	t.Helper()
+
+	logs, err := NewRangeFilter(b, begin, end, addresses, topics).Logs(context.Background())
+	if err != nil {
+		t.Fatalf("range %d-%d: failed to filter logs: %v", begin, end, err)
+	}
+	var want []uint64
+	for number := begin; number <= end; number++ {
+		if number%3 == 0 {
+			want = append(want, uint64(number))
+		}
+	}
+	if len(logs) != len(want) {
+		t.Fatalf("range %d-%d: log count mismatch: have %d, want %d", begin, end, len(logs), len(want))
+	}
+	for i, log := range logs {
+		if log.BlockNumber != want[i] || log.Address != testAddress || log.Index != 1 {
+			t.Errorf("range %d-%d: log %d mismatch: have block %d address %x index %d, want block %d", begin, end, i, log.BlockNumber, log.Address, log.Index, want[i])
+		}
+	}
+}
+
+// Tests that log queries hand over from the log index to the bloom bits and on
+// to the unindexed blocks without gaps or duplicates.
+func TestFilterIndexHandOff(t *testing.T) {
+	// Two log indexed sections, two more bloom indexed ones, and unindexed blocks.
+	// The bloom bits of the log indexed sections are left empty, matching nothing,
+	// so the logs of those sections are only found through the log index.
+	b := newTestBackend(t, 5*testSectionSize, 2, 4)
+	for section := uint64(0); section < 2; section++ {
+		b.bloomBits[section] = make([][]byte, types.BloomBitLength)
+		for bit := range b.bloomBits[section] {
+			b.bloomBits[section][bit] = make([]byte, testSectionSize/8)
+		}
+	}
+	for _, tt := range []struct {
+		begin, end int64
+	}{
+		{0, 39}, {5, 35}, {9, 14}, {15, 16}, {20, 33}, {33, 39},
+	} {
+		checkFilterLogs(t, b, tt.begin, tt.end, []common.Address{testAddress}, nil)
+		checkFilterLogs(t, b, tt.begin, tt.end, nil, [][]common.Hash{{testTopic}})
+		checkFilterLogs(t, b, tt.begin, tt.end, []common.Address{testAddress, testOther}, [][]common.Hash{{testTopic}})
+	}
+	// Topics beyond the indexed positions can't match any log
+	logs, err := NewRangeFilter(b, 0, 39, nil, [][]common.Hash{nil, nil, nil, nil, {testTopic}}).Logs(context.Background())
+	if err != nil || len(logs) != 0 {
+		t.Errorf("unindexable topic position matched: %d logs, %v", len(logs), err)
+	}
+}
+
+// Tests that a log index section whose head isn't canonical any more is left to
+// the bloom bits, along with all later sections.
+func TestFilterIndexReorgedSection(t *testing.T) {
+	b := newTestBackend(t, 5*testSectionSize, 3, 4)
+
+	// Replace section 1 by the index of a reorged head, which has no matches
+	head := b.headers[2*testSectionSize-1].Hash()
+	rawdb.DeleteLogIndexSection(b.db, 1, head)
+	rawdb.DeleteLogIndex(b.db, rawdb.AddressLogTerm(testAddress), 1, head)
+	rawdb.WriteLogIndexSection(b.db, 1, common.Hash{0x01})
+
+	for _, tt := range []struct {
+		begin, end int64
+	}{
+		{0, 39}, {8, 23}, {12, 20},
+	} {
+		checkFilterLogs(t, b, tt.begin, tt.end, []common.Address{testAddress}, nil)
+	}
+}
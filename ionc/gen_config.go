@@ -23,8 +23,16 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DiscoveryURLs           []string
 		NoPruning               bool
 		NoPrefetch              bool
+		StateDiffs              bool
+		StateDiffLimit          uint64
+		ParallelExec            bool
+		Witnesses               bool
+		CallTraceIndex          bool
+		LogIndex                bool
 		TxLookupLimit           uint64                 `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
+		MaxReorgDepth           uint64                 `toml:",omitempty"`
+		ReorgCheckpoints        []core.Checkpoint      `toml:",omitempty"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
 		LightEgress             int                    `toml:",omitempty"`
@@ -54,6 +62,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		EVMInterpreter          string
 		RPCGasCap               uint64                         `toml:",omitempty"`
 		RPCTxFeeCap             float64                        `toml:",omitempty"`
+		RPCTracerCustom         bool                           `toml:",omitempty"`
+		RPCTracerSteps          uint64                         `toml:",omitempty"`
+		RPCTracerMemory         uint64                         `toml:",omitempty"`
+		RPCTracerOutput         uint64                         `toml:",omitempty"`
+		TraceJobDir             string                         `toml:",omitempty"`
+		RPCLogsRange            uint64                         `toml:",omitempty"`
+		RPCLogsBackfill         uint64                         `toml:",omitempty"`
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
 	}
@@ -64,8 +79,16 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DiscoveryURLs = c.DiscoveryURLs
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
+	enc.StateDiffs = c.StateDiffs
+	enc.StateDiffLimit = c.StateDiffLimit
+	enc.ParallelExec = c.ParallelExec
+	enc.Witnesses = c.Witnesses
+	enc.CallTraceIndex = c.CallTraceIndex
+	enc.LogIndex = c.LogIndex
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.Whitelist = c.Whitelist
+	enc.MaxReorgDepth = c.MaxReorgDepth
+	enc.ReorgCheckpoints = c.ReorgCheckpoints
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
 	enc.LightEgress = c.LightEgress
@@ -95,6 +118,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.EVMInterpreter = c.EVMInterpreter
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
+	enc.RPCTracerCustom = c.RPCTracerCustom
+	enc.RPCTracerSteps = c.RPCTracerSteps
+	enc.RPCTracerMemory = c.RPCTracerMemory
+	enc.RPCTracerOutput = c.RPCTracerOutput
+	enc.TraceJobDir = c.TraceJobDir
+	enc.RPCLogsRange = c.RPCLogsRange
+	enc.RPCLogsBackfill = c.RPCLogsBackfill
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
 	return &enc, nil
@@ -109,8 +139,16 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DiscoveryURLs           []string
 		NoPruning               *bool
 		NoPrefetch              *bool
+		StateDiffs              *bool
+		StateDiffLimit          *uint64
+		ParallelExec            *bool
+		Witnesses               *bool
+		CallTraceIndex          *bool
+		LogIndex                *bool
 		TxLookupLimit           *uint64                `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
+		MaxReorgDepth           *uint64                `toml:",omitempty"`
+		ReorgCheckpoints        []core.Checkpoint      `toml:",omitempty"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
 		LightEgress             *int                   `toml:",omitempty"`
@@ -140,6 +178,13 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		EVMInterpreter          *string
 		RPCGasCap               *uint64                        `toml:",omitempty"`
 		RPCTxFeeCap             *float64                       `toml:",omitempty"`
+		RPCTracerCustom         *bool                          `toml:",omitempty"`
+		RPCTracerSteps          *uint64                        `toml:",omitempty"`
+		RPCTracerMemory         *uint64                        `toml:",omitempty"`
+		RPCTracerOutput         *uint64                        `toml:",omitempty"`
+		TraceJobDir             *string                        `toml:",omitempty"`
+		RPCLogsRange            *uint64                        `toml:",omitempty"`
+		RPCLogsBackfill         *uint64                        `toml:",omitempty"`
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
 	}
@@ -165,12 +210,36 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPrefetch != nil {
 		c.NoPrefetch = *dec.NoPrefetch
 	}
+	if dec.StateDiffs != nil {
+		c.StateDiffs = *dec.StateDiffs
+	}
+	if dec.StateDiffLimit != nil {
+		c.StateDiffLimit = *dec.StateDiffLimit
+	}
+	if dec.ParallelExec != nil {
+		c.ParallelExec = *dec.ParallelExec
+	}
+	if dec.Witnesses != nil {
+		c.Witnesses = *dec.Witnesses
+	}
+	if dec.CallTraceIndex != nil {
+		c.CallTraceIndex = *dec.CallTraceIndex
+	}
+	if dec.LogIndex != nil {
+		c.LogIndex = *dec.LogIndex
+	}
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
 	if dec.Whitelist != nil {
 		c.Whitelist = dec.Whitelist
 	}
+	if dec.MaxReorgDepth != nil {
+		c.MaxReorgDepth = *dec.MaxReorgDepth
+	}
+	if dec.ReorgCheckpoints != nil {
+		c.ReorgCheckpoints = dec.ReorgCheckpoints
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -258,6 +327,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
+	if dec.RPCTracerCustom != nil {
+		c.RPCTracerCustom = *dec.RPCTracerCustom
+	}
+	if dec.RPCTracerSteps != nil {
+		c.RPCTracerSteps = *dec.RPCTracerSteps
+	}
+	if dec.RPCTracerMemory != nil {
+		c.RPCTracerMemory = *dec.RPCTracerMemory
+	}
+	if dec.RPCTracerOutput != nil {
+		c.RPCTracerOutput = *dec.RPCTracerOutput
+	}
+	if dec.TraceJobDir != nil {
+		c.TraceJobDir = *dec.TraceJobDir
+	}
+	if dec.RPCLogsRange != nil {
+		c.RPCLogsRange = *dec.RPCLogsRange
+	}
+	if dec.RPCLogsBackfill != nil {
+		c.RPCLogsBackfill = *dec.RPCLogsBackfill
+	}
 	if dec.Checkpoint != nil {
 		c.Checkpoint = dec.Checkpoint
 	}
@@ -0,0 +1,161 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/consensus/ipos"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/crypto"
+	"github.com/ionchain/ionchain-core/ionc/downloader"
+	"github.com/ionchain/ionchain-core/node"
+	"github.com/ionchain/ionchain-core/params"
+)
+
+// forgerHealthTimeout is the time the forger check may spend evaluating the mint
+// power of the forger.
+const forgerHealthTimeout = 5 * time.Second
+
+// mintPowerSelector is the selector of the mintPower(address) method of the
+// staking contract.
+var mintPowerSelector = crypto.Keccak256([]byte("mintPower(address)"))[:4]
+
+// syncHealth is the state reported by the sync check.
+type syncHealth struct {
+	CurrentBlock uint64 `json:"currentBlock"`
+	HighestBlock uint64 `json:"highestBlock"`
+	MaxBehind    uint64 `json:"maxBehind"`
+}
+
+// SyncHealthCheck returns a health check failing while the chain is more than
+// maxBehind blocks behind the highest block announced by the peers.
+func SyncHealthCheck(d *downloader.Downloader, maxBehind uint64) node.HealthCheck {
+	return func() (interface{}, error) {
+		progress := d.Progress()
+		health := syncHealth{CurrentBlock: progress.CurrentBlock, HighestBlock: progress.HighestBlock, MaxBehind: maxBehind}
+		if health.HighestBlock > health.CurrentBlock+maxBehind {
+			return health, fmt.Errorf("syncing, %d blocks behind", health.HighestBlock-health.CurrentBlock)
+		}
+		return health, nil
+	}
+}
+
+// headHealth is the state reported by the head check.
+type headHealth struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Age    uint64      `json:"age"`    // Seconds since the head block was minted
+	MaxAge uint64      `json:"maxAge"` // Seconds
+}
+
+// HeadHealthCheck returns a health check failing if the current head is older
+// than the given number of block times.
+func HeadHealthCheck(current func() *types.Header, maxBlocks uint64) node.HealthCheck {
+	return func() (interface{}, error) {
+		head := current()
+		health := headHealth{Number: head.Number.Uint64(), Hash: head.Hash(), MaxAge: maxBlocks * ipos.BlockTime}
+		if now := uint64(time.Now().Unix()); now > head.Time {
+			health.Age = now - head.Time
+		}
+		if health.Age > health.MaxAge {
+			return health, fmt.Errorf("head block is %v old", time.Duration(health.Age)*time.Second)
+		}
+		return health, nil
+	}
+}
+
+// forgerHealth is the state reported by the forger check.
+type forgerHealth struct {
+	Forger    common.Address `json:"forger"`
+	MintPower string         `json:"mintPower,omitempty"` // Effective deposit in ether, in decimal
+}
+
+// forgerHealthCheck returns a health check failing unless a forger is authorized
+// to mint blocks and has a nonzero mint power in the current state.
+func forgerHealthCheck(chain *core.BlockChain, engine *ipos.IPos) node.HealthCheck {
+	return func() (interface{}, error) {
+		health := forgerHealth{Forger: engine.Signer()}
+		if health.Forger == (common.Address{}) {
+			return health, errors.New("no forger authorized")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), forgerHealthTimeout)
+		defer cancel()
+
+		power, err := mintPower(ctx, chain, health.Forger)
+		if err != nil {
+			return health, fmt.Errorf("mint power unavailable: %v", err)
+		}
+		health.MintPower = power.String()
+		if power.Sign() == 0 {
+			return health, errors.New("forger has no mint power")
+		}
+		return health, nil
+	}
+}
+
+// mintPower evaluates the mint power of an address against the state of the
+// current block, in the units the IPos engine weighs block producers by.
+func mintPower(ctx context.Context, chain *core.BlockChain, addr common.Address) (*big.Int, error) {
+	header := chain.CurrentBlock().Header()
+	statedb, err := chain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	evm := vm.NewEVM(core.NewEVMBlockContext(header, chain, nil), vm.TxContext{}, statedb, chain.Config(), vm.Config{})
+
+	// Abort the evaluation if it doesn't finish in time
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	}()
+	input := append(common.CopyBytes(mintPowerSelector), common.LeftPadBytes(addr.Bytes(), 32)...)
+	ret, _, err := evm.StaticCall(vm.AccountRef(common.Address{}), vm.IPosContractAddress, input, params.IPosMintPowerGas)
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("evaluation aborted: %v", ctx.Err())
+	}
+	if err != nil {
+		return nil, err
+	}
+	power := new(big.Int).SetBytes(ret)
+	return power.Div(power, big.NewInt(params.Ether)), nil
+}
+
+// registerHealthChecks adds the checks of the chain to the health and readiness
+// endpoints of the node.
+func (s *IonChain) registerHealthChecks(stack *node.Node) {
+	config := stack.Config()
+	stack.RegisterHealthCheck("sync", SyncHealthCheck(s.Downloader(), config.HealthSyncBlocks))
+	stack.RegisterHealthCheck("head", HeadHealthCheck(func() *types.Header {
+		return s.blockchain.CurrentBlock().Header()
+	}, config.HealthHeadBlocks))
+	if engine, ok := s.engine.(*ipos.IPos); ok {
+		stack.RegisterHealthCheck("forger", forgerHealthCheck(s.blockchain, engine))
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/consensus/ipos"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/params"
+)
+
+// Tests that the forger check evaluates the mint power against the chain state,
+// reporting it without truncation and failing with the errors of the staking
+// contract.
+func TestForgerHealthCheck(t *testing.T) {
+	power, _ := new(big.Int).SetString("100000000000000000000000000000", 10) // Beyond uint64, in ether
+	deposit := new(big.Int).Mul(power, big.NewInt(params.Ether))
+	deposit.Add(deposit, big.NewInt(1)) // Sub-ether remainders don't count
+
+	tests := []struct {
+		code  []byte
+		power string
+		fail  bool
+	}{
+		// PUSH32 deposit, PUSH1 0, MSTORE, PUSH1 32, PUSH1 0, RETURN
+		{append(append([]byte{0x7f}, common.LeftPadBytes(deposit.Bytes(), 32)...), common.FromHex("0x60005260206000f3")...), power.String(), false},
+		// Returns nothing
+		{common.FromHex("0x00"), "0", true},
+		// REVERT(0, 0)
+		{common.FromHex("0x60006000fd"), "", true},
+	}
+	for i, tt := range tests {
+		db := rawdb.NewMemoryDatabase()
+		genesis := &core.Genesis{
+			Config:     params.TestChainConfig,
+			BaseTarget: big.NewInt(1),
+			Alloc:      core.GenesisAlloc{vm.IPosContractAddress: {Code: tt.code, Balance: new(big.Int)}},
+		}
+		genesis.MustCommit(db)
+
+		engine := ipos.New(db, "")
+		chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to create chain: %v", i, err)
+		}
+		check := forgerHealthCheck(chain, engine)
+		if _, err := check(); err == nil {
+			t.Errorf("test %d: check passed without a forger", i)
+		}
+		engine.Authorize(common.HexToAddress("0xf0"), nil)
+
+		result, err := check()
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: failure mismatch: have %v, want fail %v", i, err, tt.fail)
+		}
+		if have := result.(forgerHealth).MintPower; have != tt.power {
+			t.Errorf("test %d: mint power mismatch: have %s, want %s", i, have, tt.power)
+		}
+		chain.Stop()
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/ioncdb"
+)
+
+const (
+	// logIndexThrottling is the time to wait between processing two consecutive
+	// log index sections, to keep the initial indexing from overloading the disk.
+	logIndexThrottling = 100 * time.Millisecond
+
+	// logIndexTopics is the number of topic positions indexed.
+	logIndexTopics = 4
+)
+
+// LogIndexer implements a core.ChainIndexer, building up an index of the logs of
+// the canonical chain by emitting address and by topic, which resolves log
+// queries to exact log positions.
+//
+// The index of a section is stored under the hash of the section head, so the
+// index of sections replaced by a reorg is ignored until the indexer rolls them
+// back and deletes it.
+type LogIndexer struct {
+	size     uint64                         // section size to index logs for
+	db       ioncdb.Database                // database instance to read receipts from and write the index into
+	section  uint64                         // section number being processed currently
+	head     common.Hash                    // hash of the last header processed
+	postings map[string][]rawdb.LogPosition // positions of the logs of the section by term
+}
+
+// NewLogIndexer returns a chain indexer that generates the log index of the
+// canonical chain.
+func NewLogIndexer(db ioncdb.Database, size, confirms uint64) *core.ChainIndexer {
+	backend := &LogIndexer{
+		db:   db,
+		size: size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, confirms, logIndexThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (l *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	l.section, l.head = section, common.Hash{}
+	l.postings = make(map[string][]rawdb.LogPosition)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a block to the
+// index.
+func (l *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	hash, number := header.Hash(), header.Number.Uint64()
+
+	receipts := rawdb.ReadRawReceipts(l.db, hash, number)
+	if receipts == nil && header.ReceiptHash != types.EmptyRootHash {
+		return fmt.Errorf("missing receipts of block #%d [%x]", number, hash[:4])
+	}
+	logIndexTerms(receipts, number, l.add)
+	l.head = hash
+	return nil
+}
+
+// logIndexTerms calls add with the terms of every log of a block, along with the
+// position of the log.
+func logIndexTerms(receipts types.Receipts, number uint64, add func(term []byte, pos rawdb.LogPosition)) {
+	var index uint32
+	for i, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			pos := rawdb.LogPosition{Block: number, TxIndex: uint32(i), LogIndex: index}
+			add(rawdb.AddressLogTerm(log.Address), pos)
+			for j, topic := range log.Topics {
+				if j == logIndexTopics {
+					break
+				}
+				add(rawdb.TopicLogTerm(j, topic), pos)
+			}
+			index++
+		}
+	}
+}
+
+func (l *LogIndexer) add(term []byte, pos rawdb.LogPosition) {
+	positions := l.postings[string(term)]
+	// A log can't match a term twice, but avoid duplicates in case it does.
+	if n := len(positions); n > 0 && positions[n-1] == pos {
+		return
+	}
+	l.postings[string(term)] = append(positions, pos)
+}
+
+// Commit implements core.ChainIndexerBackend, writing the index of the section
+// into the database.
+func (l *LogIndexer) Commit() error {
+	batch := l.db.NewBatch()
+	for term, positions := range l.postings {
+		rawdb.WriteLogIndex(batch, []byte(term), l.section, l.head, positions)
+		if batch.ValueSize() >= ioncdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	// Mark the section complete only after all of its postings
+	rawdb.WriteLogIndexSection(batch, l.section, l.head)
+	l.postings = nil
+	return batch.Write()
+}
+
+// Rollback implements core.ChainIndexerRollbacker, deleting the index of a section
+// reorged out of the canonical chain. The terms to delete are recomputed from the
+// receipts of the reorged blocks, which remain in the database.
+func (l *LogIndexer) Rollback(section uint64, head common.Hash) error {
+	// Unmark the section first, queries don't read the postings of incomplete sections
+	batch := l.db.NewBatch()
+	rawdb.DeleteLogIndexSection(batch, section, head)
+
+	var (
+		terms = make(map[string]struct{})
+		hash  = head
+		err   error
+	)
+	for number := (section+1)*l.size - 1; ; number-- {
+		header := rawdb.ReadHeader(l.db, hash, number)
+		if header == nil {
+			err = fmt.Errorf("missing header #%d [%x]", number, hash[:4])
+			break
+		}
+		receipts := rawdb.ReadRawReceipts(l.db, hash, number)
+		if receipts == nil && header.ReceiptHash != types.EmptyRootHash {
+			err = fmt.Errorf("missing receipts of block #%d [%x]", number, hash[:4])
+			break
+		}
+		logIndexTerms(receipts, number, func(term []byte, pos rawdb.LogPosition) {
+			terms[string(term)] = struct{}{}
+		})
+		if number == section*l.size {
+			break
+		}
+		hash = header.ParentHash
+	}
+	for term := range terms {
+		rawdb.DeleteLogIndex(batch, []byte(term), section, head)
+		if batch.ValueSize() >= ioncdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	return err
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (l *LogIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// logIndexChain feeds the log indexer the blocks whose receipts are available,
+// which during fast sync lag behind the headers.
+type logIndexChain struct {
+	*core.BlockChain
+}
+
+// CurrentHeader implements core.ChainIndexerChain, returning the header of the
+// current full block.
+func (c logIndexChain) CurrentHeader() *types.Header {
+	return c.CurrentBlock().Header()
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/ioncdb"
+)
+
+// writeLogIndexSection writes a section of blocks emitting one log each, with
+// the topic distinguishing the fork, and indexes it.
+func writeLogIndexSection(t *testing.T, db ioncdb.Database, indexer *LogIndexer, fork byte) (common.Hash, []byte) {
+	topic := common.Hash{fork}
+
+	var parent common.Hash
+	if err := indexer.Reset(context.Background(), 0, parent); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < indexer.size; i++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(i), ParentHash: parent, Extra: []byte{fork}}
+		receipts := types.Receipts{{Logs: []*types.Log{{Address: common.Address{0x0a}, Topics: []common.Hash{topic}}}}}
+
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteReceipts(db, header.Hash(), i, receipts)
+		if err := indexer.Process(context.Background(), header); err != nil {
+			t.Fatalf("failed to index block %d: %v", i, err)
+		}
+		parent = header.Hash()
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit section: %v", err)
+	}
+	return parent, rawdb.TopicLogTerm(0, topic)
+}
+
+// Tests that rolling back a section deletes the index stored under its head,
+// leaving the index of the section replacing it intact.
+func TestLogIndexRollback(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		indexer = &LogIndexer{db: db, size: 4}
+		address = rawdb.AddressLogTerm(common.Address{0x0a})
+	)
+	reorged, reorgedTopic := writeLogIndexSection(t, db, indexer, 1)
+	canonical, canonicalTopic := writeLogIndexSection(t, db, indexer, 2)
+
+	if err := indexer.Rollback(0, reorged); err != nil {
+		t.Fatalf("failed to roll back section: %v", err)
+	}
+	if rawdb.HasLogIndexSection(db, 0, reorged) {
+		t.Errorf("rolled back section still marked complete")
+	}
+	for _, term := range [][]byte{address, reorgedTopic} {
+		if positions, _ := rawdb.ReadLogIndex(db, term, 0, reorged); len(positions) != 0 {
+			t.Errorf("term %x: rolled back positions retained: %v", term, positions)
+		}
+	}
+	if !rawdb.HasLogIndexSection(db, 0, canonical) {
+		t.Errorf("canonical section unmarked")
+	}
+	for _, term := range [][]byte{address, canonicalTopic} {
+		if positions, _ := rawdb.ReadLogIndex(db, term, 0, canonical); len(positions) != int(indexer.size) {
+			t.Errorf("term %x: canonical positions mismatch: have %d, want %d", term, len(positions), indexer.size)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/ioncdb"
+	"github.com/ionchain/ionchain-core/log"
+)
+
+// traceJobCheckpointInterval is the time interval between persisting the
+// progress of a running trace job.
+const traceJobCheckpointInterval = 8 * time.Second
+
+// Statuses of the trace jobs.
+const (
+	TraceJobRunning   = "running"
+	TraceJobDone      = "done"
+	TraceJobFailed    = "failed"
+	TraceJobCancelled = "cancelled"
+)
+
+var errTraceJobNotFound = errors.New("trace job not found")
+
+// TraceJob is a chain tracing job running in the background, writing the traces
+// of every block with transactions as a JSON line into its output file.
+//
+// The progress is checkpointed as the last block written, along with the size
+// of the output up to and including it. Jobs interrupted by a shutdown resume
+// from their checkpoint, discarding anything written after it.
+type TraceJob struct {
+	ID      string         `json:"id"`
+	Start   hexutil.Uint64 `json:"start"` // Block the tracing starts after
+	End     hexutil.Uint64 `json:"end"`   // Last block to trace
+	Config  *TraceConfig   `json:"config,omitempty"`
+	Output  string         `json:"output"` // Path of the JSONL output file
+	Status  string         `json:"status"` // One of running, done, failed or cancelled
+	Error   string         `json:"error,omitempty"`
+	Current hexutil.Uint64 `json:"current"` // Last block traced
+	Offset  int64          `json:"offset"`  // Size of the output after the current block
+}
+
+// TraceJobConfig holds extra parameters to chain tracing jobs.
+type TraceJobConfig struct {
+	TraceConfig
+	Dir *string // Directory to write the output into, within the trace job root
+}
+
+// traceJobManager runs the chain tracing jobs and persists their progress into
+// the database.
+type traceJobManager struct {
+	api *PrivateDebugAPI
+	db  ioncdb.Database
+	dir string // Root directory of the job outputs
+
+	jobs    map[string]*TraceJob
+	running map[string]chan interface{} // Interrupt channels of the running jobs
+	cancels map[string]bool             // Running jobs being cancelled, rather than interrupted
+	stopped bool                        // Whether the manager was stopped, no job may start
+	lock    sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// newTraceJobManager creates a trace job manager, loading all the previously
+// created jobs from the database. The outputs of the jobs are confined to the
+// given root directory.
+func newTraceJobManager(eth *IonChain, db ioncdb.Database, dir string) *traceJobManager {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "ionc-tracejobs")
+	}
+	m := &traceJobManager{
+		api:     &PrivateDebugAPI{eth: eth},
+		db:      db,
+		dir:     dir,
+		jobs:    make(map[string]*TraceJob),
+		running: make(map[string]chan interface{}),
+		cancels: make(map[string]bool),
+	}
+	for _, blob := range rawdb.ReadTraceJobs(db) {
+		job := new(TraceJob)
+		if err := json.Unmarshal(blob, job); err != nil {
+			log.Error("Invalid trace job JSON", "err", err)
+			continue
+		}
+		m.jobs[job.ID] = job
+	}
+	return m
+}
+
+// start resumes all the jobs interrupted by a previous shutdown. The starting
+// states may need to be regenerated, so the jobs are resumed in the background.
+func (m *traceJobManager) start() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, job := range m.jobs {
+		if job.Status == TraceJobRunning {
+			log.Info("Resuming trace job", "id", job.ID, "current", uint64(job.Current), "end", uint64(job.End))
+			go m.run(job)
+		}
+	}
+}
+
+// stop interrupts all the running jobs, waiting for them to checkpoint their
+// progress.
+func (m *traceJobManager) stop() {
+	m.lock.Lock()
+	m.stopped = true
+	for _, interrupt := range m.running {
+		close(interrupt)
+	}
+	m.running = make(map[string]chan interface{})
+	m.lock.Unlock()
+
+	m.wg.Wait()
+}
+
+// submit creates a new job tracing the blocks after start up to end, and starts
+// running it.
+func (m *traceJobManager) submit(start, end *types.Block, config *TraceJobConfig) (*TraceJob, error) {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	job := &TraceJob{
+		ID:      hexutil.Encode(id[:]),
+		Start:   hexutil.Uint64(start.NumberU64()),
+		End:     hexutil.Uint64(end.NumberU64()),
+		Status:  TraceJobRunning,
+		Current: hexutil.Uint64(start.NumberU64()),
+	}
+	var dir string
+	if config != nil {
+		job.Config = &config.TraceConfig
+		if config.Dir != nil {
+			dir = *config.Dir
+		}
+	}
+	dir, err := m.outputDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	job.Output = filepath.Join(dir, job.ID+".jsonl")
+
+	m.lock.Lock()
+	m.jobs[job.ID] = job
+	m.persist(job)
+	m.lock.Unlock()
+
+	m.run(job)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.copy(job), nil
+}
+
+// outputDir resolves and creates the output directory of a job. Relative paths
+// are resolved against the root directory of the trace jobs, and the directory
+// must not be outside of it.
+func (m *traceJobManager) outputDir(dir string) (string, error) {
+	root, err := filepath.Abs(m.dir)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	if !withinDir(root, dir) {
+		return "", fmt.Errorf("trace job directory %s outside of %s", dir, root)
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	// Check again with the links resolved, the tree may link outside. The deepest
+	// existing ancestor is checked before creating anything, the directory itself
+	// once created.
+	existing := dir
+	for {
+		if _, err := os.Stat(existing); err == nil || existing == root {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	confined := func(path string) error {
+		realPath, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return err
+		}
+		if !withinDir(realRoot, realPath) {
+			return fmt.Errorf("trace job directory %s outside of %s", realPath, realRoot)
+		}
+		return nil
+	}
+	if err := confined(existing); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := confined(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// withinDir reports whether path is root itself or nested under it.
+func withinDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// run starts tracing a job from its last checkpoint. The starting state of the
+// job may need to be regenerated, so the lock must not be held.
+func (m *traceJobManager) run(job *TraceJob) {
+	// Roll the output back to the checkpoint and open it for appending
+	output, err := os.OpenFile(job.Output, os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		if err = output.Truncate(job.Offset); err == nil {
+			_, err = output.Seek(job.Offset, 0)
+		}
+		if err != nil {
+			output.Close()
+		}
+	}
+	var from, to *types.Block
+	if err == nil {
+		chain := m.api.eth.blockchain
+		if from = chain.GetBlockByNumber(uint64(job.Current)); from == nil {
+			err = fmt.Errorf("block #%d not found", job.Current)
+		} else if to = chain.GetBlockByNumber(uint64(job.End)); to == nil {
+			err = fmt.Errorf("block #%d not found", job.End)
+		}
+		if err != nil {
+			output.Close()
+		}
+	}
+	if err != nil || job.Current == job.End {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		if err == nil {
+			output.Close()
+		}
+		m.finish(job, err)
+		return
+	}
+	// Register the job before tracing, so it can be interrupted while its
+	// starting state is being regenerated
+	interrupt := make(chan interface{})
+
+	m.lock.Lock()
+	if m.stopped {
+		m.lock.Unlock()
+		output.Close()
+		return
+	}
+	m.running[job.ID] = interrupt
+	m.wg.Add(1)
+	m.lock.Unlock()
+
+	var (
+		checkpoint = time.Now()
+		failure    error // Output failure stopping the job, the sink and done run on the same goroutine
+	)
+	sink := func(result *blockTraceResult) {
+		if failure != nil {
+			return // Results already in flight when the job was stopped
+		}
+		var line []byte
+		if len(result.Traces) > 0 {
+			var err error
+			if line, err = json.Marshal(result); err == nil {
+				line = append(line, '\n')
+				_, err = output.Write(line)
+			}
+			if err != nil {
+				log.Error("Failed to write block traces", "id", job.ID, "block", uint64(result.Block), "err", err)
+				failure = fmt.Errorf("block #%d: %v", result.Block, err)
+
+				m.lock.Lock()
+				m.interrupt(job.ID)
+				m.lock.Unlock()
+				return
+			}
+		}
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		job.Current, job.Offset = result.Block, job.Offset+int64(len(line))
+		if time.Since(checkpoint) > traceJobCheckpointInterval {
+			if err := output.Sync(); err == nil {
+				m.persist(job)
+			}
+			checkpoint = time.Now()
+		}
+	}
+	done := func(err error) {
+		defer m.wg.Done()
+
+		if serr := output.Sync(); serr != nil && err == nil {
+			err = serr
+		}
+		output.Close()
+
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		delete(m.running, job.ID)
+		if failure != nil {
+			delete(m.cancels, job.ID)
+			m.finish(job, failure)
+			return
+		}
+		if m.cancels[job.ID] {
+			delete(m.cancels, job.ID)
+			job.Status = TraceJobCancelled
+			m.persist(job)
+			return
+		}
+		if err == errChainTraceAborted {
+			m.persist(job) // Interrupted by a shutdown, checkpoint to resume later
+			return
+		}
+		m.finish(job, err)
+	}
+	if err := m.api.traceBlocks(context.Background(), from, to, job.Config, interrupt, sink, done); err != nil {
+		output.Close()
+
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		m.interrupt(job.ID)
+		switch {
+		case m.cancels[job.ID]:
+			delete(m.cancels, job.ID)
+			job.Status = TraceJobCancelled
+			m.persist(job)
+		case m.stopped:
+			// Interrupted by a shutdown, resume later
+		default:
+			m.finish(job, err)
+		}
+		m.wg.Done()
+	}
+}
+
+// interrupt stops a running job, if it wasn't stopped yet. The lock is assumed
+// to be held.
+func (m *traceJobManager) interrupt(id string) {
+	if interrupt, ok := m.running[id]; ok {
+		close(interrupt)
+		delete(m.running, id)
+	}
+}
+
+// finish marks a job as done or failed and persists it. The lock is assumed to
+// be held.
+func (m *traceJobManager) finish(job *TraceJob, err error) {
+	if err != nil {
+		log.Warn("Trace job failed", "id", job.ID, "current", uint64(job.Current), "err", err)
+		job.Status, job.Error = TraceJobFailed, err.Error()
+	} else {
+		log.Info("Trace job finished", "id", job.ID, "output", job.Output)
+		job.Status = TraceJobDone
+	}
+	m.persist(job)
+}
+
+// persist stores the current state of a job in the database. The lock is assumed
+// to be held.
+func (m *traceJobManager) persist(job *TraceJob) {
+	blob, err := json.Marshal(job)
+	if err != nil {
+		log.Error("Failed to encode trace job", "id", job.ID, "err", err)
+		return
+	}
+	rawdb.WriteTraceJob(m.db, job.ID, blob)
+}
+
+// copy returns a snapshot of a job safe to hand out. The lock is assumed to be
+// held.
+func (m *traceJobManager) copy(job *TraceJob) *TraceJob {
+	cpy := *job
+	return &cpy
+}
+
+// cancel stops a running job, keeping its output up to the last block traced.
+func (m *traceJobManager) cancel(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.jobs[id]; !ok {
+		return errTraceJobNotFound
+	}
+	interrupt, ok := m.running[id]
+	if !ok {
+		return errors.New("trace job not running")
+	}
+	m.cancels[id] = true
+	close(interrupt)
+	delete(m.running, id)
+	return nil
+}
+
+// status retrieves a snapshot of a job.
+func (m *traceJobManager) status(id string) (*TraceJob, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, errTraceJobNotFound
+	}
+	return m.copy(job), nil
+}
+
+// list retrieves a snapshot of all the jobs, ordered by id.
+func (m *traceJobManager) list() []*TraceJob {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	jobs := make([]*TraceJob, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, m.copy(job))
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// remove deletes a job which is not running, along with its output.
+func (m *traceJobManager) remove(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return errTraceJobNotFound
+	}
+	if _, ok := m.running[id]; ok || m.cancels[id] || job.Status == TraceJobRunning {
+		return errors.New("trace job still running")
+	}
+	if err := os.Remove(job.Output); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(m.jobs, id)
+	rawdb.DeleteTraceJob(m.db, id)
+	return nil
+}
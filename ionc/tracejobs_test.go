@@ -0,0 +1,346 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ionc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/ionc/tracers"
+)
+
+// traceJobGateTracer is the name of a native tracer blocking until its gate is
+// closed, holding the trace jobs running it in the middle of the tracing.
+const traceJobGateTracer = "traceJobGateTracer"
+
+// newTraceJobGate registers the gated tracer, returning the gate to close.
+func newTraceJobGate() chan struct{} {
+	gate := make(chan struct{})
+	tracers.RegisterNative(traceJobGateTracer, func(statedb vm.StateDB, config json.RawMessage) (tracers.ResultTracer, error) {
+		<-gate
+		return tracers.NewTracer("callTracer", statedb, config)
+	})
+	return gate
+}
+
+// newTraceJobTest creates a trace job manager over a test chain of the given
+// length, with its outputs confined to a temporary directory.
+func newTraceJobTest(t *testing.T, length int) (*IonChain, *traceJobManager, string) {
+	eth := newTraceTestChain(t, length)
+
+	dir, err := ioutil.TempDir("", "tracejobs")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	return eth, newTraceJobManager(eth, eth.chainDb, dir), dir
+}
+
+// waitTraceJob waits until a job stops running, returning its final state.
+func waitTraceJob(t *testing.T, m *traceJobManager, id string) *TraceJob {
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		job, err := m.status(id)
+		if err != nil {
+			t.Fatalf("failed to retrieve job status: %v", err)
+		}
+		if job.Status != TraceJobRunning {
+			return job
+		}
+	}
+	t.Fatalf("trace job %s still running", id)
+	return nil
+}
+
+// persistedTraceJob retrieves the state of a job stored in the database.
+func persistedTraceJob(t *testing.T, m *traceJobManager, id string) *TraceJob {
+	for _, blob := range rawdb.ReadTraceJobs(m.db) {
+		job := new(TraceJob)
+		if err := json.Unmarshal(blob, job); err != nil {
+			t.Fatalf("failed to decode persisted job: %v", err)
+		}
+		if job.ID == id {
+			return job
+		}
+	}
+	t.Fatalf("trace job %s not persisted", id)
+	return nil
+}
+
+// checkTraceJobOutput checks that the output of a job contains the traces of the
+// blocks after from up to to in order, one line each, returning the raw output.
+func checkTraceJobOutput(t *testing.T, eth *IonChain, path string, from, to uint64) []byte {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read job output: %v", err)
+	}
+	lines := bytes.Split(bytes.TrimSuffix(blob, []byte("\n")), []byte("\n"))
+	if len(lines) != int(to-from) {
+		t.Fatalf("output line count mismatch: have %d, want %d", len(lines), to-from)
+	}
+	for i, line := range lines {
+		var result blockTraceResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			t.Fatalf("line %d: failed to decode traces: %v", i, err)
+		}
+		block := eth.blockchain.GetBlockByNumber(from + uint64(i) + 1)
+		if uint64(result.Block) != block.NumberU64() || result.Hash != block.Hash() {
+			t.Errorf("line %d: block mismatch: have #%d [%x], want #%d [%x]", i, result.Block, result.Hash, block.NumberU64(), block.Hash())
+		}
+		if len(result.Traces) != 1 || result.Traces[0].Error != "" {
+			t.Errorf("line %d: invalid traces: %s", i, line)
+		}
+	}
+	return blob
+}
+
+// Tests that a submitted job traces every block of its range into its output and
+// persists its completion.
+func TestTraceJobRun(t *testing.T) {
+	eth, m, dir := newTraceJobTest(t, 8)
+	defer eth.blockchain.Stop()
+	defer os.RemoveAll(dir)
+
+	tracer, sub := "callTracer", "sub"
+	job, err := m.submit(eth.blockchain.GetBlockByNumber(2), eth.blockchain.GetBlockByNumber(8), &TraceJobConfig{
+		TraceConfig: TraceConfig{Tracer: &tracer},
+		Dir:         &sub,
+	})
+	if err != nil {
+		t.Fatalf("failed to submit job: %v", err)
+	}
+	if want := filepath.Join(dir, sub, job.ID+".jsonl"); job.Output != want {
+		t.Errorf("output path mismatch: have %s, want %s", job.Output, want)
+	}
+	job = waitTraceJob(t, m, job.ID)
+	if job.Status != TraceJobDone || job.Error != "" {
+		t.Fatalf("job status mismatch: have %s (%s), want %s", job.Status, job.Error, TraceJobDone)
+	}
+	blob := checkTraceJobOutput(t, eth, job.Output, 2, 8)
+	if job.Current != 8 || job.Offset != int64(len(blob)) {
+		t.Errorf("checkpoint mismatch: have #%d at %d, want #8 at %d", job.Current, job.Offset, len(blob))
+	}
+	stored, _ := json.Marshal(persistedTraceJob(t, m, job.ID))
+	if want, _ := json.Marshal(job); !bytes.Equal(stored, want) {
+		t.Errorf("persisted job mismatch: have %s, want %s", stored, want)
+	}
+	if err := m.cancel(job.ID); err == nil {
+		t.Errorf("cancelled a finished job")
+	}
+	if err := m.remove(job.ID); err != nil {
+		t.Fatalf("failed to remove job: %v", err)
+	}
+	if _, err := os.Stat(job.Output); !os.IsNotExist(err) {
+		t.Errorf("output of removed job still present: %v", err)
+	}
+	if _, err := m.status(job.ID); err != errTraceJobNotFound {
+		t.Errorf("removed job status error mismatch: have %v, want %v", err, errTraceJobNotFound)
+	}
+}
+
+// Tests that a job resumes from its persisted checkpoint, discarding anything
+// written after it.
+func TestTraceJobResume(t *testing.T) {
+	eth, m, dir := newTraceJobTest(t, 8)
+	defer eth.blockchain.Stop()
+	defer os.RemoveAll(dir)
+
+	// Trace the whole range in one go as the reference output, the timings aside
+	tracer := "callTracer"
+	config := &TraceJobConfig{TraceConfig: TraceConfig{Tracer: &tracer}}
+
+	job, err := m.submit(eth.blockchain.Genesis(), eth.blockchain.GetBlockByNumber(8), config)
+	if err != nil {
+		t.Fatalf("failed to submit job: %v", err)
+	}
+	job = waitTraceJob(t, m, job.ID)
+	want := checkTraceJobOutput(t, eth, job.Output, 0, 8)
+
+	// Persist a job checkpointed after block 3, with a partial line written after it
+	offset := 0
+	for i := 0; i < 3; i++ {
+		offset += bytes.IndexByte(want[offset:], '\n') + 1
+	}
+	output := filepath.Join(dir, "resumed.jsonl")
+	if err := ioutil.WriteFile(output, append(append([]byte{}, want[:offset]...), want[offset:offset+16]...), 0644); err != nil {
+		t.Fatalf("failed to write partial output: %v", err)
+	}
+	blob, _ := json.Marshal(&TraceJob{
+		ID:      "0x01",
+		End:     8,
+		Config:  &config.TraceConfig,
+		Output:  output,
+		Status:  TraceJobRunning,
+		Current: 3,
+		Offset:  int64(offset),
+	})
+	rawdb.WriteTraceJob(eth.chainDb, "0x01", blob)
+
+	// Restart the manager and check that the job continues from its checkpoint
+	m = newTraceJobManager(eth, eth.chainDb, dir)
+	m.start()
+
+	job = waitTraceJob(t, m, "0x01")
+	if job.Status != TraceJobDone || job.Error != "" {
+		t.Fatalf("job status mismatch: have %s (%s), want %s", job.Status, job.Error, TraceJobDone)
+	}
+	have := checkTraceJobOutput(t, eth, output, 0, 8)
+	if !bytes.Equal(have[:offset], want[:offset]) {
+		t.Errorf("checkpointed output mismatch:\nhave %s\nwant %s", have[:offset], want[:offset])
+	}
+	if job.Offset != int64(len(have)) {
+		t.Errorf("offset mismatch: have %d, want %d", job.Offset, len(have))
+	}
+}
+
+// Tests that cancelling a job marks it cancelled for good, whereas a shutdown
+// leaves it running to be resumed by the next manager.
+func TestTraceJobCancelStop(t *testing.T) {
+	eth, m, dir := newTraceJobTest(t, 8)
+	defer eth.blockchain.Stop()
+	defer os.RemoveAll(dir)
+
+	tracer := traceJobGateTracer
+	config := &TraceJobConfig{TraceConfig: TraceConfig{Tracer: &tracer}}
+
+	// Cancel a job held in the middle of the tracing
+	gate := newTraceJobGate()
+	job, err := m.submit(eth.blockchain.Genesis(), eth.blockchain.GetBlockByNumber(8), config)
+	if err != nil {
+		t.Fatalf("failed to submit job: %v", err)
+	}
+	if err := m.cancel(job.ID); err != nil {
+		t.Fatalf("failed to cancel job: %v", err)
+	}
+	if err := m.cancel(job.ID); err == nil {
+		t.Errorf("cancelled a job twice")
+	}
+	close(gate)
+
+	job = waitTraceJob(t, m, job.ID)
+	if job.Status != TraceJobCancelled || job.Error != "" {
+		t.Errorf("cancelled job status mismatch: have %s (%s), want %s", job.Status, job.Error, TraceJobCancelled)
+	}
+	if stored := persistedTraceJob(t, m, job.ID); stored.Status != TraceJobCancelled {
+		t.Errorf("persisted cancelled job status mismatch: have %s, want %s", stored.Status, TraceJobCancelled)
+	}
+	// Shut the manager down while another job is held
+	gate = newTraceJobGate()
+	job, err = m.submit(eth.blockchain.Genesis(), eth.blockchain.GetBlockByNumber(8), config)
+	if err != nil {
+		t.Fatalf("failed to submit job: %v", err)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		m.stop()
+		close(stopped)
+	}()
+	for {
+		m.lock.Lock()
+		interrupted := len(m.running) == 0
+		m.lock.Unlock()
+		if interrupted {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(gate)
+	<-stopped
+
+	if job, _ = m.status(job.ID); job.Status != TraceJobRunning {
+		t.Errorf("interrupted job status mismatch: have %s, want %s", job.Status, TraceJobRunning)
+	}
+	stored := persistedTraceJob(t, m, job.ID)
+	if stored.Status != TraceJobRunning || stored.Current != job.Current || stored.Offset != job.Offset {
+		t.Errorf("persisted checkpoint mismatch: have %+v, want %+v", stored, job)
+	}
+	// Restart the manager and check that only the interrupted job resumes
+	m = newTraceJobManager(eth, eth.chainDb, dir)
+	m.start()
+
+	if job = waitTraceJob(t, m, job.ID); job.Status != TraceJobDone {
+		t.Fatalf("resumed job status mismatch: have %s (%s), want %s", job.Status, job.Error, TraceJobDone)
+	}
+	checkTraceJobOutput(t, eth, job.Output, 0, 8)
+
+	if jobs := m.list(); len(jobs) != 2 {
+		t.Errorf("job count mismatch: have %d, want 2", len(jobs))
+	}
+}
+
+// Tests that job outputs are confined to the trace job root directory.
+func TestTraceJobOutputDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "tracejobs")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	outside, err := ioutil.TempDir("", "tracejobs-outside")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(outside)
+
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	m := &traceJobManager{dir: root}
+
+	tests := []struct {
+		dir  string
+		want string // Resolved directory, empty if rejected
+	}{
+		{"", root},
+		{"a/b", filepath.Join(root, "a", "b")},
+		{"a/../c", filepath.Join(root, "c")},
+		{filepath.Join(root, "d"), filepath.Join(root, "d")},
+		{"..", ""},
+		{"../x", ""},
+		{"a/../../x", ""},
+		{outside, ""},
+		{"link", ""},
+		{"link/x", ""},
+	}
+	for i, tt := range tests {
+		dir, err := m.outputDir(tt.dir)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("test %d (%q): accepted directory outside of the root: %s", i, tt.dir, dir)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d (%q): failed to resolve directory: %v", i, tt.dir, err)
+			continue
+		}
+		if dir != tt.want {
+			t.Errorf("test %d (%q): directory mismatch: have %s, want %s", i, tt.dir, dir, tt.want)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Errorf("test %d (%q): directory not created: %v", i, tt.dir, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "x")); !os.IsNotExist(err) {
+		t.Errorf("directory created outside of the root: %v", err)
+	}
+}
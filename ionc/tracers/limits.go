@@ -0,0 +1,96 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import "fmt"
+
+// Kinds of tracer failures.
+const (
+	ErrorDisabled  = "disabled"  // Custom tracer code is not allowed by the node
+	ErrorException = "exception" // The tracer code threw an exception
+	ErrorTimeout   = "timeout"   // Tracing exceeded its wall time
+	ErrorSteps     = "steps"     // The tracer exceeded its step budget
+	ErrorMemory    = "memory"    // The tracer state exceeded its memory limit
+	ErrorOutput    = "output"    // The tracer result exceeded its size limit
+)
+
+// tracerErrorCode is the JSON-RPC error code of the tracer failures.
+const tracerErrorCode = -32015
+
+// Error is a tracer failure, reported over RPC along with its kind and the limit
+// exceeded, if any, so that clients can tell resource exhaustion apart from bugs
+// in their tracer code.
+type Error struct {
+	Kind    string `json:"kind"`
+	Limit   uint64 `json:"limit,omitempty"` // Limit exceeded, for resource exhaustions
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ErrorCode returns the JSON-RPC error code of tracer failures.
+func (e *Error) ErrorCode() int {
+	return tracerErrorCode
+}
+
+// ErrorData returns the kind of the failure and the limit exceeded.
+func (e *Error) ErrorData() interface{} {
+	return e
+}
+
+// limitError creates a tracer failure for an exceeded resource limit.
+func limitError(kind string, limit uint64) *Error {
+	return &Error{Kind: kind, Limit: limit, Message: fmt.Sprintf("tracer exceeded %s limit (%d)", kind, limit)}
+}
+
+// ErrCustomTracerDisabled is returned if a tracer is neither native nor built
+// into the node, and running custom tracer code is not allowed.
+var ErrCustomTracerDisabled = &Error{Kind: ErrorDisabled, Message: "custom tracer code is disabled"}
+
+// TimeoutError creates a tracer failure for exceeding the wall time limit, given
+// in milliseconds.
+func TimeoutError(limit uint64) *Error {
+	return &Error{Kind: ErrorTimeout, Limit: limit, Message: "execution timeout"}
+}
+
+// Limits are the resource limits of a JavaScript tracer, zero meaning unlimited.
+//
+// The duktape heap can't be metered directly, so the memory is approximated by
+// the JSON encoded size of the tracer object and of the global variables, sampled
+// at increasing intervals of steps and before the result is assembled. The limit
+// may thus be overshot by a fraction of the state gathered. State that can't be
+// encoded, such as cyclic objects, counts as exceeding the limit. Variables
+// captured in closures are out of reach of the measurement, only the step limit
+// bounds them.
+type Limits struct {
+	Steps  uint64 // Number of step and fault invocations
+	Memory uint64 // Size of the tracer state in bytes
+	Output uint64 // Size of the JSON result in bytes
+}
+
+// IsBuiltin returns whether the tracer is a native or JavaScript tracer shipped
+// with the node, rather than custom tracer code.
+func IsBuiltin(code string) bool {
+	if _, ok := native[code]; ok {
+		return true
+	}
+	_, ok := tracer(code)
+	return ok
+}
@@ -0,0 +1,166 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/log"
+)
+
+// ResultTracer is a transaction tracer assembling a JSON result, implemented by
+// both the JavaScript and the native tracers.
+type ResultTracer interface {
+	vm.Tracer
+
+	// GetResult returns the result of the trace, or any accumulated error.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop(err error)
+}
+
+// NativeConstructor creates a native tracer operating on the state database the
+// traced transaction is executed on, configured by tracer specific options.
+type NativeConstructor func(statedb vm.StateDB, config json.RawMessage) (ResultTracer, error)
+
+// native contains all the registered native tracers by name.
+var native = make(map[string]NativeConstructor)
+
+// RegisterNative makes a native tracer available by name. Native tracers take
+// precedence over the JavaScript ones registered under the same name.
+func RegisterNative(name string, ctor NativeConstructor) {
+	native[name] = ctor
+}
+
+// NewTracer creates the native tracer registered under the given name, or falls
+// back to interpreting the code as a JavaScript tracer. The configuration is
+// only supported by native tracers.
+func NewTracer(code string, statedb vm.StateDB, config json.RawMessage) (ResultTracer, error) {
+	if ctor, ok := native[code]; ok {
+		return ctor(statedb, config)
+	}
+	if len(config) > 0 && string(config) != "null" {
+		return nil, errors.New("tracer config only supported by native tracers")
+	}
+	return New(code)
+}
+
+// nativeTracer contains the interruption handling shared by the native tracers.
+type nativeTracer struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+	err       error  // Error, if one has occurred
+
+	precompiles map[common.Address]struct{} // Precompiles active in the traced block, resolved lazily
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *nativeTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// stopped returns whether tracing was interrupted, setting the error if so.
+func (t *nativeTracer) stopped() bool {
+	if t.err != nil {
+		return true
+	}
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.err = t.reason
+		return true
+	}
+	return false
+}
+
+// CaptureStart implements the Tracer interface, ignoring the event.
+func (t *nativeTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the Tracer interface, ignoring the event.
+func (t *nativeTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rdata []byte, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureFault implements the Tracer interface, ignoring the event.
+func (t *nativeTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the Tracer interface, ignoring the event.
+func (t *nativeTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// peekStack returns the nth-from-the-top element of the stack, or zero if the
+// stack is too shallow.
+func peekStack(stack *vm.Stack, n int) *uint256.Int {
+	if len(stack.Data()) <= n {
+		log.Warn("Tracer accessed out of bound stack", "size", len(stack.Data()), "index", n)
+		return new(uint256.Int)
+	}
+	return stack.Back(n)
+}
+
+// peekAddress returns the nth-from-the-top element of the stack as an address.
+func peekAddress(stack *vm.Stack, n int) common.Address {
+	return common.Address(peekStack(stack, n).Bytes20())
+}
+
+// memorySlice returns a copy of the requested memory range, or nil if it is out
+// of bounds.
+func memorySlice(memory *vm.Memory, offset, size uint64) []byte {
+	if size == 0 {
+		return []byte{}
+	}
+	if offset+size < offset || uint64(memory.Len()) < offset+size {
+		log.Warn("Tracer accessed out of bound memory", "available", memory.Len(), "offset", offset, "size", size)
+		return nil
+	}
+	return memory.GetCopy(int64(offset), int64(size))
+}
+
+// isPrecompiled returns whether the address is one of the precompiled contracts
+// active in the traced block, which tracers treat as fancy opcodes rather than
+// calls.
+func (t *nativeTracer) isPrecompiled(env *vm.EVM, addr common.Address) bool {
+	if t.precompiles == nil {
+		t.precompiles = activePrecompiles(env)
+	}
+	_, ok := t.precompiles[addr]
+	return ok
+}
+
+// activePrecompiles returns the set of precompiles active in the block executed
+// by the given EVM.
+func activePrecompiles(env *vm.EVM) map[common.Address]struct{} {
+	rules := env.ChainConfig().Rules(env.Context.BlockNumber)
+
+	precompiles := make(map[common.Address]struct{})
+	for _, addr := range vm.ActivePrecompiles(rules) {
+		precompiles[addr] = struct{}{}
+	}
+	return precompiles
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/core/vm"
+)
+
+func init() {
+	RegisterNative("4byteTracer", newFourByteTracer)
+}
+
+// fourByteTracer is a native implementation of the JavaScript 4byte tracer,
+// which counts the 4 byte method identifiers and call data sizes of all the
+// calls made by a transaction.
+type fourByteTracer struct {
+	nativeTracer
+
+	ids map[string]int // Number of calls by "<id>-<size>" key
+}
+
+// newFourByteTracer creates a native 4byte tracer.
+func newFourByteTracer(statedb vm.StateDB, config json.RawMessage) (ResultTracer, error) {
+	return &fourByteTracer{ids: make(map[string]int)}, nil
+}
+
+// store counts a call with the given method identifier and data size.
+func (t *fourByteTracer) store(id []byte, size uint64) {
+	t.ids[hexutil.Encode(id)+"-"+strconv.FormatUint(size, 10)]++
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *fourByteTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	// Save the outer calldata also
+	if len(input) >= 4 {
+		t.store(input[:4], uint64(len(input)-4))
+	}
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rdata []byte, contract *vm.Contract, depth int, err error) error {
+	if t.stopped() || err != nil {
+		return nil
+	}
+	// Skip any opcodes that are not internal calls, find the input otherwise
+	var in int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		in = 3
+	case vm.DELEGATECALL, vm.STATICCALL:
+		in = 2
+	default:
+		return nil
+	}
+	// Skip any pre-compile invocations, those are just fancy opcodes
+	if t.isPrecompiled(env, peekAddress(stack, 1)) {
+		return nil
+	}
+	if size := peekStack(stack, in+1).Uint64(); size >= 4 {
+		if id := memorySlice(memory, peekStack(stack, in).Uint64(), 4); id != nil {
+			t.store(id, size-4)
+		}
+	}
+	return nil
+}
+
+// GetResult returns the number of calls by method identifier and data size.
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return json.Marshal(t.ids)
+}
@@ -0,0 +1,280 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/core/vm"
+)
+
+func init() {
+	RegisterNative("callTracer", newCallTracer)
+}
+
+// callFrame is a single call made during a transaction, serialized in the same
+// form as by the JavaScript call tracer.
+type callFrame struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Gas     *hexutil.Uint64 `json:"gas,omitempty"`
+	GasUsed *hexutil.Uint64 `json:"gasUsed,omitempty"`
+	Input   *hexutil.Bytes  `json:"input,omitempty"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Time    string          `json:"time,omitempty"`
+	Calls   []*callFrame    `json:"calls,omitempty"`
+
+	gasIn   uint64 // Gas available before the call opcode
+	gasCost uint64 // Cost of the call opcode
+	outOff  uint64 // Memory offset of the call output
+	outLen  uint64 // Memory size of the call output
+}
+
+// callTracer is a native implementation of the JavaScript call tracer, which
+// reports all the internal calls made by a transaction.
+type callTracer struct {
+	nativeTracer
+
+	root      callFrame    // Outer call of the transaction
+	callstack []*callFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether we've just descended into an inner call
+}
+
+// newCallTracer creates a native call tracer.
+func newCallTracer(statedb vm.StateDB, config json.RawMessage) (ResultTracer, error) {
+	return &callTracer{callstack: []*callFrame{{}}}, nil
+}
+
+// push adds a call to the call stack.
+func (t *callTracer) push(call *callFrame) {
+	t.callstack = append(t.callstack, call)
+	t.descended = true
+}
+
+// pop removes the topmost call from the call stack.
+func (t *callTracer) pop() *callFrame {
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	return call
+}
+
+// top returns the topmost call of the call stack.
+func (t *callTracer) top() *callFrame {
+	return t.callstack[len(t.callstack)-1]
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.root = callFrame{
+		Type:  "CALL",
+		From:  from,
+		To:    &to,
+		Value: (*hexutil.Big)(new(big.Int).Set(value)),
+		Gas:   uint64Ptr(gas),
+		Input: bytesPtr(append([]byte{}, input...)),
+	}
+	if create {
+		t.root.Type = "CREATE"
+	}
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rdata []byte, contract *vm.Contract, depth int, err error) error {
+	if t.stopped() {
+		return nil
+	}
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		inOff, inLen := peekStack(stack, 1).Uint64(), peekStack(stack, 2).Uint64()
+		t.push(&callFrame{
+			Type:    op.String(),
+			From:    contract.Address(),
+			Input:   bytesPtr(memorySlice(memory, inOff, inLen)),
+			Value:   (*hexutil.Big)(peekStack(stack, 0).ToBig()),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		return nil
+
+	case vm.SELFDESTRUCT:
+		to := peekAddress(stack, 0)
+		parent := t.top()
+		parent.Calls = append(parent.Calls, &callFrame{
+			Type:  op.String(),
+			From:  contract.Address(),
+			To:    &to,
+			Value: (*hexutil.Big)(new(big.Int).Set(env.StateDB.GetBalance(contract.Address()))),
+		})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := peekAddress(stack, 1)
+		if t.isPrecompiled(env, to) {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		inOff, inLen := peekStack(stack, 2+off).Uint64(), peekStack(stack, 3+off).Uint64()
+		call := &callFrame{
+			Type:    op.String(),
+			From:    contract.Address(),
+			To:      &to,
+			Input:   bytesPtr(memorySlice(memory, inOff, inLen)),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  peekStack(stack, 4+off).Uint64(),
+			outLen:  peekStack(stack, 5+off).Uint64(),
+		}
+		if op == vm.CALL || op == vm.CALLCODE {
+			call.Value = (*hexutil.Big)(peekStack(stack, 2).ToBig())
+		}
+		t.push(call)
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve its true allowance. It
+	// needs to be extracted from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	// Calls to plain accounts have no steps, so their gas can't be determined.
+	if t.descended {
+		if depth >= len(t.callstack) {
+			t.top().Gas = uint64Ptr(gas)
+		}
+		t.descended = false
+	}
+	if op == vm.REVERT {
+		t.top().Error = "execution reverted"
+		return nil
+	}
+	if depth != len(t.callstack)-1 {
+		return nil
+	}
+	// An inner call returned, pop it off the call stack and get the results
+	call := t.pop()
+
+	ret := peekStack(stack, 0)
+	if call.Type == "CREATE" || call.Type == "CREATE2" {
+		call.GasUsed = uint64Ptr(call.gasIn - call.gasCost - gas)
+		if !ret.IsZero() {
+			to := common.Address(ret.Bytes20())
+			call.To = &to
+			call.Output = bytesPtr(append([]byte{}, env.StateDB.GetCode(to)...))
+		} else if call.Error == "" {
+			call.Error = "internal failure"
+		}
+	} else {
+		if call.Gas != nil {
+			call.GasUsed = uint64Ptr(call.gasIn - call.gasCost + uint64(*call.Gas) - gas)
+		}
+		if !ret.IsZero() {
+			call.Output = bytesPtr(memorySlice(memory, call.outOff, call.outLen))
+		} else if call.Error == "" {
+			call.Error = "internal failure"
+		}
+	}
+	parent := t.top()
+	parent.Calls = append(parent.Calls, call)
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	if t.stopped() {
+		return nil
+	}
+	t.fault(err)
+	return nil
+}
+
+// fault flattens the failed topmost call into its parent.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.top().Error != "" {
+		return
+	}
+	call := t.pop()
+	call.Error = err.Error()
+
+	// Consume all available gas
+	if call.Gas != nil {
+		call.GasUsed = call.Gas
+	}
+	if len(t.callstack) > 0 {
+		parent := t.top()
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	// Last call failed too, leave it in the stack
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	t.root.GasUsed = uint64Ptr(gasUsed)
+	t.root.Output = bytesPtr(append([]byte{}, output...))
+	t.root.Time = d.String()
+	if err != nil {
+		t.root.Error = err.Error()
+	}
+	return nil
+}
+
+// GetResult returns the outer call of the transaction, with all the inner calls
+// nested within.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	result := t.root
+	result.Calls = t.callstack[0].Calls
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	}
+	if result.Error != "" && (result.Error != "execution reverted" || result.Output == nil || len(*result.Output) == 0) {
+		result.Output = nil
+	}
+	return json.Marshal(&result)
+}
+
+// uint64Ptr returns a pointer to the hex form of the given number.
+func uint64Ptr(n uint64) *hexutil.Uint64 {
+	return (*hexutil.Uint64)(&n)
+}
+
+// bytesPtr returns a pointer to the hex form of the given bytes, or nil if the
+// bytes are nil.
+func bytesPtr(b []byte) *hexutil.Bytes {
+	if b == nil {
+		return nil
+	}
+	return (*hexutil.Bytes)(&b)
+}
@@ -0,0 +1,236 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/crypto"
+)
+
+func init() {
+	RegisterNative("prestateTracer", newPrestateTracer)
+}
+
+// prestateConfig are the options of the prestate tracer.
+type prestateConfig struct {
+	DiffMode bool `json:"diffMode"` // Report the pre and post state of the modified accounts only
+}
+
+// prestateAccount is the state of an account, serialized in the same form as
+// by the JavaScript prestate tracer.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// diffAccount is the state of an account in diff mode, omitting any unchanged
+// fields in the post state.
+type diffAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// prestateDiff is the result of the prestate tracer in diff mode.
+type prestateDiff struct {
+	Pre  map[common.Address]*diffAccount `json:"pre"`
+	Post map[common.Address]*diffAccount `json:"post"`
+}
+
+// prestateTracer is a native implementation of the JavaScript prestate tracer,
+// which reports the state of all the accounts accessed by a transaction prior
+// to its execution. In diff mode, both the pre and post state of the modified
+// accounts are reported.
+type prestateTracer struct {
+	nativeTracer
+
+	config  prestateConfig
+	statedb vm.StateDB
+	pre     map[common.Address]*prestateAccount
+	created common.Address // Contract created by the transaction, if any
+	create  bool           // Whether the transaction is a contract creation
+}
+
+// newPrestateTracer creates a native prestate tracer.
+func newPrestateTracer(statedb vm.StateDB, config json.RawMessage) (ResultTracer, error) {
+	t := &prestateTracer{
+		statedb: statedb,
+		pre:     make(map[common.Address]*prestateAccount),
+	}
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &t.config); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// lookupAccount injects the specified account into the prestate.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.pre[addr]; ok {
+		return
+	}
+	t.pre[addr] = &prestateAccount{
+		Balance: (*hexutil.Big)(new(big.Int).Set(t.statedb.GetBalance(addr))),
+		Nonce:   t.statedb.GetNonce(addr),
+		Code:    common.CopyBytes(t.statedb.GetCode(addr)),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage injects the specified storage entry of the given account into
+// the prestate.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.pre[addr].Storage[key]; !ok {
+		t.pre[addr].Storage[key] = t.statedb.GetState(addr, key)
+	}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+
+	// The value was already transferred and the caller's nonce bumped, revert
+	// those to get the actual pre state. The gas purchase can't be reverted, so
+	// the caller's balance is reported without the gas allowance.
+	fromBal, toBal := (*big.Int)(t.pre[from].Balance), (*big.Int)(t.pre[to].Balance)
+	toBal.Sub(toBal, value)
+	fromBal.Add(fromBal, value)
+	t.pre[from].Nonce--
+
+	// Any existing state of a created contract would have caused the transaction
+	// to be rejected as invalid in the first place
+	if create {
+		t.create, t.created = true, to
+	}
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rdata []byte, contract *vm.Contract, depth int, err error) error {
+	if t.stopped() || err != nil {
+		return nil
+	}
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.EXTCODEHASH, vm.BALANCE, vm.SELFDESTRUCT:
+		t.lookupAccount(peekAddress(stack, 0))
+
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, t.statedb.GetNonce(from)))
+
+	case vm.CREATE2:
+		offset, size := peekStack(stack, 1).Uint64(), peekStack(stack, 2).Uint64()
+		salt := peekStack(stack, 3).Bytes32()
+		t.lookupAccount(crypto.CreateAddress2(contract.Address(), salt, crypto.Keccak256(memorySlice(memory, offset, size))))
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(peekAddress(stack, 1))
+
+	case vm.SLOAD, vm.SSTORE:
+		t.lookupStorage(contract.Address(), common.Hash(peekStack(stack, 0).Bytes32()))
+	}
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the assembled prestate, or the pre and post state of the
+// modified accounts in diff mode.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.create {
+		delete(t.pre, t.created)
+	}
+	if !t.config.DiffMode {
+		return json.Marshal(t.pre)
+	}
+	return json.Marshal(t.diff())
+}
+
+// diff compares the prestate of the accessed accounts to their current state,
+// which is the post state once the transaction was executed.
+func (t *prestateTracer) diff() *prestateDiff {
+	result := &prestateDiff{
+		Pre:  make(map[common.Address]*diffAccount),
+		Post: make(map[common.Address]*diffAccount),
+	}
+	if t.create {
+		t.pre[t.created] = &prestateAccount{Balance: new(hexutil.Big), Storage: make(map[common.Hash]common.Hash)}
+	}
+	for addr, pre := range t.pre {
+		var (
+			post     = new(diffAccount)
+			modified bool
+		)
+		if balance := t.statedb.GetBalance(addr); balance.Cmp((*big.Int)(pre.Balance)) != 0 {
+			post.Balance, modified = (*hexutil.Big)(new(big.Int).Set(balance)), true
+		}
+		if nonce := t.statedb.GetNonce(addr); nonce != pre.Nonce {
+			post.Nonce, modified = nonce, true
+		}
+		if code := t.statedb.GetCode(addr); !bytes.Equal(code, pre.Code) {
+			post.Code, modified = common.CopyBytes(code), true
+		}
+		storage := make(map[common.Hash]common.Hash)
+		for key, val := range pre.Storage {
+			if have := t.statedb.GetState(addr, key); have != val {
+				if post.Storage == nil {
+					post.Storage = make(map[common.Hash]common.Hash)
+				}
+				post.Storage[key], storage[key], modified = have, val, true
+			}
+		}
+		suicided := t.statedb.HasSuicided(addr)
+		if !modified && !suicided {
+			continue
+		}
+		// Accounts which didn't exist before have no pre state, destructed ones
+		// have no post state
+		if pre.Balance.ToInt().Sign() != 0 || pre.Nonce != 0 || len(pre.Code) != 0 || len(storage) != 0 {
+			result.Pre[addr] = &diffAccount{
+				Balance: pre.Balance,
+				Nonce:   pre.Nonce,
+				Code:    pre.Code,
+			}
+			if len(storage) > 0 {
+				result.Pre[addr].Storage = storage
+			}
+		}
+		if !suicided {
+			result.Post[addr] = post
+		}
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/vm"
+)
+
+func init() {
+	RegisterNative("profile", newProfileTracer)
+}
+
+// profileConfig are the options of the profile tracer.
+type profileConfig struct {
+	Format string `json:"format"` // Either "json" (default) or "folded"
+	Weight string `json:"weight"` // Weight of the folded stacks, "gas" (default) or "time"
+}
+
+// profileTracer attributes the gas and wall time spent by a transaction to the
+// opcodes, contracts and program counters executing them, reporting either the
+// aggregated profile or the folded call stacks for flame graph tools.
+type profileTracer struct {
+	nativeTracer
+
+	config   profileConfig
+	profiler *vm.Profiler
+}
+
+// newProfileTracer creates a native profile tracer.
+func newProfileTracer(statedb vm.StateDB, config json.RawMessage) (ResultTracer, error) {
+	t := &profileTracer{
+		config:   profileConfig{Format: "json", Weight: "gas"},
+		profiler: vm.NewProfiler(),
+	}
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &t.config); err != nil {
+			return nil, err
+		}
+	}
+	if t.config.Format != "json" && t.config.Format != "folded" {
+		return nil, fmt.Errorf("unknown profile format %q", t.config.Format)
+	}
+	if t.config.Weight != "gas" && t.config.Weight != "time" {
+		return nil, fmt.Errorf("unknown profile weight %q", t.config.Weight)
+	}
+	return t, nil
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *profileTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return t.profiler.CaptureStart(from, to, create, input, gas, value)
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *profileTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rdata []byte, contract *vm.Contract, depth int, err error) error {
+	if t.stopped() {
+		return nil
+	}
+	return t.profiler.CaptureState(env, pc, op, gas, cost, memory, stack, rStack, rdata, contract, depth, err)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *profileTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return t.profiler.CaptureEnd(output, gasUsed, d, err)
+}
+
+// GetResult returns the profile, or the folded call stacks as a string.
+func (t *profileTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.config.Format == "folded" {
+		var folded strings.Builder
+		if err := t.profiler.WriteFolded(&folded, t.config.Weight); err != nil {
+			return nil, err
+		}
+		return json.Marshal(folded.String())
+	}
+	return json.Marshal(t.profiler.Result())
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core"
+	"github.com/ionchain/ionchain-core/core/rawdb"
+	"github.com/ionchain/ionchain-core/core/state"
+	"github.com/ionchain/ionchain-core/core/types"
+	"github.com/ionchain/ionchain-core/core/vm"
+	"github.com/ionchain/ionchain-core/params"
+	"github.com/ionchain/ionchain-core/rlp"
+)
+
+// nativeTracerTest is a call tracer test case, decoding only the parts of the
+// genesis needed to replay the transaction.
+type nativeTracerTest struct {
+	Genesis struct {
+		Config *params.ChainConfig `json:"config"`
+		Alloc  core.GenesisAlloc   `json:"alloc"`
+	} `json:"genesis"`
+	Context *callContext `json:"context"`
+	Input   string       `json:"input"`
+	Result  *callTrace   `json:"result"`
+}
+
+// makeNativeTestState creates a state database filled with the given accounts.
+func makeNativeTestState(t *testing.T, alloc core.GenesisAlloc) *state.StateDB {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	for addr, account := range alloc {
+		statedb.SetCode(addr, account.Code)
+		statedb.SetNonce(addr, account.Nonce)
+		if account.Balance != nil {
+			statedb.SetBalance(addr, account.Balance)
+		}
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	statedb, err = state.New(root, statedb.Database(), nil)
+	if err != nil {
+		t.Fatalf("failed to reopen state: %v", err)
+	}
+	return statedb
+}
+
+// runCallTracerTest executes the transaction of a call tracer test case with
+// the given tracer, returning the parsed trace.
+func runCallTracerTest(t *testing.T, test *nativeTracerTest, tracer ResultTracer) *callTrace {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+	txContext := vm.TxContext{
+		Origin:   origin,
+		GasPrice: tx.GasPrice(),
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+	}
+	statedb := makeNativeTestState(t, test.Genesis.Alloc)
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	trace := new(callTrace)
+	if err := json.Unmarshal(res, trace); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	return trace
+}
+
+// Iterates over all the input-output datasets of the JavaScript call tracer and
+// ensures the native call tracer produces the very same traces.
+func TestNativeCallTracer(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(nativeTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			jsTracer, err := New("callTracer")
+			if err != nil {
+				t.Fatalf("failed to create javascript call tracer: %v", err)
+			}
+			nativeTracer, err := NewTracer("callTracer", nil, nil)
+			if err != nil {
+				t.Fatalf("failed to create native call tracer: %v", err)
+			}
+			want := runCallTracerTest(t, test, jsTracer)
+			have := runCallTracerTest(t, test, nativeTracer)
+			if !jsonEqual(have, want) {
+				t.Fatalf("native trace mismatch: \nhave %+v\nwant %+v", have, want)
+			}
+			if !jsonEqual(have, test.Result) {
+				t.Fatalf("trace mismatch: \nhave %+v\nwant %+v", have, test.Result)
+			}
+		})
+	}
+}
+
+// Tests that the native tracers treat exactly the precompiles active in the
+// traced block as such, including the IPos staking precompile.
+func TestNativePrecompiles(t *testing.T) {
+	config := *params.TestChainConfig
+	config.IPosStakingBlock = big.NewInt(10)
+
+	tests := []struct {
+		number uint64
+		addr   common.Address
+		want   bool
+	}{
+		{9, common.BytesToAddress([]byte{1}), true},
+		{9, common.BytesToAddress([]byte{9}), true},
+		{9, common.BytesToAddress([]byte{10}), false},
+		{9, vm.IPosStakingAddress, false},
+		{10, vm.IPosStakingAddress, true},
+		{10, common.BytesToAddress([]byte{1}), true},
+	}
+	for i, tt := range tests {
+		context := vm.BlockContext{BlockNumber: new(big.Int).SetUint64(tt.number)}
+		evm := vm.NewEVM(context, vm.TxContext{}, nil, &config, vm.Config{})
+
+		tracer := new(nativeTracer)
+		if have := tracer.isPrecompiled(evm, tt.addr); have != tt.want {
+			t.Errorf("test %d: block #%d, %x: precompile mismatch: have %v, want %v", i, tt.number, tt.addr, have, tt.want)
+		}
+	}
+}
@@ -292,6 +292,7 @@ type Tracer struct {
 
 	tracerObject int // Stack index of the tracer JavaScript object
 	stateObject  int // Stack index of the global state to pull arguments from
+	sizeObject   int // Stack index of the function measuring the tracer state
 
 	opWrapper       *opWrapper       // Wrapper around the VM opcode
 	stackWrapper    *stackWrapper    // Wrapper around the VM stack
@@ -309,8 +310,14 @@ type Tracer struct {
 	ctx map[string]interface{} // Transaction context gathered throughout execution
 	err error                  // Error, if one has occurred
 
+	precompiles map[common.Address]struct{} // Precompiles active in the traced block
+
 	interrupt uint32 // Atomic flag to signal execution interruption
 	reason    error  // Textual reason for the interruption
+
+	limits Limits // Resource limits of the tracer
+	steps  uint64 // Number of step and fault invocations so far
+	sample uint64 // Number of steps to measure the tracer state at
 }
 
 // New instantiates a new tracer instance. code specifies a Javascript snippet,
@@ -399,7 +406,7 @@ func New(code string) (*Tracer, error) {
 		return 1
 	})
 	tracer.vm.PushGlobalGoFunction("isPrecompiled", func(ctx *duktape.Context) int {
-		_, ok := vm.PrecompiledContractsIstanbul[common.BytesToAddress(popSlice(ctx))]
+		_, ok := tracer.precompiles[common.BytesToAddress(popSlice(ctx))]
 		ctx.PushBoolean(ok)
 		return 1
 	})
@@ -493,9 +500,63 @@ func New(code string) (*Tracer, error) {
 	tracer.dbWrapper.pushObject(tracer.vm)
 	tracer.vm.PutPropString(tracer.stateObject, "db")
 
+	// Push the function measuring the tracer state as object #2 into the JSVM stack.
+	// Called without a receiver, this is the global object holding the variables
+	// the tracer defined (the functions set up above aren't encoded).
+	if err := tracer.vm.PevalString("(function(obj) { return JSON.stringify(obj).length + JSON.stringify(this).length; })"); err != nil {
+		return nil, err
+	}
+	tracer.sizeObject = tracer.vm.GetTopIndex()
+
 	return tracer, nil
 }
 
+// SetLimits configures the resource limits of the tracer.
+func (jst *Tracer) SetLimits(limits Limits) {
+	jst.limits = limits
+}
+
+// metered accounts a step or fault invocation against the resource limits of
+// the tracer, setting the error if any of them is exceeded.
+func (jst *Tracer) metered() bool {
+	jst.steps++
+	if jst.limits.Steps > 0 && jst.steps > jst.limits.Steps {
+		jst.err = limitError(ErrorSteps, jst.limits.Steps)
+		return false
+	}
+	if jst.limits.Memory > 0 && jst.steps >= jst.sample {
+		// Measuring is linear in the state size, so back off geometrically
+		jst.sample = jst.steps + jst.steps/8 + 1024
+		if jst.exceedsMemory() {
+			jst.err = limitError(ErrorMemory, jst.limits.Memory)
+			return false
+		}
+	}
+	return true
+}
+
+// exceedsMemory reports whether the tracer state exceeds the memory limit. State
+// which can't be measured is treated as exceeding it, otherwise it could be used
+// to hide the rest of the state from the measurement.
+func (jst *Tracer) exceedsMemory() bool {
+	size, ok := jst.size()
+	return !ok || size > jst.limits.Memory
+}
+
+// size returns the JSON encoded size of the tracer object and globals, or false
+// if they can't be encoded (e.g. they contain cycles).
+func (jst *Tracer) size() (uint64, bool) {
+	jst.vm.Dup(jst.sizeObject)
+	jst.vm.Dup(jst.tracerObject)
+	code := jst.vm.Pcall(1)
+	defer jst.vm.Pop()
+
+	if code != 0 {
+		return 0, false
+	}
+	return uint64(jst.vm.GetNumber(-1)), true
+}
+
 // Stop terminates execution of the tracer at the first opportune moment.
 func (jst *Tracer) Stop(err error) {
 	jst.reason = err
@@ -521,8 +582,9 @@ func (jst *Tracer) call(method string, args ...string) (json.RawMessage, error)
 	return json.RawMessage(jst.vm.JsonEncode(-1)), nil
 }
 
+// wrapError converts an exception thrown by the tracer code into a tracer failure.
 func wrapError(context string, err error) error {
-	return fmt.Errorf("%v    in server-side tracer function '%v'", err, context)
+	return &Error{Kind: ErrorException, Message: fmt.Sprintf("%v    in server-side tracer function '%v'", err, context)}
 }
 
 // CaptureStart implements the Tracer interface to initialize the tracing operation.
@@ -546,6 +608,7 @@ func (jst *Tracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost
 		// Initialize the context if it wasn't done yet
 		if !jst.inited {
 			jst.ctx["block"] = env.Context.BlockNumber.Uint64()
+			jst.precompiles = activePrecompiles(env)
 			jst.inited = true
 		}
 		// If tracing was interrupted, set the error and stop
@@ -570,6 +633,9 @@ func (jst *Tracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost
 			jst.errorValue = new(string)
 			*jst.errorValue = err.Error()
 		}
+		if !jst.metered() {
+			return nil
+		}
 		_, err := jst.call("step", "log", "db")
 		if err != nil {
 			jst.err = wrapError("step", err)
@@ -586,6 +652,9 @@ func (jst *Tracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost
 		jst.errorValue = new(string)
 		*jst.errorValue = err.Error()
 
+		if !jst.metered() {
+			return nil
+		}
 		_, err := jst.call("fault", "log", "db")
 		if err != nil {
 			jst.err = wrapError("fault", err)
@@ -637,10 +706,16 @@ func (jst *Tracer) GetResult() (json.RawMessage, error) {
 	}
 	jst.vm.PutPropString(jst.stateObject, "ctx")
 
+	// Account the state gathered since the last sample, before it's used
+	if jst.err == nil && jst.limits.Memory > 0 && jst.exceedsMemory() {
+		jst.err = limitError(ErrorMemory, jst.limits.Memory)
+	}
 	// Finalize the trace and return the results
 	result, err := jst.call("result", "ctx", "db")
 	if err != nil {
 		jst.err = wrapError("result", err)
+	} else if jst.limits.Output > 0 && uint64(len(result)) > jst.limits.Output {
+		jst.err = limitError(ErrorOutput, jst.limits.Output)
 	}
 	// Clean up the JavaScript environment
 	jst.vm.DestroyHeap()
@@ -178,3 +178,37 @@ func TestHaltBetweenSteps(t *testing.T) {
 		t.Errorf("Expected timeout error, got %v", err)
 	}
 }
+
+func TestTracerLimits(t *testing.T) {
+	tests := []struct {
+		code   string
+		limits Limits
+		kind   string
+	}{
+		{"{steps: 0, step: function() { this.steps++; }, fault: function() {}, result: function() { return this.steps; }}", Limits{Steps: 3}, ""},
+		{"{steps: 0, step: function() { this.steps++; }, fault: function() {}, result: function() { return this.steps; }}", Limits{Steps: 2}, ErrorSteps},
+		{"{blob: '', step: function() { this.blob += 'x'; }, fault: function() {}, result: function() { return null; }}", Limits{Memory: 8}, ErrorMemory},
+		{"{step: function() {}, fault: function() {}, result: function() { return 'too long'; }}", Limits{Output: 8}, ErrorOutput},
+		{"{step: function() {}, fault: function() {}, result: function() { return null; }}", Limits{Memory: 1024}, ""},
+		{"{step: function() { this.self = this; }, fault: function() {}, result: function() { return null; }}", Limits{Memory: 1024}, ErrorMemory},
+		{"{step: function() { blob = (typeof blob === 'undefined' ? '' : blob) + 'xxxxxxxxxxxxxxxx'; }, fault: function() {}, result: function() { return null; }}", Limits{Memory: 32}, ErrorMemory},
+	}
+	for i, tt := range tests {
+		tracer, err := New(tt.code)
+		if err != nil {
+			t.Fatalf("test %d: failed to create tracer: %v", i, err)
+		}
+		tracer.SetLimits(tt.limits)
+
+		_, err = runTrace(tracer)
+		if tt.kind == "" {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if terr, ok := err.(*Error); !ok || terr.Kind != tt.kind {
+			t.Errorf("test %d: error mismatch: have %v, want %s limit", i, err, tt.kind)
+		}
+	}
+}
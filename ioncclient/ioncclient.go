@@ -401,6 +401,10 @@ func (ec *Client) SubscribeFilterLogs(ctx context.Context, q ionchain.FilterQuer
 	if err != nil {
 		return nil, err
 	}
+	if q.BlockHash == nil && q.FromBlock == nil {
+		// Only stream new logs, a starting block backfills the logs since that block
+		delete(arg.(map[string]interface{}), "fromBlock")
+	}
 	return ec.c.EthSubscribe(ctx, ch, "logs", arg)
 }
 
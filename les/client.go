@@ -177,6 +177,8 @@ func New(stack *node.Node, config *ionc.Config) (*LightIonchain, error) {
 	stack.RegisterAPIs(leth.APIs())
 	stack.RegisterProtocols(leth.Protocols())
 	stack.RegisterLifecycle(leth)
+	stack.RegisterHealthCheck("sync", ionc.SyncHealthCheck(leth.handler.downloader, stack.Config().HealthSyncBlocks))
+	stack.RegisterHealthCheck("head", ionc.HeadHealthCheck(leth.blockchain.CurrentHeader, stack.Config().HealthHeadBlocks))
 
 	return leth, nil
 }
@@ -239,7 +241,7 @@ func (s *LightIonchain) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true),
+			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true, s.config.RPCLogsRange, s.config.RPCLogsBackfill),
 			Public:    true,
 		}, {
 			Namespace: "net",
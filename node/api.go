@@ -45,6 +45,10 @@ func (n *Node) apis() []rpc.API {
 			Namespace: "debug",
 			Version:   "1.0",
 			Service:   debug.Handler,
+		}, {
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   &privateDebugAPI{n},
 		}, {
 			Namespace: "web3",
 			Version:   "1.0",
@@ -184,6 +188,9 @@ func (api *privateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 		CorsAllowedOrigins: api.node.config.HTTPCors,
 		Vhosts:             api.node.config.HTTPVirtualHosts,
 		Modules:            api.node.config.HTTPModules,
+		auth:               api.node.rpcAuth,
+		limiter:            api.node.rpcLimiter,
+		stats:              api.node.rpcStats,
 	}
 	if cors != nil {
 		config.CorsAllowedOrigins = nil
@@ -243,6 +250,9 @@ func (api *privateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 	config := wsConfig{
 		Modules: api.node.config.WSModules,
 		Origins: api.node.config.WSOrigins,
+		auth:    api.node.rpcAuth,
+		limiter: api.node.rpcLimiter,
+		stats:   api.node.rpcStats,
 		// ExposeAll: api.node.config.WSExposeAll,
 	}
 	if apis != nil {
@@ -311,6 +321,23 @@ func (api *publicAdminAPI) Datadir() string {
 	return api.node.DataDir()
 }
 
+// privateDebugAPI is the collection of debugging API methods of the node exposed
+// only over a secure RPC channel.
+type privateDebugAPI struct {
+	node *Node // Node interfaced by this API
+}
+
+// RpcStats returns the call statistics of the n slowest methods served over HTTP
+// and websocket, 10 by default. Methods with the most calls over the slow threshold
+// come first, then the ones with the highest mean latency.
+func (api *privateDebugAPI) RpcStats(n *int) []rpc.MethodStats {
+	limit := 10
+	if n != nil {
+		limit = *n
+	}
+	return api.node.rpcStats.Top(limit)
+}
+
 // publicWeb3API offers helper utils
 type publicWeb3API struct {
 	stack *Node
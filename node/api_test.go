@@ -0,0 +1,69 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ionchain/ionchain-core/rpc"
+)
+
+// Tests that the call statistics default to the 10 slowest methods, and that a
+// non-positive count returns all of them.
+func TestRpcStats(t *testing.T) {
+	stats := rpc.NewCallStats(0)
+
+	server := rpc.NewServer()
+	server.SetCallStats(stats)
+	for i := 0; i < 12; i++ {
+		if err := server.RegisterName(fmt.Sprintf("ns%d", i), new(authTestService)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := rpc.DialInProc(server)
+	defer server.Stop()
+	defer client.Close()
+
+	var res string
+	for i := 0; i < 12; i++ {
+		if err := client.Call(&res, fmt.Sprintf("ns%d_ping", i)); err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+	}
+	api := &privateDebugAPI{node: &Node{rpcStats: stats}}
+
+	count := func(n int) *int { return &n }
+	for _, tt := range []struct {
+		n    *int
+		want int
+	}{
+		{nil, 10},
+		{count(3), 3},
+		{count(20), 12},
+		{count(0), 12},
+		{count(-1), 12},
+	} {
+		label := "default"
+		if tt.n != nil {
+			label = fmt.Sprint(*tt.n)
+		}
+		if have := len(api.RpcStats(tt.n)); have != tt.want {
+			t.Errorf("count %s: length mismatch: have %d, want %d", label, have, tt.want)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ionchain/ionchain-core/accounts"
 	"github.com/ionchain/ionchain-core/accounts/external"
@@ -32,6 +33,7 @@ import (
 	//"github.com/ionchain/ionchain-core/accounts/scwallet"
 	//"github.com/ionchain/ionchain-core/accounts/usbwallet"
 	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/core/rawdb"
 	"github.com/ionchain/ionchain-core/crypto"
 	"github.com/ionchain/ionchain-core/log"
 	"github.com/ionchain/ionchain-core/p2p"
@@ -162,6 +164,68 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// RPCAuthFile is the path of a JSON file holding the HS256 JWT secret and the
+	// static API keys that HTTP and websocket RPC clients need to authenticate
+	// with, along with the namespaces and methods each of them may call. The
+	// handlers mounted on the HTTP server, such as GraphQL, accept any of them,
+	// only the health and readiness endpoints are left open. If the field is
+	// empty, the HTTP and websocket endpoints are not authenticated.
+	RPCAuthFile string `toml:",omitempty"`
+
+	// RPCBatchLimit is the maximum number of requests in a batch sent to the HTTP
+	// and websocket endpoints (0 = no limit).
+	RPCBatchLimit int `toml:",omitempty"`
+
+	// RPCResponseLimit is the maximum size in bytes of the response to a request
+	// or batch sent to the HTTP and websocket endpoints (0 = no limit).
+	RPCResponseLimit int `toml:",omitempty"`
+
+	// RPCRateLimit is the number of requests per second each HTTP and websocket
+	// client may send, clients being told apart by their authentication subject
+	// or IP address (0 = no limit).
+	RPCRateLimit float64 `toml:",omitempty"`
+
+	// RPCComputeLimit is the number of compute units per second each HTTP and
+	// websocket client may spend (0 = no limit). The cost of the methods is given
+	// by RPCComputeCosts, keyed by method or namespace, and is 1 if missing.
+	RPCComputeLimit float64        `toml:",omitempty"`
+	RPCComputeCosts map[string]int `toml:",omitempty"`
+
+	// RPCSlowThreshold is the duration above which calls served over HTTP and
+	// websocket are logged as slow (0 = don't log). The latency of the calls is
+	// available through debug_rpcStats regardless.
+	RPCSlowThreshold time.Duration `toml:",omitempty"`
+
+	// RPCTLSCert and RPCTLSKey are the PEM files of the certificate chain and
+	// private key the HTTP and websocket endpoints serve TLS with. If they are
+	// empty, the endpoints serve plaintext. The files are read again on ReloadTLS.
+	RPCTLSCert string `toml:",omitempty"`
+	RPCTLSKey  string `toml:",omitempty"`
+
+	// RPCTLSClientCA is a PEM bundle of certificate authorities. If it is set, HTTP
+	// and websocket clients must present a certificate issued by one of them. The
+	// namespaces the certificate subjects may call are given by RPCAuthFile;
+	// without it, every client with a valid certificate may call all methods.
+	RPCTLSClientCA string `toml:",omitempty"`
+
+	// HTTPHealth enables the /health and /ready endpoints of the HTTP server, which
+	// report the checks named by HealthChecks and ReadyChecks respectively and
+	// respond with 503 Service Unavailable if any of them fails.
+	HTTPHealth   bool     `toml:",omitempty"`
+	HealthChecks []string `toml:",omitempty"`
+	ReadyChecks  []string `toml:",omitempty"`
+
+	// HealthMinPeers is the number of peers below which the peers check fails.
+	HealthMinPeers int `toml:",omitempty"`
+
+	// HealthSyncBlocks is the number of blocks the chain may be behind the highest
+	// block announced by the peers before the sync check fails.
+	HealthSyncBlocks uint64 `toml:",omitempty"`
+
+	// HealthHeadBlocks is the age of the head block, in block times, above which
+	// the head check fails.
+	HealthHeadBlocks uint64 `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -179,6 +243,16 @@ type Config struct {
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
+	// AncientBlobStore is an optional blob store that databases opened with a
+	// freezer move their immutable chain segments into, instead of flat files in
+	// the ancient directory. It allows multiple nodes to share cold chain history.
+	AncientBlobStore rawdb.BlobStore `toml:"-"`
+
+	// AncientBlobDir is the directory of a blob store shared by multiple nodes,
+	// typically a network mount, used as AncientBlobStore if that isn't set. If
+	// it's a relative path, it's resolved against the data directory.
+	AncientBlobDir string `toml:",omitempty"`
+
 	staticNodesWarning     bool
 	trustedNodesWarning    bool
 	oldGethResourceWarning bool
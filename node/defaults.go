@@ -46,6 +46,17 @@ var DefaultConfig = Config{
 	WSPort:              DefaultWSPort,
 	WSModules:           []string{"net", "web3"},
 	GraphQLVirtualHosts: []string{"localhost"},
+	RPCComputeCosts: map[string]int{
+		"debug":             100,
+		"eth_getLogs":       20,
+		"eth_getFilterLogs": 20,
+		"eth_call":          10,
+		"eth_estimateGas":   10,
+	},
+	ReadyChecks:      []string{"sync", "head", "peers"},
+	HealthMinPeers:   1,
+	HealthSyncBlocks: 10,
+	HealthHeadBlocks: 20,
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   50,
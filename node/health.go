@@ -0,0 +1,103 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ionchain/ionchain-core/p2p"
+)
+
+// HealthCheck reports the state of a subsystem of the node to the health and
+// readiness endpoints of the HTTP server. It returns details of the state, which
+// must be JSON encodable, and an error if the subsystem is unhealthy.
+type HealthCheck func() (interface{}, error)
+
+// healthResult is the outcome of a health check.
+type healthResult struct {
+	Healthy bool        `json:"healthy"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// healthReport is the response body of the health and readiness endpoints.
+type healthReport struct {
+	Healthy bool                    `json:"healthy"`
+	Checks  map[string]healthResult `json:"checks"`
+}
+
+// healthHandler serves the report of a set of health checks.
+type healthHandler struct {
+	checks map[string]HealthCheck
+}
+
+// newHealthHandler creates a handler reporting the named checks among the
+// registered ones.
+func newHealthHandler(registered map[string]HealthCheck, names []string) (*healthHandler, error) {
+	h := &healthHandler{checks: make(map[string]HealthCheck, len(names))}
+	for _, name := range names {
+		check, ok := registered[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown health check %q", name)
+		}
+		h.checks[name] = check
+	}
+	return h, nil
+}
+
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	report := healthReport{Healthy: true, Checks: make(map[string]healthResult, len(h.checks))}
+	for name, check := range h.checks {
+		details, err := check()
+		result := healthResult{Healthy: err == nil, Details: details}
+		if err != nil {
+			result.Error = err.Error()
+			report.Healthy = false
+		}
+		report.Checks[name] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if !report.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// peersHealth is the state reported by the peers check.
+type peersHealth struct {
+	Peers    int `json:"peers"`
+	MinPeers int `json:"minPeers"`
+}
+
+// peersHealthCheck returns a health check failing if the node has fewer than the
+// given number of peers.
+func peersHealthCheck(server *p2p.Server, min int) HealthCheck {
+	return func() (interface{}, error) {
+		health := peersHealth{Peers: server.PeerCount(), MinPeers: min}
+		if health.Peers < min {
+			return health, fmt.Errorf("%d peers, need %d", health.Peers, min)
+		}
+		return health, nil
+	}
+}
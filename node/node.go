@@ -57,6 +57,13 @@ type Node struct {
 	ipc           *ipcServer  // Stores information about the ipc http server
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
+	rpcAuth    *rpcAuthenticator // Token verifier of the HTTP and WebSocket clients, nil if unauthenticated
+	rpcLimiter *rpc.Limiter      // Resource limits of the HTTP and WebSocket clients, nil if unlimited
+	rpcStats   *rpc.CallStats    // Latency statistics of the calls served over HTTP and WebSocket
+	rpcTLS     *rpcTLS           // TLS credentials of the HTTP and WebSocket endpoints, nil if plaintext
+
+	healthChecks map[string]HealthCheck // Checks reported by the health and readiness endpoints
+
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
 
@@ -102,11 +109,15 @@ func New(conf *Config) (*Node, error) {
 		log:           conf.Logger,
 		stop:          make(chan struct{}),
 		server:        &p2p.Server{Config: conf.P2P},
+		rpcStats:      rpc.NewCallStats(conf.RPCSlowThreshold),
 		databases:     make(map[*closeTrackingDB]struct{}),
 	}
 
-	// Register built-in APIs.
+	// Register built-in APIs and health checks.
 	node.rpcAPIs = append(node.rpcAPIs, node.apis()...)
+	node.healthChecks = map[string]HealthCheck{
+		"peers": peersHealthCheck(node.server, conf.HealthMinPeers),
+	}
 
 	// Acquire the instance directory lock.
 	if err := node.openDataDir(); err != nil {
@@ -337,12 +348,45 @@ func (n *Node) startRPC() error {
 		}
 	}
 
+	// Load the TLS credentials of the HTTP and WebSocket endpoints.
+	if n.config.RPCTLSCert != "" || n.config.RPCTLSKey != "" {
+		creds, err := newRPCTLS(n.config.RPCTLSCert, n.config.RPCTLSKey, n.config.RPCTLSClientCA)
+		if err != nil {
+			return err
+		}
+		n.rpcTLS = creds
+		n.http.tls, n.ws.tls = creds, creds
+	} else if n.config.RPCTLSClientCA != "" {
+		return errors.New("TLS client CA given without a TLS certificate")
+	}
+	// Load the credentials of the HTTP and WebSocket clients.
+	if n.config.RPCAuthFile != "" {
+		auth, err := loadRPCAuthenticator(n.config.RPCAuthFile)
+		if err != nil {
+			return err
+		}
+		n.rpcAuth = auth
+	}
+	// Share the resource limits among the HTTP and WebSocket endpoints.
+	if n.config.RPCBatchLimit > 0 || n.config.RPCResponseLimit > 0 || n.config.RPCRateLimit > 0 || n.config.RPCComputeLimit > 0 {
+		n.rpcLimiter = rpc.NewLimiter(rpc.Limits{
+			BatchItems:   n.config.RPCBatchLimit,
+			ResponseSize: n.config.RPCResponseLimit,
+			RequestRate:  n.config.RPCRateLimit,
+			ComputeRate:  n.config.RPCComputeLimit,
+			Costs:        n.config.RPCComputeCosts,
+		})
+	}
+
 	// Configure HTTP.
 	if n.config.HTTPHost != "" {
 		config := httpConfig{
 			CorsAllowedOrigins: n.config.HTTPCors,
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
+			auth:               n.rpcAuth,
+			limiter:            n.rpcLimiter,
+			stats:              n.rpcStats,
 		}
 		if err := n.http.setListenAddr(n.config.HTTPHost, n.config.HTTPPort); err != nil {
 			return err
@@ -350,6 +394,11 @@ func (n *Node) startRPC() error {
 		if err := n.http.enableRPC(n.rpcAPIs, config); err != nil {
 			return err
 		}
+		if n.config.HTTPHealth {
+			if err := n.enableHealth(); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Configure WebSocket.
@@ -358,6 +407,9 @@ func (n *Node) startRPC() error {
 		config := wsConfig{
 			Modules: n.config.WSModules,
 			Origins: n.config.WSOrigins,
+			auth:    n.rpcAuth,
+			limiter: n.rpcLimiter,
+			stats:   n.rpcStats,
 		}
 		if err := server.setListenAddr(n.config.WSHost, n.config.WSPort); err != nil {
 			return err
@@ -373,6 +425,23 @@ func (n *Node) startRPC() error {
 	return n.ws.start()
 }
 
+// enableHealth mounts the health and readiness endpoints on the HTTP server.
+func (n *Node) enableHealth() error {
+	health, err := newHealthHandler(n.healthChecks, n.config.HealthChecks)
+	if err != nil {
+		return err
+	}
+	ready, err := newHealthHandler(n.healthChecks, n.config.ReadyChecks)
+	if err != nil {
+		return err
+	}
+	n.http.mux.Handle("/health", health)
+	n.http.mux.Handle("/ready", ready)
+	n.http.handlerNames["/health"] = "Health checks"
+	n.http.handlerNames["/ready"] = "Health checks"
+	return nil
+}
+
 func (n *Node) wsServerForPort(port int) *httpServer {
 	if n.config.HTTPHost == "" || n.http.port == port {
 		return n.http
@@ -443,6 +512,14 @@ func (n *Node) RegisterAPIs(apis []rpc.API) {
 	n.rpcAPIs = append(n.rpcAPIs, apis...)
 }
 
+// APIs returns all the APIs registered on the node, including its own.
+func (n *Node) APIs() []rpc.API {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return append([]rpc.API{}, n.rpcAPIs...)
+}
+
 // RegisterHandler mounts a handler on the given path on the canonical HTTP server.
 //
 // The name of the handler is shown in a log message when the HTTP server starts
@@ -458,6 +535,18 @@ func (n *Node) RegisterHandler(name, path string, handler http.Handler) {
 	n.http.handlerNames[path] = name
 }
 
+// RegisterHealthCheck adds a check the health and readiness endpoints of the HTTP
+// server can report under the given name.
+func (n *Node) RegisterHealthCheck(name string, check HealthCheck) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.state != initializingState {
+		panic("can't register health check on running/stopped node")
+	}
+	n.healthChecks[name] = check
+}
+
 // Attach creates an RPC client attached to an in-process API handler.
 func (n *Node) Attach() (*rpc.Client, error) {
 	return rpc.DialInProc(n.inprocHandler), nil
@@ -512,15 +601,32 @@ func (n *Node) IPCEndpoint() string {
 
 // HTTPEndpoint returns the URL of the HTTP server.
 func (n *Node) HTTPEndpoint() string {
-	return "http://" + n.http.listenAddr()
+	return n.http.scheme("http") + "://" + n.http.listenAddr()
 }
 
 // WSEndpoint retrieves the current WS endpoint used by the protocol stack.
 func (n *Node) WSEndpoint() string {
 	if n.http.wsAllowed() {
-		return "ws://" + n.http.listenAddr()
+		return n.http.scheme("ws") + "://" + n.http.listenAddr()
 	}
-	return "ws://" + n.ws.listenAddr()
+	return n.ws.scheme("ws") + "://" + n.ws.listenAddr()
+}
+
+// ReloadTLS reads the TLS certificate, key and client CA bundle of the HTTP and
+// WebSocket endpoints from their files again. Connections established afterwards
+// use the new credentials. It does nothing if the endpoints don't serve TLS.
+func (n *Node) ReloadTLS() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.rpcTLS == nil {
+		return nil
+	}
+	if err := n.rpcTLS.reload(); err != nil {
+		return err
+	}
+	n.log.Info("Reloaded RPC TLS credentials", "cert", n.config.RPCTLSCert)
+	return nil
 }
 
 // EventMux retrieves the event multiplexer used by all the network services in
@@ -571,13 +677,23 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer,
 		db = rawdb.NewMemoryDatabase()
 	} else {
 		root := n.ResolvePath(name)
-		switch {
-		case freezer == "":
-			freezer = filepath.Join(root, "ancient")
-		case !filepath.IsAbs(freezer):
-			freezer = n.ResolvePath(freezer)
+		store := n.config.AncientBlobStore
+		if store == nil && n.config.AncientBlobDir != "" {
+			if store, err = rawdb.NewDirBlobStore(n.ResolvePath(n.config.AncientBlobDir)); err != nil {
+				return nil, err
+			}
+		}
+		if store != nil {
+			db, err = rawdb.NewLevelDBDatabaseWithBlobFreezer(root, cache, handles, store, namespace)
+		} else {
+			switch {
+			case freezer == "":
+				freezer = filepath.Join(root, "ancient")
+			case !filepath.IsAbs(freezer):
+				freezer = n.ResolvePath(freezer)
+			}
+			db, err = rawdb.NewLevelDBDatabaseWithFreezer(root, cache, handles, freezer, namespace)
 		}
-		db, err = rawdb.NewLevelDBDatabaseWithFreezer(root, cache, handles, freezer, namespace)
 	}
 
 	if err == nil {
@@ -0,0 +1,239 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/log"
+	"github.com/ionchain/ionchain-core/rpc"
+)
+
+// jwtClockSkew is the tolerance allowed when checking the validity period of
+// JWT tokens.
+const jwtClockSkew = 5 * time.Second
+
+var (
+	errMissingToken = errors.New("missing authentication token")
+	errInvalidToken = errors.New("invalid authentication token")
+	errExpiredToken = errors.New("expired authentication token")
+)
+
+// rpcAuthFile is the content of the RPC authentication file:
+//
+//	{
+//	  "jwtSecret": "0x...",
+//	  "keys": [{"key": "...", "subject": "partner", "allow": ["eth", "txpool", "debug_trace*"]}],
+//	  "certs": [{"subject": "CN=indexer,O=IonChain", "allow": ["eth", "net"]}]
+//	}
+//
+// JWT tokens signed with the secret carry the allowed namespaces and methods in
+// their "allow" claim, and the client identity in the "sub" claim. Clients with a
+// TLS certificate verified against the client CA bundle are granted the methods
+// of their certificate's subject, given in full or as common name only, and need
+// no token.
+type rpcAuthFile struct {
+	JWTSecret hexutil.Bytes `json:"jwtSecret"`
+	Keys      []struct {
+		Key     string   `json:"key"`
+		Subject string   `json:"subject"`
+		Allow   []string `json:"allow"`
+	} `json:"keys"`
+	Certs []struct {
+		Subject string   `json:"subject"`
+		Allow   []string `json:"allow"`
+	} `json:"certs"`
+}
+
+// jwtClaims are the claims of the JWT tokens accepted by the RPC endpoints.
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Allow     []string `json:"allow"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+}
+
+// rpcAuthenticator verifies the tokens of the RPC clients, resolving them into
+// the methods they may call.
+type rpcAuthenticator struct {
+	secret []byte                  // HS256 secret of the JWT tokens, nil if disabled
+	keys   map[[32]byte]*rpc.Grant // Grants of the static API keys by key hash
+	certs  map[string]*rpc.Grant   // Grants of the client certificates by subject
+}
+
+// loadRPCAuthenticator creates an authenticator from the given file.
+func loadRPCAuthenticator(path string) (*rpcAuthenticator, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file rpcAuthFile
+	if err := json.Unmarshal(blob, &file); err != nil {
+		return nil, fmt.Errorf("invalid RPC auth file %s: %v", path, err)
+	}
+	auth := &rpcAuthenticator{
+		keys:  make(map[[32]byte]*rpc.Grant),
+		certs: make(map[string]*rpc.Grant),
+	}
+	if len(file.JWTSecret) > 0 {
+		if len(file.JWTSecret) < 32 {
+			return nil, fmt.Errorf("JWT secret too short: have %d bytes, want at least 32", len(file.JWTSecret))
+		}
+		auth.secret = file.JWTSecret
+	}
+	for i, key := range file.Keys {
+		if key.Key == "" {
+			return nil, fmt.Errorf("API key #%d is empty", i)
+		}
+		hash := sha256.Sum256([]byte(key.Key))
+		if _, ok := auth.keys[hash]; ok {
+			return nil, fmt.Errorf("API key #%d is duplicated", i)
+		}
+		auth.keys[hash] = &rpc.Grant{Subject: key.Subject, Allow: key.Allow}
+	}
+	for i, cert := range file.Certs {
+		if cert.Subject == "" {
+			return nil, fmt.Errorf("certificate subject #%d is empty", i)
+		}
+		if _, ok := auth.certs[cert.Subject]; ok {
+			return nil, fmt.Errorf("certificate subject %q is duplicated", cert.Subject)
+		}
+		auth.certs[cert.Subject] = &rpc.Grant{Subject: cert.Subject, Allow: cert.Allow}
+	}
+	return auth, nil
+}
+
+// authenticateCert resolves the verified client certificate of a TLS connection
+// into the grant of its subject.
+func (a *rpcAuthenticator) authenticateCert(state *tls.ConnectionState) (*rpc.Grant, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	subject := state.VerifiedChains[0][0].Subject
+	if grant, ok := a.certs[subject.String()]; ok {
+		return grant, true
+	}
+	if grant, ok := a.certs[subject.CommonName]; ok && subject.CommonName != "" {
+		return grant, true
+	}
+	return nil, false
+}
+
+// authenticate resolves a token, either an API key or a JWT, into its grant.
+func (a *rpcAuthenticator) authenticate(token string) (*rpc.Grant, error) {
+	if token == "" {
+		return nil, errMissingToken
+	}
+	// API keys are looked up by hash to avoid leaking them through timing
+	if grant, ok := a.keys[sha256.Sum256([]byte(token))]; ok {
+		return grant, nil
+	}
+	if a.secret == nil || strings.Count(token, ".") != 2 {
+		return nil, errInvalidToken
+	}
+	return a.verifyJWT(token, time.Now())
+}
+
+// verifyJWT checks the signature and validity period of an HS256 JWT token.
+func (a *rpcAuthenticator) verifyJWT(token string, now time.Time) (*rpc.Grant, error) {
+	parts := strings.Split(token, ".")
+
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if blob, err := base64.RawURLEncoding.DecodeString(parts[0]); err != nil || json.Unmarshal(blob, &header) != nil || header.Alg != "HS256" {
+		return nil, errInvalidToken
+	}
+	var claims jwtClaims
+	if blob, err := base64.RawURLEncoding.DecodeString(parts[1]); err != nil || json.Unmarshal(blob, &claims) != nil {
+		return nil, errInvalidToken
+	}
+	if claims.ExpiresAt != nil && now.Add(-jwtClockSkew).Unix() > *claims.ExpiresAt {
+		return nil, errExpiredToken
+	}
+	if claims.NotBefore != nil && now.Add(jwtClockSkew).Unix() < *claims.NotBefore {
+		return nil, errInvalidToken
+	}
+	return &rpc.Grant{Subject: claims.Subject, Allow: claims.Allow}, nil
+}
+
+// authExemptPaths are the paths of the handlers served without authentication,
+// the health and readiness probes of load balancers and orchestrators.
+var authExemptPaths = map[string]bool{"/health": true, "/ready": true}
+
+// newMuxAuthHandler returns a handler authenticating the requests to the handlers
+// registered via Node.RegisterHandler, such as GraphQL, like the JSON-RPC ones.
+// Any valid token is accepted, the granted methods only restrict JSON-RPC calls.
+// The health and readiness probes are exempt.
+func newMuxAuthHandler(auth *rpcAuthenticator, mux http.Handler) http.Handler {
+	authed := newAuthHandler(auth, mux)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authExemptPaths[r.URL.Path] {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		authed.ServeHTTP(w, r)
+	})
+}
+
+// newAuthHandler returns a handler rejecting requests without a valid token and
+// restricting the calls of the others to the methods granted to their token.
+// The token is taken from the bearer authorization header, or from the "token"
+// query parameter for websocket clients unable to set headers. Clients with a
+// client certificate listed in the auth file need no token.
+func newAuthHandler(auth *rpcAuthenticator, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if grant, ok := auth.authenticateCert(r.TLS); ok {
+			next.ServeHTTP(w, r.WithContext(rpc.WithGrant(r.Context(), grant)))
+			return
+		}
+		var token string
+		if header := r.Header.Get("Authorization"); len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+			token = strings.TrimSpace(header[7:])
+		} else if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			token = r.URL.Query().Get("token")
+		}
+		grant, err := auth.authenticate(token)
+		if err != nil {
+			log.Warn("Rejected unauthenticated RPC request", "remote", r.RemoteAddr, "err", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rpc"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(rpc.WithGrant(r.Context(), grant)))
+	})
+}
@@ -0,0 +1,292 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/log"
+	"github.com/ionchain/ionchain-core/rpc"
+)
+
+var testJWTSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// newTestAuthenticator writes an auth file with a JWT secret and an API key, and
+// loads it.
+func newTestAuthenticator(t *testing.T) *rpcAuthenticator {
+	dir, err := ioutil.TempDir("", "rpcauth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "auth.json")
+	file := `{
+		"jwtSecret": "` + hexutil.Encode(testJWTSecret) + `",
+		"keys": [{"key": "partner-key", "subject": "partner", "allow": ["eth", "debug_trace*"]}]
+	}`
+	if err := ioutil.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+	auth, err := loadRPCAuthenticator(path)
+	if err != nil {
+		t.Fatalf("failed to load auth file: %v", err)
+	}
+	return auth
+}
+
+// signTestJWT assembles a JWT token with the given header algorithm and claims,
+// signed with the secret.
+func signTestJWT(secret []byte, alg string, claims interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestRPCAuthenticate(t *testing.T) {
+	var (
+		auth = newTestAuthenticator(t)
+		now  = time.Now().Unix()
+	)
+	tests := []struct {
+		token   string
+		subject string
+		err     error
+	}{
+		{"", "", errMissingToken},
+		{"partner-key", "partner", nil},
+		{"partner-key2", "", errInvalidToken},
+		{signTestJWT(testJWTSecret, "HS256", map[string]interface{}{"sub": "alice", "allow": []string{"eth"}}), "alice", nil},
+		{signTestJWT(testJWTSecret, "HS256", map[string]interface{}{"sub": "alice", "exp": now + 60, "nbf": now - 60}), "alice", nil},
+		{signTestJWT(testJWTSecret, "HS256", map[string]interface{}{"sub": "alice", "exp": now - 60}), "", errExpiredToken},
+		{signTestJWT(testJWTSecret, "HS256", map[string]interface{}{"sub": "alice", "nbf": now + 60}), "", errInvalidToken},
+		{signTestJWT(testJWTSecret, "none", map[string]interface{}{"sub": "alice"}), "", errInvalidToken},
+		{signTestJWT([]byte("fedcba9876543210fedcba9876543210"), "HS256", map[string]interface{}{"sub": "alice"}), "", errInvalidToken},
+		{"a.b.c", "", errInvalidToken},
+	}
+	for i, tt := range tests {
+		grant, err := auth.authenticate(tt.token)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err == nil && grant.Subject != tt.subject {
+			t.Errorf("test %d: subject mismatch: have %q, want %q", i, grant.Subject, tt.subject)
+		}
+	}
+	// Tokens only pass within the clock skew of their validity period
+	token := signTestJWT(testJWTSecret, "HS256", map[string]interface{}{"exp": now})
+	if _, err := auth.verifyJWT(token, time.Unix(now, 0).Add(jwtClockSkew-time.Second)); err != nil {
+		t.Errorf("token rejected within clock skew: %v", err)
+	}
+	if _, err := auth.verifyJWT(token, time.Unix(now, 0).Add(jwtClockSkew+time.Second)); err != errExpiredToken {
+		t.Errorf("token accepted past clock skew: %v", err)
+	}
+}
+
+func TestRPCAuthGrants(t *testing.T) {
+	grant := &rpc.Grant{Allow: []string{"eth", "debug_trace*", "admin_peers"}}
+
+	tests := []struct {
+		method string
+		allow  bool
+	}{
+		{"eth_blockNumber", true},
+		{"eth_getLogs", true},
+		{"debug_traceTransaction", true},
+		{"debug_setHead", false},
+		{"admin_peers", true},
+		{"admin_addPeer", false},
+		{"ionc_blockNumber", false},
+		{"rpc_modules", true},
+	}
+	for _, tt := range tests {
+		if have := grant.Allows(tt.method); have != tt.allow {
+			t.Errorf("%s: allowed mismatch: have %v, want %v", tt.method, have, tt.allow)
+		}
+	}
+	if !(&rpc.Grant{Allow: []string{"*"}}).Allows("admin_addPeer") {
+		t.Errorf("wildcard grant rejected method")
+	}
+}
+
+func TestRPCAuthHandler(t *testing.T) {
+	var (
+		auth  = newTestAuthenticator(t)
+		grant *rpc.Grant
+	)
+	handler := newAuthHandler(auth, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grant = rpc.GrantFromContext(r.Context())
+	}))
+	jwt := signTestJWT(testJWTSecret, "HS256", map[string]interface{}{"sub": "alice", "allow": []string{"net"}})
+
+	tests := []struct {
+		header  string
+		query   string
+		ws      bool
+		status  int
+		subject string
+	}{
+		{"", "", false, http.StatusUnauthorized, ""},
+		{"Bearer wrong", "", false, http.StatusUnauthorized, ""},
+		{"Bearer partner-key", "", false, http.StatusOK, "partner"},
+		{"bearer " + jwt, "", false, http.StatusOK, "alice"},
+		{"", "partner-key", false, http.StatusUnauthorized, ""}, // Query tokens are for websockets only
+		{"", "partner-key", true, http.StatusOK, "partner"},
+	}
+	for i, tt := range tests {
+		grant = nil
+
+		req := httptest.NewRequest("POST", "/?token="+tt.query, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		if tt.ws {
+			req.Header.Set("Upgrade", "websocket")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, rec.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			if grant != nil {
+				t.Errorf("test %d: rejected request reached the server", i)
+			}
+			continue
+		}
+		if grant == nil || grant.Subject != tt.subject {
+			t.Errorf("test %d: grant mismatch: have %+v, want subject %q", i, grant, tt.subject)
+		}
+	}
+}
+
+type authTestService struct{}
+
+func (s *authTestService) Ping() string      { return "pong" }
+func (s *authTestService) TracePing() string { return "pong" }
+
+func TestRPCAuthMethodGrants(t *testing.T) {
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", new(authTestService)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("debug", new(authTestService)); err != nil {
+		t.Fatal(err)
+	}
+	httpsrv := httptest.NewServer(newAuthHandler(newTestAuthenticator(t), server))
+	defer httpsrv.Close()
+
+	client, err := rpc.DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.SetHeader("Authorization", "Bearer partner-key")
+
+	tests := []struct {
+		method string
+		allow  bool
+	}{
+		{"eth_ping", true},
+		{"debug_tracePing", true},
+		{"debug_ping", false},
+	}
+	for _, tt := range tests {
+		var result string
+		err := client.Call(&result, tt.method)
+		if tt.allow && (err != nil || result != "pong") {
+			t.Errorf("%s: call failed: %q, %v", tt.method, result, err)
+		}
+		if !tt.allow && err == nil {
+			t.Errorf("%s: call not denied", tt.method)
+		}
+	}
+}
+
+// Tests that the handlers registered via Node.RegisterHandler, such as GraphQL,
+// are authenticated like the JSON-RPC endpoint, except for the health probes.
+func TestRPCAuthRegisteredHandlers(t *testing.T) {
+	srv := newHTTPServer(log.Root(), rpc.DefaultHTTPTimeouts)
+	if err := srv.enableRPC(nil, httpConfig{Vhosts: []string{"*"}, auth: newTestAuthenticator(t)}); err != nil {
+		t.Fatalf("failed to enable RPC: %v", err)
+	}
+	var grant *rpc.Grant
+	for _, path := range []string{"/graphql", "/health", "/ready"} {
+		srv.mux.Handle(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			grant = rpc.GrantFromContext(r.Context())
+		}))
+	}
+	tests := []struct {
+		path    string
+		header  string
+		ws      bool
+		status  int
+		subject string
+	}{
+		{"/", "", false, http.StatusUnauthorized, ""},
+		{"/graphql", "", false, http.StatusUnauthorized, ""},
+		{"/graphql", "Bearer wrong", false, http.StatusUnauthorized, ""},
+		{"/graphql", "", true, http.StatusUnauthorized, ""},
+		{"/graphql", "Bearer partner-key", false, http.StatusOK, "partner"},
+		{"/graphql?token=partner-key", "", true, http.StatusOK, "partner"},
+		{"/health", "", false, http.StatusOK, ""},
+		{"/ready", "", false, http.StatusOK, ""},
+	}
+	for i, tt := range tests {
+		grant = nil
+
+		req := httptest.NewRequest("POST", tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		if tt.ws {
+			req.Header.Set("Upgrade", "websocket")
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("test %d (%s): status mismatch: have %d, want %d", i, tt.path, rec.Code, tt.status)
+			continue
+		}
+		if tt.status == http.StatusOK && tt.subject != "" && (grant == nil || grant.Subject != tt.subject) {
+			t.Errorf("test %d (%s): grant mismatch: have %+v, want subject %q", i, tt.path, grant, tt.subject)
+		}
+		if tt.status != http.StatusOK && grant != nil {
+			t.Errorf("test %d (%s): rejected request reached the handler", i, tt.path)
+		}
+	}
+}
@@ -19,6 +19,7 @@ package node
 import (
 	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -39,16 +40,23 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
+	auth               *rpcAuthenticator // Token verifier of the clients, nil if unauthenticated
+	limiter            *rpc.Limiter      // Resource limits of the clients, nil if unlimited
+	stats              *rpc.CallStats    // Latency statistics of the served calls
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
 	Origins []string
 	Modules []string
+	auth    *rpcAuthenticator // Token verifier of the clients, nil if unauthenticated
+	limiter *rpc.Limiter      // Resource limits of the clients, nil if unlimited
+	stats   *rpc.CallStats    // Latency statistics of the served calls
 }
 
 type rpcHandler struct {
 	http.Handler
+	mux    http.Handler // Handlers registered via Node.RegisterHandler, authenticated alike
 	server *rpc.Server
 }
 
@@ -60,6 +68,7 @@ type httpServer struct {
 	mu       sync.Mutex
 	server   *http.Server
 	listener net.Listener // non-nil when server is running
+	tls      *rpcTLS      // TLS credentials, nil to serve plaintext
 
 	// HTTP RPC handler things.
 	httpConfig  httpConfig
@@ -137,17 +146,21 @@ func (h *httpServer) start() error {
 		h.disableWS()
 		return err
 	}
+	if h.tls != nil {
+		listener = tls.NewListener(listener, h.tls.config())
+	}
 	h.listener = listener
 	go h.server.Serve(listener)
 
 	// if server is websocket only, return after logging
 	if h.wsAllowed() && !h.rpcAllowed() {
-		h.log.Info("WebSocket enabled", "url", fmt.Sprintf("ws://%v", listener.Addr()))
+		h.log.Info("WebSocket enabled", "url", fmt.Sprintf("%s://%v", h.scheme("ws"), listener.Addr()))
 		return nil
 	}
 	// Log http endpoint.
 	h.log.Info("HTTP server started",
 		"endpoint", listener.Addr(),
+		"tls", h.tls != nil,
 		"cors", strings.Join(h.httpConfig.CorsAllowedOrigins, ","),
 		"vhosts", strings.Join(h.httpConfig.Vhosts, ","),
 	)
@@ -162,16 +175,25 @@ func (h *httpServer) start() error {
 	for _, path := range paths {
 		name := h.handlerNames[path]
 		if !logged[name] {
-			log.Info(name+" enabled", "url", "http://"+listener.Addr().String()+path)
+			log.Info(name+" enabled", "url", h.scheme("http")+"://"+listener.Addr().String()+path)
 			logged[name] = true
 		}
 	}
 	return nil
 }
 
+// scheme returns the URL scheme of the server for the given plaintext scheme,
+// "http" or "ws".
+func (h *httpServer) scheme(plain string) string {
+	if h.tls != nil {
+		return plain + "s"
+	}
+	return plain
+}
+
 func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	rpc := h.httpHandler.Load().(*rpcHandler)
-	if r.RequestURI == "/" {
+	if r.URL.Path == "/" {
 		// Serve JSON-RPC on the root path.
 		ws := h.wsHandler.Load().(*rpcHandler)
 		if ws != nil && isWebsocket(r) {
@@ -186,7 +208,7 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Requests to a path below root are handled by the mux,
 		// which has all the handlers registered via Node.RegisterHandler.
 		// These are made available when RPC is enabled.
-		h.mux.ServeHTTP(w, r)
+		rpc.mux.ServeHTTP(w, r)
 		return
 	}
 	w.WriteHeader(404)
@@ -235,12 +257,15 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetLimiter(config.limiter)
+	srv.SetCallStats(config.stats)
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts),
+		Handler: NewHTTPHandlerStack(newAuthHandler(config.auth, srv), config.CorsAllowedOrigins, config.Vhosts),
+		mux:     newMuxAuthHandler(config.auth, &h.mux),
 		server:  srv,
 	})
 	return nil
@@ -267,12 +292,14 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetLimiter(config.limiter)
+	srv.SetCallStats(config.stats)
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
-		Handler: srv.WebsocketHandler(config.Origins),
+		Handler: newAuthHandler(config.auth, srv.WebsocketHandler(config.Origins)),
 		server:  srv,
 	})
 	return nil
@@ -408,7 +435,8 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 
 func newGzipHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		// WebSocket upgrades need to hijack the connection, don't wrap them
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
 			next.ServeHTTP(w, r)
 			return
 		}
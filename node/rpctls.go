@@ -0,0 +1,97 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// rpcTLS holds the TLS credentials of the HTTP and WebSocket endpoints. They are
+// read from files and can be reloaded while the endpoints are serving.
+type rpcTLS struct {
+	certFile string // PEM certificate chain of the server
+	keyFile  string // PEM private key of the server
+	caFile   string // PEM bundle of the client certificate authorities, empty if not verified
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool // nil if client certificates aren't verified
+}
+
+// newRPCTLS loads the TLS credentials from the given files.
+func newRPCTLS(certFile, keyFile, caFile string) (*rpcTLS, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both a TLS certificate and key are required")
+	}
+	t := &rpcTLS{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := t.reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// reload reads the credentials from their files again. New connections use the new
+// credentials, established connections are unaffected. The current credentials are
+// kept if any of the files is invalid.
+func (t *rpcTLS) reload() error {
+	cert, err := tls.LoadX509KeyPair(t.certFile, t.keyFile)
+	if err != nil {
+		return fmt.Errorf("invalid TLS key pair: %v", err)
+	}
+	var pool *x509.CertPool
+	if t.caFile != "" {
+		pem, err := ioutil.ReadFile(t.caFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in TLS client CA file %s", t.caFile)
+		}
+	}
+	t.mu.Lock()
+	t.cert, t.clientCA = &cert, pool
+	t.mu.Unlock()
+	return nil
+}
+
+// config returns the TLS configuration of the listeners, which resolves the current
+// credentials on every handshake.
+func (t *rpcTLS) config() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			t.mu.RLock()
+			defer t.mu.RUnlock()
+
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*t.cert},
+				NextProtos:   []string{"http/1.1"}, // WebSocket upgrades need HTTP/1.1
+			}
+			if t.clientCA != nil {
+				config.ClientCAs = t.clientCA
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return config, nil
+		},
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ionchain/ionchain-core/rpc"
+)
+
+// testCert is a generated certificate along with its key.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCert generates a certificate for the given common name, signed by the
+// parent or self-signed if there's none. Server certificates are valid for the
+// loopback address.
+func newTestCert(t *testing.T, name string, parent *testCert, server bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"IonChain"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	switch {
+	case parent == nil:
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	case server:
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+	default:
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write stores the certificate and its key as PEM files in the directory.
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// tlsCredential returns the TLS form of the certificate.
+func (c *testCert) tlsCredential() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+// newTLSTestNode starts a node serving the test API over HTTPS and WSS on the
+// same port, with the given configuration of the TLS and authentication files.
+func newTLSTestNode(t *testing.T, config *Config) *Node {
+	config.HTTPHost, config.HTTPModules = "127.0.0.1", []string{"test"}
+	config.WSHost, config.WSModules = "127.0.0.1", []string{"test"}
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	stack.RegisterAPIs([]rpc.API{{Namespace: "test", Version: "1.0", Service: new(authTestService), Public: true}})
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	return stack
+}
+
+// tlsTestClient returns the TLS client configuration trusting the given CA, and
+// presenting the given certificate if any.
+func tlsTestClient(ca *testCert, cert *testCert) *tls.Config {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	config := &tls.Config{RootCAs: roots}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{cert.tlsCredential()}
+	}
+	return config
+}
+
+// callTLSTestNode calls a method of the test API over both HTTPS and WSS,
+// returning the errors of the calls.
+func callTLSTestNode(t *testing.T, stack *Node, config *tls.Config, method string) (error, error) {
+	var errs [2]error
+
+	client, err := rpc.DialHTTPWithClient(stack.HTTPEndpoint(), &http.Client{Transport: &http.Transport{TLSClientConfig: config}})
+	if err != nil {
+		t.Fatalf("failed to create HTTPS client: %v", err)
+	}
+	var res string
+	if errs[0] = client.Call(&res, method); errs[0] == nil && res != "pong" {
+		t.Errorf("HTTPS result mismatch: have %q, want pong", res)
+	}
+	client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, errs[1] = rpc.DialWebsocketWithDialer(ctx, stack.WSEndpoint(), "", websocket.Dialer{TLSClientConfig: config})
+	if errs[1] == nil {
+		if errs[1] = client.Call(&res, method); errs[1] == nil && res != "pong" {
+			t.Errorf("WSS result mismatch: have %q, want pong", res)
+		}
+		client.Close()
+	}
+	return errs[0], errs[1]
+}
+
+// Tests that the endpoints serve HTTPS and WSS, and that the credentials are
+// reloaded from their files unless any of them is invalid.
+func TestRPCTLSReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpctls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "ca", nil, false)
+	certFile, keyFile := newTestCert(t, "server", ca, true).write(t, dir, "server")
+
+	stack := newTLSTestNode(t, &Config{RPCTLSCert: certFile, RPCTLSKey: keyFile})
+	defer stack.Close()
+
+	if stack.HTTPEndpoint()[:8] != "https://" || stack.WSEndpoint()[:6] != "wss://" {
+		t.Fatalf("endpoint scheme mismatch: %s, %s", stack.HTTPEndpoint(), stack.WSEndpoint())
+	}
+	if errHTTP, errWS := callTLSTestNode(t, stack, tlsTestClient(ca, nil), "test_ping"); errHTTP != nil || errWS != nil {
+		t.Fatalf("calls failed: HTTPS %v, WSS %v", errHTTP, errWS)
+	}
+	// Corrupt the key pair and check that the current credentials are kept
+	if err := ioutil.WriteFile(certFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := stack.ReloadTLS(); err == nil {
+		t.Fatalf("reloaded an invalid certificate")
+	}
+	if errHTTP, errWS := callTLSTestNode(t, stack, tlsTestClient(ca, nil), "test_ping"); errHTTP != nil || errWS != nil {
+		t.Fatalf("calls failed after a rejected reload: HTTPS %v, WSS %v", errHTTP, errWS)
+	}
+	// Replace the certificate with one from another CA and check that it's served
+	other := newTestCert(t, "other ca", nil, false)
+	newTestCert(t, "server", other, true).write(t, dir, "server")
+
+	if err := stack.ReloadTLS(); err != nil {
+		t.Fatalf("failed to reload credentials: %v", err)
+	}
+	if errHTTP, errWS := callTLSTestNode(t, stack, tlsTestClient(ca, nil), "test_ping"); errHTTP == nil || errWS == nil {
+		t.Errorf("replaced certificate still served: HTTPS %v, WSS %v", errHTTP, errWS)
+	}
+	if errHTTP, errWS := callTLSTestNode(t, stack, tlsTestClient(other, nil), "test_ping"); errHTTP != nil || errWS != nil {
+		t.Errorf("calls failed with the reloaded certificate: HTTPS %v, WSS %v", errHTTP, errWS)
+	}
+}
+
+// Tests that clients must present a certificate issued by the client CA, and that
+// certificate subjects are granted the methods of the auth file.
+func TestRPCTLSClientCerts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpctls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "ca", nil, false)
+	certFile, keyFile := newTestCert(t, "server", ca, true).write(t, dir, "server")
+	caFile, _ := ca.write(t, dir, "ca")
+
+	authFile := filepath.Join(dir, "auth.json")
+	if err := ioutil.WriteFile(authFile, []byte(`{"certs": [
+		{"subject": "indexer", "allow": ["test_ping"]},
+		{"subject": "CN=admin,O=IonChain", "allow": ["test"]}
+	]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	stack := newTLSTestNode(t, &Config{RPCTLSCert: certFile, RPCTLSKey: keyFile, RPCTLSClientCA: caFile, RPCAuthFile: authFile})
+	defer stack.Close()
+
+	var (
+		indexer  = newTestCert(t, "indexer", ca, false)
+		admin    = newTestCert(t, "admin", ca, false)
+		stranger = newTestCert(t, "stranger", ca, false)
+		rogue    = newTestCert(t, "indexer", newTestCert(t, "rogue ca", nil, false), false)
+	)
+	tests := []struct {
+		name   string
+		cert   *testCert
+		method string
+		ok     bool
+	}{
+		{"no certificate", nil, "test_ping", false},
+		{"untrusted issuer", rogue, "test_ping", false},
+		{"common name grant", indexer, "test_ping", true},
+		{"common name grant, other method", indexer, "test_tracePing", false},
+		{"full subject grant", admin, "test_tracePing", true},
+		{"unknown subject", stranger, "test_ping", false},
+	}
+	for _, tt := range tests {
+		errHTTP, errWS := callTLSTestNode(t, stack, tlsTestClient(ca, tt.cert), tt.method)
+		if (errHTTP == nil) != tt.ok || (errWS == nil) != tt.ok {
+			t.Errorf("%s: outcome mismatch: HTTPS %v, WSS %v, want ok %v", tt.name, errHTTP, errWS, tt.ok)
+		}
+	}
+}
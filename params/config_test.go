@@ -0,0 +1,68 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"math/big"
+	"testing"
+)
+
+// Tests that the optional IPos staking fork can be scheduled on configs which
+// don't enable the later test forks, but not before the forks preceding it.
+func TestCheckConfigForkOrder(t *testing.T) {
+	withStaking := func(base *ChainConfig, staking, yolo *big.Int) *ChainConfig {
+		config := *base
+		config.IPosStakingBlock, config.YoloV2Block = staking, yolo
+		return &config
+	}
+	tests := []struct {
+		config *ChainConfig
+		fail   bool
+	}{
+		{config: MainnetChainConfig},
+		{config: withStaking(MainnetChainConfig, big.NewInt(10000000), nil)},
+		{config: withStaking(MainnetChainConfig, big.NewInt(10000000), big.NewInt(10000000))},
+		{config: withStaking(MainnetChainConfig, big.NewInt(9000000), nil), fail: true},
+		{config: withStaking(MainnetChainConfig, big.NewInt(10000001), big.NewInt(10000000)), fail: true},
+		{config: withStaking(AllEthashProtocolChanges, big.NewInt(0), nil)},
+	}
+	for i, tt := range tests {
+		err := tt.config.CheckConfigForkOrder()
+		if tt.fail && err == nil {
+			t.Errorf("test %d: invalid fork order accepted", i)
+		}
+		if !tt.fail && err != nil {
+			t.Errorf("test %d: valid fork order rejected: %v", i, err)
+		}
+	}
+}
+
+// Tests that the IPos staking fork switches on at its configured block.
+func TestIPosStakingRules(t *testing.T) {
+	config := *TestChainConfig
+	config.IPosStakingBlock = big.NewInt(10)
+
+	if config.Rules(big.NewInt(9)).IsIPosStaking {
+		t.Errorf("staking precompile active before its fork block")
+	}
+	if !config.Rules(big.NewInt(10)).IsIPosStaking {
+		t.Errorf("staking precompile inactive at its fork block")
+	}
+	if TestChainConfig.Rules(big.NewInt(1000000)).IsIPosStaking {
+		t.Errorf("staking precompile active without a fork block")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Grant is the set of methods an authenticated client may call. Each entry of
+// Allow is either a namespace ("eth"), a method ("debug_traceTransaction"), or
+// a method prefix ending in a wildcard ("debug_trace*"). A single "*" allows all
+// methods. The "rpc" namespace, describing the server, is always allowed.
+type Grant struct {
+	Subject string   // Identity of the client, for audit logs
+	Allow   []string // Namespaces, methods and method prefixes allowed
+}
+
+// Allows returns whether the grant permits calling the given method.
+func (g *Grant) Allows(method string) bool {
+	namespace := method
+	if i := strings.Index(method, serviceMethodSeparator); i >= 0 {
+		namespace = method[:i]
+	}
+	if namespace == MetadataApi {
+		return true
+	}
+	for _, allowed := range g.Allow {
+		switch {
+		case allowed == "*":
+			return true
+		case strings.HasSuffix(allowed, "*"):
+			if strings.HasPrefix(method, allowed[:len(allowed)-1]) {
+				return true
+			}
+		case allowed == namespace || allowed == method:
+			return true
+		}
+	}
+	return false
+}
+
+type grantContextKey struct{}
+
+// WithGrant returns a copy of the context restricting the RPC calls served with
+// it to the given grant.
+func WithGrant(ctx context.Context, grant *Grant) context.Context {
+	return context.WithValue(ctx, grantContextKey{}, grant)
+}
+
+// GrantFromContext retrieves the grant of an authenticated client, or nil if the
+// calls are not restricted.
+func GrantFromContext(ctx context.Context) *Grant {
+	grant, _ := ctx.Value(grantContextKey{}).(*Grant)
+	return grant
+}
+
+// permissionDeniedError is returned for calls not permitted by the grant of the
+// client.
+type permissionDeniedError struct{ method string }
+
+func (e *permissionDeniedError) ErrorCode() int { return -32604 }
+
+func (e *permissionDeniedError) Error() string {
+	return fmt.Sprintf("permission denied for method %s", e.method)
+}
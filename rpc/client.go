@@ -77,12 +77,18 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool
 	services *serviceRegistry
+	limiter  *Limiter   // resource limits of served connections, nil on the client side
+	stats    *CallStats // latency statistics of served calls, nil on the client side
 
 	idCounter uint32
 
 	// This function, if non-nil, is called when the connection is lost.
 	reconnectFunc reconnectFunc
 
+	// resilience is set for clients created by DialResilient, which reconnect
+	// in the background and resume subscriptions.
+	resilience *resilience
+
 	// writeConn is used for writing to the connection on the caller's goroutine. It should
 	// only be accessed outside of dispatch, with the write lock held. The write lock is
 	// taken by sending on reqInit and released by sending on reqSent.
@@ -111,7 +117,12 @@ type clientConn struct {
 
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
+	if wc, ok := conn.(*websocketCodec); ok && wc.grant != nil {
+		ctx = WithGrant(ctx, wc.grant)
+	}
 	handler := newHandler(ctx, conn, c.idgen, c.services)
+	handler.limiter = c.limiter
+	handler.stats = c.stats
 	return &clientConn{conn, handler}
 }
 
@@ -126,10 +137,11 @@ type readOp struct {
 }
 
 type requestOp struct {
-	ids  []json.RawMessage
-	err  error
-	resp chan *jsonrpcMessage // receives up to len(ids) responses
-	sub  *ClientSubscription  // only set for EthSubscribe requests
+	ids    []json.RawMessage
+	err    error
+	resp   chan *jsonrpcMessage // receives up to len(ids) responses
+	sub    *ClientSubscription  // only set for EthSubscribe requests
+	resume bool                 // set when sub is re-established after reconnecting
 }
 
 func (op *requestOp) wait(ctx context.Context, c *Client) (*jsonrpcMessage, error) {
@@ -197,17 +209,28 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), nil, nil)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, limiter *Limiter, stats *CallStats) *Client {
+	c := makeClient(conn, idgen, services, limiter, stats)
+	if !c.isHTTP {
+		go c.dispatch(conn)
+	}
+	return c
+}
+
+// makeClient creates a client for the given connection without starting dispatch.
+func makeClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, limiter *Limiter, stats *CallStats) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
+		limiter:     limiter,
+		stats:       stats,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
@@ -219,9 +242,6 @@ func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *C
 		reqSent:     make(chan error, 1),
 		reqTimeout:  make(chan *requestOp),
 	}
-	if !isHTTP {
-		go c.dispatch(conn)
-	}
 	return c
 }
 
@@ -452,6 +472,7 @@ func (c *Client) Subscribe(ctx context.Context, namespace string, channel interf
 		resp: make(chan *jsonrpcMessage),
 		sub:  newClientSubscription(c, namespace, chanVal),
 	}
+	op.sub.params = msg.Params
 
 	// Send the subscription request.
 	// The arrival and validity of the response is signaled on sub.quit.
@@ -534,6 +555,41 @@ func (c *Client) reconnect(ctx context.Context) error {
 	}
 }
 
+// reconnectLoop re-establishes the connection of a resilient client after dead was
+// lost. It holds the write lock until connected, so calls wait for the new connection.
+func (c *Client) reconnectLoop(dead ServerCodec) {
+	select {
+	case c.reqInit <- new(requestOp):
+	case <-c.closing:
+		return
+	}
+	// A call may have noticed the broken connection and reconnected already.
+	if c.writeConn != nil && c.writeConn != jsonWriter(dead) {
+		c.reqSent <- nil
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-c.closing:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+	newconn, err := c.reconnectFunc(ctx)
+	cancel()
+	if err == nil {
+		select {
+		case c.reconnected <- newconn:
+			c.writeConn = newconn
+		case <-c.didClose:
+			newconn.close()
+			err = ErrClientQuit
+		}
+	}
+	c.reqSent <- err
+}
+
 // dispatch is the main loop of the client.
 // It sends read messages to waiting calls to Call and BatchCall
 // and subscription notifications to registered subscriptions.
@@ -543,6 +599,7 @@ func (c *Client) dispatch(codec ServerCodec) {
 		reqInitLock = c.reqInit // nil while the send lock is held
 		conn        = c.newClientConn(codec)
 		reading     = true
+		lostAt      time.Time // when a resilient client lost its connection
 	)
 	defer func() {
 		close(c.closing)
@@ -550,6 +607,11 @@ func (c *Client) dispatch(codec ServerCodec) {
 			conn.close(ErrClientQuit, nil)
 			c.drainRead()
 		}
+		if c.resilience != nil {
+			for _, sub := range c.resilience.takeOrphans() {
+				sub.quitWithError(false, ErrClientQuit)
+			}
+		}
 		close(c.didClose)
 	}()
 
@@ -571,6 +633,13 @@ func (c *Client) dispatch(codec ServerCodec) {
 
 		case err := <-c.readErr:
 			conn.handler.log.Debug("RPC connection read error", "err", err)
+			if c.resilience != nil {
+				// Keep the subscriptions alive, they are resumed after reconnecting.
+				c.resilience.addOrphans(conn.handler.detachClientSubs())
+				c.resilience.emit(ConnectionEvent{Endpoint: c.resilience.currentEndpoint(), Err: err})
+				lostAt = time.Now()
+				go c.reconnectLoop(conn.codec)
+			}
 			conn.close(err, lastOp)
 			reading = false
 
@@ -583,6 +652,10 @@ func (c *Client) dispatch(codec ServerCodec) {
 				// In those cases the caller will notice first and reconnect. Closing the
 				// handler terminates all waiting requests (closing op.resp) except for
 				// lastOp, which will be transferred to the new handler.
+				if c.resilience != nil {
+					c.resilience.addOrphans(conn.handler.detachClientSubs())
+					lostAt = time.Now()
+				}
 				conn.close(errClientReconnected, lastOp)
 				c.drainRead()
 			}
@@ -592,6 +665,9 @@ func (c *Client) dispatch(codec ServerCodec) {
 			// Re-register the in-flight request on the new handler
 			// because that's where it will be sent.
 			conn.handler.addRequestOp(lastOp)
+			if c.resilience != nil {
+				go c.resumeSubscriptions(c.resilience.currentEndpoint(), lostAt)
+			}
 
 		// Send path:
 		case op := <-reqInitLock:
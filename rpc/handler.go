@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
@@ -60,6 +61,10 @@ type handler struct {
 	cancelRoot     func()                         // cancel function for rootCtx
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
+	grant          *Grant     // methods the client may call, nil if unrestricted
+	limiter        *Limiter   // resource limits of the client, nil if unlimited
+	client         string     // identity the client is rate limited by
+	stats          *CallStats // latency statistics of served calls, nil if not collected
 	allowSubscribe bool
 
 	subLock    sync.Mutex
@@ -84,10 +89,12 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		allowSubscribe: true,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
+		grant:          GrantFromContext(connCtx),
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
 	}
+	h.client = clientID(h.grant, conn.remoteAddr())
 	h.unsubscribeCb = newCallback(reflect.Value{}, reflect.ValueOf(h.unsubscribe))
 	return h
 }
@@ -101,6 +108,23 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	if h.limiter != nil && h.limiter.limits.BatchItems > 0 && len(msgs) > h.limiter.limits.BatchItems {
+		batchLimitedMeter.Mark(1)
+		err := &limitExceededError{fmt.Sprintf("batch too large (%d > %d)", len(msgs), h.limiter.limits.BatchItems)}
+		h.startCallProc(func(cp *callProc) {
+			answers := make([]*jsonrpcMessage, 0, len(msgs))
+			for _, msg := range msgs {
+				if msg.hasValidID() {
+					answers = append(answers, msg.errorResponse(err))
+				}
+			}
+			if len(answers) == 0 {
+				answers = append(answers, errorMessage(err))
+			}
+			h.conn.writeJSON(cp.ctx, answers)
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -115,9 +139,11 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
 		answers := make([]*jsonrpcMessage, 0, len(msgs))
+		size := 0
 		for _, msg := range calls {
 			if answer := h.handleCallMsg(cp, msg); answer != nil {
-				answers = append(answers, answer)
+				size += len(answer.Result)
+				answers = append(answers, h.limitResponse(msg, answer, size))
 			}
 		}
 		h.addSubscriptions(cp.notifiers)
@@ -139,7 +165,7 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 		answer := h.handleCallMsg(cp, msg)
 		h.addSubscriptions(cp.notifiers)
 		if answer != nil {
-			h.conn.writeJSON(cp.ctx, answer)
+			h.conn.writeJSON(cp.ctx, h.limitResponse(msg, answer, len(answer.Result)))
 		}
 		for _, n := range cp.notifiers {
 			n.activate()
@@ -147,6 +173,16 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	})
 }
 
+// limitResponse replaces an answer by an error if the total size of the results
+// sent in response to the request or batch exceeds the limit.
+func (h *handler) limitResponse(msg *jsonrpcMessage, answer *jsonrpcMessage, size int) *jsonrpcMessage {
+	if h.limiter == nil || h.limiter.limits.ResponseSize <= 0 || size <= h.limiter.limits.ResponseSize {
+		return answer
+	}
+	responseLimitedMeter.Mark(1)
+	return msg.errorResponse(&limitExceededError{fmt.Sprintf("response too large (max %d bytes)", h.limiter.limits.ResponseSize)})
+}
+
 // close cancels all requests except for inflightReq and waits for
 // call goroutines to shut down.
 func (h *handler) close(err error, inflightReq *requestOp) {
@@ -156,6 +192,16 @@ func (h *handler) close(err error, inflightReq *requestOp) {
 	h.cancelServerSubscriptions(err)
 }
 
+// detachClientSubs removes the active client subscriptions without ending them.
+func (h *handler) detachClientSubs() []*ClientSubscription {
+	subs := make([]*ClientSubscription, 0, len(h.clientSubs))
+	for id, sub := range h.clientSubs {
+		delete(h.clientSubs, id)
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
 // addRequestOp registers a request operation.
 func (h *handler) addRequestOp(op *requestOp) {
 	for _, id := range op.ids {
@@ -280,9 +326,13 @@ func (h *handler) handleResponse(msg *jsonrpcMessage) {
 		op.err = msg.Error
 		return
 	}
-	if op.err = json.Unmarshal(msg.Result, &op.sub.subid); op.err == nil {
-		go op.sub.start()
-		h.clientSubs[op.sub.subid] = op.sub
+	var subid string
+	if op.err = json.Unmarshal(msg.Result, &subid); op.err == nil {
+		op.sub.setID(subid)
+		if !op.resume {
+			go op.sub.start()
+		}
+		h.clientSubs[subid] = op.sub
 	}
 }
 
@@ -317,6 +367,19 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if msg.Method == openRPCDiscoverMethod {
+		msg.Method = MetadataApi + serviceMethodSeparator + "discover"
+	}
+	if h.grant != nil && !msg.isUnsubscribe() && !h.grant.Allows(msg.Method) {
+		h.log.Warn("Denied unauthorized RPC call", "subject", h.grant.Subject, "method", msg.Method)
+		return msg.errorResponse(&permissionDeniedError{method: msg.Method})
+	}
+	if h.limiter != nil && !msg.isUnsubscribe() {
+		if err := h.limiter.allow(h.client, msg.Method); err != nil {
+			h.log.Debug("Rate limited RPC call", "client", h.client, "method", msg.Method, "err", err)
+			return msg.errorResponse(err)
+		}
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -345,8 +408,17 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 		} else {
 			successfulRequestGauge.Inc(1)
 		}
-		rpcServingTimer.UpdateSince(start)
-		newRPCServingTimer(msg.Method, answer.Error == nil).UpdateSince(start)
+		if answer.Error != nil && answer.Error.Code == limitExceededCode {
+			callLimitedMeter.Mark(1)
+		}
+		elapsed := time.Since(start)
+		rpcServingTimer.Update(elapsed)
+		newRPCServingTimer(msg.Method, answer.Error == nil).Update(elapsed)
+		newRPCLatencyHistogram(msg.Method).Update(int64(elapsed / time.Microsecond))
+
+		if h.stats != nil && h.stats.record(msg.Method, elapsed, answer.Error != nil) {
+			h.log.Warn("Slow RPC call", "method", msg.Method, "params", paramsDigest(msg.Params), "t", elapsed, "client", h.client)
+		}
 	}
 	return answer
 }
@@ -377,7 +449,7 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	args = args[1:]
 
 	// Install notifier in context so the subscription handler can find it.
-	n := &Notifier{h: h, namespace: namespace}
+	n := &Notifier{h: h, namespace: namespace, active: make(chan struct{})}
 	cp.notifiers = append(cp.notifiers, n)
 	ctx := context.WithValue(cp.ctx, notifierKey{}, n)
 
@@ -0,0 +1,157 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Command docgen extracts the doc comments of the RPC service methods in the given
+// packages into the table the OpenRPC discovery documents describe the methods
+// with. The services are the types named *API, along with the ones listed in the
+// -types flag.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	outFlag   = flag.String("out", "", "Output file of the generated table")
+	typesFlag = flag.String("types", "", "Comma separated service types not named *API")
+)
+
+func main() {
+	flag.Parse()
+	if *outFlag == "" || flag.NArg() == 0 {
+		fatalf("usage: docgen -out <file> <package dir>...")
+	}
+	services := make(map[string]bool)
+	for _, name := range strings.Split(*typesFlag, ",") {
+		services[name] = name != ""
+	}
+	docs := make(map[string]string)
+	for _, dir := range flag.Args() {
+		path, err := importPath(dir)
+		if err != nil {
+			fatalf("failed to resolve import path of %s: %v", dir, err)
+		}
+		if err := collect(dir, path, services, docs); err != nil {
+			fatalf("failed to parse %s: %v", dir, err)
+		}
+	}
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out bytes.Buffer
+	fmt.Fprintln(&out, "// Code generated by rpc/internal/docgen. DO NOT EDIT.")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "package rpc")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "// methodDocs are the doc comments of the methods the node serves over RPC, keyed")
+	fmt.Fprintln(&out, "// by the fully qualified name of their Go implementation.")
+	fmt.Fprintln(&out, "var methodDocs = map[string]string{")
+	for _, key := range keys {
+		fmt.Fprintf(&out, "\t%q: %q,\n", key, docs[key])
+	}
+	fmt.Fprintln(&out, "}")
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		fatalf("failed to format table: %v", err)
+	}
+	if err := ioutil.WriteFile(*outFlag, src, 0644); err != nil {
+		fatalf("failed to write table: %v", err)
+	}
+}
+
+// importPath resolves the import path of a package directory from the module
+// definition enclosing it.
+func importPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		if mod, err := ioutil.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+			for _, line := range strings.Split(string(mod), "\n") {
+				if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+					rel, err := filepath.Rel(root, abs)
+					if err != nil {
+						return "", err
+					}
+					if rel == "." {
+						return fields[1], nil
+					}
+					return fields[1] + "/" + filepath.ToSlash(rel), nil
+				}
+			}
+			return "", fmt.Errorf("no module path in %s", filepath.Join(root, "go.mod"))
+		}
+		if root == filepath.Dir(root) {
+			return "", fmt.Errorf("no go.mod above %s", abs)
+		}
+	}
+}
+
+// collect gathers the doc comments of the exported methods of the services in a
+// package, keyed as pkgpath.Type.Method, the receiver being dereferenced.
+func collect(dir, path string, services map[string]bool, docs map[string]string) error {
+	fset := token.NewFileSet()
+	notest := func(fi os.FileInfo) bool { return !strings.HasSuffix(fi.Name(), "_test.go") }
+
+	pkgs, err := parser.ParseDir(fset, dir, notest, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Recv == nil || fn.Doc == nil || !fn.Name.IsExported() {
+					continue
+				}
+				recv := fn.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				ident, ok := recv.(*ast.Ident)
+				if !ok || !(strings.HasSuffix(ident.Name, "API") || services[ident.Name]) {
+					continue
+				}
+				key := path + "." + ident.Name + "." + fn.Name.Name
+				if _, ok := docs[key]; !ok {
+					docs[key] = strings.TrimSpace(fn.Doc.Text())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,164 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// limitExceededCode is the JSON-RPC error code of requests exceeding a limit.
+	limitExceededCode = -32005
+
+	// limiterIdleTimeout is the time after which the budget of an inactive client
+	// is forgotten. It must exceed the time needed to refill the budgets.
+	limiterIdleTimeout = 10 * time.Minute
+)
+
+// Limits restricts the resources RPC clients may consume. Zero fields mean no
+// limit.
+type Limits struct {
+	BatchItems   int            // Maximum number of requests in a batch
+	ResponseSize int            // Maximum size in bytes of the response to a request or batch
+	RequestRate  float64        // Requests a client may send per second
+	ComputeRate  float64        // Compute units a client may spend per second
+	Costs        map[string]int // Compute units of methods or whole namespaces, 1 if missing
+}
+
+// Limiter enforces the limits on the calls of the clients. Clients are told
+// apart by the subject of their grant if authenticated, by their IP otherwise.
+// A limiter may be shared by several servers, so that clients have the same
+// budget across all the endpoints.
+//
+// Each budget holds one second worth of its rate, letting clients burst until it
+// is exhausted, but the compute budget always covers the most expensive method.
+type Limiter struct {
+	limits  Limits
+	maxCost int
+
+	clients map[string]*clientBudget
+	pruned  time.Time // Last time the idle clients were forgotten
+	lock    sync.Mutex
+}
+
+// clientBudget is the remaining budget of a client.
+type clientBudget struct {
+	requests *rate.Limiter
+	compute  *rate.Limiter
+	seen     time.Time
+}
+
+// NewLimiter creates a limiter enforcing the given limits.
+func NewLimiter(limits Limits) *Limiter {
+	l := &Limiter{
+		limits:  limits,
+		maxCost: 1,
+		clients: make(map[string]*clientBudget),
+		pruned:  time.Now(),
+	}
+	for _, cost := range limits.Costs {
+		if cost > l.maxCost {
+			l.maxCost = cost
+		}
+	}
+	return l
+}
+
+// cost returns the compute units charged for calling a method.
+func (l *Limiter) cost(method string) int {
+	if cost, ok := l.limits.Costs[method]; ok {
+		return cost
+	}
+	if i := strings.Index(method, serviceMethodSeparator); i >= 0 {
+		if cost, ok := l.limits.Costs[method[:i]]; ok {
+			return cost
+		}
+	}
+	return 1
+}
+
+// allow charges a call of the method to the budgets of the client, failing if
+// either of them is exhausted.
+func (l *Limiter) allow(client string, method string) error {
+	if l.limits.RequestRate <= 0 && l.limits.ComputeRate <= 0 {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.pruned) > limiterIdleTimeout {
+		for id, budget := range l.clients {
+			if now.Sub(budget.seen) > limiterIdleTimeout {
+				delete(l.clients, id)
+			}
+		}
+		l.pruned = now
+	}
+	budget := l.clients[client]
+	if budget == nil {
+		budget = new(clientBudget)
+		if rps := l.limits.RequestRate; rps > 0 {
+			budget.requests = rate.NewLimiter(rate.Limit(rps), int(math.Ceil(rps)))
+		}
+		if cps := l.limits.ComputeRate; cps > 0 {
+			burst := int(math.Ceil(cps))
+			if burst < l.maxCost {
+				burst = l.maxCost
+			}
+			budget.compute = rate.NewLimiter(rate.Limit(cps), burst)
+		}
+		l.clients[client] = budget
+	}
+	budget.seen = now
+
+	if budget.requests != nil && !budget.requests.AllowN(now, 1) {
+		rateLimitedMeter.Mark(1)
+		return &limitExceededError{fmt.Sprintf("request rate limit exceeded (%v/s)", l.limits.RequestRate)}
+	}
+	if budget.compute != nil && !budget.compute.AllowN(now, l.cost(method)) {
+		computeLimitedMeter.Mark(1)
+		return &limitExceededError{fmt.Sprintf("compute unit limit exceeded (%v/s)", l.limits.ComputeRate)}
+	}
+	return nil
+}
+
+// clientID returns the identity a client is rate limited by: the subject of its
+// grant if authenticated, or the IP of its connection.
+func clientID(grant *Grant, remote string) string {
+	if grant != nil && grant.Subject != "" {
+		return "sub:" + grant.Subject
+	}
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
+
+// limitExceededError is returned if a request exceeds one of the limits.
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return limitExceededCode }
+
+func (e *limitExceededError) Error() string { return e.message }
@@ -0,0 +1,168 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// limitsTestService serves calls with results of chosen sizes.
+type limitsTestService struct{}
+
+func (s *limitsTestService) Echo(str string) string {
+	return str
+}
+
+func (s *limitsTestService) Blob(n int) string {
+	return strings.Repeat("x", n)
+}
+
+// newLimitsTestClient serves the test service under the test and heavy
+// namespaces with the given limits, returning an in-process client.
+func newLimitsTestClient(t *testing.T, limits Limits) (*Client, func()) {
+	server := NewServer()
+	server.SetLimiter(NewLimiter(limits))
+	for _, namespace := range []string{"test", "heavy"} {
+		if err := server.RegisterName(namespace, new(limitsTestService)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := DialInProc(server)
+	return client, func() {
+		client.Close()
+		server.Stop()
+	}
+}
+
+// checkLimitError checks whether an error is a limit exceeded one if it is
+// expected to be, or nil otherwise.
+func checkLimitError(t *testing.T, context string, err error, limited bool) {
+	t.Helper()
+
+	if !limited {
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", context, err)
+		}
+		return
+	}
+	if err == nil {
+		t.Errorf("%s: limit not enforced", context)
+		return
+	}
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != limitExceededCode {
+		t.Errorf("%s: error mismatch: have %v, want code %d", context, err, limitExceededCode)
+	}
+}
+
+// Tests that the request budget lets a burst of one second worth of requests
+// through, and rejects the rest.
+func TestLimiterRequestRate(t *testing.T) {
+	client, stop := newLimitsTestClient(t, Limits{RequestRate: 0.1})
+	defer stop()
+
+	var res string
+	checkLimitError(t, "first call", client.Call(&res, "test_echo", "a"), false)
+	checkLimitError(t, "second call", client.Call(&res, "test_echo", "a"), true)
+	checkLimitError(t, "other namespace", client.Call(&res, "heavy_echo", "a"), true)
+}
+
+// Tests that the compute budget charges the method costs, falling back to the
+// namespace costs, and always covers the most expensive method.
+func TestLimiterComputeRate(t *testing.T) {
+	limits := Limits{
+		ComputeRate: 0.1, // Budget of 10 units set by the most expensive method
+		Costs:       map[string]int{"heavy": 4, "heavy_echo": 1, "test_blob": 10},
+	}
+	client, stop := newLimitsTestClient(t, limits)
+	defer stop()
+
+	var res string
+	for i, tt := range []struct {
+		method  string
+		arg     interface{}
+		limited bool
+	}{
+		{"heavy_blob", 1, false}, // 6 units left
+		{"heavy_blob", 1, false}, // 2 units left
+		{"heavy_blob", 1, true},
+		{"heavy_echo", "a", false}, // 1 unit left
+		{"test_echo", "a", false},  // Exhausted
+		{"test_echo", "a", true},
+	} {
+		checkLimitError(t, fmt.Sprintf("call %d (%s)", i, tt.method), client.Call(&res, tt.method, tt.arg), tt.limited)
+	}
+	// The budget must fit the most expensive method despite the low rate
+	client, stop = newLimitsTestClient(t, limits)
+	defer stop()
+
+	checkLimitError(t, "most expensive call", client.Call(&res, "test_blob", 1), false)
+	checkLimitError(t, "cheapest call", client.Call(&res, "heavy_echo", "a"), true)
+}
+
+// Tests that batches above the limit are rejected with an error for every
+// element, and that the ones at the limit pass.
+func TestLimiterBatchItems(t *testing.T) {
+	client, stop := newLimitsTestClient(t, Limits{BatchItems: 2})
+	defer stop()
+
+	for _, size := range []int{2, 3} {
+		batch := make([]BatchElem, size)
+		for i := range batch {
+			batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"a"}, Result: new(string)}
+		}
+		if err := client.BatchCall(batch); err != nil {
+			t.Fatalf("batch of %d: call failed: %v", size, err)
+		}
+		for i, elem := range batch {
+			checkLimitError(t, fmt.Sprintf("batch of %d, element %d", size, i), elem.Error, size > 2)
+		}
+	}
+}
+
+// Tests that results are replaced by errors once the total size of the response
+// exceeds the limit, cumulatively across the elements of a batch.
+func TestLimiterResponseSize(t *testing.T) {
+	client, stop := newLimitsTestClient(t, Limits{ResponseSize: 100})
+	defer stop()
+
+	// A blob of n bytes is encoded as a JSON string of n+2 bytes
+	var res string
+	checkLimitError(t, "response at the limit", client.Call(&res, "test_blob", 98), false)
+	checkLimitError(t, "response above the limit", client.Call(&res, "test_blob", 99), true)
+
+	for _, tt := range []struct {
+		last    int // Size of the last blob, after three of 30 bytes
+		limited bool
+	}{
+		{2, false}, // 32, 64, 96 and 100 bytes in total
+		{3, true},  // 32, 64, 96 and 101 bytes in total
+	} {
+		batch := make([]BatchElem, 4)
+		for i := range batch {
+			batch[i] = BatchElem{Method: "test_blob", Args: []interface{}{30}, Result: new(string)}
+		}
+		batch[3].Args = []interface{}{tt.last}
+		if err := client.BatchCall(batch); err != nil {
+			t.Fatalf("batch call failed: %v", err)
+		}
+		for i, elem := range batch {
+			checkLimitError(t, fmt.Sprintf("batch ending with %d bytes, element %d", tt.last, i), elem.Error, i == 3 && tt.limited)
+		}
+	}
+}
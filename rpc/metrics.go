@@ -27,6 +27,12 @@ var (
 	successfulRequestGauge = metrics.NewRegisteredGauge("rpc/success", nil)
 	failedReqeustGauge     = metrics.NewRegisteredGauge("rpc/failure", nil)
 	rpcServingTimer        = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+
+	rateLimitedMeter     = metrics.NewRegisteredMeter("rpc/limited/rate", nil)     // Calls over the request rate of their client
+	computeLimitedMeter  = metrics.NewRegisteredMeter("rpc/limited/compute", nil)  // Calls over the compute budget of their client
+	batchLimitedMeter    = metrics.NewRegisteredMeter("rpc/limited/batch", nil)    // Batches over the length limit
+	responseLimitedMeter = metrics.NewRegisteredMeter("rpc/limited/response", nil) // Responses over the size limit
+	callLimitedMeter     = metrics.NewRegisteredMeter("rpc/limited/call", nil)     // Calls refused by a method for exceeding its own limits
 )
 
 func newRPCServingTimer(method string, valid bool) metrics.Timer {
@@ -37,3 +43,10 @@ func newRPCServingTimer(method string, valid bool) metrics.Timer {
 	m := fmt.Sprintf("rpc/duration/%s/%s", method, flag)
 	return metrics.GetOrRegisterTimer(m, nil)
 }
+
+// newRPCLatencyHistogram returns the histogram of the latency of a method's calls
+// in microseconds.
+func newRPCLatencyHistogram(method string) metrics.Histogram {
+	m := fmt.Sprintf("rpc/latency/%s", method)
+	return metrics.GetOrRegisterHistogram(m, nil, metrics.NewExpDecaySample(1028, 0.015))
+}
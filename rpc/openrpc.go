@@ -0,0 +1,404 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:generate go run ./internal/docgen -out openrpc_docs.go -types HandlerT,RPCService . ../internal/ioncapi ../internal/debug ../ionc ../ionc/downloader ../ionc/filters ../les ../les/lespay/client ../node
+
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+)
+
+const (
+	// openRPCVersion is the version of the OpenRPC specification the discovery
+	// documents conform to.
+	openRPCVersion = "1.2.6"
+
+	// openRPCDiscoverMethod is the method name reserved by OpenRPC for serving the
+	// discovery document, an alias of rpc_discover.
+	openRPCDiscoverMethod = "rpc.discover"
+)
+
+// Schema is a JSON schema.
+type Schema map[string]interface{}
+
+// OpenRPCDocument is an OpenRPC discovery document, describing the methods of a
+// server along with the JSON schemas of their parameters and results.
+//
+// OpenRPC has no notion of subscriptions: the <namespace>_subscribe methods take
+// the name of the subscription as first parameter, and the parameters of each
+// subscription are listed in the x-subscriptions extension.
+type OpenRPCDocument struct {
+	OpenRPC       string           `json:"openrpc"`
+	Info          OpenRPCInfo      `json:"info"`
+	Methods       []*OpenRPCMethod `json:"methods"`
+	Components    OpenRPCSchemas   `json:"components"`
+	Subscriptions []*OpenRPCMethod `json:"x-subscriptions,omitempty"`
+}
+
+// OpenRPCInfo is the metadata of a discovery document.
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCSchemas holds the schemas of the named types, referenced by the method
+// descriptions.
+type OpenRPCSchemas struct {
+	Schemas map[string]Schema `json:"schemas"`
+}
+
+// OpenRPCMethod describes a method of a discovery document.
+type OpenRPCMethod struct {
+	Name           string               `json:"name"`
+	Description    string               `json:"description,omitempty"`
+	ParamStructure string               `json:"paramStructure,omitempty"`
+	Params         []*OpenRPCDescriptor `json:"params"`
+	Result         *OpenRPCDescriptor   `json:"result,omitempty"`
+}
+
+// OpenRPCDescriptor describes a parameter or result of a method.
+type OpenRPCDescriptor struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required,omitempty"`
+	Schema   Schema `json:"schema"`
+}
+
+var (
+	hexIntegerSchema = Schema{"type": "string", "pattern": "^0x[0-9a-fA-F]+$", "description": "Hex encoded unsigned integer"}
+	blockTagSchema   = Schema{"type": "string", "enum": []string{"earliest", "latest", "pending"}}
+	blockNumSchema   = Schema{"oneOf": []Schema{hexIntegerSchema, blockTagSchema}}
+	hashSchema       = hexBytesSchema(common.HashLength)
+
+	// knownSchemas are the schemas of the types encoded in JSON differently than
+	// their Go structure suggests.
+	knownSchemas = map[reflect.Type]Schema{
+		reflect.TypeOf(hexutil.Big{}):             hexIntegerSchema,
+		reflect.TypeOf(hexutil.Uint64(0)):         hexIntegerSchema,
+		reflect.TypeOf(hexutil.Uint(0)):           hexIntegerSchema,
+		reflect.TypeOf(hexutil.Bytes{}):           {"type": "string", "pattern": "^0x([0-9a-fA-F]{2})*$", "description": "Hex encoded bytes"},
+		reflect.TypeOf(big.Int{}):                 {"type": "integer"},
+		reflect.TypeOf(common.MixedcaseAddress{}): hexBytesSchema(common.AddressLength),
+		reflect.TypeOf(BlockNumber(0)):            blockNumSchema,
+		reflect.TypeOf(BlockNumberOrHash{}): {"oneOf": []Schema{blockNumSchema, hashSchema, {
+			"type": "object",
+			"properties": Schema{
+				"blockNumber":      blockNumSchema,
+				"blockHash":        hashSchema,
+				"requireCanonical": Schema{"type": "boolean"},
+			},
+		}}},
+		reflect.TypeOf(ID("")):               {"type": "string", "description": "Subscription or filter identifier"},
+		reflect.TypeOf(json.RawMessage{}):    {},
+		reflect.TypeOf((*error)(nil)).Elem(): {},
+	}
+
+	// knownParamNames are the names of the parameters of the known types.
+	knownParamNames = map[reflect.Type]string{
+		reflect.TypeOf(BlockNumber(0)):      "blockNumber",
+		reflect.TypeOf(BlockNumberOrHash{}): "block",
+		reflect.TypeOf(ID("")):              "id",
+		reflect.TypeOf(hexutil.Bytes{}):     "data",
+	}
+
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// hexBytesSchema returns the schema of a hex encoded byte array of the given
+// length, such as an address or a hash.
+func hexBytesSchema(length int) Schema {
+	return Schema{"type": "string", "pattern": fmt.Sprintf("^0x[0-9a-fA-F]{%d}$", 2*length)}
+}
+
+// implements returns whether a type or a pointer to it implements an interface.
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PtrTo(t).Implements(iface)
+}
+
+// schemaBuilder derives JSON schemas from Go types, collecting the schemas of
+// the named structs as components.
+type schemaBuilder struct {
+	schemas map[string]Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		schemas: make(map[string]Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schema returns the JSON schema of the values of a type.
+func (b *schemaBuilder) schema(t reflect.Type) Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if schema, ok := knownSchemas[t]; ok {
+		return schema
+	}
+	switch {
+	case t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8 && implements(t, textMarshalerType):
+		return hexBytesSchema(t.Len())
+	case implements(t, jsonMarshalerType):
+		return Schema{"description": fmt.Sprintf("Custom JSON encoding of %s", t)}
+	case implements(t, textMarshalerType):
+		return Schema{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Array:
+		return Schema{"type": "array", "items": b.schema(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + b.name(t)}
+	default:
+		return Schema{}
+	}
+}
+
+// name returns the component name of a named struct, deriving its schema the
+// first time it is seen.
+func (b *schemaBuilder) name(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, ok := b.schemas[name]; ok {
+		name = strings.Title(path.Base(t.PkgPath())) + t.Name()
+		for i := 2; b.schemas[name] != nil; i++ {
+			name = fmt.Sprintf("%s%s%d", strings.Title(path.Base(t.PkgPath())), t.Name(), i)
+		}
+	}
+	// Reserve the name before deriving the schema, the type may be recursive
+	b.names[t] = name
+	b.schemas[name] = Schema{}
+	b.schemas[name] = b.object(t)
+	return name
+}
+
+// object returns the schema of a struct, following the encoding/json rules for
+// the field names and embedded structs.
+func (b *schemaBuilder) object(t reflect.Type) Schema {
+	properties, required := make(map[string]Schema), []string{}
+	b.fields(t, properties, &required)
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (b *schemaBuilder) fields(t reflect.Type, properties map[string]Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, opts = tag[:i], tag[i:]
+		}
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.fields(ft, properties, required)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(opts, ",string") {
+			properties[name] = Schema{"type": "string"}
+		} else {
+			properties[name] = b.schema(field.Type)
+		}
+		if !strings.Contains(opts, ",omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// params describes the parameters of a callback, named after their types where
+// possible. Trailing pointers are optional, as they may be omitted by callers.
+func (b *schemaBuilder) params(types []reflect.Type) []*OpenRPCDescriptor {
+	params := make([]*OpenRPCDescriptor, len(types))
+	seen := make(map[string]int)
+	for i, t := range types {
+		base := t
+		for base.Kind() == reflect.Ptr {
+			base = base.Elem()
+		}
+		name, ok := knownParamNames[base]
+		switch {
+		case ok:
+		case base.Name() != "" && knownSchemas[base] == nil && (base.Kind() == reflect.Struct || base.Kind() == reflect.Array):
+			name = formatName(base.Name())
+		default:
+			name = fmt.Sprintf("arg%d", i)
+		}
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s%d", name, seen[name])
+		}
+		params[i] = &OpenRPCDescriptor{Name: name, Required: true, Schema: b.schema(t)}
+	}
+	for i := len(types) - 1; i >= 0 && types[i].Kind() == reflect.Ptr; i-- {
+		params[i].Required = false
+	}
+	return params
+}
+
+// result describes the result of a callback.
+func (b *schemaBuilder) result(cb *callback) *OpenRPCDescriptor {
+	fntype := cb.fn.Type()
+	if fntype.NumOut() == 0 || cb.errPos == 0 {
+		return &OpenRPCDescriptor{Name: "result", Schema: Schema{"type": "null"}}
+	}
+	return &OpenRPCDescriptor{Name: "result", Schema: b.schema(fntype.Out(0))}
+}
+
+// describe returns the description of a callback, the doc comment of the method
+// implementing it.
+func describe(cb *callback) string {
+	fn := runtime.FuncForPC(cb.fn.Pointer())
+	if fn == nil {
+		return ""
+	}
+	return methodDocs[docKey(fn.Name())]
+}
+
+// docKey converts the runtime name of a method, either pkg.(*T).M or pkg.T.M,
+// into the key of its doc comment.
+func docKey(name string) string {
+	name = strings.TrimSuffix(name, "-fm")
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
+
+// discover creates the discovery document of all the services in the registry.
+func (r *serviceRegistry) discover() *OpenRPCDocument {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := newSchemaBuilder()
+	doc := &OpenRPCDocument{
+		OpenRPC: openRPCVersion,
+		Info:    OpenRPCInfo{Title: "JSON-RPC API", Version: "1.0.0"},
+		Methods: []*OpenRPCMethod{},
+	}
+	// Iterate in order, the components are named after the types seen first
+	namespaces := make([]string, 0, len(r.services))
+	for namespace := range r.services {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	for _, namespace := range namespaces {
+		svc := r.services[namespace]
+		for _, name := range sortedKeys(svc.callbacks) {
+			cb := svc.callbacks[name]
+			doc.Methods = append(doc.Methods, &OpenRPCMethod{
+				Name:           namespace + serviceMethodSeparator + name,
+				Description:    describe(cb),
+				ParamStructure: "by-position",
+				Params:         b.params(cb.argTypes),
+				Result:         b.result(cb),
+			})
+		}
+		if len(svc.subscriptions) == 0 {
+			continue
+		}
+		names := sortedKeys(svc.subscriptions)
+		for _, name := range names {
+			cb := svc.subscriptions[name]
+			doc.Subscriptions = append(doc.Subscriptions, &OpenRPCMethod{
+				Name:        namespace + serviceMethodSeparator + name,
+				Description: describe(cb),
+				Params:      b.params(cb.argTypes),
+			})
+		}
+		doc.Methods = append(doc.Methods, &OpenRPCMethod{
+			Name:           namespace + subscribeMethodSuffix,
+			Description:    "Creates a subscription, see x-subscriptions for the parameters of each",
+			ParamStructure: "by-position",
+			Params: []*OpenRPCDescriptor{
+				{Name: "subscription", Required: true, Schema: Schema{"type": "string", "enum": names}},
+			},
+			Result: &OpenRPCDescriptor{Name: "subscriptionId", Schema: knownSchemas[reflect.TypeOf(ID(""))]},
+		}, &OpenRPCMethod{
+			Name:           namespace + unsubscribeMethodSuffix,
+			Description:    "Cancels a subscription",
+			ParamStructure: "by-position",
+			Params: []*OpenRPCDescriptor{
+				{Name: "subscriptionId", Required: true, Schema: knownSchemas[reflect.TypeOf(ID(""))]},
+			},
+			Result: &OpenRPCDescriptor{Name: "result", Schema: Schema{"type": "boolean"}},
+		})
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool { return doc.Methods[i].Name < doc.Methods[j].Name })
+	sort.Slice(doc.Subscriptions, func(i, j int) bool { return doc.Subscriptions[i].Name < doc.Subscriptions[j].Name })
+	doc.Components.Schemas = b.schemas
+	return doc
+}
+
+// sortedKeys returns the names of a set of callbacks in order.
+func sortedKeys(callbacks map[string]*callback) []string {
+	names := make([]string, 0, len(callbacks))
+	for name := range callbacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
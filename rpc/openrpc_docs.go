@@ -0,0 +1,184 @@
+// Code generated by rpc/internal/docgen. DO NOT EDIT.
+
+package rpc
+
+// methodDocs are the doc comments of the methods the node serves over RPC, keyed
+// by the fully qualified name of their Go implementation.
+var methodDocs = map[string]string{
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.BacktraceAt":                                              "BacktraceAt sets the log backtrace location. See package log for details on\nthe pattern syntax.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.BlockProfile":                                             "BlockProfile turns on goroutine profiling for nsec seconds and writes profile data to\nfile. It uses a profile rate of 1 for most accurate information. If a different rate is\ndesired, set the rate and write the profile manually.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.CpuProfile":                                               "CpuProfile turns on CPU profiling for nsec seconds and writes\nprofile data to file.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.FreeOSMemory":                                             "FreeOSMemory forces a garbage collection.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.GcStats":                                                  "GcStats returns GC statistics.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.GoTrace":                                                  "GoTrace turns on tracing for nsec seconds and writes\ntrace data to file.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.MemStats":                                                 "MemStats returns detailed runtime memory statistics.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.MutexProfile":                                             "MutexProfile turns on mutex profiling for nsec seconds and writes profile data to file.\nIt uses a profile rate of 1 for most accurate information. If a different rate is\ndesired, set the rate and write the profile manually.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.SetBlockProfileRate":                                      "SetBlockProfileRate sets the rate of goroutine block profile data collection.\nrate 0 disables block profiling.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.SetGCPercent":                                             "SetGCPercent sets the garbage collection target percentage. It returns the previous\nsetting. A negative value disables GC.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.SetMutexProfileFraction":                                  "SetMutexProfileFraction sets the rate of mutex profiling.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.Stacks":                                                   "Stacks returns a printed representation of the stacks of all goroutines.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.StartCPUProfile":                                          "StartCPUProfile turns on CPU profiling, writing to the given file.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.StartGoTrace":                                             "StartGoTrace turns on tracing, writing to the given file.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.StopCPUProfile":                                           "StopCPUProfile stops an ongoing CPU profile.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.StopGoTrace":                                              "StopTrace stops an ongoing trace.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.Verbosity":                                                "Verbosity sets the log verbosity ceiling. The verbosity of individual packages\nand source files can be raised using Vmodule.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.Vmodule":                                                  "Vmodule sets the log verbosity pattern. See package log for details on the\npattern syntax.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.WriteBlockProfile":                                        "WriteBlockProfile writes a goroutine blocking profile to the given file.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.WriteMemProfile":                                          "WriteMemProfile writes an allocation profile to the given file.\nNote that the profiling rate cannot be set through the API,\nit must be set on the command line.",
+	"github.com/ionchain/ionchain-core/internal/debug.HandlerT.WriteMutexProfile":                                        "WriteMutexProfile writes a goroutine blocking profile to the given file.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.DeriveAccount":                                 "DeriveAccount requests a HD wallet to derive a new account, optionally pinning\nit for later reuse.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.EcRecover":                                     "EcRecover returns the address for the account that was used to create the signature.\nNote, this function is compatible with eth_sign and personal_sign. As such it recovers\nthe address of:\nhash = keccak256(\"\\x19Ionchain Signed Message:\\n\"${message length}${message})\naddr = ecrecover(hash, signature)\n\nNote, the signature must conform to the secp256k1 curve R, S and V values, where\nthe V value must be 27 or 28 for legacy reasons.\n\nhttps://github.com/ionchain/ionchain-core/wiki/Management-APIs#personal_ecRecover",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.ImportRawKey":                                  "ImportRawKey stores the given hex encoded ECDSA key into the key directory,\nencrypting it with the passphrase.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.ListAccounts":                                  "listAccounts will return a list of addresses for accounts this node manages.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.ListWallets":                                   "ListWallets will return a list of wallets this node manages.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.LockAccount":                                   "LockAccount will lock the account associated with the given address when it's unlocked.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.NewAccount":                                    "NewAccount will create a new account and returns the address for the new account.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.OpenWallet":                                    "OpenWallet initiates a hardware wallet opening procedure, establishing a USB\nconnection and attempting to authenticate via the provided passphrase. Note,\nthe method may return an extra challenge requiring a second open (e.g. the\nTrezor PIN matrix challenge).",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.SendTransaction":                               "SendTransaction will create a transaction from the given arguments and\ntries to sign it with the key associated with args.To. If the given passwd isn't\nable to decrypt the key it fails.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.Sign":                                          "Sign calculates an IonChain ECDSA signature for:\nkeccack256(\"\\x19Ionchain Signed Message:\\n\" + len(message) + message))\n\nNote, the produced signature conforms to the secp256k1 curve R, S and V values,\nwhere the V value will be 27 or 28 for legacy reasons.\n\nThe key used to calculate the signature is decrypted with the given password.\n\nhttps://github.com/ionchain/ionchain-core/wiki/Management-APIs#personal_sign",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.SignAndSendTransaction":                        "SignAndSendTransaction was renamed to SendTransaction. This method is deprecated\nand will be removed in the future. It primary goal is to give clients time to update.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.SignTransaction":                               "SignTransaction will create a transaction from the given arguments and\ntries to sign it with the key associated with args.To. If the given passwd isn't\nable to decrypt the key it fails. The transaction is returned in RLP-form, not broadcast\nto other nodes",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateAccountAPI.UnlockAccount":                                 "UnlockAccount will unlock the account associated with the given address with\nthe given password for duration seconds. If duration is nil it will use a\ndefault of 300 seconds. It returns an indication if the account was unlocked.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateDebugAPI.ChaindbCompact":                                  "ChaindbCompact flattens the entire key-value database into a single level,\nremoving all unused slots and merging all keys.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateDebugAPI.ChaindbProperty":                                 "ChaindbProperty returns leveldb properties of the key-value database.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PrivateDebugAPI.SetHead":                                         "SetHead rewinds the head of the blockchain to a previous block.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicAccountAPI.Accounts":                                       "Accounts returns the collection of accounts this node manages",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.BlockNumber":                                 "BlockNumber returns the block number of the chain head.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.Call":                                        "Call executes the given transaction on the state for the given block number.\n\nAdditionally, the caller can specify a batch of contract for fields overriding.\n\nNote, this function doesn't make and changes in the state/blockchain and is\nuseful to execute and retrieve values.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.CallMany":                                    "CallMany executes an ordered list of calls and signed transactions on top of\nthe state of the given block, each one seeing the state changes of the ones\nbefore it, and returns their results.\n\nAdditionally, the caller can override the context of the simulated block and\nthe fields of any accounts.\n\nNote, this function doesn't make any changes in the state/blockchain and is\nuseful to simulate transaction bundles.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.ChainId":                                     "ChainId returns the chainID value for transaction replay protection.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.CreateAccessList":                            "CreateAccessList creates an access list for the given transaction. If the\naccess list creation fails an error is returned. If the transaction itself\nfails, a vmErr is returned in the result.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.EstimateGas":                                 "EstimateGas returns an estimate of the amount of gas needed to execute the\ngiven transaction against the current pending block.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetBalance":                                  "GetBalance returns the amount of wei for the given address in the state of the\ngiven block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta\nblock numbers are also allowed.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetBlockByHash":                              "GetBlockByHash returns the requested block. When fullTx is true all transactions in the block are returned in full\ndetail, otherwise only the transaction hash is returned.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetBlockByNumber":                            "GetBlockByNumber returns the requested canonical block.\n* When blockNr is -1 the chain head is returned.\n* When blockNr is -2 the pending chain head is returned.\n* When fullTx is true all transactions in the block are returned, otherwise\n  only the transaction hash is returned.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetCode":                                     "GetCode returns the code stored at the given address in the state for the given block number.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetHeaderByHash":                             "GetHeaderByHash returns the requested header by hash.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetHeaderByNumber":                           "GetHeaderByNumber returns the requested canonical block header.\n* When blockNr is -1 the chain head is returned.\n* When blockNr is -2 the pending chain head is returned.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetProof":                                    "GetProof returns the Merkle-proof for a given account and optionally some storage keys.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetStorageAt":                                "GetStorageAt returns the storage from the state at the given address, key and\nblock number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block\nnumbers are also allowed.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetUncleByBlockHashAndIndex":                 "GetUncleByBlockHashAndIndex returns the uncle block for the given block hash and index. When fullTx is true\nall transactions in the block are returned in full detail, otherwise only the transaction hash is returned.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetUncleByBlockNumberAndIndex":               "GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index. When fullTx is true\nall transactions in the block are returned in full detail, otherwise only the transaction hash is returned.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetUncleCountByBlockHash":                    "GetUncleCountByBlockHash returns number of uncles in the block for the given block hash",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicBlockChainAPI.GetUncleCountByBlockNumber":                  "GetUncleCountByBlockNumber returns number of uncles in the block for the given block number",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicDebugAPI.GetBlockRlp":                                      "GetBlockRlp retrieves the RLP encoded for of a single block.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicDebugAPI.PrintBlock":                                       "PrintBlock retrieves a block and returns its pretty printed form.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicIonchainAPI.GasPrice":                                      "GasPrice returns a suggestion for a gas price.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicIonchainAPI.ProtocolVersion":                               "ProtocolVersion returns the current IonChain protocol version this node supports",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicIonchainAPI.Syncing":                                       "Syncing returns false in case the node is currently not syncing with the network. It can be up to date or has not\nyet received the latest block headers from its pears. In case it is synchronizing:\n- startingBlock: block number this node started to synchronise from\n- currentBlock:  block number this node is currently importing\n- highestBlock:  block number of the highest block header this node has received from peers\n- pulledStates:  number of state entries processed until now\n- knownStates:   number of known state entries that still need to be pulled",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicNetAPI.Listening":                                          "Listening returns an indication if the node is listening for network connections.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicNetAPI.PeerCount":                                          "PeerCount returns the number of connected peers",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicNetAPI.Version":                                            "Version returns the current ethereum protocol version.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.FillTransaction":                        "FillTransaction fills the defaults (nonce, gas, gasPrice) on a given unsigned transaction,\nand returns it to the caller for further processing (signing + broadcast)",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.GetBlockTransactionCountByHash":         "GetBlockTransactionCountByHash returns the number of transactions in the block with the given hash.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.GetBlockTransactionCountByNumber":       "GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.GetRawTransactionByBlockHashAndIndex":   "GetRawTransactionByBlockHashAndIndex returns the bytes of the transaction for the given block hash and index.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.GetRawTransactionByBlockNumberAndIndex": "GetRawTransactionByBlockNumberAndIndex returns the bytes of the transaction for the given block number and index.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.GetRawTransactionByHash":                "GetRawTransactionByHash returns the bytes of the transaction for the given hash.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.GetTransactionByBlockHashAndIndex":      "GetTransactionByBlockHashAndIndex returns the transaction for the given block hash and index.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.GetTransactionByBlockNumberAndIndex":    "GetTransactionByBlockNumberAndIndex returns the transaction for the given block number and index.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.GetTransactionByHash":                   "GetTransactionByHash returns the transaction for the given hash",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.GetTransactionCount":                    "GetTransactionCount returns the number of transactions the given address has sent for the given block number",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.GetTransactionReceipt":                  "GetTransactionReceipt returns the transaction receipt for the given transaction hash.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.PendingTransactions":                    "PendingTransactions returns the transactions that are in the transaction pool\nand have a from address that is one of the accounts this node manages.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.Resend":                                 "Resend accepts an existing transaction and a new gas price and limit. It will remove\nthe given transaction from the pool and reinsert it with the new gas price and limit.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.SendRawTransaction":                     "SendRawTransaction will add the signed transaction to the transaction pool.\nThe sender is responsible for signing the transaction and using the correct nonce.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.SendTransaction":                        "SendTransaction creates a transaction for the given argument, sign it and submit it to the\ntransaction pool.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.Sign":                                   "Sign calculates an ECDSA signature for:\nkeccack256(\"\\x19Ionchain Signed Message:\\n\" + len(message) + message).\n\nNote, the produced signature conforms to the secp256k1 curve R, S and V values,\nwhere the V value will be 27 or 28 for legacy reasons.\n\nThe account associated with addr must be unlocked.\n\nhttps://github.com/ethereum/wiki/wiki/JSON-RPC#eth_sign",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTransactionPoolAPI.SignTransaction":                        "SignTransaction will sign the given transaction with the from account.\nThe node needs to have the private key of the account corresponding with\nthe given from address and it needs to be unlocked.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTxPoolAPI.Content":                                         "Content returns the transactions contained within the transaction pool.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTxPoolAPI.Inspect":                                         "Inspect retrieves the content of the transaction pool and flattens it into an\neasily inspectable list.",
+	"github.com/ionchain/ionchain-core/internal/ioncapi.PublicTxPoolAPI.Status":                                          "Status returns the number of pending and queued transaction in the pool.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateAdminAPI.AddCheckpoint":                                               "AddCheckpoint pins a block, refusing any future chain reorg or head rewind\nwhich would drop it or make a different block canonical at its height. The\ncheckpoint is persisted in the database until removed.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateAdminAPI.Checkpoints":                                                 "Checkpoints returns the blocks pinned against chain reorgs.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateAdminAPI.ExportChain":                                                 "ExportChain exports the current blockchain into a local file,\nor a range of blocks if first and last are non-nil",
+	"github.com/ionchain/ionchain-core/ionc.PrivateAdminAPI.ImportChain":                                                 "ImportChain imports a blockchain from a local file.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateAdminAPI.RemoveCheckpoint":                                            "RemoveCheckpoint unpins the checkpoint at the given height, returning whether\nthere was any.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateAdminAPI.SetMaxReorgDepth":                                            "SetMaxReorgDepth sets the maximum number of canonical blocks a chain reorg may\ndrop, zero disabling the limit.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.CancelTraceJob":                                              "CancelTraceJob stops a running trace job, keeping the traces written so far.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.GetBadBlocks":                                                "GetBadBlocks returns a list of the last 'bad blocks' that the client has seen on the network\nand returns them as a JSON list of block-hashes",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.GetBlockWitness":                                             "GetBlockWitness returns the stateless witness of a block in its compact (snappy\ncompressed RLP) encoding. Witnesses not recorded during import are regenerated\nif the parent state is still available.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.GetModifiedAccountsByHash":                                   "GetModifiedAccountsByHash returns all accounts that have changed between the\ntwo blocks specified. A change is defined as a difference in nonce, balance,\ncode hash, or storage hash.\n\nWith one parameter, returns the list of accounts modified in the specified block.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.GetModifiedAccountsByNumber":                                 "GetModifiedAccountsByNumber returns all accounts that have changed between the\ntwo blocks specified. A change is defined as a difference in nonce, balance,\ncode hash, or storage hash.\n\nWith one parameter, returns the list of accounts modified in the specified block.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.ListTraceJobs":                                               "ListTraceJobs retrieves all the trace jobs, running or not.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.Preimage":                                                    "Preimage is a debug API function that returns the preimage for a sha3 hash, if known.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.RemoveTraceJob":                                              "RemoveTraceJob deletes a trace job which is not running, along with its output.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.StandardTraceBadBlockToFile":                                 "StandardTraceBadBlockToFile dumps the structured logs created during the\nexecution of EVM against a block pulled from the pool of bad ones to the\nlocal file system and returns a list of files to the caller.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.StandardTraceBlockToFile":                                    "StandardTraceBlockToFile dumps the structured logs created during the\nexecution of EVM to the local file system and returns a list of files\nto the caller.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.StartTraceJob":                                               "StartTraceJob starts tracing the blocks after start up to end in the background,\nwriting the traces of each block with transactions as a JSON line into a file.\nUnlike TraceChain, the job outlives the connection and is resumed from its last\ncheckpoint after a restart.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.StorageRangeAt":                                              "StorageRangeAt returns the storage at the given block height and transaction index.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.TraceBadBlock":                                               "TraceBadBlock returns the structured logs created during the execution of\nEVM against a block pulled from the pool of bad ones and returns them as a JSON\nobject.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.TraceBlock":                                                  "TraceBlock returns the structured logs created during the execution of EVM\nand returns them as a JSON object.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.TraceBlockByHash":                                            "TraceBlockByHash returns the structured logs created during the execution of\nEVM and returns them as a JSON object.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.TraceBlockByNumber":                                          "TraceBlockByNumber returns the structured logs created during the execution of\nEVM and returns them as a JSON object.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.TraceBlockFromFile":                                          "TraceBlockFromFile returns the structured logs created during the execution of\nEVM and returns them as a JSON object.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.TraceCall":                                                   "TraceCall lets you trace a given eth_call. It collects the structured logs created during the execution of EVM\nif the given transaction was added on top of the provided block and returns them as a JSON object.\nYou can provide -2 as a block number to trace on top of the pending block.\n\nAdditionally, the caller can override the fields of any accounts and the\ncontext of the block the call is executed in.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.TraceChain":                                                  "TraceChain returns the structured logs created during the execution of EVM\nbetween two blocks (excluding start) and returns them as a JSON object.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.TraceJobStatus":                                              "TraceJobStatus retrieves the status and progress of a trace job.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateDebugAPI.TraceTransaction":                                            "TraceTransaction returns the structured logs created during the execution of EVM\nand returns them as a JSON object.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateMinerAPI.SetEtherbase":                                                "SetEtherbase sets the etherbase of the miner",
+	"github.com/ionchain/ionchain-core/ionc.PrivateMinerAPI.SetExtra":                                                    "SetExtra sets the extra data string that is included when this miner mines a block.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateMinerAPI.SetGasPrice":                                                 "SetGasPrice sets the minimum accepted gas price for the miner.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateMinerAPI.SetRecommitInterval":                                         "SetRecommitInterval updates the interval for miner sealing work recommitting.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateMinerAPI.Start":                                                       "Start starts the miner with the given number of threads. If threads is nil,\nthe number of workers started is equal to the number of logical CPUs that are\nusable by this process. If mining is already running, this method adjust the\nnumber of threads allowed to use and updates the minimum price required by the\ntransaction pool.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateMinerAPI.Stop":                                                        "Stop terminates the miner, both at the consensus engine level as well as at\nthe block creation level.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateTraceAPI.Block":                                                       "Block returns the flattened call traces of all the transactions in a block.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateTraceAPI.Filter":                                                      "Filter returns the flattened call traces within a block range matching the\ngiven address criteria. It's served exclusively from the call trace index, so\nthe whole range must already be indexed.\n\nThe range ends at the head of the index by default. Without a starting block,\nit spans the whole index if filtering by address, or the most blocks allowed\notherwise.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateTraceAPI.ReplayBlockTransactions":                                     "ReplayBlockTransactions re-executes all the transactions of a block, returning\nthe requested kinds of traces for each. The supported trace types are \"trace\"\nfor the flattened call traces and \"stateDiff\" for the modified state.",
+	"github.com/ionchain/ionchain-core/ionc.PrivateTraceAPI.Transaction":                                                 "Transaction returns the flattened call traces of a single transaction.",
+	"github.com/ionchain/ionchain-core/ionc.PublicDebugAPI.AccountRange":                                                 "AccountRange enumerates all accounts in the given block and start point in paging request",
+	"github.com/ionchain/ionchain-core/ionc.PublicDebugAPI.DumpBlock":                                                    "DumpBlock retrieves the entire state of the database at a given block.",
+	"github.com/ionchain/ionchain-core/ionc.PublicIonchainAPI.ChainId":                                                   "ChainId is the EIP-155 replay-protection chain id for the current ethereum chain config.",
+	"github.com/ionchain/ionchain-core/ionc.PublicIonchainAPI.Coinbase":                                                  "Coinbase is the address that mining rewards will be send to (alias for Etherbase)",
+	"github.com/ionchain/ionchain-core/ionc.PublicIonchainAPI.Etherbase":                                                 "Etherbase is the address that mining rewards will be send to",
+	"github.com/ionchain/ionchain-core/ionc.PublicMinerAPI.Mining":                                                       "Mining returns an indication if this node is currently mining.",
+	"github.com/ionchain/ionchain-core/ionc/downloader.PublicDownloaderAPI.SubscribeSyncStatus":                          "SubscribeSyncStatus creates a subscription that will broadcast new synchronisation updates.\nThe given channel must receive interface values, the result can either",
+	"github.com/ionchain/ionchain-core/ionc/downloader.PublicDownloaderAPI.Syncing":                                      "Syncing provides information when this nodes starts synchronising with the IonChain network and when it's finished.",
+	"github.com/ionchain/ionchain-core/ionc/filters.PublicFilterAPI.GetFilterChanges":                                    "GetFilterChanges returns the logs for the filter with the given id since\nlast time it was called. This can be used for polling.\n\nFor pending transaction and block filters the result is []common.Hash.\n(pending)Log filters return []Log.\n\nhttps://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getfilterchanges",
+	"github.com/ionchain/ionchain-core/ionc/filters.PublicFilterAPI.GetFilterLogs":                                       "GetFilterLogs returns the logs for the filter with the given id.\nIf the filter could not be found an empty array of logs is returned.\n\nhttps://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getfilterlogs",
+	"github.com/ionchain/ionchain-core/ionc/filters.PublicFilterAPI.GetLogs":                                             "GetLogs returns logs matching the given argument that are stored within the state.\n\nhttps://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getlogs",
+	"github.com/ionchain/ionchain-core/ionc/filters.PublicFilterAPI.Logs":                                                "Logs creates a subscription that fires for all new log that match the given filter criteria.\n\nIf the criteria start at a block number, the matching logs of the chain since that\nblock are sent first, followed by the new logs without gaps or duplicates. Logs of\nblocks dropped by a reorg are sent again with the removed property set to true.\nSubscriptions starting further back than the backfill limit of the node allows\nare refused.",
+	"github.com/ionchain/ionchain-core/ionc/filters.PublicFilterAPI.NewBlockFilter":                                      "NewBlockFilter creates a filter that fetches blocks that are imported into the chain.\nIt is part of the filter package since polling goes with eth_getFilterChanges.\n\nhttps://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newblockfilter",
+	"github.com/ionchain/ionchain-core/ionc/filters.PublicFilterAPI.NewFilter":                                           "NewFilter creates a new filter and returns the filter id. It can be\nused to retrieve logs when the state changes. This method cannot be\nused to fetch logs that are already stored in the state.\n\nDefault criteria for the from and to block are \"latest\".\nUsing \"latest\" as block number will return logs for mined blocks.\nUsing \"pending\" as block number returns logs for not yet mined (pending) blocks.\nIn case logs are removed (chain reorg) previously returned logs are returned\nagain but with the removed property set to true.\n\nIn case \"fromBlock\" > \"toBlock\" an error is returned.\n\nhttps://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newfilter",
+	"github.com/ionchain/ionchain-core/ionc/filters.PublicFilterAPI.NewHeads":                                            "NewHeads send a notification each time a new (header) block is appended to the chain.",
+	"github.com/ionchain/ionchain-core/ionc/filters.PublicFilterAPI.NewPendingTransactionFilter":                         "NewPendingTransactionFilter creates a filter that fetches pending transaction hashes\nas transactions enter the pending state.\n\nIt is part of the filter package because this filter can be used through the\n`eth_getFilterChanges` polling method that is also used for log filters.\n\nhttps://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newpendingtransactionfilter",
+	"github.com/ionchain/ionchain-core/ionc/filters.PublicFilterAPI.NewPendingTransactions":                              "NewPendingTransactions creates a subscription that is triggered each time a transaction\nenters the transaction pool and was signed from one of the transactions this nodes manages.",
+	"github.com/ionchain/ionchain-core/ionc/filters.PublicFilterAPI.UninstallFilter":                                     "UninstallFilter removes the filter with the given filter id.\n\nhttps://github.com/ethereum/wiki/wiki/JSON-RPC#eth_uninstallfilter",
+	"github.com/ionchain/ionchain-core/les.LightDummyAPI.Coinbase":                                                       "Coinbase is the address that mining rewards will be send to (alias for Etherbase)",
+	"github.com/ionchain/ionchain-core/les.LightDummyAPI.Etherbase":                                                      "Etherbase is the address that mining rewards will be send to",
+	"github.com/ionchain/ionchain-core/les.LightDummyAPI.Hashrate":                                                       "Hashrate returns the POW hashrate",
+	"github.com/ionchain/ionchain-core/les.LightDummyAPI.Mining":                                                         "Mining returns an indication if this node is currently mining.",
+	"github.com/ionchain/ionchain-core/les.PrivateDebugAPI.FreezeClient":                                                 "FreezeClient forces a temporary client freeze which normally happens when the server is overloaded",
+	"github.com/ionchain/ionchain-core/les.PrivateLightAPI.GetCheckpoint":                                                "GetLocalCheckpoint returns the specific local checkpoint package.\n\nThe checkpoint package consists of 3 strings:\n  result[0], 32 bytes hex encoded latest section head hash\n  result[1], 32 bytes hex encoded latest section canonical hash trie root hash\n  result[2], 32 bytes hex encoded latest section bloom trie root hash",
+	"github.com/ionchain/ionchain-core/les.PrivateLightAPI.GetCheckpointContractAddress":                                 "GetCheckpointContractAddress returns the contract contract address in hex format.",
+	"github.com/ionchain/ionchain-core/les.PrivateLightAPI.LatestCheckpoint":                                             "LatestCheckpoint returns the latest local checkpoint package.\n\nThe checkpoint package consists of 4 strings:\n  result[0], hex encoded latest section index\n  result[1], 32 bytes hex encoded latest section head hash\n  result[2], 32 bytes hex encoded latest section canonical hash trie root hash\n  result[3], 32 bytes hex encoded latest section bloom trie root hash",
+	"github.com/ionchain/ionchain-core/les.PrivateLightServerAPI.AddBalance":                                             "AddBalance adds the given amount to the balance of a client if possible and returns\nthe balance before and after the operation",
+	"github.com/ionchain/ionchain-core/les.PrivateLightServerAPI.Benchmark":                                              "Benchmark runs a request performance benchmark with a given set of measurement setups\nin multiple passes specified by passCount. The measurement time for each setup in each\npass is specified in milliseconds by length.\n\nNote: measurement time is adjusted for each pass depending on the previous ones.\nTherefore a controlled total measurement time is achievable in multiple passes.",
+	"github.com/ionchain/ionchain-core/les.PrivateLightServerAPI.ClientInfo":                                             "ClientInfo returns information about clients listed in the ids list or matching the given tags",
+	"github.com/ionchain/ionchain-core/les.PrivateLightServerAPI.PriorityClientInfo":                                     "PriorityClientInfo returns information about clients with a positive balance\nin the given ID range (stop excluded). If stop is null then the iterator stops\nonly at the end of the ID space. MaxCount limits the number of results returned.\nIf maxCount limit is applied but there are more potential results then the ID\nof the next potential result is included in the map with an empty structure\nassigned to it.",
+	"github.com/ionchain/ionchain-core/les.PrivateLightServerAPI.ServerInfo":                                             "ServerInfo returns global server parameters",
+	"github.com/ionchain/ionchain-core/les.PrivateLightServerAPI.SetClientParams":                                        "SetClientParams sets client parameters for all clients listed in the ids list\nor all connected clients if the list is empty",
+	"github.com/ionchain/ionchain-core/les.PrivateLightServerAPI.SetConnectedBias":                                       "SetConnectedBias set the connection bias, which is applied to already connected clients\nSo that already connected client won't be kicked out very soon and we can ensure all\nconnected clients can have enough time to request or sync some data.\nWhen the input parameter `bias` < 0 (illegal), return error.",
+	"github.com/ionchain/ionchain-core/les.PrivateLightServerAPI.SetDefaultParams":                                       "SetDefaultParams sets the default parameters applicable to clients connected in the future",
+	"github.com/ionchain/ionchain-core/les/lespay/client.PrivateClientAPI.Distribution":                                  "Distribution returns a distribution as a series of (X, Y) chart coordinates,\nwhere the X axis is the response time in seconds while the Y axis is the amount of\nservice value received with a response time close to the X coordinate.\nThe distribution is optionally normalized to a sum of 1.\nIf nodeStr == \"\" then the global distribution is returned, otherwise the individual\ndistribution of the specified server node.",
+	"github.com/ionchain/ionchain-core/les/lespay/client.PrivateClientAPI.RequestStats":                                  "RequestStats returns the current contents of the reference request basket, with\nrequest values meaning average per request rather than total.",
+	"github.com/ionchain/ionchain-core/les/lespay/client.PrivateClientAPI.Timeout":                                       "Timeout suggests a timeout value based on either the global distribution or the\ndistribution of the specified node. The parameter is the desired rate of timeouts\nassuming a similar distribution in the future.\nNote that the actual timeout should have a sensible minimum bound so that operating\nunder ideal working conditions for a long time (for example, using a local server\nwith very low response times) will not make it very hard for the system to accommodate\nlonger response times in the future.",
+	"github.com/ionchain/ionchain-core/les/lespay/client.PrivateClientAPI.Value":                                         "Value calculates the total service value provided either globally or by the specified\nserver node, using a weight function based on the given timeout.",
+	"github.com/ionchain/ionchain-core/node.privateAdminAPI.AddPeer":                                                     "AddPeer requests connecting to a remote node, and also maintaining the new\nconnection at all times, even reconnecting if it is lost.",
+	"github.com/ionchain/ionchain-core/node.privateAdminAPI.AddTrustedPeer":                                              "AddTrustedPeer allows a remote node to always connect, even if slots are full",
+	"github.com/ionchain/ionchain-core/node.privateAdminAPI.PeerEvents":                                                  "PeerEvents creates an RPC subscription which receives peer events from the\nnode's p2p.Server",
+	"github.com/ionchain/ionchain-core/node.privateAdminAPI.RemovePeer":                                                  "RemovePeer disconnects from a remote node if the connection exists",
+	"github.com/ionchain/ionchain-core/node.privateAdminAPI.RemoveTrustedPeer":                                           "RemoveTrustedPeer removes a remote node from the trusted peer set, but it\ndoes not disconnect it automatically.",
+	"github.com/ionchain/ionchain-core/node.privateAdminAPI.StartRPC":                                                    "StartRPC starts the HTTP RPC API server.",
+	"github.com/ionchain/ionchain-core/node.privateAdminAPI.StartWS":                                                     "StartWS starts the websocket RPC API server.",
+	"github.com/ionchain/ionchain-core/node.privateAdminAPI.StopRPC":                                                     "StopRPC shuts down the HTTP server.",
+	"github.com/ionchain/ionchain-core/node.privateAdminAPI.StopWS":                                                      "StopWS terminates all WebSocket servers.",
+	"github.com/ionchain/ionchain-core/node.privateDebugAPI.RpcStats":                                                    "RpcStats returns the call statistics of the n slowest methods served over HTTP\nand websocket, 10 by default. Methods with the most calls over the slow threshold\ncome first, then the ones with the highest mean latency.",
+	"github.com/ionchain/ionchain-core/node.publicAdminAPI.Datadir":                                                      "Datadir retrieves the current data directory the node is using.",
+	"github.com/ionchain/ionchain-core/node.publicAdminAPI.NodeInfo":                                                     "NodeInfo retrieves all the information we know about the host node at the\nprotocol granularity.",
+	"github.com/ionchain/ionchain-core/node.publicAdminAPI.Peers":                                                        "Peers retrieves all the information we know about each individual peer at the\nprotocol granularity.",
+	"github.com/ionchain/ionchain-core/node.publicWeb3API.ClientVersion":                                                 "ClientVersion returns the node name",
+	"github.com/ionchain/ionchain-core/node.publicWeb3API.Sha3":                                                          "Sha3 applies the ethereum sha3 implementation on the input.\nIt assumes the input is hex encoded.",
+	"github.com/ionchain/ionchain-core/rpc.RPCService.Discover":                                                          "Discover returns the OpenRPC discovery document of the server. It is also\nserved under the rpc.discover method name reserved by OpenRPC.",
+	"github.com/ionchain/ionchain-core/rpc.RPCService.Modules":                                                           "Modules returns the list of RPC services with their version number",
+}
@@ -0,0 +1,384 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+	"github.com/ionchain/ionchain-core/log"
+)
+
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// Endpoint is an RPC server a resilient client can connect to.
+type Endpoint struct {
+	name    string
+	connect reconnectFunc
+}
+
+// URLEndpoint creates an endpoint for a WebSocket ("ws://", "wss://") URL or an IPC
+// path. HTTP endpoints are not supported because they have no connection to keep.
+func URLEndpoint(rawurl string) (Endpoint, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return Endpoint{}, err
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+		dialer := websocket.Dialer{
+			ReadBufferSize:  wsReadBuffer,
+			WriteBufferSize: wsWriteBuffer,
+			WriteBufferPool: wsBufferPool,
+		}
+		connect, err := wsConnect(rawurl, "", dialer)
+		if err != nil {
+			return Endpoint{}, err
+		}
+		u.User = nil // don't leak credentials into logs and events
+		return Endpoint{name: u.String(), connect: connect}, nil
+	case "":
+		return Endpoint{name: rawurl, connect: ipcConnect(rawurl)}, nil
+	default:
+		return Endpoint{}, fmt.Errorf("no resilient transport for URL scheme %q", u.Scheme)
+	}
+}
+
+// InProcEndpoint creates an endpoint which attaches in-process connections to the
+// given server.
+func InProcEndpoint(server *Server) Endpoint {
+	return Endpoint{name: "inproc", connect: inprocConnect(server)}
+}
+
+// String returns the URL or path of the endpoint.
+func (e Endpoint) String() string {
+	return e.name
+}
+
+// ResilientConfig configures the reconnection behavior of a resilient client.
+type ResilientConfig struct {
+	// MinBackoff and MaxBackoff bound the delay between rounds of connection attempts.
+	// The delay doubles after every round in which no endpoint could be reached, and
+	// after connections dropping within MaxBackoff of being established.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Events, if set, receives a ConnectionEvent whenever the connection is lost or
+	// re-established. Events are dropped if the channel is not ready to receive.
+	Events chan<- ConnectionEvent
+}
+
+// ConnectionEvent reports a connection change of a resilient client.
+type ConnectionEvent struct {
+	Connected bool   // false when the connection was lost, true when re-established
+	Endpoint  string // endpoint the client was or is connected to
+	Err       error  // error that caused the connection loss
+
+	// Resumed contains the subscriptions that were re-established on the new
+	// connection. Notifications sent by the server between losing the connection
+	// and resuming the subscriptions are lost. Gap is the length of that period.
+	// Log subscriptions backfilling from a block are the exception: they resume
+	// from the block of the last log received, skipping the logs received already.
+	// Subscriptions that the new server refuses end with the error on their Err
+	// channel instead.
+	Resumed []*ClientSubscription
+	Gap     time.Duration
+}
+
+// DialResilient creates a client which keeps a connection to one of the given
+// endpoints. When the connection is lost, the client reconnects in the background,
+// trying the endpoints in turn starting with the one after the failed endpoint, and
+// re-establishes all active subscriptions.
+//
+// Calls that are in flight when the connection is lost fail, since they may or may
+// not have been executed. Calls made while reconnecting wait for the connection
+// until their context expires.
+//
+// The context is used for the initial connection establishment, which tries every
+// endpoint once. It does not affect subsequent interactions with the client.
+func DialResilient(ctx context.Context, config ResilientConfig, endpoints ...Endpoint) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no endpoints given")
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultMinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = defaultMaxBackoff
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+	r := &resilience{config: config, endpoints: endpoints}
+	conn, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := makeClient(conn, randomIDGenerator(), new(serviceRegistry), nil, nil)
+	c.reconnectFunc = r.connect
+	c.resilience = r
+	go c.dispatch(conn)
+	return c, nil
+}
+
+// resilience is the reconnection state of a resilient client.
+type resilience struct {
+	config    ResilientConfig
+	endpoints []Endpoint
+
+	// These fields are accessed with the client's write lock held.
+	next      int           // index of the endpoint to try next
+	delay     time.Duration // delay before the next round of connection attempts
+	connected time.Time     // when the current connection was established
+
+	resumeMu sync.Mutex // serializes subscription resumption
+
+	mu       sync.Mutex
+	endpoint string                // endpoint of the current connection
+	orphans  []*ClientSubscription // subscriptions waiting to be resumed
+}
+
+// dial tries to connect to each endpoint once.
+func (r *resilience) dial(ctx context.Context) (ServerCodec, error) {
+	var err error
+	for range r.endpoints {
+		e := r.endpoints[r.next]
+		r.next = (r.next + 1) % len(r.endpoints)
+
+		var conn ServerCodec
+		if conn, err = e.connect(ctx); err == nil {
+			r.connected = time.Now()
+			r.mu.Lock()
+			r.endpoint = e.name
+			r.mu.Unlock()
+			return conn, nil
+		}
+		log.Debug("RPC endpoint unavailable", "endpoint", e.name, "err", err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+// connect is the reconnectFunc of resilient clients. It tries the endpoints with
+// exponential backoff until one is reachable or the context is canceled.
+func (r *resilience) connect(ctx context.Context) (ServerCodec, error) {
+	// Treat connections which didn't last as failed attempts.
+	if !r.connected.IsZero() {
+		if time.Since(r.connected) < r.config.MaxBackoff {
+			r.backoff()
+		} else {
+			r.delay = 0
+		}
+		r.connected = time.Time{}
+	}
+	for {
+		if r.delay > 0 {
+			timer := time.NewTimer(r.delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			}
+		}
+		conn, err := r.dial(ctx)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		r.backoff()
+	}
+}
+
+func (r *resilience) backoff() {
+	r.delay *= 2
+	if r.delay < r.config.MinBackoff {
+		r.delay = r.config.MinBackoff
+	}
+	if r.delay > r.config.MaxBackoff {
+		r.delay = r.config.MaxBackoff
+	}
+}
+
+func (r *resilience) currentEndpoint() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.endpoint
+}
+
+func (r *resilience) addOrphans(subs []*ClientSubscription) {
+	r.mu.Lock()
+	r.orphans = append(r.orphans, subs...)
+	r.mu.Unlock()
+}
+
+func (r *resilience) takeOrphans() []*ClientSubscription {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subs := r.orphans
+	r.orphans = nil
+	return subs
+}
+
+// emit delivers an event if the receiver is ready.
+func (r *resilience) emit(ev ConnectionEvent) {
+	if r.config.Events == nil {
+		return
+	}
+	select {
+	case r.config.Events <- ev:
+	default:
+	}
+}
+
+// resumeSubscriptions re-establishes the subscriptions of a resilient client on the
+// connection to endpoint, which was lost at lostAt.
+func (c *Client) resumeSubscriptions(endpoint string, lostAt time.Time) {
+	r := c.resilience
+	r.resumeMu.Lock()
+	defer r.resumeMu.Unlock()
+
+	var resumed []*ClientSubscription
+	for _, sub := range r.takeOrphans() {
+		select {
+		case <-sub.quit:
+			continue // unsubscribed while disconnected
+		default:
+		}
+		err := c.resubscribe(sub)
+		switch {
+		case err == nil:
+			resumed = append(resumed, sub)
+		case isConnectionError(err):
+			// Lost the new connection as well, retry on the next one.
+			select {
+			case <-c.closing:
+				sub.quitWithError(false, ErrClientQuit)
+			default:
+				r.addOrphans([]*ClientSubscription{sub})
+			}
+		default:
+			log.Debug("RPC subscription not resumed", "namespace", sub.namespace, "err", err)
+			sub.quitWithError(false, err)
+		}
+	}
+	ev := ConnectionEvent{Connected: true, Endpoint: endpoint, Resumed: resumed}
+	if !lostAt.IsZero() {
+		ev.Gap = time.Since(lostAt)
+	}
+	log.Debug("RPC client connection re-established", "endpoint", endpoint, "resumed", len(resumed), "gap", ev.Gap)
+	r.emit(ev)
+}
+
+// resubscribe repeats the subscribe call of sub on the current connection.
+func (c *Client) resubscribe(sub *ClientSubscription) error {
+	sub.mu.Lock()
+	params, skip := resumeLogs(sub.namespace, sub.params, sub.last)
+	sub.skip = skip
+	sub.mu.Unlock()
+
+	msg := &jsonrpcMessage{Version: vsn, ID: c.nextID(), Method: sub.namespace + subscribeMethodSuffix, Params: params}
+	op := &requestOp{
+		ids:    []json.RawMessage{msg.ID},
+		resp:   make(chan *jsonrpcMessage),
+		sub:    sub,
+		resume: true,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
+	defer cancel()
+
+	if err := c.send(ctx, op, msg); err != nil {
+		return err
+	}
+	_, err := op.wait(ctx, c)
+	return err
+}
+
+// resumedLog is the position of a log notification.
+type resumedLog struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	Index       hexutil.Uint   `json:"logIndex"`
+	Removed     bool           `json:"removed"`
+}
+
+// resumeLogs returns the parameters resuming a subscription. Log subscriptions
+// backfilling from a block are resumed from the block of the last log received
+// instead, along with a filter skipping the logs of that block up to that log.
+// Other subscriptions repeat their original parameters.
+func resumeLogs(namespace string, params, last json.RawMessage) (json.RawMessage, func(json.RawMessage) bool) {
+	if namespace != "eth" || last == nil {
+		return params, nil
+	}
+	var args []json.RawMessage
+	if err := json.Unmarshal(params, &args); err != nil || len(args) != 2 {
+		return params, nil
+	}
+	var kind string
+	if err := json.Unmarshal(args[0], &kind); err != nil || kind != "logs" {
+		return params, nil
+	}
+	var crit map[string]json.RawMessage
+	if err := json.Unmarshal(args[1], &crit); err != nil {
+		return params, nil
+	}
+	var from BlockNumber
+	if raw, ok := crit["fromBlock"]; !ok || json.Unmarshal(raw, &from) != nil || from < 0 {
+		return params, nil // not backfilling, nothing to repeat
+	}
+	var pos resumedLog
+	if err := json.Unmarshal(last, &pos); err != nil || uint64(pos.BlockNumber) < uint64(from) {
+		return params, nil
+	}
+	crit["fromBlock"], _ = json.Marshal(pos.BlockNumber)
+	args[1], _ = json.Marshal(crit)
+	resumed, _ := json.Marshal(args)
+
+	skip := func(result json.RawMessage) bool {
+		var log resumedLog
+		if err := json.Unmarshal(result, &log); err != nil || log.Removed || pos.Removed {
+			return false
+		}
+		return log.BlockHash == pos.BlockHash && log.Index <= pos.Index
+	}
+	return resumed, skip
+}
+
+// isConnectionError reports whether err is caused by the connection rather than
+// by the server refusing the request.
+func isConnectionError(err error) bool {
+	switch err.(type) {
+	case Error, *json.UnmarshalTypeError, *json.SyntaxError:
+		return false
+	}
+	return err != ErrClientQuit && err != context.DeadlineExceeded
+}
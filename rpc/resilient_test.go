@@ -0,0 +1,270 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ionchain/ionchain-core/common"
+	"github.com/ionchain/ionchain-core/common/hexutil"
+)
+
+// resilientTestService serves a counter subscription and a log subscription
+// backfilling the logs of a fake chain, two per block.
+type resilientTestService struct {
+	mu    sync.Mutex
+	head  uint64        // last block of the fake chain
+	froms []BlockNumber // starting blocks of the log subscriptions
+}
+
+func (s *resilientTestService) Echo(str string) string {
+	return str
+}
+
+func (s *resilientTestService) Count(ctx context.Context) (*Subscription, error) {
+	notifier, _ := NotifierFromContext(ctx)
+	sub := notifier.CreateSubscription()
+	go func() {
+		for i := 0; ; i++ {
+			if err := notifier.Notify(sub.ID, i); err != nil {
+				return
+			}
+			select {
+			case <-time.After(time.Millisecond):
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+type resilientTestCriteria struct {
+	FromBlock BlockNumber `json:"fromBlock"`
+}
+
+func (s *resilientTestService) Logs(ctx context.Context, crit resilientTestCriteria) (*Subscription, error) {
+	notifier, _ := NotifierFromContext(ctx)
+	sub := notifier.CreateSubscription()
+
+	s.mu.Lock()
+	s.froms = append(s.froms, crit.FromBlock)
+	head := s.head
+	s.mu.Unlock()
+
+	for n := uint64(crit.FromBlock); n <= head; n++ {
+		for i := 0; i < 2; i++ {
+			notifier.Notify(sub.ID, testResumedLog(n, i))
+		}
+	}
+	return sub, nil
+}
+
+func testResumedLog(number uint64, index int) resumedLog {
+	return resumedLog{
+		BlockNumber: hexutil.Uint64(number),
+		BlockHash:   common.Hash{byte(number + 1)},
+		Index:       hexutil.Uint(index),
+	}
+}
+
+// dropServerConns closes all connections of a server.
+func dropServerConns(server *Server) {
+	server.codecs.Each(func(c interface{}) bool {
+		c.(ServerCodec).close()
+		return false
+	})
+}
+
+// waitReconnect waits for a resilient client to report losing and re-establishing
+// its connection.
+func waitReconnect(t *testing.T, events chan ConnectionEvent, resumed int) {
+	t.Helper()
+	for _, connected := range []bool{false, true} {
+		select {
+		case ev := <-events:
+			if ev.Connected != connected {
+				t.Fatalf("connection event mismatch: have connected %v, want %v", ev.Connected, connected)
+			}
+			if connected && len(ev.Resumed) != resumed {
+				t.Fatalf("resumed subscription count mismatch: have %d, want %d", len(ev.Resumed), resumed)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for connection event %v", connected)
+		}
+	}
+}
+
+func dialResilientTest(t *testing.T, server *Server) (*Client, chan ConnectionEvent) {
+	events := make(chan ConnectionEvent, 16)
+	config := ResilientConfig{MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond, Events: events}
+
+	client, err := DialResilient(context.Background(), config, InProcEndpoint(server))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	return client, events
+}
+
+// Tests that a resilient client reconnects to an in-process server dropping its
+// connections, resuming the subscriptions and serving calls afterwards.
+func TestResilientInProcReconnect(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("nftest", new(resilientTestService)); err != nil {
+		t.Fatal(err)
+	}
+	client, events := dialResilientTest(t, server)
+	defer client.Close()
+
+	counts := make(chan int, 100)
+	sub, err := client.Subscribe(context.Background(), "nftest", counts, "count")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	for i := 0; i < 4; i++ {
+		if i > 0 {
+			dropServerConns(server)
+			waitReconnect(t, events, 1)
+		}
+		select {
+		case <-counts:
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed after %d reconnects: %v", i, err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no notification after %d reconnects", i)
+		}
+		var result string
+		if err := client.Call(&result, "nftest_echo", "hello"); err != nil || result != "hello" {
+			t.Fatalf("call failed after %d reconnects: %q, %v", i, result, err)
+		}
+	}
+}
+
+// Tests that a log subscription backfilling from a block is resumed from the
+// block of the last log received, without repeating the logs received already.
+func TestResilientResumeLogs(t *testing.T) {
+	var (
+		server  = NewServer()
+		service = &resilientTestService{head: 3}
+	)
+	defer server.Stop()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatal(err)
+	}
+	client, events := dialResilientTest(t, server)
+	defer client.Close()
+
+	logs := make(chan resumedLog, 100)
+	sub, err := client.EthSubscribe(context.Background(), logs, "logs", map[string]interface{}{"fromBlock": "0x0"})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	expect := func(from, to uint64) {
+		t.Helper()
+		for n := from; n <= to; n++ {
+			for i := 0; i < 2; i++ {
+				select {
+				case log := <-logs:
+					if want := testResumedLog(n, i); log != want {
+						t.Fatalf("log mismatch: have %+v, want %+v", log, want)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timed out waiting for log %d of block %d", i, n)
+				}
+			}
+		}
+		select {
+		case log := <-logs:
+			t.Fatalf("unexpected log %+v", log)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	expect(0, 3)
+
+	service.mu.Lock()
+	service.head = 5
+	service.mu.Unlock()
+
+	dropServerConns(server)
+	waitReconnect(t, events, 1)
+	expect(4, 5)
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if len(service.froms) != 2 || service.froms[0] != 0 || service.froms[1] != 3 {
+		t.Errorf("subscription starting blocks mismatch: have %v, want [0 3]", service.froms)
+	}
+}
+
+func TestResumeLogsParams(t *testing.T) {
+	last, _ := json.Marshal(testResumedLog(5, 1))
+	tests := []struct {
+		namespace string
+		params    string
+		last      json.RawMessage
+		want      string
+	}{
+		{"eth", `["newHeads"]`, last, `["newHeads"]`},
+		{"eth", `["logs",{"fromBlock":"0x2"}]`, nil, `["logs",{"fromBlock":"0x2"}]`},
+		{"eth", `["logs",{"address":[]}]`, last, `["logs",{"address":[]}]`},
+		{"eth", `["logs",{"fromBlock":"latest"}]`, last, `["logs",{"fromBlock":"latest"}]`},
+		{"eth", `["logs",{"fromBlock":"0x7"}]`, last, `["logs",{"fromBlock":"0x7"}]`},
+		{"shh", `["logs",{"fromBlock":"0x2"}]`, last, `["logs",{"fromBlock":"0x2"}]`},
+		{"eth", `["logs",{"fromBlock":"0x2","topics":[]}]`, last, `["logs",{"fromBlock":"0x5","topics":[]}]`},
+		{"eth", `["logs",{"fromBlock":"earliest"}]`, last, `["logs",{"fromBlock":"0x5"}]`},
+	}
+	for i, tt := range tests {
+		params, skip := resumeLogs(tt.namespace, json.RawMessage(tt.params), tt.last)
+		if string(params) != tt.want {
+			t.Errorf("test %d: params mismatch: have %s, want %s", i, params, tt.want)
+		}
+		if (skip != nil) != (tt.params != tt.want) {
+			t.Errorf("test %d: skip filter mismatch: have %v", i, skip != nil)
+		}
+	}
+	// The filter must only skip the logs of the resumed block up to the last one
+	_, skip := resumeLogs("eth", json.RawMessage(`["logs",{"fromBlock":"0x2"}]`), last)
+
+	removed := testResumedLog(5, 0)
+	removed.Removed = true
+	for _, tt := range []struct {
+		log  resumedLog
+		skip bool
+	}{
+		{testResumedLog(5, 0), true},
+		{testResumedLog(5, 1), true},
+		{testResumedLog(5, 2), false},
+		{testResumedLog(6, 0), false},
+		{removed, false},
+	} {
+		result, _ := json.Marshal(tt.log)
+		if have := skip(result); have != tt.skip {
+			t.Errorf("log %+v: skip mismatch: have %v, want %v", tt.log, have, tt.skip)
+		}
+	}
+}
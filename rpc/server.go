@@ -46,6 +46,8 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+	limiter  *Limiter
+	stats    *CallStats
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -66,6 +68,18 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetLimiter restricts the resources the clients of the server may consume. It
+// must be called before serving any request.
+func (s *Server) SetLimiter(limiter *Limiter) {
+	s.limiter = limiter
+}
+
+// SetCallStats makes the server record the latency of the calls it serves in stats
+// and log the slow ones. It must be called before serving any request.
+func (s *Server) SetCallStats(stats *CallStats) {
+	s.stats = stats
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
@@ -83,7 +97,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, s.limiter, s.stats)
 	<-codec.closed()
 	c.Close()
 }
@@ -99,6 +113,8 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 
 	h := newHandler(ctx, codec, s.idgen, &s.services)
 	h.allowSubscribe = false
+	h.limiter = s.limiter
+	h.stats = s.stats
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
@@ -145,3 +161,9 @@ func (s *RPCService) Modules() map[string]string {
 	}
 	return modules
 }
+
+// Discover returns the OpenRPC discovery document of the server. It is also
+// served under the rpc.discover method name reserved by OpenRPC.
+func (s *RPCService) Discover() *OpenRPCDocument {
+	return s.server.services.discover()
+}
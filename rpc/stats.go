@@ -0,0 +1,118 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CallStats collects the latency of the calls served by one or more servers. It
+// also flags calls taking longer than a threshold, which the servers log.
+type CallStats struct {
+	slowThreshold time.Duration // zero if slow calls aren't flagged
+
+	mu      sync.Mutex
+	methods map[string]*MethodStats
+}
+
+// MethodStats are the statistics of the calls to a method. Durations are in
+// nanoseconds.
+type MethodStats struct {
+	Method   string        `json:"method"`
+	Calls    uint64        `json:"calls"`
+	Failures uint64        `json:"failures"`
+	Slow     uint64        `json:"slow"` // calls over the slow threshold
+	Total    time.Duration `json:"total"`
+	Mean     time.Duration `json:"mean"`
+	Max      time.Duration `json:"max"`
+}
+
+// NewCallStats creates a collector which flags calls taking longer than slowThreshold
+// as slow. A zero threshold disables the detection of slow calls.
+func NewCallStats(slowThreshold time.Duration) *CallStats {
+	return &CallStats{
+		slowThreshold: slowThreshold,
+		methods:       make(map[string]*MethodStats),
+	}
+}
+
+// record adds a served call to the statistics and reports whether it was slow.
+func (s *CallStats) record(method string, elapsed time.Duration, failed bool) bool {
+	slow := s.slowThreshold > 0 && elapsed >= s.slowThreshold
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.methods[method]
+	if m == nil {
+		m = &MethodStats{Method: method}
+		s.methods[method] = m
+	}
+	m.Calls++
+	if failed {
+		m.Failures++
+	}
+	if slow {
+		m.Slow++
+	}
+	m.Total += elapsed
+	if elapsed > m.Max {
+		m.Max = elapsed
+	}
+	return slow
+}
+
+// Top returns the statistics of the n slowest methods, ordered by the number of slow
+// calls and then by mean latency. All methods are returned if n is not positive.
+func (s *CallStats) Top(n int) []MethodStats {
+	s.mu.Lock()
+	stats := make([]MethodStats, 0, len(s.methods))
+	for _, m := range s.methods {
+		m := *m
+		m.Mean = m.Total / time.Duration(m.Calls)
+		stats = append(stats, m)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Slow != stats[j].Slow {
+			return stats[i].Slow > stats[j].Slow
+		}
+		if stats[i].Mean != stats[j].Mean {
+			return stats[i].Mean > stats[j].Mean
+		}
+		return stats[i].Method < stats[j].Method
+	})
+	if n > 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// paramsDigest identifies the parameters of a call in logs without revealing them.
+func paramsDigest(params json.RawMessage) string {
+	if len(params) == 0 {
+		return ""
+	}
+	h := sha256.Sum256(params)
+	return hex.EncodeToString(h[:8])
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 The go-ionchain Authors
+// This file is part of the go-ionchain library.
+//
+// The go-ionchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ionchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ionchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ionchain/ionchain-core/metrics"
+)
+
+// statsTestService serves calls succeeding or failing on demand.
+type statsTestService struct{}
+
+func (s *statsTestService) Call(fail bool) error {
+	if fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+// Tests that calls at or over the threshold are flagged as slow, and that the
+// methods are ordered by slow calls, then by mean latency, then by name.
+func TestCallStatsTop(t *testing.T) {
+	stats := NewCallStats(100 * time.Millisecond)
+
+	for _, call := range []struct {
+		method  string
+		elapsed time.Duration
+		failed  bool
+		slow    bool
+	}{
+		{"a", 50 * time.Millisecond, false, false},
+		{"a", 150 * time.Millisecond, true, true},
+		{"b", 90 * time.Millisecond, false, false},
+		{"c", 100 * time.Millisecond, false, true},
+		{"c", 120 * time.Millisecond, true, true},
+		{"d", 90 * time.Millisecond, false, false},
+	} {
+		if slow := stats.record(call.method, call.elapsed, call.failed); slow != call.slow {
+			t.Errorf("%s in %v: slow mismatch: have %v, want %v", call.method, call.elapsed, slow, call.slow)
+		}
+	}
+	want := []MethodStats{
+		{Method: "c", Calls: 2, Failures: 1, Slow: 2, Total: 220 * time.Millisecond, Mean: 110 * time.Millisecond, Max: 120 * time.Millisecond},
+		{Method: "a", Calls: 2, Failures: 1, Slow: 1, Total: 200 * time.Millisecond, Mean: 100 * time.Millisecond, Max: 150 * time.Millisecond},
+		{Method: "b", Calls: 1, Total: 90 * time.Millisecond, Mean: 90 * time.Millisecond, Max: 90 * time.Millisecond},
+		{Method: "d", Calls: 1, Total: 90 * time.Millisecond, Mean: 90 * time.Millisecond, Max: 90 * time.Millisecond},
+	}
+	for _, n := range []int{-1, 0, 2, 4, 10} {
+		top := stats.Top(n)
+
+		count := len(want)
+		if n > 0 && n < count {
+			count = n
+		}
+		if len(top) != count {
+			t.Errorf("top %d: length mismatch: have %d, want %d", n, len(top), count)
+			continue
+		}
+		for i := range top {
+			if top[i] != want[i] {
+				t.Errorf("top %d: entry %d mismatch: have %+v, want %+v", n, i, top[i], want[i])
+			}
+		}
+	}
+}
+
+// Tests that no call is flagged as slow without a threshold.
+func TestCallStatsNoThreshold(t *testing.T) {
+	stats := NewCallStats(0)
+	if stats.record("a", time.Hour, false) {
+		t.Errorf("call flagged as slow without a threshold")
+	}
+	if top := stats.Top(0); len(top) != 1 || top[0].Slow != 0 || top[0].Calls != 1 {
+		t.Errorf("stats mismatch: have %+v", top)
+	}
+}
+
+// Tests that the served calls are recorded in the statistics of the server and
+// in the latency histograms of their methods.
+func TestCallStatsServer(t *testing.T) {
+	defer func(enabled bool) { metrics.Enabled = enabled }(metrics.Enabled)
+	metrics.Enabled = true
+
+	stats := NewCallStats(time.Hour)
+	server := NewServer()
+	server.SetCallStats(stats)
+	if err := server.RegisterName("stats", new(statsTestService)); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer server.Stop()
+	defer client.Close()
+
+	for i := 0; i < 4; i++ {
+		if err := client.Call(nil, "stats_call", i == 3); (err != nil) != (i == 3) {
+			t.Fatalf("call %d: error mismatch: %v", i, err)
+		}
+	}
+	top := stats.Top(0)
+	if len(top) != 1 || top[0].Method != "stats_call" || top[0].Calls != 4 || top[0].Failures != 1 || top[0].Slow != 0 {
+		t.Errorf("stats mismatch: have %+v", top)
+	}
+	histogram, ok := metrics.DefaultRegistry.Get("rpc/latency/stats_call").(metrics.Histogram)
+	if !ok {
+		t.Fatalf("latency histogram not registered")
+	}
+	if count := histogram.Count(); count != 4 {
+		t.Errorf("latency histogram count mismatch: have %d, want 4", count)
+	}
+}
@@ -99,6 +99,7 @@ type Notifier struct {
 	buffer       []json.RawMessage
 	callReturned bool
 	activated    bool
+	active       chan struct{} // closed on activation
 }
 
 // CreateSubscription returns a new subscription that is coupled to the
@@ -141,6 +142,14 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	return nil
 }
 
+// Active returns a channel that is closed when the subscription is activated, after
+// the subscription ID was sent to the client. Notifications sent before are buffered
+// without limit, so subscriptions starting with many notifications should wait for
+// activation before sending them.
+func (n *Notifier) Active() <-chan struct{} {
+	return n.active
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 // Deprecated: use subscription error channel
 func (n *Notifier) Closed() <-chan interface{} {
@@ -169,6 +178,7 @@ func (n *Notifier) activate() error {
 		}
 	}
 	n.activated = true
+	close(n.active)
 	return nil
 }
 
@@ -207,9 +217,14 @@ type ClientSubscription struct {
 	etype     reflect.Type
 	channel   reflect.Value
 	namespace string
-	subid     string
+	params    json.RawMessage // subscribe parameters, used for resuming
 	in        chan json.RawMessage
 
+	mu    sync.Mutex // protects the fields below, which change when resumed
+	subid string
+	last  json.RawMessage            // last notification received, used for resuming
+	skip  func(json.RawMessage) bool // drops notifications repeated after resuming
+
 	quitOnce sync.Once     // ensures quit is closed once
 	quit     chan struct{} // quit is closed when the subscription exits
 	errOnce  sync.Once     // ensures err is closed once
@@ -267,6 +282,9 @@ func (sub *ClientSubscription) quitWithError(unsubscribeServer bool, err error)
 }
 
 func (sub *ClientSubscription) deliver(result json.RawMessage) (ok bool) {
+	if sub.client.resilience != nil && !sub.track(result) {
+		return true
+	}
 	select {
 	case sub.in <- result:
 		return true
@@ -275,6 +293,22 @@ func (sub *ClientSubscription) deliver(result json.RawMessage) (ok bool) {
 	}
 }
 
+// track records the last notification of a resilient client's subscription,
+// reporting whether it is new rather than repeated after resuming.
+func (sub *ClientSubscription) track(result json.RawMessage) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.skip != nil {
+		if sub.skip(result) {
+			return false
+		}
+		sub.skip = nil
+	}
+	sub.last = result
+	return true
+}
+
 func (sub *ClientSubscription) start() {
 	sub.quitWithError(sub.forward())
 }
@@ -326,5 +360,17 @@ func (sub *ClientSubscription) unmarshal(result json.RawMessage) (interface{}, e
 
 func (sub *ClientSubscription) requestUnsubscribe() error {
 	var result interface{}
-	return sub.client.Call(&result, sub.namespace+unsubscribeMethodSuffix, sub.subid)
+	return sub.client.Call(&result, sub.namespace+unsubscribeMethodSuffix, sub.id())
+}
+
+func (sub *ClientSubscription) id() string {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.subid
+}
+
+func (sub *ClientSubscription) setID(id string) {
+	sub.mu.Lock()
+	sub.subid = id
+	sub.mu.Unlock()
 }
@@ -59,6 +59,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn)
+		codec.(*websocketCodec).grant = GrantFromContext(r.Context())
 		s.ServeCodec(codec, 0)
 	})
 }
@@ -182,11 +183,19 @@ func parseOriginURL(origin string) (string, string, string, error) {
 // DialWebsocketWithDialer creates a new RPC client that communicates with a JSON-RPC server
 // that is listening on the given endpoint using the provided dialer.
 func DialWebsocketWithDialer(ctx context.Context, endpoint, origin string, dialer websocket.Dialer) (*Client, error) {
+	connect, err := wsConnect(endpoint, origin, dialer)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(ctx, connect)
+}
+
+func wsConnect(endpoint, origin string, dialer websocket.Dialer) (reconnectFunc, error) {
 	endpoint, header, err := wsClientHeaders(endpoint, origin)
 	if err != nil {
 		return nil, err
 	}
-	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+	return func(ctx context.Context) (ServerCodec, error) {
 		conn, resp, err := dialer.DialContext(ctx, endpoint, header)
 		if err != nil {
 			hErr := wsHandshakeError{err: err}
@@ -196,7 +205,7 @@ func DialWebsocketWithDialer(ctx context.Context, endpoint, origin string, diale
 			return nil, hErr
 		}
 		return newWebsocketCodec(conn), nil
-	})
+	}, nil
 }
 
 // DialWebsocket creates a new RPC client that communicates with a JSON-RPC server
@@ -232,7 +241,8 @@ func wsClientHeaders(endpoint, origin string) (string, http.Header, error) {
 
 type websocketCodec struct {
 	*jsonCodec
-	conn *websocket.Conn
+	conn  *websocket.Conn
+	grant *Grant // Methods the client authenticated for, nil if unrestricted
 
 	wg        sync.WaitGroup
 	pingReset chan struct{}